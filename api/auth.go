@@ -0,0 +1,344 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/config"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey gin上下文中保存调用方身份的键
+const principalKey = "nofx_principal"
+
+// Principal 已认证的调用方
+type Principal struct {
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	Method string `json:"method"` // "api_key"、"jwt" 或 "anonymous"
+}
+
+// canAccess 调用方角色是否满足要求（operator 包含只读权限）
+func (p *Principal) canAccess(required string) bool {
+	if required == config.RoleReadOnly {
+		return p.Role == config.RoleReadOnly || p.Role == config.RoleOperator
+	}
+	return p.Role == required
+}
+
+// authenticator 基于API Key或JWT的认证器（配置热加载时整体替换）
+type authenticator struct {
+	cfg   config.APIAuthConfig
+	audit *auditLogger
+}
+
+// newAuthenticator 创建认证器；prev 的审计日志文件与新配置相同时复用，避免重复打开文件
+func newAuthenticator(cfg config.APIAuthConfig, prev *authenticator) *authenticator {
+	a := &authenticator{cfg: cfg}
+	if prev != nil && prev.audit != nil && prev.cfg.AuditLogFile == cfg.AuditLogFile {
+		a.audit = prev.audit
+		return a
+	}
+	if cfg.AuditLogFile != "" {
+		audit, err := newAuditLogger(cfg.AuditLogFile)
+		if err != nil {
+			log.Printf("⚠️  打开审计日志失败，审计记录将输出到标准日志: %v", err)
+		} else {
+			a.audit = audit
+		}
+	}
+	return a
+}
+
+// authenticate 从请求中识别调用方
+// 凭证来源（按优先级）: X-API-Key 头、Authorization: Bearer <api_key|jwt>、access_token 查询参数（供SSE等无法设置请求头的客户端使用）
+// 未携带凭证返回 (nil, nil)，凭证无效返回错误
+func (a *authenticator) authenticate(c *gin.Context) (*Principal, error) {
+	token := c.GetHeader("X-API-Key")
+	if token == "" {
+		if auth := c.GetHeader("Authorization"); auth != "" {
+			const prefix = "Bearer "
+			if !strings.HasPrefix(auth, prefix) {
+				return nil, fmt.Errorf("Authorization头格式无效，应为 'Bearer <token>'")
+			}
+			token = strings.TrimSpace(auth[len(prefix):])
+		}
+	}
+	if token == "" {
+		token = c.Query("access_token")
+	}
+	if token == "" {
+		return nil, nil
+	}
+
+	// JWT由三段组成，其余按API Key处理
+	if strings.Count(token, ".") == 2 {
+		if a.cfg.JWTSecret == "" {
+			return nil, fmt.Errorf("未启用JWT认证")
+		}
+		return verifyJWT(token, a.cfg.JWTSecret, time.Now())
+	}
+
+	for _, k := range a.cfg.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(k.Key)) == 1 {
+			return &Principal{Name: k.Name, Role: k.Role, Method: "api_key"}, nil
+		}
+	}
+	return nil, fmt.Errorf("API Key无效")
+}
+
+// authenticator 获取当前生效的认证器
+func (s *Server) authenticator() *authenticator {
+	s.securityMu.RLock()
+	defer s.securityMu.RUnlock()
+	return s.auth
+}
+
+// acquireAuthenticator 获取当前生效的认证器，并把请求登记为其审计日志的使用者
+// 请求结束后需调用返回的 release；配置热加载换掉审计日志文件时，旧文件等所有使用者释放后才关闭
+func (s *Server) acquireAuthenticator() (*authenticator, func()) {
+	s.securityMu.RLock()
+	defer s.securityMu.RUnlock()
+
+	a := s.auth
+	if a.audit == nil {
+		return a, func() {}
+	}
+	a.audit.inflight.Add(1)
+	return a, a.audit.inflight.Done
+}
+
+// applySecurityConfig 热更新认证和CORS配置（配置热加载回调）
+func (s *Server) applySecurityConfig(cfg *config.Config) {
+	s.securityMu.Lock()
+	defer s.securityMu.Unlock()
+
+	prev := s.auth
+	s.auth = newAuthenticator(cfg.APIAuth, prev)
+	s.corsOrigins = cfg.CORSAllowedOrigins
+	if prev.audit != nil && prev.audit != s.auth.audit {
+		// 替换后不会再有请求登记到旧审计日志，等进行中的请求写完再关闭
+		go prev.audit.closeWhenIdle()
+	}
+}
+
+// requireRole 认证中间件：校验调用方并要求指定角色
+// 未启用认证时只读接口保持公开（兼容旧版仪表盘），控制接口一律拒绝
+func (s *Server) requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		a := s.authenticator()
+		if !a.cfg.Enabled() {
+			if role != config.RoleReadOnly {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "控制接口需要先在配置中启用 api_auth"})
+				return
+			}
+			c.Set(principalKey, &Principal{Name: "anonymous", Role: config.RoleReadOnly, Method: "anonymous"})
+			c.Next()
+			return
+		}
+
+		principal, err := a.authenticate(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if principal == nil {
+			if role == config.RoleReadOnly && a.cfg.PublicRead {
+				c.Set(principalKey, &Principal{Name: "anonymous", Role: config.RoleReadOnly, Method: "anonymous"})
+				c.Next()
+				return
+			}
+			c.Header("WWW-Authenticate", `Bearer realm="nofx"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "需要认证"})
+			return
+		}
+
+		c.Set(principalKey, principal)
+		if !principal.canAccess(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("权限不足：需要 %s 角色", role),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// auditMiddleware 审计中间件：记录谁在何时调用了什么接口
+// 控制请求（非GET）和被拒绝的请求总是记录；只读请求在 audit_reads 开启时记录
+func (s *Server) auditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		// 按请求开始时生效的配置审计，请求处理期间热加载不会关闭其审计日志
+		a, release := s.acquireAuthenticator()
+		defer release()
+		c.Next()
+
+		status := c.Writer.Status()
+		if c.Request.Method == http.MethodOptions {
+			return
+		}
+		if c.Request.Method == http.MethodGet && status < 400 && !a.cfg.AuditReads {
+			return
+		}
+		// 健康检查和未匹配的路由不记录
+		if c.FullPath() == "" || c.FullPath() == "/health" {
+			return
+		}
+
+		entry := auditEntry{
+			Time:       start,
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Query:      redactQuery(c),
+			Status:     status,
+			ClientIP:   c.ClientIP(),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if v, ok := c.Get(principalKey); ok {
+			p := v.(*Principal)
+			entry.Principal, entry.Role, entry.AuthMethod = p.Name, p.Role, p.Method
+		}
+		a.audit.write(entry)
+	}
+}
+
+// redactQuery 返回去除 access_token 后的查询字符串
+func redactQuery(c *gin.Context) string {
+	q := c.Request.URL.Query()
+	if q.Has("access_token") {
+		q.Set("access_token", "***")
+	}
+	return q.Encode()
+}
+
+// auditEntry 审计日志条目
+type auditEntry struct {
+	Time       time.Time `json:"time"`
+	Principal  string    `json:"principal,omitempty"`
+	Role       string    `json:"role,omitempty"`
+	AuthMethod string    `json:"auth_method,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"`
+	Status     int       `json:"status"`
+	ClientIP   string    `json:"client_ip"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// auditLogger 以JSON Lines格式追加写入审计日志
+type auditLogger struct {
+	mu       sync.Mutex
+	file     *os.File
+	inflight sync.WaitGroup // 使用该审计日志的进行中请求
+}
+
+func newAuditLogger(path string) (*auditLogger, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("打开审计日志文件失败: %w", err)
+	}
+	return &auditLogger{file: file}, nil
+}
+
+// close 关闭审计日志文件
+func (al *auditLogger) close() {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.file.Close()
+}
+
+// closeWhenIdle 等待进行中的请求释放后关闭审计日志文件
+func (al *auditLogger) closeWhenIdle() {
+	al.inflight.Wait()
+	al.close()
+}
+
+// write 写入一条审计记录（auditLogger为nil时输出到标准日志）
+func (al *auditLogger) write(entry auditEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if al == nil {
+		log.Printf("📝 [审计] %s", data)
+		return
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if _, err := al.file.Write(append(data, '\n')); err != nil {
+		log.Printf("⚠️  写入审计日志失败: %v", err)
+	}
+}
+
+// jwtClaims 支持的JWT声明
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	Role      string `json:"role"`
+	ExpiresAt int64  `json:"exp"`
+	NotBefore int64  `json:"nbf"`
+}
+
+// verifyJWT 校验HS256签名的JWT并返回调用方身份
+func verifyJWT(token, secret string, now time.Time) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("JWT格式无效")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("JWT头部无效")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Alg != "HS256" {
+		return nil, fmt.Errorf("JWT仅支持HS256签名")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, fmt.Errorf("JWT签名无效")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("JWT载荷无效")
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("JWT载荷无效")
+	}
+
+	if claims.ExpiresAt == 0 {
+		return nil, fmt.Errorf("JWT缺少exp声明")
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, fmt.Errorf("JWT已过期")
+	}
+	if claims.NotBefore != 0 && now.Unix() < claims.NotBefore {
+		return nil, fmt.Errorf("JWT尚未生效")
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("JWT缺少sub声明")
+	}
+	if claims.Role != config.RoleReadOnly && claims.Role != config.RoleOperator {
+		return nil, fmt.Errorf("JWT的role声明无效: %s", claims.Role)
+	}
+
+	return &Principal{Name: claims.Subject, Role: claims.Role, Method: "jwt"}, nil
+}
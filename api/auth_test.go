@@ -0,0 +1,110 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"nofx/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditLines 读取审计日志中的请求路径（文件不存在时为空）
+func auditLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("读取审计日志失败: %v", err)
+	}
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		start := strings.Index(line, `"path":"`) + len(`"path":"`)
+		paths = append(paths, line[start:start+strings.Index(line[start:], `"`)])
+	}
+	return paths
+}
+
+// waitClosed 等待审计日志文件被关闭
+func waitClosed(al *auditLogger) bool {
+	for i := 0; i < 100; i++ {
+		al.mu.Lock()
+		_, err := al.file.Write(nil)
+		al.mu.Unlock()
+		if errors.Is(err, os.ErrClosed) {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestAuditLogReloadDuringRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		reloadTo  string // 请求处理期间热加载后的审计日志文件名
+		wantOld   []string
+		wantNew   []string
+		wantClose bool // 旧文件是否在请求结束后关闭
+	}{
+		{
+			name:      "请求处理期间换审计日志文件",
+			reloadTo:  "new.log",
+			wantOld:   []string{"/api/reload"},
+			wantNew:   []string{"/api/after"},
+			wantClose: true,
+		},
+		{
+			name:     "审计日志文件不变时复用",
+			reloadTo: "old.log",
+			wantOld:  []string{"/api/reload", "/api/after"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			oldPath, newPath := filepath.Join(dir, "old.log"), filepath.Join(dir, tt.reloadTo)
+
+			s := &Server{auth: newAuthenticator(config.APIAuthConfig{AuditLogFile: oldPath}, nil)}
+			old := s.auth.audit
+			t.Cleanup(func() { s.auth.audit.close() })
+
+			r := gin.New()
+			r.Use(s.auditMiddleware())
+			r.POST("/api/reload", func(c *gin.Context) {
+				s.applySecurityConfig(&config.Config{APIAuth: config.APIAuthConfig{AuditLogFile: newPath}})
+				c.Status(http.StatusOK)
+			})
+			r.POST("/api/after", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			for _, path := range []string{"/api/reload", "/api/after"} {
+				r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", path, nil))
+			}
+
+			if tt.wantClose && !waitClosed(old) {
+				t.Errorf("旧审计日志在请求结束后没有关闭")
+			}
+			if got := auditLines(t, oldPath); strings.Join(got, ",") != strings.Join(tt.wantOld, ",") {
+				t.Errorf("old audit log = %v, want %v", got, tt.wantOld)
+			}
+			if newPath != oldPath {
+				if got := auditLines(t, newPath); strings.Join(got, ",") != strings.Join(tt.wantNew, ",") {
+					t.Errorf("new audit log = %v, want %v", got, tt.wantNew)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"nofx/manager"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleLeaderboard 竞赛排行榜（评分排名、名次变化和排名历史）
+// 参数: season=赛季名称（默认当前赛季）, interval=5m|1h|1d|Go时长（默认配置的采样间隔）, history=true|false（默认true）
+func (s *Server) handleLeaderboard(c *gin.Context) {
+	var interval time.Duration
+	if v := strings.ToLower(c.Query("interval")); v != "" {
+		d, ok := equityResolutions[v]
+		if !ok {
+			var err error
+			if d, err = parseRangeDuration(v); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("interval参数无效: %s", v)})
+				return
+			}
+		}
+		if d < time.Minute {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "interval不能小于1分钟"})
+			return
+		}
+		interval = d
+	}
+
+	includeHistory := true
+	if v := c.Query("history"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("history参数无效: %s", v)})
+			return
+		}
+		includeHistory = b
+	}
+
+	leaderboard, err := s.traderManager.GetLeaderboard(c.Query("season"), interval)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, manager.ErrSeasonNotFound) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error": fmt.Sprintf("获取排行榜失败: %v", err),
+		})
+		return
+	}
+	if !includeHistory {
+		leaderboard.History = nil
+	}
+
+	c.JSON(http.StatusOK, leaderboard)
+}
+
+// handleSeasons 赛季列表
+func (s *Server) handleSeasons(c *gin.Context) {
+	seasons, err := s.traderManager.GetSeasons(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, seasons)
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"nofx/config"
+	"nofx/manager"
+	"nofx/trader"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// traderSettingsRequest 运行时参数调整请求（未提供的字段保持不变）
+type traderSettingsRequest struct {
+	ScanIntervalMinutes *int `json:"scan_interval_minutes"`
+	BTCETHLeverage      *int `json:"btc_eth_leverage"`
+	AltcoinLeverage     *int `json:"altcoin_leverage"`
+}
+
+// respondTraderControl 执行控制操作并返回trader最新状态
+func (s *Server) respondTraderControl(c *gin.Context, action string, op func(id string) error) {
+	traderID := c.Param("id")
+	if err := op(traderID); err != nil {
+		c.JSON(controlErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("%s成功", action),
+		"status":  trader.GetStatus(),
+	})
+}
+
+// controlErrorStatus trader不存在返回404，状态冲突（如暂停已暂停的trader）返回409，其余返回500
+func controlErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, manager.ErrTraderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, trader.ErrStateConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// handleStartTrader 启动trader
+func (s *Server) handleStartTrader(c *gin.Context) {
+	s.respondTraderControl(c, "启动", s.traderManager.StartTrader)
+}
+
+// handleStopTrader 停止trader
+func (s *Server) handleStopTrader(c *gin.Context) {
+	s.respondTraderControl(c, "停止", s.traderManager.StopTrader)
+}
+
+// handlePauseTrader 暂停trader
+func (s *Server) handlePauseTrader(c *gin.Context) {
+	s.respondTraderControl(c, "暂停", s.traderManager.PauseTrader)
+}
+
+// handleResumeTrader 恢复trader
+func (s *Server) handleResumeTrader(c *gin.Context) {
+	s.respondTraderControl(c, "恢复", s.traderManager.ResumeTrader)
+}
+
+// handleTriggerCycle 立即触发一个交易周期（异步执行）
+func (s *Server) handleTriggerCycle(c *gin.Context) {
+	traderID := c.Param("id")
+	if err := s.traderManager.TriggerCycle(traderID); err != nil {
+		c.JSON(controlErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "已触发交易周期"})
+}
+
+// handleUpdateTraderSettings 运行时调整扫描间隔和杠杆上限
+func (s *Server) handleUpdateTraderSettings(c *gin.Context) {
+	traderID := c.Param("id")
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req traderSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("请求体无效: %v", err)})
+		return
+	}
+	if req.ScanIntervalMinutes == nil && req.BTCETHLeverage == nil && req.AltcoinLeverage == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "至少需要提供 scan_interval_minutes、btc_eth_leverage 或 altcoin_leverage 之一"})
+		return
+	}
+
+	// 先校验全部参数，避免部分生效
+	btcEthLeverage, altcoinLeverage := trader.GetLeverageLimits()
+	if req.BTCETHLeverage != nil {
+		btcEthLeverage = *req.BTCETHLeverage
+	}
+	if req.AltcoinLeverage != nil {
+		altcoinLeverage = *req.AltcoinLeverage
+	}
+	if req.ScanIntervalMinutes != nil && *req.ScanIntervalMinutes <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scan_interval_minutes必须大于0"})
+		return
+	}
+
+	if req.BTCETHLeverage != nil || req.AltcoinLeverage != nil {
+		if err := s.traderManager.SetLeverageLimits(traderID, btcEthLeverage, altcoinLeverage); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.ScanIntervalMinutes != nil {
+		interval := time.Duration(*req.ScanIntervalMinutes) * time.Minute
+		if err := s.traderManager.SetScanInterval(traderID, interval); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "参数已更新",
+		"status":  trader.GetStatus(),
+	})
+}
+
+// handleCloseAllPositions 市价平掉trader的所有持仓
+func (s *Server) handleCloseAllPositions(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, err := s.traderManager.GetTrader(traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	actions, err := s.traderManager.CloseAllPositions(traderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   fmt.Sprintf("平仓失败: %v", err),
+			"actions": actions,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("已平仓 %d 个持仓", len(actions)),
+		"actions": actions,
+	})
+}
+
+// handleReloadConfig 重新加载配置文件（配置无效时返回400，运行中的trader不受影响）
+func (s *Server) handleReloadConfig(c *gin.Context) {
+	result, err := s.traderManager.ReloadConfig()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("重新加载配置失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// handleCreateTrader 创建并启动新的trader，同时写入配置文件
+// 请求体与配置文件中的trader配置格式相同；杠杆、风控等沿用全局配置
+func (s *Server) handleCreateTrader(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("读取请求体失败: %v", err)})
+		return
+	}
+	tc, err := config.ParseTraderConfig(body)
+	if err != nil {
+		respondConfigError(c, err)
+		return
+	}
+
+	if err := s.traderManager.CreateTrader(tc); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, manager.ErrInvalidTrader):
+			respondConfigError(c, err)
+			return
+		case errors.Is(err, manager.ErrTraderExists):
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": fmt.Sprintf("创建trader失败: %v", err)})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(tc.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "trader已创建",
+		"status":  trader.GetStatus(),
+	})
+}
+
+// handleDeleteTrader 停止并删除trader，同时从配置文件中移除（不会平仓）
+func (s *Server) handleDeleteTrader(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, err := s.traderManager.GetTrader(traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.traderManager.DeleteTrader(traderID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, manager.ErrLastTrader) || errors.Is(err, manager.ErrIndexedOverride) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": fmt.Sprintf("删除trader失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "trader已删除"})
+}
+
+// respondConfigError 返回400，配置校验错误附带逐条问题（path、message）
+func respondConfigError(c *gin.Context, err error) {
+	var ve *config.ValidationError
+	if errors.As(err, &ve) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "trader配置无效",
+			"problems": ve.Problems,
+		})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("trader配置无效: %v", err)})
+}
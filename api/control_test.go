@@ -0,0 +1,156 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"nofx/config"
+	"nofx/manager"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newControlServer 包含一个未启动trader（t1，扫描间隔3分钟，杠杆5x/5x）的服务器
+// 决策日志写在临时目录中
+func newControlServer(t *testing.T) *Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Chdir(t.TempDir())
+
+	tm := manager.NewTraderManager()
+	tc := config.TraderConfig{
+		ID:                  "t1",
+		Name:                "t1",
+		Enabled:             true,
+		AIModel:             "deepseek",
+		Exchange:            "binance",
+		InitialBalance:      1000,
+		ScanIntervalMinutes: 3,
+	}
+	leverage := config.LeverageConfig{BTCETHLeverage: 5, AltcoinLeverage: 5}
+	if err := tm.AddTrader(tc, "", 0, 0, 0, leverage, &config.Config{}); err != nil {
+		t.Fatalf("AddTrader() error = %v", err)
+	}
+	t.Cleanup(func() {
+		at, _ := tm.GetTrader("t1")
+		at.GetDecisionLogger().Close()
+	})
+	return &Server{traderManager: tm}
+}
+
+// callControl 以 id 路径参数调用处理函数
+func callControl(handler gin.HandlerFunc, id, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	c.Request = httptest.NewRequest("POST", "/api/traders/"+id, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler(c)
+	return w
+}
+
+func TestControlStateTransitions(t *testing.T) {
+	type call struct {
+		action     string // pause, resume, trigger, stop
+		id         string
+		wantStatus int
+	}
+
+	tests := []struct {
+		name  string
+		calls []call
+	}{
+		{
+			name: "暂停已暂停的trader",
+			calls: []call{
+				{"pause", "t1", http.StatusOK},
+				{"pause", "t1", http.StatusConflict},
+				{"resume", "t1", http.StatusOK},
+			},
+		},
+		{
+			name:  "恢复未暂停的trader",
+			calls: []call{{"resume", "t1", http.StatusConflict}},
+		},
+		{
+			name:  "触发已停止的trader",
+			calls: []call{{"trigger", "t1", http.StatusConflict}},
+		},
+		{
+			name:  "停止已停止的trader",
+			calls: []call{{"stop", "t1", http.StatusConflict}},
+		},
+		{
+			name: "trader不存在",
+			calls: []call{
+				{"pause", "missing", http.StatusNotFound},
+				{"resume", "missing", http.StatusNotFound},
+				{"trigger", "missing", http.StatusNotFound},
+				{"stop", "missing", http.StatusNotFound},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newControlServer(t)
+			handlers := map[string]gin.HandlerFunc{
+				"pause":   s.handlePauseTrader,
+				"resume":  s.handleResumeTrader,
+				"trigger": s.handleTriggerCycle,
+				"stop":    s.handleStopTrader,
+			}
+
+			for i, c := range tt.calls {
+				w := callControl(handlers[c.action], c.id, "")
+				if w.Code != c.wantStatus {
+					t.Fatalf("call %d %s %s: status = %d, want %d (%s)", i, c.action, c.id, w.Code, c.wantStatus, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateTraderSettings(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		body         string
+		wantStatus   int
+		wantMinutes  int // 请求后的扫描间隔（分钟）
+		wantLeverage [2]int
+	}{
+		{"调整扫描间隔", "t1", `{"scan_interval_minutes": 1}`, http.StatusOK, 1, [2]int{5, 5}},
+		{"只调整一个杠杆", "t1", `{"altcoin_leverage": 3}`, http.StatusOK, 3, [2]int{5, 3}},
+		{"同时调整", "t1", `{"scan_interval_minutes": 10, "btc_eth_leverage": 125, "altcoin_leverage": 1}`, http.StatusOK, 10, [2]int{125, 1}},
+		{"扫描间隔小于1分钟", "t1", `{"scan_interval_minutes": 0}`, http.StatusBadRequest, 3, [2]int{5, 5}},
+		{"扫描间隔为负", "t1", `{"scan_interval_minutes": -5}`, http.StatusBadRequest, 3, [2]int{5, 5}},
+		{"杠杆为0", "t1", `{"btc_eth_leverage": 0}`, http.StatusBadRequest, 3, [2]int{5, 5}},
+		{"杠杆超过上限", "t1", `{"altcoin_leverage": 126}`, http.StatusBadRequest, 3, [2]int{5, 5}},
+		// 先校验全部参数：杠杆无效时扫描间隔也不生效
+		{"部分参数无效时都不生效", "t1", `{"scan_interval_minutes": 10, "btc_eth_leverage": 10, "altcoin_leverage": 200}`, http.StatusBadRequest, 3, [2]int{5, 5}},
+		{"没有参数", "t1", `{}`, http.StatusBadRequest, 3, [2]int{5, 5}},
+		{"请求体无效", "t1", `{"scan_interval_minutes": "5"}`, http.StatusBadRequest, 3, [2]int{5, 5}},
+		{"trader不存在", "missing", `{"scan_interval_minutes": 5}`, http.StatusNotFound, 3, [2]int{5, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newControlServer(t)
+
+			w := callControl(s.handleUpdateTraderSettings, tt.id, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			at, _ := s.traderManager.GetTrader("t1")
+			if got := int(at.GetScanInterval().Minutes()); got != tt.wantMinutes {
+				t.Errorf("scan interval = %dm, want %dm", got, tt.wantMinutes)
+			}
+			if btcEth, altcoin := at.GetLeverageLimits(); [2]int{btcEth, altcoin} != tt.wantLeverage {
+				t.Errorf("leverage = %d/%d, want %v", btcEth, altcoin, tt.wantLeverage)
+			}
+		})
+	}
+}
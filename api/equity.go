@@ -0,0 +1,322 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/logger"
+	"nofx/market"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// benchmarkSymbol 基准收益（买入持有）使用的币种
+const benchmarkSymbol = "BTCUSDT"
+
+// equityResolutions 支持的降采样粒度（0表示不聚合）
+var equityResolutions = map[string]time.Duration{
+	"raw": 0,
+	"5m":  5 * time.Minute,
+	"1h":  time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+// EquityPoint 收益率历史数据点
+type EquityPoint struct {
+	Timestamp        string  `json:"timestamp"`
+	TotalEquity      float64 `json:"total_equity"`      // 账户净值（wallet + unrealized），降采样时为桶内收盘净值
+	OpenEquity       float64 `json:"open_equity"`       // 桶内开盘净值
+	HighEquity       float64 `json:"high_equity"`       // 桶内最高净值
+	LowEquity        float64 `json:"low_equity"`        // 桶内最低净值
+	AvailableBalance float64 `json:"available_balance"` // 可用余额
+	TotalPnL         float64 `json:"total_pnl"`         // 总盈亏（相对初始余额）
+	TotalPnLPct      float64 `json:"total_pnl_pct"`     // 总盈亏百分比
+	PositionCount    int     `json:"position_count"`    // 持仓数量
+	MarginUsedPct    float64 `json:"margin_used_pct"`   // 保证金使用率
+	CycleNumber      int     `json:"cycle_number"`
+	Samples          int     `json:"samples"`      // 桶内周期数
+	DrawdownPct      float64 `json:"drawdown_pct"` // 相对历史最高净值的回撤百分比（<=0）
+
+	// 基准：从区间起点以相同净值买入持有BTC（获取行情失败时为null）
+	BenchmarkEquity *float64 `json:"benchmark_equity"`
+	BenchmarkPnLPct *float64 `json:"benchmark_pnl_pct"`
+}
+
+// handleEquityHistory 收益率历史数据
+// 参数: resolution=auto|raw|5m|1h|1d（默认auto），range=1d|7d|30d|12h|all（默认all），也可用from/to指定时间范围
+func (s *Server) handleEquityHistory(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, to, err := parseEquityRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 只查询账户快照列，不加载prompt和思维链
+	records, err := trader.GetDecisionLogger().GetAccountHistory(from, to, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取历史数据失败: %v", err),
+		})
+		return
+	}
+
+	resolution, bucket, err := parseEquityResolution(c.DefaultQuery("resolution", "auto"), records)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 从AutoTrader获取初始余额（用于计算盈亏百分比）
+	initialBalance := 0.0
+	if status := trader.GetStatus(); status != nil {
+		if ib, ok := status["initial_balance"].(float64); ok && ib > 0 {
+			initialBalance = ib
+		}
+	}
+
+	bars := logger.DownsampleEquity(records, bucket)
+
+	// 如果无法从status获取，且有历史记录，则从第一条记录获取
+	if initialBalance == 0 && len(bars) > 0 {
+		// 第一条记录的equity作为初始余额
+		initialBalance = bars[0].Open
+	}
+
+	// 如果还是无法获取，返回错误
+	if initialBalance == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "无法获取初始余额",
+		})
+		return
+	}
+
+	benchmarkPrices := s.benchmarkPrices(bars, resolution)
+
+	history := make([]EquityPoint, 0, len(bars))
+	for i, bar := range bars {
+		point := EquityPoint{
+			Timestamp:        bar.Timestamp.Format("2006-01-02 15:04:05"),
+			TotalEquity:      bar.Close,
+			OpenEquity:       bar.Open,
+			HighEquity:       bar.High,
+			LowEquity:        bar.Low,
+			AvailableBalance: bar.AvailableBalance,
+			TotalPnL:         bar.TotalPnL,
+			TotalPnLPct:      (bar.TotalPnL / initialBalance) * 100,
+			PositionCount:    bar.PositionCount,
+			MarginUsedPct:    bar.MarginUsedPct,
+			CycleNumber:      bar.CycleNumber,
+			Samples:          bar.Samples,
+			DrawdownPct:      bar.DrawdownPct,
+		}
+
+		if benchmarkPrices != nil && benchmarkPrices[0] > 0 && benchmarkPrices[i] > 0 {
+			benchEquity := bars[0].Open * benchmarkPrices[i] / benchmarkPrices[0]
+			benchPnLPct := (benchEquity - initialBalance) / initialBalance * 100
+			point.BenchmarkEquity = &benchEquity
+			point.BenchmarkPnLPct = &benchPnLPct
+		}
+
+		history = append(history, point)
+	}
+
+	c.Header("X-Equity-Resolution", resolution)
+	c.JSON(http.StatusOK, history)
+}
+
+// parseEquityRange 解析 range 或 from/to 参数
+func parseEquityRange(c *gin.Context) (time.Time, time.Time, error) {
+	from, err := parseTimeParam(c.Query("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("from参数无效: %w", err)
+	}
+	to, err := parseTimeParam(c.Query("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("to参数无效: %w", err)
+	}
+
+	if r := strings.ToLower(c.Query("range")); r != "" && r != "all" {
+		span, err := parseRangeDuration(r)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		end := to
+		if end.IsZero() {
+			end = time.Now()
+		}
+		from = end.Add(-span)
+	}
+
+	return from, to, nil
+}
+
+// parseRangeDuration 解析时间跨度，支持 Nd（天）、Nw（周）以及Go时长格式（如12h）
+func parseRangeDuration(r string) (time.Duration, error) {
+	unit := r[len(r)-1]
+	if unit == 'd' || unit == 'w' {
+		n, err := strconv.Atoi(r[:len(r)-1])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("range参数无效: %s", r)
+		}
+		days := n
+		if unit == 'w' {
+			days = n * 7
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	span, err := time.ParseDuration(r)
+	if err != nil || span <= 0 {
+		return 0, fmt.Errorf("range参数无效: %s", r)
+	}
+	return span, nil
+}
+
+// parseEquityResolution 解析 resolution 参数，auto 时根据数据跨度选择，返回粒度名称和桶大小
+func parseEquityResolution(raw string, points []logger.AccountPoint) (string, time.Duration, error) {
+	resolution := strings.ToLower(strings.TrimSpace(raw))
+	if resolution == "auto" {
+		resolution = autoResolution(points)
+	}
+	bucket, ok := equityResolutions[resolution]
+	if !ok {
+		return "", 0, fmt.Errorf("resolution参数必须是 'auto', 'raw', '5m', '1h' 或 '1d'")
+	}
+	return resolution, bucket, nil
+}
+
+// autoResolution 根据数据跨度自动选择粒度（控制返回点数在几百个以内）
+func autoResolution(points []logger.AccountPoint) string {
+	if len(points) < 2 {
+		return "raw"
+	}
+	span := points[len(points)-1].Timestamp.Sub(points[0].Timestamp)
+	switch {
+	case span <= 24*time.Hour:
+		return "raw"
+	case span <= 3*24*time.Hour:
+		return "5m"
+	case span <= 30*24*time.Hour:
+		return "1h"
+	default:
+		return "1d"
+	}
+}
+
+// benchmarkPrices 获取每根净值K线对应时刻的BTC收盘价（失败时返回nil，不影响净值数据）
+func (s *Server) benchmarkPrices(bars []logger.EquityBar, resolution string) []float64 {
+	if len(bars) == 0 {
+		return nil
+	}
+
+	span := bars[len(bars)-1].Timestamp.Sub(bars[0].Timestamp)
+	interval, step := benchmarkInterval(span, equityResolutions[resolution])
+
+	start := bars[0].Timestamp.Add(-step)
+	end := bars[len(bars)-1].Timestamp.Add(step)
+	klines, err := s.klineCache.get(benchmarkSymbol, interval, start, end)
+	if err != nil {
+		log.Printf("⚠️  获取基准行情失败: %v", err)
+		return nil
+	}
+	if len(klines) == 0 {
+		return nil
+	}
+
+	prices := make([]float64, len(bars))
+	for i, bar := range bars {
+		// 找到开盘时间不晚于该时刻的最后一根K线
+		ts := bar.Timestamp.UnixMilli()
+		idx := sort.Search(len(klines), func(j int) bool { return klines[j].OpenTime > ts }) - 1
+		if idx >= 0 {
+			prices[i] = klines[idx].Close
+		}
+	}
+	return prices
+}
+
+// maxBenchmarkKlines 基准行情最多请求的K线数（一页之内，避免在请求处理中多次分页请求Binance）
+const maxBenchmarkKlines = 1000
+
+// benchmarkIntervals 基准行情可用的K线周期（从细到粗）
+var benchmarkIntervals = []struct {
+	name string
+	step time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+	{"1h", time.Hour},
+	{"4h", 4 * time.Hour},
+	{"1d", 24 * time.Hour},
+	{"1w", 7 * 24 * time.Hour},
+}
+
+// benchmarkInterval 根据时间跨度选择基准K线周期：不细于净值粒度，且K线数不超过 maxBenchmarkKlines
+func benchmarkInterval(span, bucket time.Duration) (string, time.Duration) {
+	for _, iv := range benchmarkIntervals {
+		if iv.step >= bucket && span/iv.step <= maxBenchmarkKlines {
+			return iv.name, iv.step
+		}
+	}
+	last := benchmarkIntervals[len(benchmarkIntervals)-1]
+	return last.name, last.step
+}
+
+// klineCacheTTL 基准K线缓存有效期（仪表盘会频繁刷新，多个trader共用同一份行情）
+const klineCacheTTL = time.Minute
+
+// klineCache 基准K线的短期缓存
+type klineCache struct {
+	mu      sync.Mutex
+	entries map[string]klineCacheEntry
+}
+
+type klineCacheEntry struct {
+	klines    []market.Kline
+	start     time.Time
+	end       time.Time
+	fetchedAt time.Time
+}
+
+func newKlineCache() *klineCache {
+	return &klineCache{entries: make(map[string]klineCacheEntry)}
+}
+
+// get 获取K线，缓存未过期且覆盖请求范围时直接返回
+func (kc *klineCache) get(symbol, interval string, start, end time.Time) ([]market.Kline, error) {
+	key := symbol + "_" + interval
+
+	kc.mu.Lock()
+	entry, ok := kc.entries[key]
+	kc.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < klineCacheTTL && !start.Before(entry.start) && !end.After(entry.end) {
+		return entry.klines, nil
+	}
+
+	klines, err := market.GetKlinesRange(symbol, interval, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	kc.mu.Lock()
+	kc.entries[key] = klineCacheEntry{klines: klines, start: start, end: end, fetchedAt: time.Now()}
+	kc.mu.Unlock()
+	return klines, nil
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"math"
+	"net/http/httptest"
+	"nofx/logger"
+	"nofx/market"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseRangeDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"天", "7d", 7 * 24 * time.Hour, false},
+		{"周", "2w", 14 * 24 * time.Hour, false},
+		{"Go时长格式", "12h", 12 * time.Hour, false},
+		{"组合时长", "1h30m", 90 * time.Minute, false},
+		{"天数为0", "0d", 0, true},
+		{"天数为负", "-1d", 0, true},
+		{"天数不是数字", "xd", 0, true},
+		{"只有单位", "d", 0, true},
+		{"时长为0", "0s", 0, true},
+		{"无法解析", "abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRangeDuration(tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRangeDuration(%q) error = %v, wantErr %v", tt.r, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseRangeDuration(%q) = %v, want %v", tt.r, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEquityRange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	to := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom time.Time
+		wantTo   time.Time
+		sinceNow time.Duration // 非0时from应约等于 now-sinceNow
+		wantErr  bool
+	}{
+		{name: "没有参数", query: ""},
+		{name: "只有from/to", query: "from=2024-01-01T00:00:00Z&to=2024-01-10T00:00:00Z", wantFrom: from, wantTo: to},
+		{name: "range=all不限制起点", query: "range=all&to=2024-01-10T00:00:00Z", wantTo: to},
+		{name: "range相对to计算起点", query: "range=1d&to=2024-01-10T00:00:00Z", wantFrom: to.Add(-24 * time.Hour), wantTo: to},
+		{name: "range覆盖from", query: "range=2w&from=2024-01-01T00:00:00Z&to=2024-01-10T00:00:00Z", wantFrom: to.Add(-14 * 24 * time.Hour), wantTo: to},
+		{name: "range大写", query: "range=12H&to=2024-01-10T00:00:00Z", wantFrom: to.Add(-12 * time.Hour), wantTo: to},
+		{name: "没有to时相对当前时间", query: "range=7d", sinceNow: 7 * 24 * time.Hour},
+		{name: "range无效", query: "range=0d", wantErr: true},
+		{name: "from无效", query: "from=yesterday", wantErr: true},
+		{name: "to无效", query: "to=2024-13-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/equity-history?"+tt.query, nil)
+
+			gotFrom, gotTo, err := parseEquityRange(c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEquityRange(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !gotTo.Equal(tt.wantTo) {
+				t.Errorf("to = %v, want %v", gotTo, tt.wantTo)
+			}
+			if tt.sinceNow > 0 {
+				if d := time.Since(gotFrom) - tt.sinceNow; d < 0 || d > time.Minute {
+					t.Errorf("from = %v, want about now-%v", gotFrom, tt.sinceNow)
+				}
+				return
+			}
+			if !gotFrom.Equal(tt.wantFrom) {
+				t.Errorf("from = %v, want %v", gotFrom, tt.wantFrom)
+			}
+		})
+	}
+}
+
+// spanPoints 构造首尾相隔span的账户快照
+func spanPoints(span time.Duration) []logger.AccountPoint {
+	start := time.Unix(1700000000, 0)
+	return []logger.AccountPoint{{Timestamp: start}, {Timestamp: start.Add(span)}}
+}
+
+func TestParseEquityResolution(t *testing.T) {
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name       string
+		raw        string
+		points     []logger.AccountPoint
+		want       string
+		wantBucket time.Duration
+		wantErr    bool
+	}{
+		{name: "auto没有数据", raw: "auto", want: "raw"},
+		{name: "auto单个点", raw: "auto", points: spanPoints(0)[:1], want: "raw"},
+		{name: "auto一天以内", raw: "auto", points: spanPoints(day), want: "raw"},
+		{name: "auto超过一天", raw: "auto", points: spanPoints(day + time.Second), want: "5m", wantBucket: 5 * time.Minute},
+		{name: "auto三天", raw: "auto", points: spanPoints(3 * day), want: "5m", wantBucket: 5 * time.Minute},
+		{name: "auto一个月以内", raw: "auto", points: spanPoints(30 * day), want: "1h", wantBucket: time.Hour},
+		{name: "auto超过一个月", raw: "auto", points: spanPoints(31 * day), want: "1d", wantBucket: day},
+		{name: "指定粒度不看数据跨度", raw: "1h", points: spanPoints(31 * day), want: "1h", wantBucket: time.Hour},
+		{name: "大小写和空白", raw: " RAW ", want: "raw"},
+		{name: "不支持的粒度", raw: "15m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, bucket, err := parseEquityResolution(tt.raw, tt.points)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEquityResolution(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want || bucket != tt.wantBucket {
+				t.Errorf("parseEquityResolution(%q) = %q, %v, want %q, %v", tt.raw, got, bucket, tt.want, tt.wantBucket)
+			}
+		})
+	}
+}
+
+func TestBenchmarkInterval(t *testing.T) {
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name   string
+		span   time.Duration
+		bucket time.Duration
+		want   string
+	}{
+		{"没有跨度", 0, 0, "5m"},
+		{"短跨度用最细周期", time.Hour, 0, "5m"},
+		{"恰好1000根", 1000 * 5 * time.Minute, 0, "5m"},
+		{"超过1000根换更粗周期", 1001 * 5 * time.Minute, 0, "15m"},
+		{"七天", 7 * day, 0, "15m"},
+		{"不细于净值粒度", time.Hour, time.Hour, "1h"},
+		{"一个月按小时", 30 * day, time.Hour, "1h"},
+		{"一年按天", 365 * day, day, "1d"},
+		{"一年不聚合", 365 * day, 0, "1d"},
+		{"跨度过长退回周线", 30 * 365 * day, 0, "1w"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, step := benchmarkInterval(tt.span, tt.bucket)
+			if got != tt.want {
+				t.Errorf("benchmarkInterval(%v, %v) = %q, want %q", tt.span, tt.bucket, got, tt.want)
+			}
+			if step < tt.bucket {
+				t.Errorf("step %v 细于净值粒度 %v", step, tt.bucket)
+			}
+		})
+	}
+}
+
+func TestBenchmarkPrices(t *testing.T) {
+	base := time.Unix(1700000000, 0).Truncate(time.Hour)
+	kline := func(offset time.Duration, close float64) market.Kline {
+		return market.Kline{OpenTime: base.Add(offset).UnixMilli(), Close: close}
+	}
+	bar := func(offset time.Duration) logger.EquityBar {
+		return logger.EquityBar{Timestamp: base.Add(offset)}
+	}
+	klines5m := []market.Kline{
+		kline(0, 100),
+		kline(5*time.Minute, 101),
+		kline(10*time.Minute, 102),
+	}
+
+	tests := []struct {
+		name       string
+		resolution string
+		bars       []logger.EquityBar
+		interval   string
+		klines     []market.Kline
+		want       []float64
+	}{
+		{
+			name:       "没有净值K线",
+			resolution: "raw",
+			interval:   "5m",
+			klines:     klines5m,
+		},
+		{
+			name:       "单根净值K线",
+			resolution: "raw",
+			bars:       []logger.EquityBar{bar(3 * time.Minute)},
+			interval:   "5m",
+			klines:     klines5m,
+			want:       []float64{100},
+		},
+		{
+			// 恰好等于K线开盘时间的点使用该K线，之后的点使用最后一根开盘不晚于它的K线
+			name:       "对齐到开盘时间不晚于该时刻的K线",
+			resolution: "raw",
+			bars:       []logger.EquityBar{bar(0), bar(4 * time.Minute), bar(5 * time.Minute), bar(14 * time.Minute)},
+			interval:   "5m",
+			klines:     klines5m,
+			want:       []float64{100, 100, 101, 102},
+		},
+		{
+			name:       "早于第一根K线的点没有价格",
+			resolution: "raw",
+			bars:       []logger.EquityBar{bar(-time.Minute), bar(6 * time.Minute)},
+			interval:   "5m",
+			klines:     klines5m,
+			want:       []float64{0, 101},
+		},
+		{
+			name:       "按净值粒度选择K线周期",
+			resolution: "1h",
+			bars:       []logger.EquityBar{bar(0), bar(time.Hour), bar(2 * time.Hour)},
+			interval:   "1h",
+			klines:     []market.Kline{kline(0, 200), kline(time.Hour, 210), kline(2*time.Hour, 190)},
+			want:       []float64{200, 210, 190},
+		},
+		{
+			name:       "没有行情",
+			resolution: "raw",
+			bars:       []logger.EquityBar{bar(0)},
+			interval:   "5m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 预先填充缓存，避免请求Binance
+			kc := newKlineCache()
+			kc.entries[benchmarkSymbol+"_"+tt.interval] = klineCacheEntry{
+				klines:    tt.klines,
+				start:     base.Add(-24 * time.Hour),
+				end:       base.Add(24 * time.Hour),
+				fetchedAt: time.Now(),
+			}
+			s := &Server{klineCache: kc}
+
+			got := s.benchmarkPrices(tt.bars, tt.resolution)
+			if len(got) != len(tt.want) {
+				t.Fatalf("benchmarkPrices() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Errorf("benchmarkPrices()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleExperiments prompt实验报告：按变体汇总所有trader的交易表现（含95%置信区间）
+// 参数: cycles=每个trader分析的最近周期数（默认100）
+func (s *Server) handleExperiments(c *gin.Context) {
+	lookback := 100
+	if v := c.Query("cycles"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxDecisionPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cycles参数必须在1-%d之间", maxDecisionPageSize)})
+			return
+		}
+		lookback = n
+	}
+
+	reports, err := s.traderManager.GetExperimentReports(lookback)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("生成实验报告失败: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
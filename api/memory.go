@@ -0,0 +1,47 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleMemory 查看trader的跨周期记忆（交易日志和下一个周期将附带的最近周期）
+func (s *Server) handleMemory(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	memory, err := trader.GetMemory()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取跨周期记忆失败: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, memory)
+}
+
+// handleResetMemory 清空trader的交易日志，之前的周期不再作为记忆（决策日志保留）
+func (s *Server) handleResetMemory(c *gin.Context) {
+	traderID := c.Param("id")
+	if _, err := s.traderManager.GetTrader(traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.traderManager.ResetMemory(traderID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "跨周期记忆已重置"})
+}
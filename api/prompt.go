@@ -0,0 +1,40 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handlePromptPreview 按trader当前的账户、持仓和市场数据渲染prompt（不调用AI，不下单）
+func (s *Server) handlePromptPreview(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview, err := at.PreviewPrompt()
+	if errors.Is(err, trader.ErrCycleRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ 渲染prompt失败 [%s]: %v", at.GetName(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("渲染prompt失败: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
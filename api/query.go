@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"nofx/logger"
+	"nofx/trader"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxDecisionPageSize 决策记录单页最大条数
+const maxDecisionPageSize = 10000
+
+// parseDecisionQuery 从query参数解析决策记录检索条件
+// 支持: from, to, symbol, action, success, limit, cursor
+func parseDecisionQuery(c *gin.Context, defaultLimit int) (logger.DecisionQuery, error) {
+	q := logger.DecisionQuery{
+		Symbol: strings.ToUpper(strings.TrimSpace(c.Query("symbol"))),
+		Action: strings.ToLower(strings.TrimSpace(c.Query("action"))),
+		Cursor: c.Query("cursor"),
+		Limit:  defaultLimit,
+	}
+
+	var err error
+	if q.From, err = parseTimeParam(c.Query("from")); err != nil {
+		return q, fmt.Errorf("from参数无效: %w", err)
+	}
+	if q.To, err = parseTimeParam(c.Query("to")); err != nil {
+		return q, fmt.Errorf("to参数无效: %w", err)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return q, fmt.Errorf("from必须早于to")
+	}
+
+	if q.Action != "" {
+		switch q.Action {
+		case "open_long", "open_short", "close_long", "close_short", "hold", "wait",
+			trader.ActionTightenStopLong, trader.ActionTightenStopShort:
+		default:
+			return q, fmt.Errorf("action参数无效: %s", q.Action)
+		}
+	}
+
+	if v := c.Query("success"); v != "" {
+		success, err := strconv.ParseBool(v)
+		if err != nil {
+			return q, fmt.Errorf("success参数无效: %s", v)
+		}
+		q.Success = &success
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return q, fmt.Errorf("limit参数无效: %s", v)
+		}
+		q.Limit = limit
+	}
+	if q.Limit > maxDecisionPageSize {
+		q.Limit = maxDecisionPageSize
+	}
+
+	return q, nil
+}
+
+// parseTimeParam 解析时间参数，支持 RFC3339、"2006-01-02 15:04:05"、"2006-01-02" 和Unix秒级时间戳
+func parseTimeParam(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s", v)
+}
@@ -4,7 +4,13 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"nofx/config"
+	"nofx/logger"
 	"nofx/manager"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,36 +20,99 @@ type Server struct {
 	router        *gin.Engine
 	traderManager *manager.TraderManager
 	port          int
+	klineCache    *klineCache // 基准收益曲线使用的K线缓存
+
+	// 认证与CORS（配置热加载时替换）
+	securityMu  sync.RWMutex
+	auth        *authenticator // API认证与审计
+	corsOrigins []string       // 允许跨域的来源（为空表示允许所有来源）
 }
 
 // NewServer 创建API服务器
-func NewServer(traderManager *manager.TraderManager, port int) *Server {
+func NewServer(traderManager *manager.TraderManager, cfg *config.Config) *Server {
 	// 设置为Release模式（减少日志输出）
 	gin.SetMode(gin.ReleaseMode)
+	// 访问日志中的密钥和 access_token 脱敏
+	gin.DefaultWriter = config.NewRedactingWriter(os.Stdout)
+	gin.DefaultErrorWriter = config.NewRedactingWriter(os.Stderr)
 
 	router := gin.Default()
 
-	// 启用CORS
-	router.Use(corsMiddleware())
-
 	s := &Server{
 		router:        router,
 		traderManager: traderManager,
-		port:          port,
+		port:          cfg.APIServerPort,
+		klineCache:    newKlineCache(),
+		auth:          newAuthenticator(cfg.APIAuth, nil),
+		corsOrigins:   cfg.CORSAllowedOrigins,
+	}
+	traderManager.OnConfigApplied(s.applySecurityConfig)
+
+	if !cfg.APIAuth.Enabled() {
+		log.Printf("⚠️  未启用API认证：只读接口对所有人开放，控制接口已禁用（请配置 api_auth）")
+	}
+	if len(cfg.CORSAllowedOrigins) == 0 {
+		log.Printf("⚠️  未配置 cors_allowed_origins，允许所有来源跨域访问")
 	}
 
+	// 启用CORS、审计日志和响应脱敏
+	router.Use(s.corsMiddleware())
+	router.Use(s.auditMiddleware())
+	router.Use(redactMiddleware())
+
 	// 设置路由
 	s.setupRoutes()
 
 	return s
 }
 
-// corsMiddleware CORS中间件
-func corsMiddleware() gin.HandlerFunc {
+// redactingResponseWriter 写出响应前对已登记的密钥脱敏
+type redactingResponseWriter struct {
+	gin.ResponseWriter
+}
+
+func (w redactingResponseWriter) Write(data []byte) (int, error) {
+	if _, err := w.ResponseWriter.WriteString(config.Redact(string(data))); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w redactingResponseWriter) WriteString(data string) (int, error) {
+	if _, err := w.ResponseWriter.WriteString(config.Redact(data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// redactMiddleware 响应脱敏中间件（防止错误信息等意外带出密钥）
+func redactMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		c.Writer = redactingResponseWriter{c.Writer}
+		c.Next()
+	}
+}
+
+// corsMiddleware CORS中间件（配置了 cors_allowed_origins 时只允许列表中的来源）
+func (s *Server) corsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		s.securityMu.RLock()
+		allowAll := len(s.corsOrigins) == 0
+		s.securityMu.RUnlock()
+		if allowAll {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		} else if origin != "" {
+			if !s.originAllowed(origin) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "来源不在CORS允许列表中"})
+				return
+			}
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Add("Vary", "Origin")
+		}
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor, X-Equity-Resolution")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusOK)
@@ -54,16 +123,30 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// originAllowed 来源是否在允许列表中（"*" 表示允许所有来源）
+func (s *Server) originAllowed(origin string) bool {
+	s.securityMu.RLock()
+	defer s.securityMu.RUnlock()
+	for _, allowed := range s.corsOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
 // setupRoutes 设置路由
 func (s *Server) setupRoutes() {
 	// 健康检查
 	s.router.Any("/health", s.handleHealth)
 
-	// API路由组
-	api := s.router.Group("/api")
+	// 只读API（readonly及以上角色）
+	api := s.router.Group("/api", s.requireRole(config.RoleReadOnly))
 	{
 		// 竞赛总览
 		api.GET("/competition", s.handleCompetition)
+		api.GET("/competition/leaderboard", s.handleLeaderboard)
+		api.GET("/competition/seasons", s.handleSeasons)
 
 		// Trader列表
 		api.GET("/traders", s.handleTraderList)
@@ -77,6 +160,27 @@ func (s *Server) setupRoutes() {
 		api.GET("/statistics", s.handleStatistics)
 		api.GET("/equity-history", s.handleEquityHistory)
 		api.GET("/performance", s.handlePerformance)
+		api.GET("/experiments", s.handleExperiments)
+		api.GET("/prompt/preview", s.handlePromptPreview)
+		api.GET("/memory", s.handleMemory)
+		api.GET("/whoami", s.handleWhoAmI)
+		api.GET("/stream", s.handleStream)
+	}
+
+	// 控制API（需要operator角色，使用路径参数 /traders/:id）
+	control := s.router.Group("/api", s.requireRole(config.RoleOperator))
+	{
+		control.POST("/traders", s.handleCreateTrader)
+		control.DELETE("/traders/:id", s.handleDeleteTrader)
+		control.POST("/traders/:id/start", s.handleStartTrader)
+		control.POST("/traders/:id/stop", s.handleStopTrader)
+		control.POST("/traders/:id/pause", s.handlePauseTrader)
+		control.POST("/traders/:id/resume", s.handleResumeTrader)
+		control.POST("/traders/:id/trigger", s.handleTriggerCycle)
+		control.POST("/traders/:id/settings", s.handleUpdateTraderSettings)
+		control.POST("/traders/:id/close-all", s.handleCloseAllPositions)
+		control.POST("/traders/:id/memory/reset", s.handleResetMemory)
+		control.POST("/config/reload", s.handleReloadConfig)
 	}
 }
 
@@ -102,6 +206,12 @@ func (s *Server) getTraderFromQuery(c *gin.Context) (*manager.TraderManager, str
 	return s.traderManager, traderID, nil
 }
 
+// handleWhoAmI 当前调用方身份
+func (s *Server) handleWhoAmI(c *gin.Context) {
+	principal, _ := c.Get(principalKey)
+	c.JSON(http.StatusOK, principal)
+}
+
 // handleCompetition 竞赛总览（对比所有trader）
 func (s *Server) handleCompetition(c *gin.Context) {
 	comparison, err := s.traderManager.GetComparisonData()
@@ -207,7 +317,19 @@ func (s *Server) handlePositions(c *gin.Context) {
 }
 
 // handleDecisions 决策日志列表
+// 支持过滤和游标分页: from, to, symbol, action, success, limit, cursor
+// 默认按时间正序返回（从旧到新），order=desc 时从新到旧；下一页游标通过 X-Next-Cursor 响应头返回
 func (s *Server) handleDecisions(c *gin.Context) {
+	s.respondDecisionPage(c, maxDecisionPageSize, "asc")
+}
+
+// handleLatestDecisions 最新决策日志（默认最近5条，最新的在前），支持与 /decisions 相同的过滤参数
+func (s *Server) handleLatestDecisions(c *gin.Context) {
+	s.respondDecisionPage(c, 5, "desc")
+}
+
+// respondDecisionPage 按query参数检索决策记录并返回
+func (s *Server) respondDecisionPage(c *gin.Context, defaultLimit int, defaultOrder string) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -220,33 +342,19 @@ func (s *Server) handleDecisions(c *gin.Context) {
 		return
 	}
 
-	// 获取所有历史决策记录（无限制）
-	records, err := trader.GetDecisionLogger().GetLatestRecords(10000)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("获取决策日志失败: %v", err),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, records)
-}
-
-// handleLatestDecisions 最新决策日志（最近5条，最新的在前）
-func (s *Server) handleLatestDecisions(c *gin.Context) {
-	_, traderID, err := s.getTraderFromQuery(c)
+	query, err := parseDecisionQuery(c, defaultLimit)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	trader, err := s.traderManager.GetTrader(traderID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	order := c.DefaultQuery("order", defaultOrder)
+	if order != "asc" && order != "desc" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order参数必须是 'asc' 或 'desc'"})
 		return
 	}
 
-	records, err := trader.GetDecisionLogger().GetLatestRecords(5)
+	page, err := trader.GetDecisionLogger().QueryDecisions(query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取决策日志失败: %v", err),
@@ -254,12 +362,17 @@ func (s *Server) handleLatestDecisions(c *gin.Context) {
 		return
 	}
 
-	// 反转数组，让最新的在前面（用于列表显示）
-	// GetLatestRecords返回的是从旧到新（用于图表），这里需要从新到旧
-	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
-		records[i], records[j] = records[j], records[i]
+	// QueryDecisions 返回从新到旧，图表等场景需要从旧到新
+	records := page.Records
+	if order == "asc" {
+		for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+			records[i], records[j] = records[j], records[i]
+		}
 	}
 
+	if page.NextCursor != "" {
+		c.Header("X-Next-Cursor", page.NextCursor)
+	}
 	c.JSON(http.StatusOK, records)
 }
 
@@ -288,8 +401,8 @@ func (s *Server) handleStatistics(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
-// handleEquityHistory 收益率历史数据
-func (s *Server) handleEquityHistory(c *gin.Context) {
+// handlePerformance AI历史表现分析（用于展示AI学习和反思）
+func (s *Server) handlePerformance(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -302,95 +415,19 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 		return
 	}
 
-	// 获取尽可能多的历史数据（几天的数据）
-	// 每3分钟一个周期：10000条 = 约20天的数据
-	records, err := trader.GetDecisionLogger().GetLatestRecords(10000)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("获取历史数据失败: %v", err),
-		})
-		return
-	}
-
-	// 构建收益率历史数据点
-	type EquityPoint struct {
-		Timestamp        string  `json:"timestamp"`
-		TotalEquity      float64 `json:"total_equity"`      // 账户净值（wallet + unrealized）
-		AvailableBalance float64 `json:"available_balance"` // 可用余额
-		TotalPnL         float64 `json:"total_pnl"`         // 总盈亏（相对初始余额）
-		TotalPnLPct      float64 `json:"total_pnl_pct"`     // 总盈亏百分比
-		PositionCount    int     `json:"position_count"`    // 持仓数量
-		MarginUsedPct    float64 `json:"margin_used_pct"`   // 保证金使用率
-		CycleNumber      int     `json:"cycle_number"`
-	}
-
-	// 从AutoTrader获取初始余额（用于计算盈亏百分比）
-	initialBalance := 0.0
-	if status := trader.GetStatus(); status != nil {
-		if ib, ok := status["initial_balance"].(float64); ok && ib > 0 {
-			initialBalance = ib
-		}
-	}
-
-	// 如果无法从status获取，且有历史记录，则从第一条记录获取
-	if initialBalance == 0 && len(records) > 0 {
-		// 第一条记录的equity作为初始余额
-		initialBalance = records[0].AccountState.TotalBalance
-	}
-
-	// 如果还是无法获取，返回错误
-	if initialBalance == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "无法获取初始余额",
-		})
-		return
-	}
-
-	var history []EquityPoint
-	for _, record := range records {
-		// TotalBalance字段实际存储的是TotalEquity
-		totalEquity := record.AccountState.TotalBalance
-		// TotalUnrealizedProfit字段实际存储的是TotalPnL（相对初始余额）
-		totalPnL := record.AccountState.TotalUnrealizedProfit
-
-		// 计算盈亏百分比
-		totalPnLPct := 0.0
-		if initialBalance > 0 {
-			totalPnLPct = (totalPnL / initialBalance) * 100
+	// 默认分析最近100个周期的交易（与prompt中的历史表现一致），净值指标至少回看 logger.PerformanceWindow
+	// 可通过 cycles 参数指定周期数
+	lookback := logger.DefaultLookbackCycles
+	if v := c.Query("cycles"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxDecisionPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cycles参数必须在1-%d之间", maxDecisionPageSize)})
+			return
 		}
-
-		history = append(history, EquityPoint{
-			Timestamp:        record.Timestamp.Format("2006-01-02 15:04:05"),
-			TotalEquity:      totalEquity,
-			AvailableBalance: record.AccountState.AvailableBalance,
-			TotalPnL:         totalPnL,
-			TotalPnLPct:      totalPnLPct,
-			PositionCount:    record.AccountState.PositionCount,
-			MarginUsedPct:    record.AccountState.MarginUsedPct,
-			CycleNumber:      record.CycleNumber,
-		})
-	}
-
-	c.JSON(http.StatusOK, history)
-}
-
-// handlePerformance AI历史表现分析（用于展示AI学习和反思）
-func (s *Server) handlePerformance(c *gin.Context) {
-	_, traderID, err := s.getTraderFromQuery(c)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	trader, err := s.traderManager.GetTrader(traderID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		return
+		lookback = n
 	}
 
-	// 分析最近100个周期的交易表现（避免长期持仓的交易记录丢失）
-	// 假设每3分钟一个周期，100个周期 = 5小时，足够覆盖大部分交易
-	performance, err := trader.GetDecisionLogger().AnalyzePerformance(100)
+	performance, err := trader.GetDecisionLogger().AnalyzePerformance(lookback)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("分析历史表现失败: %v", err),
@@ -407,15 +444,30 @@ func (s *Server) Start() error {
 	log.Printf("🌐 API服务器启动在 http://localhost%s", addr)
 	log.Printf("📊 API文档:")
 	log.Printf("  • GET  /api/competition      - 竞赛总览（对比所有trader）")
+	log.Printf("  • GET  /api/competition/leaderboard - 竞赛排行榜（支持 season, interval, history 参数）")
+	log.Printf("  • GET  /api/competition/seasons - 赛季列表")
 	log.Printf("  • GET  /api/traders          - Trader列表")
 	log.Printf("  • GET  /api/status?trader_id=xxx     - 指定trader的系统状态")
 	log.Printf("  • GET  /api/account?trader_id=xxx    - 指定trader的账户信息")
 	log.Printf("  • GET  /api/positions?trader_id=xxx  - 指定trader的持仓列表")
-	log.Printf("  • GET  /api/decisions?trader_id=xxx  - 指定trader的决策日志")
+	log.Printf("  • GET  /api/decisions?trader_id=xxx  - 指定trader的决策日志（支持 from/to/symbol/action/success/limit/cursor）")
 	log.Printf("  • GET  /api/decisions/latest?trader_id=xxx - 指定trader的最新决策")
 	log.Printf("  • GET  /api/statistics?trader_id=xxx - 指定trader的统计信息")
-	log.Printf("  • GET  /api/equity-history?trader_id=xxx - 指定trader的收益率历史数据")
-	log.Printf("  • GET  /api/performance?trader_id=xxx - 指定trader的AI学习表现分析")
+	log.Printf("  • GET  /api/equity-history?trader_id=xxx - 指定trader的收益率历史数据（支持 resolution=5m/1h/1d, range=1d/7d/30d/all）")
+	log.Printf("  • GET  /api/performance?trader_id=xxx - 指定trader的AI学习表现分析（支持 cycles=N，含夏普/索提诺/卡玛/最大回撤等指标）")
+	log.Printf("  • GET  /api/experiments      - prompt A/B实验报告（按变体汇总所有trader，支持 cycles=N）")
+	log.Printf("  • GET  /api/prompt/preview?trader_id=xxx - 按当前上下文渲染指定trader的prompt（不调用AI）")
+	log.Printf("  • GET  /api/memory?trader_id=xxx - 指定trader的跨周期记忆（交易日志和最近周期）")
+	log.Printf("  • GET  /api/whoami           - 当前调用方身份和角色")
+	log.Printf("  • GET  /api/stream?trader_id=xxx - 实时交易事件推送（SSE，trader_id为空时推送整个竞赛，支持 types 过滤）")
+	log.Printf("  • POST /api/traders              - 创建并启动新的trader（写入配置文件，需要operator角色）")
+	log.Printf("  • DELETE /api/traders/:id        - 停止并删除trader（从配置文件中移除）")
+	log.Printf("  • POST /api/traders/:id/start|stop|pause|resume - 启停/暂停/恢复指定trader（需要operator角色）")
+	log.Printf("  • POST /api/traders/:id/trigger   - 立即触发一个交易周期")
+	log.Printf("  • POST /api/traders/:id/settings  - 调整扫描间隔和杠杆上限")
+	log.Printf("  • POST /api/traders/:id/close-all - 平掉指定trader的所有持仓")
+	log.Printf("  • POST /api/traders/:id/memory/reset - 清空指定trader的跨周期记忆")
+	log.Printf("  • POST /api/config/reload         - 重新加载配置文件（新增/移除/重建/热更新trader）")
 	log.Printf("  • GET  /health               - 健康检查")
 	log.Println()
 
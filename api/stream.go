@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"nofx/trader"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamHeartbeatInterval SSE心跳间隔（防止代理断开空闲连接）
+const streamHeartbeatInterval = 15 * time.Second
+
+// streamEventTypes 可订阅的事件类型
+var streamEventTypes = map[trader.EventType]bool{
+	trader.EventCycleStart:        true,
+	trader.EventAIResponse:        true,
+	trader.EventDecisionValidated: true,
+	trader.EventOrderPlaced:       true,
+	trader.EventOrderFailed:       true,
+	trader.EventRiskHalt:          true,
+	trader.EventSafeMode:          true,
+	trader.EventCycleEnd:          true,
+	trader.EventStateChange:       true,
+}
+
+// handleStream 以SSE推送实时交易事件
+// 参数: trader_id=xxx（为空表示整个竞赛）, types=cycle_start,order_placed,...（为空表示全部）
+// 断线重连时浏览器会带上 Last-Event-ID，服务端补发缓冲区内错过的事件
+func (s *Server) handleStream(c *gin.Context) {
+	traderID := c.Query("trader_id")
+	if traderID != "" {
+		if _, err := s.traderManager.GetTrader(traderID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var types map[trader.EventType]bool
+	if v := c.Query("types"); v != "" {
+		types = make(map[trader.EventType]bool)
+		for _, t := range strings.Split(v, ",") {
+			eventType := trader.EventType(strings.TrimSpace(t))
+			if !streamEventTypes[eventType] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("types参数包含未知的事件类型: %s", eventType)})
+				return
+			}
+			types[eventType] = true
+		}
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var afterID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Last-Event-ID无效: %s", lastEventID)})
+			return
+		}
+		afterID = id
+	}
+
+	events, cancel := s.traderManager.GetEventBus().Subscribe(64, afterID)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 禁用nginx缓冲
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if traderID != "" && e.TraderID != traderID {
+				continue
+			}
+			if types != nil && !types[e.Type] {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+			c.Writer.Flush()
+		}
+	}
+}
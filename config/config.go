@@ -3,7 +3,10 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"log"
+	"nofx/decision"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -40,8 +43,69 @@ type TraderConfig struct {
 	CustomAPIKey    string `json:"custom_api_key,omitempty"`
 	CustomModelName string `json:"custom_model_name,omitempty"`
 
+	// ai_model 的token限制（未设置时使用默认值：DeepSeek 64K、Qwen 128K，自定义API不限制prompt大小；回复默认2000）
+	ContextWindow int `json:"context_window,omitempty"` // 上下文窗口（token），prompt超出时压缩或移除低优先级候选币种
+	MaxTokens     int `json:"max_tokens,omitempty"`     // 单次回复的最大token数
+
 	InitialBalance      float64 `json:"initial_balance"`
 	ScanIntervalMinutes int     `json:"scan_interval_minutes"`
+
+	// 覆盖全局配置（未设置时使用全局值），可以让同一模型以保守/激进两种风格同时参赛
+	Leverage           *LeverageConfig `json:"leverage,omitempty"`             // 杠杆倍数（为0的项使用全局值）
+	MaxDailyLoss       *float64        `json:"max_daily_loss,omitempty"`       // 最大日亏损百分比
+	MaxDrawdown        *float64        `json:"max_drawdown,omitempty"`         // 最大回撤百分比
+	StopTradingMinutes *int            `json:"stop_trading_minutes,omitempty"` // 触发风控后暂停交易的分钟数
+	DefaultCoins       []string        `json:"default_coins,omitempty"`        // 币种白名单（在共享的候选币种池中过滤）
+	SafeMode           *SafeModeConfig `json:"safe_mode,omitempty"`            // AI不可用时的安全模式（整体覆盖全局配置）
+	Memory             *MemoryConfig   `json:"memory,omitempty"`               // 跨周期记忆（整体覆盖全局配置）
+
+	// 平仓后的交易复盘（整体覆盖全局配置）
+	Reflection *ReflectionConfig `json:"reflection,omitempty"`
+
+	// 模型调用工具获取额外数据（整体覆盖全局配置）
+	Tools *ToolsConfig `json:"tools,omitempty"`
+
+	// 多模型投票（未配置时只使用 ai_model）
+	Ensemble *EnsembleConfig `json:"ensemble,omitempty"`
+
+	// 备用模型：ai_model 调用失败或熔断时按顺序尝试
+	FallbackModels []ModelConfig `json:"fallback_models,omitempty"`
+
+	// prompt模板目录（包含 system.tmpl 和/或 user.tmpl，缺少的文件使用内置模板），为空或 "default" 使用内置模板
+	PromptTemplate string `json:"prompt_template,omitempty"`
+
+	// prompt语言（zh、en、ru、uk），为空使用全局 prompt_language
+	PromptLanguage string `json:"prompt_language,omitempty"`
+
+	// 参与的prompt实验（变体的模板和语言覆盖上面两项）
+	Experiment *ExperimentAssignment `json:"experiment,omitempty"`
+}
+
+// 多模型投票方式
+const (
+	VoteMajority  = "majority"  // 多数票：超过半数模型给出相同操作才执行
+	VoteWeighted  = "weighted"  // 加权投票：相同操作的权重之和超过总权重的一半才执行
+	VoteUnanimous = "unanimous" // 一致同意：开仓需要全部模型同意，平仓按多数票
+)
+
+// EnsembleConfig 多模型投票配置：trader的 ai_model 与 models 中的模型使用相同的prompt并行决策，再投票合并
+type EnsembleConfig struct {
+	Mode          string        `json:"mode"`           // majority（默认）、weighted 或 unanimous
+	PrimaryWeight float64       `json:"primary_weight"` // trader自身 ai_model 的权重（weighted模式，默认1）
+	Models        []ModelConfig `json:"models"`         // 额外参与投票的模型
+}
+
+// ModelConfig 额外的AI模型（投票或备用）
+type ModelConfig struct {
+	Name      string  `json:"name"`                 // 显示名称（用于决策记录，默认为 model_name 或 ai_model）
+	AIModel   string  `json:"ai_model"`             // "qwen"、"deepseek" 或 "custom"
+	APIKey    string  `json:"api_key,omitempty"`    // API密钥（支持 env:/file:/keystore: 引用）
+	APIURL    string  `json:"api_url,omitempty"`    // 自定义API地址（custom，可指向本地模型）
+	ModelName string  `json:"model_name,omitempty"` // 模型名称（custom必填，其他可覆盖默认模型）
+	Weight    float64 `json:"weight,omitempty"`     // 权重（仅投票的weighted模式使用，默认1）
+
+	ContextWindow int `json:"context_window,omitempty"` // 上下文窗口（token，未设置时使用默认值）
+	MaxTokens     int `json:"max_tokens,omitempty"`     // 单次回复的最大token数（默认2000）
 }
 
 // LeverageConfig 杠杆配置
@@ -50,18 +114,132 @@ type LeverageConfig struct {
 	AltcoinLeverage int `json:"altcoin_leverage"` // 山寨币的杠杆倍数（主账户建议5-20，子账户≤5）
 }
 
+// SafeModeConfig 安全模式：AI连续多个周期调用或解析失败时，按确定性规则管理已有持仓（不开新仓）
+// 规则按顺序执行：flatten_all → max_adverse_pct → max_hold_hours → tighten_stop_pct
+type SafeModeConfig struct {
+	Enabled        bool    `json:"enabled"`
+	AfterFailures  int     `json:"after_failures"`   // AI连续失败多少个周期后触发（默认3）
+	FlattenAll     bool    `json:"flatten_all"`      // 平掉全部持仓（启用时忽略其他规则）
+	MaxAdversePct  float64 `json:"max_adverse_pct"`  // 平掉价格相对开仓价反向变动超过X%的持仓（0表示不限制）
+	MaxHoldHours   float64 `json:"max_hold_hours"`   // 平掉持仓时间超过N小时的持仓（0表示不限制）
+	TightenStopPct float64 `json:"tighten_stop_pct"` // 把止损收紧到距标记价格X%处（只收紧不放宽，0表示不调整）
+}
+
+// MemoryConfig 跨周期记忆：把最近N个周期的决策和压缩后的思维链，以及模型维护的交易日志放入下一个周期的prompt
+type MemoryConfig struct {
+	Enabled         bool `json:"enabled"`
+	Cycles          int  `json:"cycles"`            // 附带最近N个周期（默认3）
+	MaxCoTChars     int  `json:"max_cot_chars"`     // 每个周期保留的思维链字符数（默认400）
+	MaxJournalChars int  `json:"max_journal_chars"` // 交易日志的最大字符数（默认2000，超出部分截断）
+}
+
+// ReflectionConfig 交易复盘：持仓平仓后把开仓理由、开平仓时的市场快照和结果发给模型总结经验，
+// 经验保存在交易记录旁，最近的几条放入后续周期的prompt
+type ReflectionConfig struct {
+	Enabled     bool `json:"enabled"`
+	MaxPerCycle int  `json:"max_per_cycle"` // 每个周期最多复盘的交易数（默认1，复盘会额外调用一次AI）
+	MaxChars    int  `json:"max_chars"`     // 每条复盘的最大字符数（默认300）
+	Lessons     int  `json:"lessons"`       // 放入prompt的最近复盘条数（默认5）
+}
+
+// ToolsConfig 工具调用：模型在决策前可以调用工具获取额外数据（K线、盘口深度、币种历史交易）
+type ToolsConfig struct {
+	Enabled  bool `json:"enabled"`
+	MaxCalls int  `json:"max_calls"` // 每个周期最多调用次数（默认3，投票的多个模型共享）
+}
+
+// ExperimentConfig prompt A/B实验：参与的trader固定使用其中一个变体，或每个周期轮换全部变体
+// 决策记录会标注实验和变体，用于按变体统计交易表现
+type ExperimentConfig struct {
+	Name     string                `json:"name"`
+	Variants []PromptVariantConfig `json:"variants"` // 至少两个变体
+}
+
+// PromptVariantConfig prompt变体（模板或语言为空时使用trader自身的设置）
+type PromptVariantConfig struct {
+	Name           string `json:"name"`
+	PromptTemplate string `json:"prompt_template,omitempty"`
+	PromptLanguage string `json:"prompt_language,omitempty"`
+}
+
+// ExperimentAssignment trader参与的实验
+type ExperimentAssignment struct {
+	Name    string `json:"name"`              // 实验名称（对应全局 experiments）
+	Variant string `json:"variant,omitempty"` // 固定使用的变体，为空时每个周期轮换全部变体
+}
+
 // Config 总配置
 type Config struct {
-	Traders            []TraderConfig `json:"traders"`
-	UseDefaultCoins    bool           `json:"use_default_coins"` // 是否使用默认主流币种列表
-	DefaultCoins       []string       `json:"default_coins"`     // 默认主流币种池（同时也是白名单，当不为空时自动启用白名单过滤）
-	CoinPoolAPIURL     string         `json:"coin_pool_api_url"`
-	OITopAPIURL        string         `json:"oi_top_api_url"`
-	APIServerPort      int            `json:"api_server_port"`
-	MaxDailyLoss       float64        `json:"max_daily_loss"`
-	MaxDrawdown        float64        `json:"max_drawdown"`
-	StopTradingMinutes int            `json:"stop_trading_minutes"`
-	Leverage           LeverageConfig `json:"leverage"` // 杠杆配置
+	Traders            []TraderConfig     `json:"traders"`
+	UseDefaultCoins    bool               `json:"use_default_coins"` // 是否使用默认主流币种列表
+	DefaultCoins       []string           `json:"default_coins"`     // 默认主流币种池（同时也是白名单，当不为空时自动启用白名单过滤）
+	CoinPoolAPIURL     string             `json:"coin_pool_api_url"`
+	OITopAPIURL        string             `json:"oi_top_api_url"`
+	APIServerPort      int                `json:"api_server_port"`
+	MaxDailyLoss       float64            `json:"max_daily_loss"`
+	MaxDrawdown        float64            `json:"max_drawdown"`
+	StopTradingMinutes int                `json:"stop_trading_minutes"`
+	Leverage           LeverageConfig     `json:"leverage"`             // 杠杆配置
+	Competition        CompetitionConfig  `json:"competition"`          // 竞赛排行榜配置
+	CORSAllowedOrigins []string           `json:"cors_allowed_origins"` // 允许跨域访问的来源（为空时允许所有来源）
+	APIAuth            APIAuthConfig      `json:"api_auth"`             // API认证配置
+	KeystoreFile       string             `json:"keystore_file"`        // 加密密钥库文件（keystore:引用使用，默认 keystore.json）
+	SafeMode           SafeModeConfig     `json:"safe_mode"`            // AI不可用时的安全模式
+	Memory             MemoryConfig       `json:"memory"`               // 跨周期记忆（最近周期的决策和模型维护的交易日志）
+	Reflection         ReflectionConfig   `json:"reflection"`           // 平仓后的交易复盘
+	Tools              ToolsConfig        `json:"tools"`                // 模型调用工具获取额外数据
+	PromptLanguage     string             `json:"prompt_language"`      // prompt语言（zh、en、ru、uk，默认zh）
+	Experiments        []ExperimentConfig `json:"experiments"`          // prompt A/B实验
+}
+
+// API角色
+const (
+	RoleReadOnly = "readonly" // 只读：查询账户、持仓、决策日志等
+	RoleOperator = "operator" // 操作员：只读权限 + 控制接口（启停、调参、平仓）
+)
+
+// APIKeyConfig 单个API Key
+type APIKeyConfig struct {
+	Name string `json:"name"` // 调用方名称（记录在审计日志中）
+	Key  string `json:"key"`
+	Role string `json:"role"` // "readonly" 或 "operator"
+}
+
+// APIAuthConfig API认证配置
+// 配置了 api_keys 或 jwt_secret 即启用认证；未启用时只读接口保持公开，控制接口一律拒绝
+type APIAuthConfig struct {
+	APIKeys      []APIKeyConfig `json:"api_keys"`
+	JWTSecret    string         `json:"jwt_secret"`     // HS256签名密钥，JWT需包含 sub 和 role 声明
+	PublicRead   bool           `json:"public_read"`    // 启用认证后是否仍允许匿名只读访问（如公开展示的仪表盘）
+	AuditLogFile string         `json:"audit_log_file"` // 审计日志文件（JSON Lines，默认 audit.log）
+	AuditReads   bool           `json:"audit_reads"`    // 是否记录只读请求（默认只记录控制请求和被拒绝的请求）
+}
+
+// Enabled 是否启用认证
+func (ac *APIAuthConfig) Enabled() bool {
+	return len(ac.APIKeys) > 0 || ac.JWTSecret != ""
+}
+
+// SeasonConfig 竞赛赛季配置
+type SeasonConfig struct {
+	Name  string `json:"name"`
+	Start string `json:"start"` // 开始时间（RFC3339 或 "2006-01-02 15:04:05"，本地时区）
+	End   string `json:"end"`   // 结束时间（为空表示不限）
+}
+
+// ScoringConfig 排行榜评分规则
+// 得分 = 收益率(%) × return_weight + 年化夏普 × sharpe_weight - 最大回撤(%) × drawdown_penalty
+type ScoringConfig struct {
+	ReturnWeight    float64 `json:"return_weight"`
+	SharpeWeight    float64 `json:"sharpe_weight"`
+	DrawdownPenalty float64 `json:"drawdown_penalty"`
+}
+
+// CompetitionConfig 竞赛排行榜配置
+type CompetitionConfig struct {
+	Seasons             []SeasonConfig `json:"seasons"`               // 赛季列表（为空时从第一条记录开始排名）
+	Scoring             ScoringConfig  `json:"scoring"`               // 评分规则（全部为0时按收益率排名）
+	RankIntervalMinutes int            `json:"rank_interval_minutes"` // 排名历史的采样间隔（默认60分钟）
 }
 
 // legacyCoinWhitelistConfig 用于向后兼容的旧配置结构
@@ -75,15 +253,33 @@ type legacyConfig struct {
 	CoinWhitelist legacyCoinWhitelistConfig `json:"coin_whitelist"`
 }
 
-// LoadConfig 从文件加载配置
+// LoadConfig 从文件加载配置，并依次应用 NOFX_* 环境变量和命令行覆盖
+// 存在覆盖时配置文件可以不存在（例如完全通过环境变量配置的容器部署）
 func LoadConfig(filename string) (*Config, error) {
+	overrides := activeOverrides()
 	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) && len(overrides) > 0 {
+		log.Printf("⚠️  配置文件 %s 不存在，仅使用环境变量和命令行参数", filename)
+		data, err = []byte("{}"), nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("读取配置文件失败: %w", err)
 	}
+	if data, err = applyOverrides(data, overrides); err != nil {
+		return nil, fmt.Errorf("应用配置覆盖失败: %w", err)
+	}
+
+	// 按schema检查未知字段和类型错误
+	problems, err := schemaProblems(data)
+	if err != nil {
+		return nil, err
+	}
 
 	var config Config
 	if err := json.Unmarshal(data, &config); err != nil {
+		if len(problems) > 0 {
+			return nil, fmt.Errorf("配置验证失败: %w", &ValidationError{Problems: problems})
+		}
 		return nil, fmt.Errorf("解析配置文件失败: %w", err)
 	}
 
@@ -129,104 +325,628 @@ func LoadConfig(filename string) (*Config, error) {
 		}
 	}
 
-	// 验证配置
-	if err := config.Validate(); err != nil {
+	// 解析密钥引用（env:/file:/keystore:）
+	plaintext, secretProblems := config.resolveSecrets()
+	problems = append(problems, secretProblems...)
+	plaintext = filterEnvProvided(plaintext, overrides)
+	if len(plaintext) > 0 {
+		log.Printf("⚠️  警告: %d 个密钥以明文保存在配置文件中（%s），建议改用 env:、file: 或 keystore: 引用",
+			len(plaintext), strings.Join(plaintext, ", "))
+	}
+
+	// 验证配置（连同上面发现的问题一起报告）
+	if err := mergeProblems(problems, config.Validate()); err != nil {
 		return nil, fmt.Errorf("配置验证失败: %w", err)
 	}
 
 	return &config, nil
 }
 
-// Validate 验证配置有效性
+// Validate 验证配置有效性并设置默认值，一次返回全部问题（*ValidationError）
 func (c *Config) Validate() error {
+	var v validator
+
 	if len(c.Traders) == 0 {
-		return fmt.Errorf("至少需要配置一个trader")
+		v.add("$.traders", "至少需要配置一个trader")
 	}
 
 	traderIDs := make(map[string]bool)
-	for i, trader := range c.Traders {
-		if trader.ID == "" {
-			return fmt.Errorf("trader[%d]: ID不能为空", i)
-		}
-		if traderIDs[trader.ID] {
-			return fmt.Errorf("trader[%d]: ID '%s' 重复", i, trader.ID)
+	for i := range c.Traders {
+		trader := &c.Traders[i]
+		path := fmt.Sprintf("$.traders[%d]", i)
+		if trader.ID != "" && traderIDs[trader.ID] {
+			v.add(path+".id", "ID '%s' 重复", trader.ID)
 		}
 		traderIDs[trader.ID] = true
 
-		if trader.Name == "" {
-			return fmt.Errorf("trader[%d]: Name不能为空", i)
+		trader.validate(&v, path)
+	}
+
+	if c.APIServerPort <= 0 {
+		c.APIServerPort = 8080 // 默认8080端口
+	}
+	if c.APIServerPort > 65535 {
+		v.add("$.api_server_port", "端口必须在1-65535之间")
+	}
+
+	if c.MaxDailyLoss < 0 {
+		v.add("$.max_daily_loss", "不能为负数")
+	}
+	if c.MaxDrawdown < 0 {
+		v.add("$.max_drawdown", "不能为负数")
+	}
+	if c.StopTradingMinutes < 0 {
+		v.add("$.stop_trading_minutes", "不能为负数")
+	}
+
+	// 设置杠杆默认值（适配币安子账户限制，最大5倍）
+	if c.Leverage.BTCETHLeverage <= 0 {
+		c.Leverage.BTCETHLeverage = 5 // 默认5倍（安全值，适配子账户）
+	}
+	if c.Leverage.BTCETHLeverage > maxLeverage {
+		v.add("$.leverage.btc_eth_leverage", "不能超过%dx", maxLeverage)
+	} else if c.Leverage.BTCETHLeverage > 5 {
+		fmt.Printf("⚠️  警告: BTC/ETH杠杆设置为%dx，如果使用子账户可能会失败（子账户限制≤5x）\n", c.Leverage.BTCETHLeverage)
+	}
+	if c.Leverage.AltcoinLeverage <= 0 {
+		c.Leverage.AltcoinLeverage = 5 // 默认5倍（安全值，适配子账户）
+	}
+	if c.Leverage.AltcoinLeverage > maxLeverage {
+		v.add("$.leverage.altcoin_leverage", "不能超过%dx", maxLeverage)
+	} else if c.Leverage.AltcoinLeverage > 5 {
+		fmt.Printf("⚠️  警告: 山寨币杠杆设置为%dx，如果使用子账户可能会失败（子账户限制≤5x）\n", c.Leverage.AltcoinLeverage)
+	}
+
+	// 币种格式（是否在交易所上线由 ValidateSymbols 检查）
+	validateCoins(&v, "$.default_coins", c.DefaultCoins)
+
+	c.SafeMode.validate(&v, "$.safe_mode")
+	c.Memory.validate(&v, "$.memory")
+	c.Reflection.validate(&v, "$.reflection")
+	c.Tools.validate(&v, "$.tools")
+	if c.PromptLanguage == "" {
+		c.PromptLanguage = decision.DefaultLanguage
+	}
+	validatePromptLanguage(&v, "$.prompt_language", c.PromptLanguage)
+
+	experimentNames := make(map[string]bool)
+	for i := range c.Experiments {
+		path := fmt.Sprintf("$.experiments[%d]", i)
+		c.Experiments[i].validate(&v, path)
+		if name := c.Experiments[i].Name; name != "" {
+			if experimentNames[name] {
+				v.add(path+".name", "实验 '%s' 重复", name)
+			}
+			experimentNames[name] = true
 		}
-		if trader.AIModel != "qwen" && trader.AIModel != "deepseek" && trader.AIModel != "custom" {
-			return fmt.Errorf("trader[%d]: ai_model必须是 'qwen', 'deepseek' 或 'custom'", i)
+	}
+	for i := range c.Traders {
+		if err := c.CheckExperiment(c.Traders[i].Experiment); err != nil {
+			v.add(fmt.Sprintf("$.traders[%d].experiment", i), "%v", err)
 		}
+	}
+	c.APIAuth.validate(&v, "$.api_auth")
+	c.Competition.validate(&v, "$.competition")
 
-		// 验证交易平台配置
-		if trader.Exchange == "" {
-			trader.Exchange = "binance" // 默认使用币安
+	return v.err()
+}
+
+// Validate 验证单个trader配置并设置默认值（ID唯一性由调用方检查）
+func (tc *TraderConfig) Validate() error {
+	var v validator
+	tc.validate(&v, "$")
+	return v.err()
+}
+
+// validate 验证trader配置，问题路径以 path 为前缀
+func (tc *TraderConfig) validate(v *validator, path string) {
+	if tc.ID == "" {
+		v.add(path+".id", "ID不能为空")
+	}
+	if tc.Name == "" {
+		v.add(path+".name", "Name不能为空")
+	}
+	if tc.AIModel != "qwen" && tc.AIModel != "deepseek" && tc.AIModel != "custom" {
+		v.add(path+".ai_model", "必须是 'qwen', 'deepseek' 或 'custom'")
+	}
+
+	// 验证交易平台配置
+	if tc.Exchange == "" {
+		tc.Exchange = "binance" // 默认使用币安
+	}
+
+	// 根据平台验证对应的密钥
+	switch tc.Exchange {
+	case "binance":
+		if tc.BinanceAPIKey == "" {
+			v.add(path+".binance_api_key", "使用币安时必须配置")
+		}
+		if tc.BinanceSecretKey == "" {
+			v.add(path+".binance_secret_key", "使用币安时必须配置")
+		}
+	case "hyperliquid":
+		if tc.HyperliquidPrivateKey == "" {
+			v.add(path+".hyperliquid_private_key", "使用Hyperliquid时必须配置")
+		}
+	case "aster":
+		if tc.AsterUser == "" {
+			v.add(path+".aster_user", "使用Aster时必须配置")
+		}
+		if tc.AsterSigner == "" {
+			v.add(path+".aster_signer", "使用Aster时必须配置")
 		}
-		if trader.Exchange != "binance" && trader.Exchange != "hyperliquid" && trader.Exchange != "aster" {
-			return fmt.Errorf("trader[%d]: exchange必须是 'binance', 'hyperliquid' 或 'aster'", i)
+		if tc.AsterPrivateKey == "" {
+			v.add(path+".aster_private_key", "使用Aster时必须配置")
 		}
+	default:
+		v.add(path+".exchange", "必须是 'binance', 'hyperliquid' 或 'aster'")
+	}
 
-		// 根据平台验证对应的密钥
-		if trader.Exchange == "binance" {
-			if trader.BinanceAPIKey == "" || trader.BinanceSecretKey == "" {
-				return fmt.Errorf("trader[%d]: 使用币安时必须配置binance_api_key和binance_secret_key", i)
-			}
-		} else if trader.Exchange == "hyperliquid" {
-			if trader.HyperliquidPrivateKey == "" {
-				return fmt.Errorf("trader[%d]: 使用Hyperliquid时必须配置hyperliquid_private_key", i)
-			}
-		} else if trader.Exchange == "aster" {
-			if trader.AsterUser == "" || trader.AsterSigner == "" || trader.AsterPrivateKey == "" {
-				return fmt.Errorf("trader[%d]: 使用Aster时必须配置aster_user, aster_signer和aster_private_key", i)
-			}
+	if tc.AIModel == "qwen" && tc.QwenKey == "" {
+		v.add(path+".qwen_key", "使用Qwen时必须配置")
+	}
+	if tc.AIModel == "deepseek" && tc.DeepSeekKey == "" {
+		v.add(path+".deepseek_key", "使用DeepSeek时必须配置")
+	}
+	if tc.AIModel == "custom" {
+		if tc.CustomAPIURL == "" {
+			v.add(path+".custom_api_url", "使用自定义API时必须配置")
+		}
+		if tc.CustomAPIKey == "" {
+			v.add(path+".custom_api_key", "使用自定义API时必须配置")
+		}
+		if tc.CustomModelName == "" {
+			v.add(path+".custom_model_name", "使用自定义API时必须配置")
 		}
+	}
+	if tc.InitialBalance <= 0 {
+		v.add(path+".initial_balance", "必须大于0")
+	}
+	if tc.ScanIntervalMinutes < 0 {
+		v.add(path+".scan_interval_minutes", "不能为负数")
+	}
+	if tc.ScanIntervalMinutes == 0 {
+		tc.ScanIntervalMinutes = 3 // 默认3分钟
+	}
+	validateTokenLimits(v, path, tc.ContextWindow, tc.MaxTokens)
 
-		if trader.AIModel == "qwen" && trader.QwenKey == "" {
-			return fmt.Errorf("trader[%d]: 使用Qwen时必须配置qwen_key", i)
+	// trader级覆盖
+	if tc.Leverage != nil {
+		if tc.Leverage.BTCETHLeverage < 0 || tc.Leverage.BTCETHLeverage > maxLeverage {
+			v.add(path+".leverage.btc_eth_leverage", "必须在0-%d之间（0表示使用全局值）", maxLeverage)
 		}
-		if trader.AIModel == "deepseek" && trader.DeepSeekKey == "" {
-			return fmt.Errorf("trader[%d]: 使用DeepSeek时必须配置deepseek_key", i)
+		if tc.Leverage.AltcoinLeverage < 0 || tc.Leverage.AltcoinLeverage > maxLeverage {
+			v.add(path+".leverage.altcoin_leverage", "必须在0-%d之间（0表示使用全局值）", maxLeverage)
 		}
-		if trader.AIModel == "custom" {
-			if trader.CustomAPIURL == "" {
-				return fmt.Errorf("trader[%d]: 使用自定义API时必须配置custom_api_url", i)
-			}
-			if trader.CustomAPIKey == "" {
-				return fmt.Errorf("trader[%d]: 使用自定义API时必须配置custom_api_key", i)
-			}
-			if trader.CustomModelName == "" {
-				return fmt.Errorf("trader[%d]: 使用自定义API时必须配置custom_model_name", i)
+	}
+	if tc.MaxDailyLoss != nil && *tc.MaxDailyLoss < 0 {
+		v.add(path+".max_daily_loss", "不能为负数")
+	}
+	if tc.MaxDrawdown != nil && *tc.MaxDrawdown < 0 {
+		v.add(path+".max_drawdown", "不能为负数")
+	}
+	if tc.StopTradingMinutes != nil && *tc.StopTradingMinutes < 0 {
+		v.add(path+".stop_trading_minutes", "不能为负数")
+	}
+	validateCoins(v, path+".default_coins", tc.DefaultCoins)
+	if tc.SafeMode != nil {
+		tc.SafeMode.validate(v, path+".safe_mode")
+	}
+	if tc.Memory != nil {
+		tc.Memory.validate(v, path+".memory")
+	}
+	if tc.Reflection != nil {
+		tc.Reflection.validate(v, path+".reflection")
+	}
+	if tc.Tools != nil {
+		tc.Tools.validate(v, path+".tools")
+	}
+
+	if tc.Ensemble != nil {
+		tc.Ensemble.validate(v, path+".ensemble", tc.AIModel)
+	}
+	names := map[string]bool{tc.AIModel: true}
+	for i := range tc.FallbackModels {
+		tc.FallbackModels[i].validate(v, fmt.Sprintf("%s.fallback_models[%d]", path, i), names)
+	}
+
+	if tc.PromptLanguage != "" {
+		validatePromptLanguage(v, path+".prompt_language", tc.PromptLanguage)
+	}
+	if tc.PromptTemplate != "" && (tc.PromptLanguage == "" || decision.IsSupportedLanguage(tc.PromptLanguage)) {
+		if _, err := decision.LoadPromptTemplate(tc.PromptTemplate, tc.PromptLanguage); err != nil {
+			v.add(path+".prompt_template", "%v", err)
+		}
+	}
+}
+
+// validate 验证prompt实验配置
+func (ec *ExperimentConfig) validate(v *validator, path string) {
+	if ec.Name == "" {
+		v.add(path+".name", "实验名称不能为空")
+	} else if strings.Contains(ec.Name, "/") {
+		v.add(path+".name", "实验名称不能包含 '/'")
+	}
+	if len(ec.Variants) < 2 {
+		v.add(path+".variants", "至少需要两个变体")
+	}
+
+	names := make(map[string]bool)
+	for i, variant := range ec.Variants {
+		variantPath := fmt.Sprintf("%s.variants[%d]", path, i)
+		if variant.Name == "" {
+			v.add(variantPath+".name", "变体名称不能为空")
+		} else if names[variant.Name] {
+			v.add(variantPath+".name", "变体 '%s' 重复", variant.Name)
+		}
+		names[variant.Name] = true
+
+		if variant.PromptLanguage != "" {
+			validatePromptLanguage(v, variantPath+".prompt_language", variant.PromptLanguage)
+		}
+		if variant.PromptTemplate != "" && (variant.PromptLanguage == "" || decision.IsSupportedLanguage(variant.PromptLanguage)) {
+			if _, err := decision.LoadPromptTemplate(variant.PromptTemplate, variant.PromptLanguage); err != nil {
+				v.add(variantPath+".prompt_template", "%v", err)
 			}
 		}
-		if trader.InitialBalance <= 0 {
-			return fmt.Errorf("trader[%d]: initial_balance必须大于0", i)
+	}
+}
+
+// FindExperiment 按名称查找prompt实验，不存在时返回nil
+func (c *Config) FindExperiment(name string) *ExperimentConfig {
+	for i := range c.Experiments {
+		if c.Experiments[i].Name == name {
+			return &c.Experiments[i]
 		}
-		if trader.ScanIntervalMinutes <= 0 {
-			trader.ScanIntervalMinutes = 3 // 默认3分钟
+	}
+	return nil
+}
+
+// CheckExperiment 检查trader参与的实验和变体是否存在（未参与实验时返回nil）
+func (c *Config) CheckExperiment(a *ExperimentAssignment) error {
+	if a == nil {
+		return nil
+	}
+	exp := c.FindExperiment(a.Name)
+	if exp == nil {
+		return fmt.Errorf("实验 '%s' 不存在", a.Name)
+	}
+	if a.Variant == "" {
+		return nil
+	}
+	for _, variant := range exp.Variants {
+		if variant.Name == a.Variant {
+			return nil
 		}
 	}
+	return fmt.Errorf("实验 '%s' 中不存在变体 '%s'", a.Name, a.Variant)
+}
 
-	if c.APIServerPort <= 0 {
-		c.APIServerPort = 8080 // 默认8080端口
+// validatePromptLanguage 检查prompt语言是否受支持
+func validatePromptLanguage(v *validator, path, lang string) {
+	if !decision.IsSupportedLanguage(lang) {
+		v.add(path, "必须是 %s 之一", strings.Join(decision.Languages, ", "))
 	}
+}
 
-	// 设置杠杆默认值（适配币安子账户限制，最大5倍）
-	if c.Leverage.BTCETHLeverage <= 0 {
-		c.Leverage.BTCETHLeverage = 5 // 默认5倍（安全值，适配子账户）
+// validate 验证多模型投票配置并设置默认值
+func (ec *EnsembleConfig) validate(v *validator, path, primaryModel string) {
+	switch ec.Mode {
+	case "":
+		ec.Mode = VoteMajority
+	case VoteMajority, VoteWeighted, VoteUnanimous:
+	default:
+		v.add(path+".mode", "必须是 '%s', '%s' 或 '%s'", VoteMajority, VoteWeighted, VoteUnanimous)
 	}
-	if c.Leverage.BTCETHLeverage > 5 {
-		fmt.Printf("⚠️  警告: BTC/ETH杠杆设置为%dx，如果使用子账户可能会失败（子账户限制≤5x）\n", c.Leverage.BTCETHLeverage)
+	if ec.PrimaryWeight < 0 {
+		v.add(path+".primary_weight", "不能为负数")
+	} else if ec.PrimaryWeight == 0 {
+		ec.PrimaryWeight = 1
 	}
-	if c.Leverage.AltcoinLeverage <= 0 {
-		c.Leverage.AltcoinLeverage = 5 // 默认5倍（安全值，适配子账户）
+	if len(ec.Models) == 0 {
+		v.add(path+".models", "至少需要配置一个额外的模型")
 	}
-	if c.Leverage.AltcoinLeverage > 5 {
-		fmt.Printf("⚠️  警告: 山寨币杠杆设置为%dx，如果使用子账户可能会失败（子账户限制≤5x）\n", c.Leverage.AltcoinLeverage)
+
+	names := map[string]bool{primaryModel: true}
+	for i := range ec.Models {
+		ec.Models[i].validate(v, fmt.Sprintf("%s.models[%d]", path, i), names)
 	}
+}
 
-	return nil
+// validate 验证模型配置并设置默认值，names 为同组中已使用的名称
+func (m *ModelConfig) validate(v *validator, path string, names map[string]bool) {
+	switch m.AIModel {
+	case "qwen", "deepseek":
+	case "custom":
+		if m.APIURL == "" {
+			v.add(path+".api_url", "使用自定义API时必须配置")
+		}
+		if m.ModelName == "" {
+			v.add(path+".model_name", "使用自定义API时必须配置")
+		}
+	default:
+		v.add(path+".ai_model", "必须是 'qwen', 'deepseek' 或 'custom'")
+	}
+	if m.APIKey == "" {
+		v.add(path+".api_key", "不能为空")
+	}
+	if m.Weight < 0 {
+		v.add(path+".weight", "不能为负数")
+	} else if m.Weight == 0 {
+		m.Weight = 1
+	}
+	validateTokenLimits(v, path, m.ContextWindow, m.MaxTokens)
+
+	if m.Name == "" {
+		m.Name = m.AIModel
+		if m.ModelName != "" {
+			m.Name = m.ModelName
+		}
+	}
+	if names[m.Name] {
+		v.add(path+".name", "名称 '%s' 重复（与 ai_model 或其他模型相同时请设置不同的name）", m.Name)
+	}
+	names[m.Name] = true
+}
+
+// validateTokenLimits 验证上下文窗口和回复token数（0表示使用默认值）
+func validateTokenLimits(v *validator, path string, contextWindow, maxTokens int) {
+	if contextWindow < 0 {
+		v.add(path+".context_window", "不能为负数")
+	}
+	if maxTokens < 0 {
+		v.add(path+".max_tokens", "不能为负数")
+	}
+	if contextWindow > 0 && maxTokens >= contextWindow {
+		v.add(path+".max_tokens", "必须小于 context_window (%d)", contextWindow)
+	}
+}
+
+// validate 验证安全模式配置并设置默认值
+func (sm *SafeModeConfig) validate(v *validator, path string) {
+	if sm.AfterFailures < 0 {
+		v.add(path+".after_failures", "不能为负数")
+	} else if sm.AfterFailures == 0 {
+		sm.AfterFailures = 3
+	}
+	if sm.MaxAdversePct < 0 || sm.MaxAdversePct >= 100 {
+		v.add(path+".max_adverse_pct", "必须在0-100之间（0表示不限制）")
+	}
+	if sm.MaxHoldHours < 0 {
+		v.add(path+".max_hold_hours", "不能为负数")
+	}
+	if sm.TightenStopPct < 0 || sm.TightenStopPct >= 100 {
+		v.add(path+".tighten_stop_pct", "必须在0-100之间（0表示不调整）")
+	}
+	if sm.Enabled && !sm.FlattenAll && sm.MaxAdversePct == 0 && sm.MaxHoldHours == 0 && sm.TightenStopPct == 0 {
+		v.add(path, "启用后至少需要配置一条规则（flatten_all、max_adverse_pct、max_hold_hours 或 tighten_stop_pct）")
+	}
+}
+
+// maxMemoryCycles 跨周期记忆最多附带的周期数（避免prompt过长）
+const maxMemoryCycles = 20
+
+// validate 验证跨周期记忆配置并设置默认值
+func (mc *MemoryConfig) validate(v *validator, path string) {
+	if mc.Cycles < 0 || mc.Cycles > maxMemoryCycles {
+		v.add(path+".cycles", "必须在0-%d之间（0表示默认值）", maxMemoryCycles)
+	} else if mc.Cycles == 0 {
+		mc.Cycles = 3
+	}
+	if mc.MaxCoTChars < 0 {
+		v.add(path+".max_cot_chars", "不能为负数")
+	} else if mc.MaxCoTChars == 0 {
+		mc.MaxCoTChars = 400
+	}
+	if mc.MaxJournalChars < 0 {
+		v.add(path+".max_journal_chars", "不能为负数")
+	} else if mc.MaxJournalChars == 0 {
+		mc.MaxJournalChars = 2000
+	}
+}
+
+// maxReflectionLessons 放入prompt的复盘最多条数（避免prompt过长）
+const maxReflectionLessons = 20
+
+// validate 验证交易复盘配置并设置默认值
+func (rc *ReflectionConfig) validate(v *validator, path string) {
+	if rc.MaxPerCycle < 0 {
+		v.add(path+".max_per_cycle", "不能为负数")
+	} else if rc.MaxPerCycle == 0 {
+		rc.MaxPerCycle = 1
+	}
+	if rc.MaxChars < 0 {
+		v.add(path+".max_chars", "不能为负数")
+	} else if rc.MaxChars == 0 {
+		rc.MaxChars = 300
+	}
+	if rc.Lessons < 0 || rc.Lessons > maxReflectionLessons {
+		v.add(path+".lessons", "必须在0-%d之间（0表示默认值）", maxReflectionLessons)
+	} else if rc.Lessons == 0 {
+		rc.Lessons = 5
+	}
+}
+
+// maxToolCalls 每个周期最多允许的工具调用次数（每次调用都会增加一轮AI请求）
+const maxToolCalls = 10
+
+// validate 验证工具调用配置并设置默认值
+func (tc *ToolsConfig) validate(v *validator, path string) {
+	if tc.MaxCalls < 0 || tc.MaxCalls > maxToolCalls {
+		v.add(path+".max_calls", "必须在0-%d之间（0表示默认值）", maxToolCalls)
+	} else if tc.MaxCalls == 0 {
+		tc.MaxCalls = 3
+	}
+}
+
+// Clone 深拷贝trader配置（覆盖项、投票和备用模型配置，修改副本不影响原配置）
+func (tc TraderConfig) Clone() TraderConfig {
+	if tc.Leverage != nil {
+		leverage := *tc.Leverage
+		tc.Leverage = &leverage
+	}
+	if tc.MaxDailyLoss != nil {
+		v := *tc.MaxDailyLoss
+		tc.MaxDailyLoss = &v
+	}
+	if tc.MaxDrawdown != nil {
+		v := *tc.MaxDrawdown
+		tc.MaxDrawdown = &v
+	}
+	if tc.StopTradingMinutes != nil {
+		v := *tc.StopTradingMinutes
+		tc.StopTradingMinutes = &v
+	}
+	tc.DefaultCoins = append([]string(nil), tc.DefaultCoins...)
+	if tc.SafeMode != nil {
+		safeMode := *tc.SafeMode
+		tc.SafeMode = &safeMode
+	}
+	if tc.Memory != nil {
+		memory := *tc.Memory
+		tc.Memory = &memory
+	}
+	if tc.Reflection != nil {
+		reflection := *tc.Reflection
+		tc.Reflection = &reflection
+	}
+	if tc.Tools != nil {
+		tools := *tc.Tools
+		tc.Tools = &tools
+	}
+	if tc.Experiment != nil {
+		experiment := *tc.Experiment
+		tc.Experiment = &experiment
+	}
+	if tc.Ensemble != nil {
+		ensemble := *tc.Ensemble
+		ensemble.Models = append([]ModelConfig(nil), tc.Ensemble.Models...)
+		tc.Ensemble = &ensemble
+	}
+	tc.FallbackModels = append([]ModelConfig(nil), tc.FallbackModels...)
+	return tc
+}
+
+// validateCoins 检查币种格式和重复
+func validateCoins(v *validator, path string, coins []string) {
+	seen := make(map[string]bool)
+	for i, coin := range coins {
+		coinPath := fmt.Sprintf("%s[%d]", path, i)
+		if !symbolPattern.MatchString(coin) {
+			v.add(coinPath, "'%s' 不是有效的币种（应为大写字母和数字，如 BTCUSDT）", coin)
+			continue
+		}
+		symbol := normalizeSymbol(coin)
+		if seen[symbol] {
+			v.add(coinPath, "币种 %s 重复", symbol)
+		}
+		seen[symbol] = true
+	}
+}
+
+// validate 验证API认证配置并设置默认值
+func (ac *APIAuthConfig) validate(v *validator, path string) {
+	names := make(map[string]bool)
+	keys := make(map[string]bool)
+	for i, k := range ac.APIKeys {
+		keyPath := fmt.Sprintf("%s.api_keys[%d]", path, i)
+		if k.Name == "" {
+			v.add(keyPath+".name", "不能为空")
+		} else if names[k.Name] {
+			v.add(keyPath+".name", "'%s' 重复", k.Name)
+		}
+		names[k.Name] = true
+
+		if len(k.Key) < 16 {
+			v.add(keyPath+".key", "长度不能少于16个字符")
+		} else if keys[k.Key] {
+			v.add(keyPath+".key", "与其他key重复")
+		}
+		keys[k.Key] = true
+
+		if k.Role != RoleReadOnly && k.Role != RoleOperator {
+			v.add(keyPath+".role", "必须是 '%s' 或 '%s'", RoleReadOnly, RoleOperator)
+		}
+	}
+
+	if ac.JWTSecret != "" && len(ac.JWTSecret) < 32 {
+		v.add(path+".jwt_secret", "长度不能少于32个字符")
+	}
+	if ac.AuditLogFile == "" {
+		ac.AuditLogFile = "audit.log"
+	}
+}
+
+// validate 验证竞赛配置并设置默认值
+func (cc *CompetitionConfig) validate(v *validator, path string) {
+	s := &cc.Scoring
+	if s.ReturnWeight < 0 || s.SharpeWeight < 0 || s.DrawdownPenalty < 0 {
+		v.add(path+".scoring", "权重不能为负数")
+	}
+	if s.ReturnWeight == 0 && s.SharpeWeight == 0 && s.DrawdownPenalty == 0 {
+		s.ReturnWeight = 1 // 默认按收益率排名
+	}
+
+	if cc.RankIntervalMinutes <= 0 {
+		cc.RankIntervalMinutes = 60
+	}
+
+	names := make(map[string]bool)
+	for i, season := range cc.Seasons {
+		seasonPath := fmt.Sprintf("%s.seasons[%d]", path, i)
+		if season.Name == "" {
+			v.add(seasonPath+".name", "不能为空")
+		} else if names[season.Name] {
+			v.add(seasonPath+".name", "'%s' 重复", season.Name)
+		}
+		names[season.Name] = true
+
+		start, end, err := season.Period()
+		if err != nil {
+			v.add(seasonPath, "%v", err)
+			continue
+		}
+		if start.IsZero() {
+			v.add(seasonPath+".start", "不能为空")
+		}
+		if !end.IsZero() && !start.Before(end) {
+			v.add(seasonPath+".end", "必须晚于start")
+		}
+	}
+}
+
+// Period 解析赛季起止时间（未设置的时间返回零值）
+func (sc SeasonConfig) Period() (time.Time, time.Time, error) {
+	start, err := parseConfigTime(sc.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("start无效: %w", err)
+	}
+	end, err := parseConfigTime(sc.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("end无效: %w", err)
+	}
+	return start, end, nil
+}
+
+// GetRankInterval 获取排名历史采样间隔
+func (cc *CompetitionConfig) GetRankInterval() time.Duration {
+	return time.Duration(cc.RankIntervalMinutes) * time.Minute
+}
+
+// parseConfigTime 解析配置中的时间，支持 RFC3339、"2006-01-02 15:04:05" 和 "2006-01-02"
+func parseConfigTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s", v)
 }
 
 // GetScanInterval 获取扫描间隔
@@ -234,6 +954,87 @@ func (tc *TraderConfig) GetScanInterval() time.Duration {
 	return time.Duration(tc.ScanIntervalMinutes) * time.Minute
 }
 
+// EffectiveLeverage trader的杠杆配置（trader级设置覆盖全局值）
+func (tc *TraderConfig) EffectiveLeverage(global LeverageConfig) LeverageConfig {
+	if tc.Leverage == nil {
+		return global
+	}
+	leverage := global
+	if tc.Leverage.BTCETHLeverage > 0 {
+		leverage.BTCETHLeverage = tc.Leverage.BTCETHLeverage
+	}
+	if tc.Leverage.AltcoinLeverage > 0 {
+		leverage.AltcoinLeverage = tc.Leverage.AltcoinLeverage
+	}
+	return leverage
+}
+
+// EffectiveRiskLimits trader的风控参数（trader级设置覆盖全局值）
+func (tc *TraderConfig) EffectiveRiskLimits(maxDailyLoss, maxDrawdown float64, stopTradingMinutes int) (float64, float64, int) {
+	if tc.MaxDailyLoss != nil {
+		maxDailyLoss = *tc.MaxDailyLoss
+	}
+	if tc.MaxDrawdown != nil {
+		maxDrawdown = *tc.MaxDrawdown
+	}
+	if tc.StopTradingMinutes != nil {
+		stopTradingMinutes = *tc.StopTradingMinutes
+	}
+	return maxDailyLoss, maxDrawdown, stopTradingMinutes
+}
+
+// EffectiveSafeMode trader的安全模式配置（trader级设置整体覆盖全局值）
+func (tc *TraderConfig) EffectiveSafeMode(global SafeModeConfig) SafeModeConfig {
+	if tc.SafeMode != nil {
+		return *tc.SafeMode
+	}
+	return global
+}
+
+// EffectiveMemory trader的跨周期记忆配置（trader级设置整体覆盖全局值）
+func (tc *TraderConfig) EffectiveMemory(global MemoryConfig) MemoryConfig {
+	if tc.Memory != nil {
+		return *tc.Memory
+	}
+	return global
+}
+
+// EffectiveReflection trader的交易复盘配置（trader级设置整体覆盖全局值）
+func (tc *TraderConfig) EffectiveReflection(global ReflectionConfig) ReflectionConfig {
+	if tc.Reflection != nil {
+		return *tc.Reflection
+	}
+	return global
+}
+
+// EffectiveTools trader的工具调用配置（trader级设置整体覆盖全局值）
+func (tc *TraderConfig) EffectiveTools(global ToolsConfig) ToolsConfig {
+	if tc.Tools != nil {
+		return *tc.Tools
+	}
+	return global
+}
+
+// EffectivePromptLanguage trader的prompt语言（trader未设置时使用全局值）
+func (tc *TraderConfig) EffectivePromptLanguage(global string) string {
+	if tc.PromptLanguage != "" {
+		return tc.PromptLanguage
+	}
+	return global
+}
+
+// EffectiveWhitelist trader的币种白名单（trader未设置时使用全局 DefaultCoins，trader级币种统一为USDT交易对）
+func (tc *TraderConfig) EffectiveWhitelist(global []string) []string {
+	if len(tc.DefaultCoins) == 0 {
+		return global
+	}
+	coins := make([]string, len(tc.DefaultCoins))
+	for i, coin := range tc.DefaultCoins {
+		coins[i] = normalizeSymbol(coin)
+	}
+	return coins
+}
+
 // IsCoinInWhitelist 检查币种是否在白名单中
 // 如果 DefaultCoins 不为空，则使用 DefaultCoins 作为白名单；否则允许所有币种
 func (c *Config) IsCoinInWhitelist(coin string) bool {
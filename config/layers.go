@@ -0,0 +1,485 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 配置按以下顺序分层合并，后者覆盖前者：
+//   默认值 → 配置文件 → NOFX_* 环境变量 → 命令行 -set 参数
+//
+// 环境变量名由JSON路径转大写并用下划线连接，数组用下标表示，例如：
+//   NOFX_API_SERVER_PORT=9090
+//   NOFX_LEVERAGE_BTC_ETH_LEVERAGE=10
+//   NOFX_DEFAULT_COINS=BTCUSDT,ETHUSDT         （字符串数组用逗号分隔）
+//   NOFX_TRADERS_0_BINANCE_SECRET_KEY=env:X   （第1个trader，值同样支持密钥引用）
+//   NOFX_TRADERS_BINANCE_QWEN_SCAN_INTERVAL_MINUTES=5 （ID为 binance_qwen 的trader）
+// 命令行参数使用点号路径，例如 -set traders.1.scan_interval_minutes=5 或 -set traders.binance_qwen.scan_interval_minutes=5
+//
+// 数组元素可以用下标或ID引用。下标在删除前面的trader后会指向下一个trader，ID则始终指向同一个trader；
+// 环境变量中ID的字母不区分大小写，字母和数字以外的字符写成下划线；按ID引用只能覆盖已有的元素，ID不存在时忽略该覆盖
+
+// EnvPrefix 配置覆盖环境变量的前缀
+const EnvPrefix = "NOFX_"
+
+// elementIDField 可以按ID引用的数组元素的ID字段
+const elementIDField = "id"
+
+// errUnknownElementID 覆盖按ID引用的数组元素不存在（例如trader已删除），该覆盖被忽略
+var errUnknownElementID = errors.New("没有该ID的元素")
+
+// reservedEnv 以 NOFX_ 开头但不属于配置覆盖的环境变量
+var reservedEnv = map[string]bool{
+	KeystorePassphraseEnv:     true,
+	KeystorePassphraseFileEnv: true,
+}
+
+// override 一项配置覆盖
+type override struct {
+	source string   // 来源（环境变量名或命令行参数）
+	path   []string // JSON路径
+	value  string
+}
+
+var (
+	flagOverridesMu sync.RWMutex
+	flagOverrides   []override
+)
+
+// SetFlagOverrides 设置命令行覆盖（"路径=值" 形式，如 "leverage.btc_eth_leverage=10"）
+// 之后每次加载配置（包括热加载）都会应用
+func SetFlagOverrides(sets []string) error {
+	var parsed []override
+	for _, s := range sets {
+		key, value, ok := strings.Cut(s, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("-set 参数格式应为 路径=值: %s", s)
+		}
+		path := strings.Split(key, ".")
+		if _, err := leafType(reflect.TypeOf(Config{}), path); err != nil {
+			return fmt.Errorf("-set %s: %w", key, err)
+		}
+		parsed = append(parsed, override{source: "-set " + key, path: path, value: value})
+	}
+
+	flagOverridesMu.Lock()
+	defer flagOverridesMu.Unlock()
+	flagOverrides = parsed
+	return nil
+}
+
+// envOverrides 收集 NOFX_* 环境变量覆盖（按变量名排序），无法识别的变量返回在 unknown 中
+func envOverrides() (overrides []override, unknown []string) {
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, EnvPrefix) || reservedEnv[key] {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(key, EnvPrefix))
+		path, ok := matchEnvName(reflect.TypeOf(Config{}), name)
+		if !ok {
+			unknown = append(unknown, key)
+			continue
+		}
+		overrides = append(overrides, override{source: key, path: path, value: value})
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].source < overrides[j].source })
+	sort.Strings(unknown)
+	return overrides, unknown
+}
+
+// activeOverrides 当前生效的全部覆盖（环境变量在前，命令行在后）
+func activeOverrides() []override {
+	overrides, unknown := envOverrides()
+	for _, key := range unknown {
+		log.Printf("⚠️  忽略无法识别的环境变量 %s", key)
+	}
+
+	flagOverridesMu.RLock()
+	defer flagOverridesMu.RUnlock()
+	return append(overrides, flagOverrides...)
+}
+
+// applyOverrides 将环境变量和命令行覆盖合并到配置文件内容上，返回合并后的JSON
+func applyOverrides(data []byte, overrides []override) ([]byte, error) {
+	if len(overrides) == 0 {
+		return data, nil
+	}
+
+	var root interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&root); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	if _, ok := root.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("解析配置文件失败: 顶层必须是JSON对象")
+	}
+
+	for i, o := range overrides {
+		path, err := resolvePath(root, reflect.TypeOf(Config{}), o.path)
+		if errors.Is(err, errUnknownElementID) {
+			log.Printf("⚠️  忽略配置覆盖 %s: %v", o.source, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", o.source, err)
+		}
+		// 记录解析后的下标路径（filterEnvProvided 按下标路径比较）
+		overrides[i].path = path
+
+		updated, err := setPath(root, reflect.TypeOf(Config{}), path, o.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", o.source, err)
+		}
+		root = updated
+		log.Printf("⚙️  配置覆盖: %s ← %s", strings.Join(path, "."), o.source)
+	}
+	return json.Marshal(root)
+}
+
+// IndexedTraderOverrides 返回按下标引用第 from 个及之后trader的覆盖来源
+// 删除第 from 个trader后，这些覆盖会落到原本的下一个trader上
+func IndexedTraderOverrides(from int) []string {
+	var sources []string
+	for _, o := range activeOverrides() {
+		if len(o.path) < 2 || o.path[0] != "traders" {
+			continue
+		}
+		if index, err := strconv.Atoi(o.path[1]); err == nil && index >= from {
+			sources = append(sources, o.source)
+		}
+	}
+	return sources
+}
+
+// filterEnvProvided 去掉由环境变量提供的字段路径（环境变量中的明文密钥不在配置文件中）
+func filterEnvProvided(paths []string, overrides []override) []string {
+	fromEnv := make(map[string]bool)
+	for _, o := range overrides {
+		if strings.HasPrefix(o.source, EnvPrefix) {
+			fromEnv[strings.Join(o.path, ".")] = true
+		}
+	}
+	kept := paths[:0]
+	for _, p := range paths {
+		if !fromEnv[p] {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// jsonFieldName 结构体字段的JSON名（忽略的字段返回空字符串）
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" || !f.IsExported() {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// derefType 指针类型（可选的trader级覆盖）按其指向的类型处理
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isLeafType 可以直接由字符串覆盖的类型（标量和字符串数组）
+func isLeafType(t reflect.Type) bool {
+	switch derefType(t).Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// hasIDField 数组元素是否可以按ID引用
+func hasIDField(t reflect.Type) bool {
+	t = derefType(t)
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if jsonFieldName(t.Field(i)) == elementIDField && t.Field(i).Type.Kind() == reflect.String {
+			return true
+		}
+	}
+	return false
+}
+
+// envKey 将ID转换为环境变量中的写法（小写，字母和数字以外的字符换成下划线）
+func envKey(id string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+// matchEnvName 将小写的环境变量名（去掉前缀）匹配到配置的JSON路径
+// 字段名本身包含下划线，因此按结构体字段逐级尝试前缀匹配
+// 数组元素的ID也可能包含下划线，按ID引用时从最短的ID开始尝试
+func matchEnvName(t reflect.Type, name string) ([]string, bool) {
+	t = derefType(t)
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := jsonFieldName(field)
+			if tag == "" {
+				continue
+			}
+			if name == tag && isLeafType(field.Type) {
+				return []string{tag}, true
+			}
+			if rest, ok := strings.CutPrefix(name, tag+"_"); ok {
+				if sub, ok := matchEnvName(field.Type, rest); ok {
+					return append([]string{tag}, sub...), true
+				}
+			}
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Struct {
+			return nil, false
+		}
+		index, rest, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, false
+		}
+		if _, err := strconv.Atoi(index); err == nil {
+			if sub, ok := matchEnvName(t.Elem(), rest); ok {
+				return append([]string{index}, sub...), true
+			}
+			return nil, false
+		}
+		if !hasIDField(t.Elem()) {
+			return nil, false
+		}
+		for i := len(index); i < len(name); i++ {
+			if name[i] != '_' {
+				continue
+			}
+			if sub, ok := matchEnvName(t.Elem(), name[i+1:]); ok {
+				return append([]string{name[:i]}, sub...), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// leafType 校验JSON路径并返回其指向字段的类型
+func leafType(t reflect.Type, path []string) (reflect.Type, error) {
+	t = derefType(t)
+	if len(path) == 0 {
+		if !isLeafType(t) {
+			return nil, fmt.Errorf("只能覆盖单个值或字符串数组")
+		}
+		return t, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if jsonFieldName(t.Field(i)) == path[0] {
+				return leafType(t.Field(i).Type, path[1:])
+			}
+		}
+		return nil, fmt.Errorf("未知的配置项 '%s'", path[0])
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Struct {
+			index, err := strconv.Atoi(path[0])
+			if (err != nil && !hasIDField(t.Elem())) || index < 0 {
+				return nil, fmt.Errorf("数组下标无效 '%s'", path[0])
+			}
+			return leafType(t.Elem(), path[1:])
+		}
+	}
+	return nil, fmt.Errorf("'%s' 不是对象或数组", path[0])
+}
+
+// resolvePath 将路径中按ID引用的数组元素换成下标（ID不存在时返回 errUnknownElementID）
+// 其余路径错误留给 setPath 报告
+func resolvePath(node interface{}, t reflect.Type, path []string) ([]string, error) {
+	t = derefType(t)
+	if len(path) == 0 {
+		return path, nil
+	}
+
+	var child interface{}
+	var childType reflect.Type
+	segment := path[0]
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if jsonFieldName(t.Field(i)) == segment {
+				obj, _ := node.(map[string]interface{})
+				child, childType = obj[segment], t.Field(i).Type
+				break
+			}
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Struct {
+			return path, nil
+		}
+		arr, _ := node.([]interface{})
+		index, err := strconv.Atoi(segment)
+		if err != nil {
+			index = elementIndex(arr, segment)
+			if index < 0 {
+				return nil, fmt.Errorf("%w: '%s'", errUnknownElementID, segment)
+			}
+			segment = strconv.Itoa(index)
+		}
+		if index >= 0 && index < len(arr) {
+			child = arr[index]
+		}
+		childType = t.Elem()
+	}
+	if childType == nil {
+		return path, nil
+	}
+
+	rest, err := resolvePath(child, childType, path[1:])
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{segment}, rest...), nil
+}
+
+// elementIndex 查找ID匹配的数组元素下标（按环境变量写法比较），不存在时返回-1
+func elementIndex(arr []interface{}, id string) int {
+	for i, elem := range arr {
+		obj, _ := elem.(map[string]interface{})
+		if elemID, _ := obj[elementIDField].(string); elemID != "" && envKey(elemID) == envKey(id) {
+			return i
+		}
+	}
+	return -1
+}
+
+// setPath 在通用JSON值中按路径写入覆盖值（按字段类型转换），返回更新后的值
+// 数组下标超出长度时补齐空对象，可以通过环境变量新增trader
+func setPath(node interface{}, t reflect.Type, path []string, raw string) (interface{}, error) {
+	t = derefType(t)
+	if len(path) == 0 {
+		return parseLeaf(t, raw)
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, _ := node.(map[string]interface{})
+		if obj == nil {
+			obj = make(map[string]interface{})
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if jsonFieldName(t.Field(i)) != path[0] {
+				continue
+			}
+			v, err := setPath(obj[path[0]], t.Field(i).Type, path[1:], raw)
+			if err != nil {
+				return nil, err
+			}
+			obj[path[0]] = v
+			return obj, nil
+		}
+		return nil, fmt.Errorf("未知的配置项 '%s'", path[0])
+
+	case reflect.Slice:
+		index, err := strconv.Atoi(path[0])
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("数组下标无效 '%s'", path[0])
+		}
+		arr, _ := node.([]interface{})
+		for len(arr) <= index {
+			arr = append(arr, map[string]interface{}{})
+		}
+		v, err := setPath(arr[index], t.Elem(), path[1:], raw)
+		if err != nil {
+			return nil, err
+		}
+		arr[index] = v
+		return arr, nil
+	}
+	return nil, fmt.Errorf("'%s' 不是对象或数组", path[0])
+}
+
+// parseLeaf 将覆盖值按字段类型转换
+func parseLeaf(t reflect.Type, raw string) (interface{}, error) {
+	t = derefType(t)
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' 不是有效的布尔值", raw)
+		}
+		return v, nil
+	case reflect.Int, reflect.Int64:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' 不是有效的整数", raw)
+		}
+		return v, nil
+	case reflect.Float64:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' 不是有效的数字", raw)
+		}
+		return v, nil
+	case reflect.Slice:
+		values := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("不支持覆盖该类型的配置项")
+}
+
+// Masked 返回密钥已替换为 *** 的配置副本（用于展示生效配置）
+func (c *Config) Masked() *Config {
+	masked := *c
+	masked.Traders = make([]TraderConfig, len(c.Traders))
+	for i := range c.Traders {
+		masked.Traders[i] = c.Traders[i].Clone()
+	}
+	for i := range masked.Traders {
+		for _, f := range masked.Traders[i].secretFields() {
+			if *f.value != "" {
+				*f.value = redactedPlaceholder
+			}
+		}
+	}
+	masked.APIAuth.APIKeys = append([]APIKeyConfig(nil), c.APIAuth.APIKeys...)
+	for i := range masked.APIAuth.APIKeys {
+		masked.APIAuth.APIKeys[i].Key = redactedPlaceholder
+	}
+	if masked.APIAuth.JWTSecret != "" {
+		masked.APIAuth.JWTSecret = redactedPlaceholder
+	}
+	return &masked
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchEnvName(t *testing.T) {
+	// 字段名和父级名称共享前缀的结构，检查按字段逐级回溯
+	type nested struct {
+		Max      int `json:"max"`
+		MaxCalls int `json:"max_calls"`
+	}
+	type keyed struct {
+		ID       string `json:"id"`
+		MaxCalls int    `json:"max_calls"`
+	}
+	type prefixed struct {
+		A  int `json:"a"`
+		AB struct {
+			C int `json:"c"`
+		} `json:"a_b"`
+		Tools nested   `json:"tools"`
+		Items []nested `json:"items"`
+		Tags  []string `json:"tags"`
+		Skip  string   `json:"-"`
+		Ptr   *nested  `json:"ptr"`
+		Keyed []keyed  `json:"keyed"`
+	}
+
+	tests := []struct {
+		name     string
+		typ      reflect.Type
+		env      string
+		wantPath []string
+		wantOK   bool
+	}{
+		// 实际的配置结构
+		{"顶层字段含下划线", reflect.TypeOf(Config{}), "api_server_port", []string{"api_server_port"}, true},
+		{"嵌套字段含下划线", reflect.TypeOf(Config{}), "leverage_btc_eth_leverage", []string{"leverage", "btc_eth_leverage"}, true},
+		{"字符串数组", reflect.TypeOf(Config{}), "default_coins", []string{"default_coins"}, true},
+		{"trader数组下标", reflect.TypeOf(Config{}), "traders_0_scan_interval_minutes", []string{"traders", "0", "scan_interval_minutes"}, true},
+		{"trader密钥", reflect.TypeOf(Config{}), "traders_1_deepseek_key", []string{"traders", "1", "deepseek_key"}, true},
+		{"非叶子节点", reflect.TypeOf(Config{}), "leverage", nil, false},
+		{"未知字段", reflect.TypeOf(Config{}), "scan_interval", nil, false},
+		{"非数字下标按ID匹配", reflect.TypeOf(Config{}), "traders_x_name", []string{"traders", "x", "name"}, true},
+		{"缺少数组元素字段", reflect.TypeOf(Config{}), "traders_0", nil, false},
+		{"trader ID", reflect.TypeOf(Config{}), "traders_binance_qwen_scan_interval_minutes", []string{"traders", "binance_qwen", "scan_interval_minutes"}, true},
+		{"trader ID后缺少字段", reflect.TypeOf(Config{}), "traders_binance_qwen", nil, false},
+
+		// 前缀回溯
+		{"短字段名完全匹配", reflect.TypeOf(prefixed{}), "a", []string{"a"}, true},
+		{"短字段名前缀不匹配时尝试更长的字段名", reflect.TypeOf(prefixed{}), "a_b_c", []string{"a_b", "c"}, true},
+		{"父级名称后的字段名共享前缀", reflect.TypeOf(prefixed{}), "tools_max_calls", []string{"tools", "max_calls"}, true},
+		{"共享前缀的短字段名", reflect.TypeOf(prefixed{}), "tools_max", []string{"tools", "max"}, true},
+		{"结构体数组中共享前缀", reflect.TypeOf(prefixed{}), "items_2_max_calls", []string{"items", "2", "max_calls"}, true},
+		{"指针字段", reflect.TypeOf(prefixed{}), "ptr_max_calls", []string{"ptr", "max_calls"}, true},
+		{"按ID引用时从最短的ID开始", reflect.TypeOf(prefixed{}), "keyed_a_max_calls", []string{"keyed", "a", "max_calls"}, true},
+		{"ID含下划线", reflect.TypeOf(prefixed{}), "keyed_a_b_max_calls", []string{"keyed", "a_b", "max_calls"}, true},
+		{"没有ID字段的数组不能按ID引用", reflect.TypeOf(prefixed{}), "items_a_max_calls", nil, false},
+		{"字符串数组不能带下标", reflect.TypeOf(prefixed{}), "tags_0", nil, false},
+		{"忽略的字段", reflect.TypeOf(prefixed{}), "skip", nil, false},
+		{"多余的后缀", reflect.TypeOf(prefixed{}), "tools_max_calls_x", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, ok := matchEnvName(tt.typ, tt.env)
+			if ok != tt.wantOK {
+				t.Fatalf("matchEnvName(%q) ok = %v, want %v (path %v)", tt.env, ok, tt.wantOK, path)
+			}
+			if ok && !reflect.DeepEqual(path, tt.wantPath) {
+				t.Errorf("matchEnvName(%q) = %v, want %v", tt.env, path, tt.wantPath)
+			}
+		})
+	}
+}
+
+// writeTestConfig 写入只包含trader的最小配置文件
+func writeTestConfig(t *testing.T, ids ...string) string {
+	var traders []string
+	for _, id := range ids {
+		traders = append(traders, fmt.Sprintf(`{"id": %q, "name": %q, "enabled": true, "ai_model": "deepseek",
+			"deepseek_key": "k", "binance_api_key": "k", "binance_secret_key": "s", "initial_balance": 1000}`, id, id))
+	}
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := fmt.Sprintf(`{"traders": [%s]}`, strings.Join(traders, ","))
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// scanIntervals 各trader的扫描间隔（按ID）
+func scanIntervals(t *testing.T, path string) map[string]int {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intervals := make(map[string]int)
+	for _, tc := range cfg.Traders {
+		intervals[tc.ID] = tc.ScanIntervalMinutes
+	}
+	return intervals
+}
+
+func TestIDOverrideSurvivesTraderDeletion(t *testing.T) {
+	path := writeTestConfig(t, "binance-a", "binance-b", "binance-c")
+	t.Setenv("NOFX_TRADERS_BINANCE_C_SCAN_INTERVAL_MINUTES", "7")
+	t.Setenv("NOFX_TRADERS_BINANCE_A_SCAN_INTERVAL_MINUTES", "9")
+
+	want := map[string]int{"binance-a": 9, "binance-b": 3, "binance-c": 7}
+	if got := scanIntervals(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("删除前 = %v, want %v", got, want)
+	}
+	if sources := IndexedTraderOverrides(0); len(sources) != 0 {
+		t.Errorf("IndexedTraderOverrides = %v, want 无", sources)
+	}
+
+	// 删除第一个trader后重新加载：覆盖仍指向同一个trader，已删除trader的覆盖被忽略
+	if err := RemoveTraderFromFile(path, "binance-a"); err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]int{"binance-b": 3, "binance-c": 7}
+	if got := scanIntervals(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("删除后 = %v, want %v", got, want)
+	}
+}
+
+func TestIndexedTraderOverrides(t *testing.T) {
+	t.Setenv("NOFX_TRADERS_1_SCAN_INTERVAL_MINUTES", "5")
+	t.Setenv("NOFX_TRADERS_BINANCE_A_SCAN_INTERVAL_MINUTES", "9")
+	if err := SetFlagOverrides([]string{"traders.2.enabled=false", "traders.binance-b.enabled=true"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetFlagOverrides(nil) })
+
+	tests := []struct {
+		from int
+		want []string
+	}{
+		{0, []string{"NOFX_TRADERS_1_SCAN_INTERVAL_MINUTES", "-set traders.2.enabled"}},
+		{2, []string{"-set traders.2.enabled"}},
+		{3, nil},
+	}
+	for _, tt := range tests {
+		if got := IndexedTraderOverrides(tt.from); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("IndexedTraderOverrides(%d) = %v, want %v", tt.from, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redactedPlaceholder 脱敏后的占位符
+const redactedPlaceholder = "***"
+
+// minSecretLength 短于此长度的值不做脱敏（避免误替换普通文本）
+const minSecretLength = 8
+
+var (
+	secretsMu      sync.RWMutex
+	secretValues   = make(map[string]bool)
+	secretReplacer *strings.Replacer
+
+	// URL中的访问令牌（如SSE使用的 ?access_token=）
+	tokenQueryPattern = regexp.MustCompile(`(access_token=)[^&\s"]+`)
+)
+
+// RegisterSecret 登记需要在日志和API响应中脱敏的密钥值
+func RegisterSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	values := []string{value}
+	// 0x前缀的私钥可能以不带前缀的形式出现
+	if trimmed := strings.TrimPrefix(value, "0x"); trimmed != value && len(trimmed) >= minSecretLength {
+		values = append(values, trimmed)
+	}
+	changed := false
+	for _, v := range values {
+		if !secretValues[v] {
+			secretValues[v] = true
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	// 长的密钥优先替换，避免一个密钥是另一个密钥的子串时只替换一部分
+	all := make([]string, 0, len(secretValues))
+	for v := range secretValues {
+		all = append(all, v)
+	}
+	sort.Slice(all, func(i, j int) bool { return len(all[i]) > len(all[j]) })
+	pairs := make([]string, 0, len(all)*2)
+	for _, v := range all {
+		pairs = append(pairs, v, redactedPlaceholder)
+	}
+	secretReplacer = strings.NewReplacer(pairs...)
+}
+
+// Redact 将文本中已登记的密钥和URL中的访问令牌替换为 ***
+func Redact(s string) string {
+	secretsMu.RLock()
+	replacer := secretReplacer
+	secretsMu.RUnlock()
+
+	if replacer != nil {
+		s = replacer.Replace(s)
+	}
+	if strings.Contains(s, "access_token=") {
+		s = tokenQueryPattern.ReplaceAllString(s, "${1}"+redactedPlaceholder)
+	}
+	return s
+}
+
+// redactingWriter 写入前对内容脱敏
+type redactingWriter struct {
+	w io.Writer
+}
+
+// NewRedactingWriter 创建脱敏Writer（用于标准日志和HTTP访问日志输出）
+func NewRedactingWriter(w io.Writer) io.Writer {
+	return &redactingWriter{w: w}
+}
+
+func (rw *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxLeverage 交易所允许的最大杠杆倍数
+const maxLeverage = 125
+
+// symbolPattern 币种格式（如 BTCUSDT、1000PEPEUSDT，未带USDT后缀时自动补全）
+var symbolPattern = regexp.MustCompile(`^[A-Za-z0-9]{1,20}$`)
+
+// normalizeSymbol 标准化币种为USDT交易对（与 market.Normalize 一致）
+func normalizeSymbol(symbol string) string {
+	symbol = strings.ToUpper(symbol)
+	if strings.HasSuffix(symbol, "USDT") {
+		return symbol
+	}
+	return symbol + "USDT"
+}
+
+// Problem 一个配置问题
+type Problem struct {
+	Path    string `json:"path"` // JSON路径，如 $.traders[0].exchange
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// ValidationError 配置校验失败，包含全部问题
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].String()
+	}
+	lines := make([]string, 0, len(e.Problems)+1)
+	lines = append(lines, fmt.Sprintf("共%d个问题:", len(e.Problems)))
+	for _, p := range e.Problems {
+		lines = append(lines, "  • "+p.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validator 收集配置问题
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) add(path, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// err 没有问题时返回nil
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+// mergeProblems 合并结构校验和语义校验的问题
+func mergeProblems(schemaProblems []Problem, err error) error {
+	var problems []Problem
+	problems = append(problems, schemaProblems...)
+	if err != nil {
+		ve, ok := err.(*ValidationError)
+		if !ok {
+			return err
+		}
+		problems = append(problems, ve.Problems...)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+// jsonSchema 由配置结构体生成的JSON Schema（draft-07子集）
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"` // 字符串或字符串数组（允许null）
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+}
+
+// schemaFor 由Go类型生成schema：对象不允许未知字段，数组允许null
+func schemaFor(t reflect.Type) *jsonSchema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := schemaFor(t.Elem())
+		if name, ok := s.Type.(string); ok {
+			s.Type = []string{name, "null"}
+		}
+		return s
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	case reflect.Slice:
+		return &jsonSchema{Type: []string{"array", "null"}, Items: schemaFor(t.Elem())}
+	case reflect.Struct:
+		closed := false
+		s := &jsonSchema{Type: "object", Properties: make(map[string]*jsonSchema), AdditionalProperties: &closed}
+		for i := 0; i < t.NumField(); i++ {
+			if name := jsonFieldName(t.Field(i)); name != "" {
+				s.Properties[name] = schemaFor(t.Field(i).Type)
+			}
+		}
+		return s
+	}
+	return &jsonSchema{}
+}
+
+// configSchema 配置文件的schema（包含向后兼容的旧字段和编辑器使用的 $schema）
+func configSchema() *jsonSchema {
+	s := schemaFor(reflect.TypeOf(Config{}))
+	for name, prop := range schemaFor(reflect.TypeOf(legacyConfig{})).Properties {
+		s.Properties[name] = prop
+	}
+	s.Properties["$schema"] = &jsonSchema{Type: "string"}
+	return s
+}
+
+// Schema 返回配置文件的JSON Schema（可供编辑器做补全和校验）
+func Schema() ([]byte, error) {
+	s := configSchema()
+	s.Schema = "http://json-schema.org/draft-07/schema#"
+	s.Title = "nofx config.json"
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// checkSchema 按schema检查JSON值的类型和未知字段
+func checkSchema(value interface{}, s *jsonSchema, path string, problems *[]Problem) {
+	if value == nil {
+		if types, ok := s.Type.([]string); ok && types[len(types)-1] == "null" {
+			return
+		}
+		*problems = append(*problems, Problem{Path: path, Message: fmt.Sprintf("不能为null，应为%s", typeName(s))})
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if !schemaAllows(s, "object") {
+			break
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := s.Properties[k]
+			if !ok {
+				*problems = append(*problems, Problem{Path: path + "." + k, Message: "未知的配置项" + suggestKey(k, s)})
+				continue
+			}
+			checkSchema(v[k], prop, path+"."+k, problems)
+		}
+		return
+	case []interface{}:
+		if !schemaAllows(s, "array") {
+			break
+		}
+		for i, item := range v {
+			checkSchema(item, s.Items, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+		return
+	case string:
+		if schemaAllows(s, "string") {
+			return
+		}
+	case bool:
+		if schemaAllows(s, "boolean") {
+			return
+		}
+	case json.Number:
+		if schemaAllows(s, "number") {
+			return
+		}
+		if schemaAllows(s, "integer") {
+			if _, err := v.Int64(); err == nil {
+				return
+			}
+		}
+	}
+	*problems = append(*problems, Problem{Path: path, Message: fmt.Sprintf("类型错误，应为%s", typeName(s))})
+}
+
+// schemaAllows schema是否允许该类型
+func schemaAllows(s *jsonSchema, t string) bool {
+	switch st := s.Type.(type) {
+	case string:
+		return st == t
+	case []string:
+		for _, x := range st {
+			if x == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeName 类型的中文描述（用于错误信息）
+func typeName(s *jsonSchema) string {
+	names := map[string]string{
+		"string": "字符串", "boolean": "布尔值", "integer": "整数", "number": "数字", "object": "对象", "array": "数组",
+	}
+	switch st := s.Type.(type) {
+	case string:
+		return names[st]
+	case []string:
+		return names[st[0]]
+	}
+	return "未知类型"
+}
+
+// suggestKey 为拼写错误的字段给出建议（如 scan_interval → scan_interval_minutes）
+func suggestKey(key string, s *jsonSchema) string {
+	lower := strings.ToLower(key)
+	var candidates []string
+	for name := range s.Properties {
+		if name == "$schema" {
+			continue
+		}
+		prefixMatch := len(lower) >= 4 && len(name) >= 4 && (strings.HasPrefix(name, lower) || strings.HasPrefix(lower, name))
+		if name == lower || prefixMatch || strings.ReplaceAll(name, "_", "") == strings.ReplaceAll(lower, "_", "") {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.Strings(candidates)
+	return fmt.Sprintf("，是否为 '%s'？", strings.Join(candidates, "' 或 '"))
+}
+
+// schemaProblems 按schema检查配置文件内容
+func schemaProblems(data []byte) ([]Problem, error) {
+	var root interface{}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	if err := dec.Decode(&root); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	var problems []Problem
+	checkSchema(root, configSchema(), "$", &problems)
+	return problems, nil
+}
+
+// ValidateSymbols 检查全局和trader级 default_coins 是否都是可交易的合约（known 为交易所返回的symbol集合）
+func (c *Config) ValidateSymbols(known map[string]bool) error {
+	var v validator
+	checkCoins := func(path string, coins []string) {
+		for i, coin := range coins {
+			symbol := normalizeSymbol(coin)
+			if !known[symbol] {
+				v.add(fmt.Sprintf("%s[%d]", path, i), "%s 不是可交易的USDT永续合约（币种不存在或已下线）", symbol)
+			}
+		}
+	}
+	checkCoins("$.default_coins", c.DefaultCoins)
+	for i := range c.Traders {
+		checkCoins(fmt.Sprintf("$.traders[%d].default_coins", i), c.Traders[i].DefaultCoins)
+	}
+	return v.err()
+}
+
+// ParseTraderConfig 解析单个trader配置（与配置文件中的traders[]使用相同的schema，拒绝未知字段）
+func ParseTraderConfig(data []byte) (TraderConfig, error) {
+	var tc TraderConfig
+	var root interface{}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	if err := dec.Decode(&root); err != nil {
+		return tc, fmt.Errorf("解析trader配置失败: %w", err)
+	}
+
+	var problems []Problem
+	checkSchema(root, schemaFor(reflect.TypeOf(TraderConfig{})), "$", &problems)
+	if len(problems) > 0 {
+		return tc, &ValidationError{Problems: problems}
+	}
+	if err := json.Unmarshal(data, &tc); err != nil {
+		return tc, fmt.Errorf("解析trader配置失败: %w", err)
+	}
+	return tc, nil
+}
@@ -0,0 +1,325 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 密钥引用前缀：配置值可以引用环境变量、文件或加密密钥库，而不是明文保存在config.json中
+const (
+	secretPrefixEnv      = "env:"      // env:BINANCE_SECRET 读取环境变量
+	secretPrefixFile     = "file:"     // file:/run/secrets/binance_secret 读取文件内容（去除首尾空白）
+	secretPrefixKeystore = "keystore:" // keystore:binance_secret 从加密密钥库读取
+)
+
+// 密钥库口令来源（环境变量）
+const (
+	KeystorePassphraseEnv     = "NOFX_KEYSTORE_PASSPHRASE"      // 口令
+	KeystorePassphraseFileEnv = "NOFX_KEYSTORE_PASSPHRASE_FILE" // 口令文件路径（如Docker secrets）
+)
+
+// DefaultKeystoreFile 未配置 keystore_file 时使用的密钥库文件
+const DefaultKeystoreFile = "keystore.json"
+
+// keystoreIterations PBKDF2迭代次数
+const keystoreIterations = 600000
+
+// secretField 配置中的一个密钥字段
+type secretField struct {
+	name  string
+	value *string
+}
+
+// secretFields trader配置中的密钥字段
+// 字段名为点号路径（与配置覆盖的路径格式一致），如 ensemble.models.0.api_key
+func (tc *TraderConfig) secretFields() []secretField {
+	fields := []secretField{
+		{"binance_api_key", &tc.BinanceAPIKey},
+		{"binance_secret_key", &tc.BinanceSecretKey},
+		{"hyperliquid_private_key", &tc.HyperliquidPrivateKey},
+		{"aster_private_key", &tc.AsterPrivateKey},
+		{"qwen_key", &tc.QwenKey},
+		{"deepseek_key", &tc.DeepSeekKey},
+		{"custom_api_key", &tc.CustomAPIKey},
+	}
+	if tc.Ensemble != nil {
+		for i := range tc.Ensemble.Models {
+			fields = append(fields, secretField{fmt.Sprintf("ensemble.models.%d.api_key", i), &tc.Ensemble.Models[i].APIKey})
+		}
+	}
+	for i := range tc.FallbackModels {
+		fields = append(fields, secretField{fmt.Sprintf("fallback_models.%d.api_key", i), &tc.FallbackModels[i].APIKey})
+	}
+	return fields
+}
+
+// jsonPath 将点号路径转换为问题报告使用的JSON路径（数组下标用方括号）
+func jsonPath(dotted string) string {
+	parts := strings.Split(dotted, ".")
+	var sb strings.Builder
+	for _, p := range parts {
+		if _, err := strconv.Atoi(p); err == nil {
+			sb.WriteString("[" + p + "]")
+		} else {
+			sb.WriteString("." + p)
+		}
+	}
+	return sb.String()
+}
+
+// IsSecretReference 值是否为密钥引用（env:/file:/keystore:）
+func IsSecretReference(value string) bool {
+	return strings.HasPrefix(value, secretPrefixEnv) ||
+		strings.HasPrefix(value, secretPrefixFile) ||
+		strings.HasPrefix(value, secretPrefixKeystore)
+}
+
+// secretResolver 解析密钥引用（密钥库在第一次使用时解密）
+type secretResolver struct {
+	keystoreFile string
+	keystore     map[string]string
+}
+
+func newSecretResolver(keystoreFile string) *secretResolver {
+	if keystoreFile == "" {
+		keystoreFile = DefaultKeystoreFile
+	}
+	return &secretResolver{keystoreFile: keystoreFile}
+}
+
+// resolve 解析单个配置值（非引用的值原样返回）
+func (r *secretResolver) resolve(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretPrefixEnv):
+		name := strings.TrimPrefix(value, secretPrefixEnv)
+		v := os.Getenv(name)
+		if v == "" {
+			return "", fmt.Errorf("环境变量 %s 未设置", name)
+		}
+		return v, nil
+
+	case strings.HasPrefix(value, secretPrefixFile):
+		path := strings.TrimPrefix(value, secretPrefixFile)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("读取密钥文件失败: %w", err)
+		}
+		v := strings.TrimSpace(string(data))
+		if v == "" {
+			return "", fmt.Errorf("密钥文件 %s 为空", path)
+		}
+		return v, nil
+
+	case strings.HasPrefix(value, secretPrefixKeystore):
+		name := strings.TrimPrefix(value, secretPrefixKeystore)
+		if r.keystore == nil {
+			passphrase, err := KeystorePassphrase()
+			if err != nil {
+				return "", err
+			}
+			secrets, err := OpenKeystore(r.keystoreFile, passphrase)
+			if err != nil {
+				return "", err
+			}
+			r.keystore = secrets
+		}
+		v, ok := r.keystore[name]
+		if !ok || v == "" {
+			return "", fmt.Errorf("密钥库 %s 中不存在 '%s'", r.keystoreFile, name)
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+// resolveField 解析一个密钥字段并登记到日志脱敏；返回该字段是否以明文保存
+func (r *secretResolver) resolveField(f secretField) (bool, error) {
+	if *f.value == "" {
+		return false, nil
+	}
+	plaintext := !IsSecretReference(*f.value)
+	v, err := r.resolve(*f.value)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", f.name, err)
+	}
+	*f.value = v
+	RegisterSecret(v)
+	return plaintext, nil
+}
+
+// resolveSecrets 解析配置中的所有密钥引用
+// 返回以明文填写的trader密钥字段路径（如 traders.0.binance_secret_key），以及无法解析的引用
+func (c *Config) resolveSecrets() ([]string, []Problem) {
+	r := newSecretResolver(c.KeystoreFile)
+	var plaintext []string
+	var v validator
+
+	for i := range c.Traders {
+		for _, f := range c.Traders[i].secretFields() {
+			isPlain, err := r.resolveField(f)
+			if err != nil {
+				v.add(fmt.Sprintf("$.traders[%d]%s", i, jsonPath(f.name)), "%v", errors.Unwrap(err))
+				continue
+			}
+			if isPlain {
+				plaintext = append(plaintext, fmt.Sprintf("traders.%d.%s", i, f.name))
+			}
+		}
+	}
+
+	for i := range c.APIAuth.APIKeys {
+		if _, err := r.resolveField(secretField{"key", &c.APIAuth.APIKeys[i].Key}); err != nil {
+			v.add(fmt.Sprintf("$.api_auth.api_keys[%d].key", i), "%v", errors.Unwrap(err))
+		}
+	}
+	if _, err := r.resolveField(secretField{"jwt_secret", &c.APIAuth.JWTSecret}); err != nil {
+		v.add("$.api_auth.jwt_secret", "%v", errors.Unwrap(err))
+	}
+	return plaintext, v.problems
+}
+
+// ResolveTraderSecrets 解析单个trader配置中的密钥引用（用于通过API创建的trader）
+func (c *Config) ResolveTraderSecrets(tc *TraderConfig) error {
+	r := newSecretResolver(c.KeystoreFile)
+	for _, f := range tc.secretFields() {
+		if _, err := r.resolveField(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KeystorePassphrase 从环境变量读取密钥库口令
+func KeystorePassphrase() (string, error) {
+	if v := os.Getenv(KeystorePassphraseEnv); v != "" {
+		return v, nil
+	}
+	if path := os.Getenv(KeystorePassphraseFileEnv); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("读取密钥库口令文件失败: %w", err)
+		}
+		if v := strings.TrimRight(string(data), "\r\n"); v != "" {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("使用keystore:引用时必须设置环境变量 %s 或 %s", KeystorePassphraseEnv, KeystorePassphraseFileEnv)
+}
+
+// keystoreFile 加密密钥库文件格式（PBKDF2-SHA256派生密钥，AES-256-GCM加密JSON对象）
+type keystoreFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// OpenKeystore 用口令解密密钥库，返回 名称→密钥
+func OpenKeystore(path, passphrase string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥库失败: %w", err)
+	}
+	var ks keystoreFile
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("解析密钥库失败: %w", err)
+	}
+	if ks.Version != 1 || ks.KDF != "pbkdf2-sha256" {
+		return nil, fmt.Errorf("不支持的密钥库格式: version=%d kdf=%s", ks.Version, ks.KDF)
+	}
+
+	salt, err1 := base64.StdEncoding.DecodeString(ks.Salt)
+	nonce, err2 := base64.StdEncoding.DecodeString(ks.Nonce)
+	ciphertext, err3 := base64.StdEncoding.DecodeString(ks.Ciphertext)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return nil, fmt.Errorf("解析密钥库失败: %w", err)
+	}
+
+	gcm, err := keystoreCipher(passphrase, salt, ks.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("解析密钥库失败: nonce长度无效")
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("解密密钥库失败（口令错误或文件已损坏）")
+	}
+
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("解析密钥库内容失败: %w", err)
+	}
+	return secrets, nil
+}
+
+// SaveKeystore 用口令加密并保存密钥库（每次保存使用新的salt和nonce，文件权限0600）
+func SaveKeystore(path, passphrase string, secrets map[string]string) error {
+	if passphrase == "" {
+		return fmt.Errorf("密钥库口令不能为空")
+	}
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("序列化密钥库失败: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("生成salt失败: %w", err)
+	}
+	gcm, err := keystoreCipher(passphrase, salt, keystoreIterations)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("生成nonce失败: %w", err)
+	}
+
+	data, err := json.MarshalIndent(keystoreFile{
+		Version:    1,
+		KDF:        "pbkdf2-sha256",
+		Iterations: keystoreIterations,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, nil)),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化密钥库失败: %w", err)
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			return fmt.Errorf("创建密钥库失败: %w", err)
+		}
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// keystoreCipher 由口令派生AES-256-GCM
+func keystoreCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if iterations <= 0 {
+		return nil, fmt.Errorf("密钥库迭代次数无效")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("初始化加密失败: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
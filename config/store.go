@@ -0,0 +1,180 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// AppendTrader 将trader配置追加写入配置文件
+// 只改写 traders 数组，其余配置项保持原样（包括顺序）
+func AppendTrader(filename string, tc TraderConfig) error {
+	entry, err := json.Marshal(tc)
+	if err != nil {
+		return fmt.Errorf("序列化trader配置失败: %w", err)
+	}
+
+	return rewriteTraders(filename, func(traders []json.RawMessage) ([]json.RawMessage, error) {
+		for _, raw := range traders {
+			if traderID(raw) == tc.ID {
+				return nil, fmt.Errorf("配置文件中已存在trader ID '%s'", tc.ID)
+			}
+		}
+		return append(traders, entry), nil
+	})
+}
+
+// RemoveTraderFromFile 从配置文件中删除指定ID的trader（不存在时不修改文件）
+func RemoveTraderFromFile(filename, id string) error {
+	return rewriteTraders(filename, func(traders []json.RawMessage) ([]json.RawMessage, error) {
+		kept := make([]json.RawMessage, 0, len(traders))
+		for _, raw := range traders {
+			if traderID(raw) != id {
+				kept = append(kept, raw)
+			}
+		}
+		if len(kept) == len(traders) {
+			return nil, nil
+		}
+		return kept, nil
+	})
+}
+
+// traderID 读取原始trader配置中的ID
+func traderID(raw json.RawMessage) string {
+	var t struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(raw, &t)
+	return t.ID
+}
+
+// rewriteTraders 读取配置文件，用 fn 改写 traders 数组后原子地写回
+// fn 返回 nil 表示无需修改
+func rewriteTraders(filename string, fn func([]json.RawMessage) ([]json.RawMessage, error)) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	keys, values, err := decodeOrderedObject(data)
+	if err != nil {
+		return fmt.Errorf("解析配置文件失败: %w", err)
+	}
+
+	var traders []json.RawMessage
+	if raw, ok := values["traders"]; ok {
+		if err := json.Unmarshal(raw, &traders); err != nil {
+			return fmt.Errorf("解析traders失败: %w", err)
+		}
+	}
+
+	traders, err = fn(traders)
+	if err != nil {
+		return err
+	}
+	if traders == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(traders)
+	if err != nil {
+		return fmt.Errorf("序列化traders失败: %w", err)
+	}
+	if _, ok := values["traders"]; !ok {
+		keys = append([]string{"traders"}, keys...)
+	}
+	values["traders"] = raw
+
+	return writeFileAtomic(filename, encodeOrderedObject(keys, values))
+}
+
+// decodeOrderedObject 解析JSON对象，保留顶层键的顺序
+func decodeOrderedObject(data []byte) ([]string, map[string]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("顶层必须是JSON对象")
+	}
+
+	var keys []string
+	values := make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, err
+		}
+		if _, dup := values[key]; !dup {
+			keys = append(keys, key)
+		}
+		values[key] = raw
+	}
+	return keys, values, nil
+}
+
+// encodeOrderedObject 按给定顺序输出JSON对象（两个空格缩进）
+func encodeOrderedObject(keys []string, values map[string]json.RawMessage) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(values[key])
+	}
+	buf.WriteByte('}')
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return buf.Bytes()
+	}
+	out.WriteByte('\n')
+	return out.Bytes()
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免写入中途崩溃或被热加载读到不完整的文件
+// 目录不可写或单文件挂载（如Docker bind mount）无法替换时，退回到原地写入
+func writeFileAtomic(filename string, data []byte) error {
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if err := replaceFile(filename, data, mode); err != nil {
+		if werr := os.WriteFile(filename, data, mode); werr != nil {
+			return fmt.Errorf("写入配置文件失败: %w", werr)
+		}
+	}
+	return nil
+}
+
+// replaceFile 写入同目录的临时文件后重命名为目标文件
+func replaceFile(filename string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
@@ -0,0 +1,135 @@
+package decision
+
+import (
+	"log"
+	"nofx/market"
+	"nofx/mcp"
+	"sort"
+)
+
+// compactSeriesPoints 压缩后每个序列保留的最近数据点数
+const compactSeriesPoints = 3
+
+// BudgetReport prompt超出token预算时的压缩记录（保存在决策日志中）
+type BudgetReport struct {
+	BudgetTokens    int      `json:"budget_tokens"`         // system + user prompt 的token预算
+	OriginalTokens  int      `json:"original_tokens"`       // 压缩前的估算token数
+	EstimatedTokens int      `json:"estimated_tokens"`      // 最终prompt的估算token数
+	Compressed      []string `json:"compressed,omitempty"`  // 只保留最近几个序列数据点的候选币种
+	Dropped         []string `json:"dropped,omitempty"`     // 从prompt中移除的候选币种
+	OverBudget      bool     `json:"over_budget,omitempty"` // 移除全部候选币种后仍超出预算（持仓数据不会被压缩）
+}
+
+// renderWithinBudget 渲染prompt，超出token预算时按优先级从低到高依次压缩候选币种的序列数据，仍超出时再移除候选币种
+// 持仓币种的数据始终完整保留；budget<=0 表示不限制，未超出预算时返回的记录为nil
+func (pt *PromptTemplate) renderWithinBudget(ctx *Context, budget int) (string, string, *BudgetReport, error) {
+	data := newPromptData(ctx, pt.Language)
+	systemPrompt, userPrompt, err := pt.render(data)
+	if err != nil || budget <= 0 {
+		return systemPrompt, userPrompt, nil, err
+	}
+	tokens := estimatePromptTokens(systemPrompt, userPrompt)
+	if tokens <= budget {
+		return systemPrompt, userPrompt, nil, nil
+	}
+
+	report := &BudgetReport{BudgetTokens: budget, OriginalTokens: tokens}
+	candidates := data.Candidates
+	order := candidatesByPriority(candidates)
+	dropped := make(map[int]bool)
+
+	// rerender 按当前的压缩和移除状态重新渲染并估算
+	rerender := func() error {
+		data.Candidates = make([]PromptCandidate, 0, len(candidates))
+		for i, c := range candidates {
+			if dropped[i] {
+				continue
+			}
+			c.Index = len(data.Candidates) + 1
+			data.Candidates = append(data.Candidates, c)
+		}
+		var err error
+		if systemPrompt, userPrompt, err = pt.render(data); err != nil {
+			return err
+		}
+		tokens = estimatePromptTokens(systemPrompt, userPrompt)
+		return nil
+	}
+
+	// 1. 压缩：只保留最近几个序列数据点
+	for _, i := range order {
+		if tokens <= budget {
+			break
+		}
+		candidates[i].Data = market.Trim(candidates[i].Data, compactSeriesPoints)
+		report.Compressed = append(report.Compressed, candidates[i].Symbol)
+		if err := rerender(); err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	// 2. 移除：从优先级最低的候选币种开始
+	for _, i := range order {
+		if tokens <= budget {
+			break
+		}
+		dropped[i] = true
+		report.Dropped = append(report.Dropped, candidates[i].Symbol)
+		if err := rerender(); err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	// 被移除的币种不再记为压缩
+	if len(report.Dropped) > 0 {
+		kept := report.Compressed[:0]
+		for _, symbol := range report.Compressed {
+			if !contains(report.Dropped, symbol) {
+				kept = append(kept, symbol)
+			}
+		}
+		report.Compressed = kept
+	}
+
+	report.EstimatedTokens = tokens
+	report.OverBudget = tokens > budget
+	log.Printf("✂️  prompt估算%d tokens超出预算%d：压缩%d个候选币种，移除%d个，压缩后%d tokens",
+		report.OriginalTokens, budget, len(report.Compressed), len(report.Dropped), tokens)
+	if report.OverBudget {
+		log.Printf("⚠️  移除全部候选币种后prompt仍超出预算，请调大 context_window 或减少持仓")
+	}
+	return systemPrompt, userPrompt, report, nil
+}
+
+// estimatePromptTokens system + user prompt 的估算token数
+func estimatePromptTokens(systemPrompt, userPrompt string) int {
+	return mcp.EstimateTokens(systemPrompt) + mcp.EstimateTokens(userPrompt)
+}
+
+// candidatesByPriority 候选币种下标，按优先级从低到高排序
+// 优先级：AI500+OI_Top双重信号 > Hyperliquid OI增长 > 其他，同级时候选顺序靠后的优先级更低
+func candidatesByPriority(candidates []PromptCandidate) []int {
+	priority := func(c PromptCandidate) int {
+		p := 0
+		if len(c.Sources) > 1 {
+			p += 2
+		}
+		if c.OITop != nil && c.OITop.OIDeltaValue > 0 {
+			p++
+		}
+		return p
+	}
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		pa, pb := priority(candidates[order[a]]), priority(candidates[order[b]])
+		if pa != pb {
+			return pa < pb
+		}
+		return order[a] > order[b]
+	})
+	return order
+}
@@ -0,0 +1,206 @@
+package decision
+
+import (
+	"nofx/market"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sampleMarketData 构造带完整序列的市场数据
+func sampleMarketData(symbol string, price float64) *market.Data {
+	return &market.Data{
+		Symbol:        symbol,
+		CurrentPrice:  price,
+		PriceChange1h: 0.35,
+		PriceChange4h: -1.2,
+		CurrentEMA20:  price * 0.99,
+		CurrentMACD:   12.3456,
+		CurrentRSI7:   61.5,
+		OpenInterest:  &market.OIData{Latest: 1200, Average: 1100},
+		FundingRate:   0.0001,
+		IntradaySeries: &market.IntradayData{
+			MidPrices:   []float64{price * 0.98, price * 0.99, price},
+			EMA20Values: []float64{price * 0.97, price * 0.98},
+			MACDValues:  []float64{1.1, -0.4},
+			RSI7Values:  []float64{55, 61.5},
+			RSI14Values: []float64{52, 58},
+		},
+		LongerTermContext: &market.LongerTermData{
+			EMA20:         price * 0.95,
+			EMA50:         price * 0.9,
+			ATR3:          price * 0.01,
+			ATR14:         price * 0.02,
+			CurrentVolume: 1500,
+			AverageVolume: 1300,
+			MACDValues:    []float64{5, 6},
+			RSI14Values:   []float64{48, 51},
+		},
+	}
+}
+
+// seriesMarketData 构造每个序列有 n 个数据点的市场数据
+func seriesMarketData(symbol string, price float64, n int) *market.Data {
+	data := sampleMarketData(symbol, price)
+	series := make([]float64, n)
+	for i := range series {
+		series[i] = price * (1 + float64(i)/1000)
+	}
+	data.IntradaySeries = &market.IntradayData{
+		MidPrices:   series,
+		EMA20Values: series,
+		MACDValues:  series,
+		RSI7Values:  series,
+		RSI14Values: series,
+	}
+	data.LongerTermContext.MACDValues = series
+	data.LongerTermContext.RSI14Values = series
+	return data
+}
+
+// sameSymbols 比较币种列表（nil 与空列表视为相同）
+func sameSymbols(got, want []string) bool {
+	if len(got) == 0 && len(want) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+func TestRenderWithinBudget(t *testing.T) {
+	// 优先级从低到高：DOGE（仅OI_Top，候选顺序靠后）→ SOL → XRP（Hyperliquid OI增长）→ ETH（双重信号）
+	newContext := func() *Context {
+		return &Context{
+			CurrentTime: "2025-01-01 00:00:00",
+			Account:     AccountInfo{TotalEquity: 1000, AvailableBalance: 900, PositionCount: 1},
+			Positions: []PositionInfo{
+				{Symbol: "BTCUSDT", Side: "long", EntryPrice: 94000, MarkPrice: 95000, Leverage: 5},
+			},
+			CandidateCoins: []CandidateCoin{
+				{Symbol: "SOLUSDT", Sources: []string{"ai500"}},
+				{Symbol: "ETHUSDT", Sources: []string{"ai500", "oi_top"}},
+				{Symbol: "XRPUSDT", Sources: []string{"ai500"}},
+				{Symbol: "DOGEUSDT", Sources: []string{"oi_top"}},
+			},
+			MarketDataMap: map[string]*market.Data{
+				"BTCUSDT":  seriesMarketData("BTCUSDT", 95000, 40),
+				"SOLUSDT":  seriesMarketData("SOLUSDT", 180, 40),
+				"ETHUSDT":  seriesMarketData("ETHUSDT", 3500, 40),
+				"XRPUSDT":  seriesMarketData("XRPUSDT", 2.5, 40),
+				"DOGEUSDT": seriesMarketData("DOGEUSDT", 0.12, 40),
+			},
+			OITopDataMap: map[string]*market.OITopData{
+				"XRPUSDT": {Rank: 1, OIDeltaValue: 1000},
+			},
+			BTCETHLeverage:  10,
+			AltcoinLeverage: 5,
+		}
+	}
+
+	pt, err := LoadPromptTemplate(DefaultPromptTemplate, LangChinese)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fullSystem, fullUser, err := pt.Render(newContext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := estimatePromptTokens(fullSystem, fullUser)
+
+	// 移除全部候选币种后的prompt（候选币种标题仍按市场数据计数）
+	bare := newContext()
+	bare.CandidateCoins = nil
+	bareSystem, bareUser, err := pt.Render(bare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	minimum := estimatePromptTokens(bareSystem, bareUser)
+
+	allDropped := []string{"DOGEUSDT", "SOLUSDT", "XRPUSDT", "ETHUSDT"}
+	tests := []struct {
+		name           string
+		budget         int
+		wantReport     bool
+		wantCompressed []string
+		wantDropped    []string
+		wantOver       bool
+	}{
+		{name: "不限制预算", budget: 0},
+		{name: "正好等于预算", budget: full},
+		{name: "远低于预算", budget: full * 10},
+		{
+			name:           "略超预算时只压缩优先级最低的币种",
+			budget:         full - 1,
+			wantReport:     true,
+			wantCompressed: []string{"DOGEUSDT"},
+		},
+		{
+			name:        "只容得下持仓时移除全部候选币种",
+			budget:      minimum,
+			wantReport:  true,
+			wantDropped: allDropped,
+		},
+		{
+			name:        "移除全部候选币种后仍超出预算",
+			budget:      1,
+			wantReport:  true,
+			wantDropped: allDropped,
+			wantOver:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newContext()
+			system, user, report, err := pt.renderWithinBudget(ctx, tt.budget)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.wantReport {
+				if report != nil {
+					t.Fatalf("report = %+v, want nil", report)
+				}
+				if system != fullSystem || user != fullUser {
+					t.Error("未超出预算时prompt不应改变")
+				}
+				return
+			}
+			if report == nil {
+				t.Fatal("report = nil")
+			}
+			if report.BudgetTokens != tt.budget || report.OriginalTokens != full {
+				t.Errorf("预算/原始token = %d/%d, want %d/%d", report.BudgetTokens, report.OriginalTokens, tt.budget, full)
+			}
+			if !sameSymbols(report.Compressed, tt.wantCompressed) {
+				t.Errorf("Compressed = %v, want %v", report.Compressed, tt.wantCompressed)
+			}
+			if !sameSymbols(report.Dropped, tt.wantDropped) {
+				t.Errorf("Dropped = %v, want %v", report.Dropped, tt.wantDropped)
+			}
+			if report.OverBudget != tt.wantOver {
+				t.Errorf("OverBudget = %v, want %v", report.OverBudget, tt.wantOver)
+			}
+			if got := estimatePromptTokens(system, user); report.EstimatedTokens != got {
+				t.Errorf("EstimatedTokens = %d, 实际 %d", report.EstimatedTokens, got)
+			}
+			if !tt.wantOver && report.EstimatedTokens > tt.budget {
+				t.Errorf("EstimatedTokens = %d 超出预算 %d", report.EstimatedTokens, tt.budget)
+			}
+			for _, symbol := range tt.wantDropped {
+				if strings.Contains(user, ". "+symbol) {
+					t.Errorf("被移除的 %s 仍在prompt中", symbol)
+				}
+			}
+
+			// 持仓数据完整保留，上下文中的市场数据不被修改
+			if !strings.Contains(user, market.Format(ctx.MarketDataMap["BTCUSDT"])) {
+				t.Error("持仓的市场数据被压缩")
+			}
+			for symbol, data := range ctx.MarketDataMap {
+				if len(data.IntradaySeries.MidPrices) != 40 {
+					t.Errorf("%s 的市场数据被修改", symbol)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package decision
+
+import (
+	"fmt"
+	"log"
+	"nofx/mcp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 投票方式（与配置中的 ensemble.mode 一致）
+const (
+	VoteMajority  = "majority"  // 多数票：超过半数模型给出相同操作才执行
+	VoteWeighted  = "weighted"  // 加权投票：相同操作的权重之和超过总权重的一半才执行
+	VoteUnanimous = "unanimous" // 一致同意：开仓需要全部模型同意，平仓按多数票
+)
+
+// Voter 参与投票的一个模型
+type Voter struct {
+	Name   string
+	Client *mcp.Client
+	Weight float64
+}
+
+// ModelOutput 投票中单个模型的原始输出和解析结果
+type ModelOutput struct {
+	Model       string     `json:"model"`
+	UsedModel   string     `json:"used_model,omitempty"` // 该模型故障时实际使用的备用模型
+	Weight      float64    `json:"weight"`
+	RawResponse string     `json:"raw_response"`      // 模型原始输出
+	CoTTrace    string     `json:"cot_trace"`         // 思维链
+	Journal     string     `json:"journal,omitempty"` // 更新后的交易日志
+	Decisions   []Decision `json:"decisions"`         // 解析出的决策
+	Error       string     `json:"error,omitempty"`   // 调用或解析失败原因（该模型视为弃权）
+	DurationMs  int64      `json:"duration_ms"`
+
+	// 实际使用的模型参数（调用失败时为空）
+	Params *mcp.Params `json:"params,omitempty"`
+}
+
+// GetEnsembleDecision 多个模型使用相同的prompt并行决策，再按投票方式合并决策列表
+// 调用失败或决策无效的模型视为弃权；全部模型失败时返回错误
+func GetEnsembleDecision(ctx *Context, voters []Voter, mode string) (*FullDecision, error) {
+	if err := fetchMarketDataForContext(ctx); err != nil {
+		return nil, fmt.Errorf("获取市场数据失败: %w", err)
+	}
+
+	systemPrompt, userPrompt, budget, err := buildPrompts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]ModelOutput, len(voters))
+	var wg sync.WaitGroup
+	for i, voter := range voters {
+		wg.Add(1)
+		go func(i int, voter Voter) {
+			defer wg.Done()
+			start := time.Now()
+			out := ModelOutput{Model: voter.Name, Weight: voter.Weight}
+
+			result, err := voter.Client.CallWithFallbackTools(systemPrompt, userPrompt, ctx.Tools)
+			if err != nil {
+				out.Error = fmt.Sprintf("调用AI API失败: %v", err)
+			} else {
+				out.RawResponse = result.Content
+				out.Params = &result.Params
+				if result.Fallback {
+					out.UsedModel = result.Model
+				}
+				parsed, err := parseFullDecisionResponse(result.Content, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, ctx.Language())
+				if parsed != nil {
+					out.CoTTrace = parsed.CoTTrace
+					out.Decisions = parsed.Decisions
+					out.Journal = parsed.Journal
+				}
+				if err != nil {
+					// 错误信息中已包含思维链，记录中只保留第一行
+					out.Error, _, _ = strings.Cut(fmt.Sprintf("解析AI响应失败: %v", err), "\n")
+				}
+			}
+			out.DurationMs = time.Since(start).Milliseconds()
+			outputs[i] = out
+		}(i, voter)
+	}
+	wg.Wait()
+
+	var cot strings.Builder
+	var used []string
+	for _, out := range outputs {
+		if out.Error == "" {
+			model := out.Model
+			if out.UsedModel != "" {
+				model = out.UsedModel
+			}
+			used = append(used, model)
+			log.Printf("🗳️  %s: %d 个决策 (%dms)", out.Model, len(out.Decisions), out.DurationMs)
+		} else {
+			log.Printf("⚠️  %s 弃权: %s", out.Model, out.Error)
+		}
+		fmt.Fprintf(&cot, "【%s】\n%s\n\n", out.Model, out.CoTTrace)
+	}
+
+	decisions, summary := mergeVotes(outputs, mode)
+	full := &FullDecision{
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		Budget:       budget,
+		CoTTrace:     strings.TrimSpace(cot.String()),
+		Decisions:    decisions,
+		Timestamp:    time.Now(),
+		Model:        strings.Join(used, "+"),
+		ModelOutputs: outputs,
+		VoteSummary:  summary,
+	}
+	if len(used) == 0 {
+		return full, fmt.Errorf("全部%d个模型决策失败", len(voters))
+	}
+
+	// 交易日志只保留一份：使用第一个有效模型（主模型优先）的日志
+	for _, out := range outputs {
+		if out.Error == "" && out.Journal != "" {
+			full.Journal = out.Journal
+			break
+		}
+	}
+	return full, nil
+}
+
+// ballot 一个模型对某币种某操作的投票
+type ballot struct {
+	voter    int
+	decision Decision
+}
+
+// mergeVotes 按币种和操作统计投票，返回通过的决策和分歧摘要
+// 每个有效模型对每个（币种, 操作）最多投一票，未提及的币种视为不操作；
+// 同一币种的开多和开空同时通过时（加权投票的极端情况）均不执行
+func mergeVotes(outputs []ModelOutput, mode string) ([]Decision, []string) {
+	var validVoters []int
+	totalWeight := 0.0
+	for i, out := range outputs {
+		if out.Error == "" {
+			validVoters = append(validVoters, i)
+			totalWeight += out.Weight
+		}
+	}
+	if len(validVoters) == 0 {
+		return []Decision{}, nil
+	}
+
+	// 按首次出现的顺序收集币种及每个操作的投票
+	var symbols []string
+	votes := make(map[string]map[string][]ballot)
+	for _, i := range validVoters {
+		for _, d := range outputs[i].Decisions {
+			if votes[d.Symbol] == nil {
+				votes[d.Symbol] = make(map[string][]ballot)
+				symbols = append(symbols, d.Symbol)
+			}
+			already := false
+			for _, b := range votes[d.Symbol][d.Action] {
+				if b.voter == i {
+					already = true
+					break
+				}
+			}
+			if !already {
+				votes[d.Symbol][d.Action] = append(votes[d.Symbol][d.Action], ballot{voter: i, decision: d})
+			}
+		}
+	}
+
+	passes := func(action string, ballots []ballot) bool {
+		weight := 0.0
+		for _, b := range ballots {
+			weight += outputs[b.voter].Weight
+		}
+		switch {
+		case mode == VoteWeighted:
+			return weight*2 > totalWeight
+		case mode == VoteUnanimous && isOpenAction(action):
+			return len(ballots) == len(validVoters)
+		default:
+			return len(ballots)*2 > len(validVoters)
+		}
+	}
+
+	decisions := []Decision{}
+	var summary []string
+	for _, symbol := range symbols {
+		actions := make([]string, 0, len(votes[symbol]))
+		for action := range votes[symbol] {
+			actions = append(actions, action)
+		}
+		sort.Strings(actions)
+
+		var passed []string
+		for _, action := range actions {
+			if passes(action, votes[symbol][action]) {
+				passed = append(passed, action)
+			}
+		}
+		if contains(passed, "open_long") && contains(passed, "open_short") {
+			passed = removeActions(passed, "open_long", "open_short")
+		}
+		// 有实际操作通过时不再输出 hold/wait
+		if hasTradeAction(passed) {
+			passed = removeActions(passed, "hold", "wait")
+		}
+
+		for _, action := range passed {
+			decisions = append(decisions, mergeBallots(outputs, votes[symbol][action], len(validVoters)))
+		}
+
+		// 全体有效模型给出完全相同的操作时无分歧，不记录摘要
+		unanimous := len(actions) == 1 && len(votes[symbol][actions[0]]) == len(validVoters)
+		if !unanimous {
+			summary = append(summary, voteSummary(symbol, outputs, votes[symbol], actions, validVoters, passed))
+		}
+	}
+	return decisions, summary
+}
+
+// mergeBallots 合并同一操作的多个投票：止损止盈采用信心度最高的模型，杠杆和仓位取最小值（保守）
+func mergeBallots(outputs []ModelOutput, ballots []ballot, validCount int) Decision {
+	base := ballots[0]
+	totalWeight, weightedConfidence := 0.0, 0.0
+	for _, b := range ballots {
+		if b.decision.Confidence > base.decision.Confidence ||
+			(b.decision.Confidence == base.decision.Confidence && outputs[b.voter].Weight > outputs[base.voter].Weight) {
+			base = b
+		}
+		totalWeight += outputs[b.voter].Weight
+		weightedConfidence += float64(b.decision.Confidence) * outputs[b.voter].Weight
+	}
+
+	merged := base.decision
+	if isOpenAction(merged.Action) {
+		for _, b := range ballots {
+			if b.decision.Leverage < merged.Leverage {
+				merged.Leverage = b.decision.Leverage
+			}
+			if b.decision.PositionSizeUSD < merged.PositionSizeUSD {
+				merged.PositionSizeUSD = b.decision.PositionSizeUSD
+			}
+		}
+	}
+	if totalWeight > 0 {
+		merged.Confidence = int(weightedConfidence/totalWeight + 0.5)
+	}
+	merged.Reasoning = strings.TrimSpace(fmt.Sprintf("[投票 %d/%d] %s", len(ballots), validCount, base.decision.Reasoning))
+	return merged
+}
+
+// voteSummary 描述一个币种的分歧，如 "BTCUSDT: open_long[deepseek,qwen] wait[custom] → open_long"
+func voteSummary(symbol string, outputs []ModelOutput, votes map[string][]ballot, actions []string, validVoters []int, passed []string) string {
+	mentioned := make(map[int]bool)
+	parts := make([]string, 0, len(actions)+1)
+	for _, action := range actions {
+		names := make([]string, 0, len(votes[action]))
+		for _, b := range votes[action] {
+			names = append(names, outputs[b.voter].Model)
+			mentioned[b.voter] = true
+		}
+		parts = append(parts, fmt.Sprintf("%s[%s]", action, strings.Join(names, ",")))
+	}
+	var absent []string
+	for _, i := range validVoters {
+		if !mentioned[i] {
+			absent = append(absent, outputs[i].Model)
+		}
+	}
+	if len(absent) > 0 {
+		parts = append(parts, fmt.Sprintf("未提及[%s]", strings.Join(absent, ",")))
+	}
+
+	result := "不操作"
+	if len(passed) > 0 {
+		result = strings.Join(passed, ",")
+	}
+	return fmt.Sprintf("%s: %s → %s", symbol, strings.Join(parts, " "), result)
+}
+
+// isOpenAction 是否为开仓操作
+func isOpenAction(action string) bool {
+	return action == "open_long" || action == "open_short"
+}
+
+// hasTradeAction 是否包含 hold/wait 以外的操作
+func hasTradeAction(actions []string) bool {
+	for _, a := range actions {
+		if a != "hold" && a != "wait" {
+			return true
+		}
+	}
+	return false
+}
+
+// contains 字符串切片是否包含指定值
+func contains(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// removeActions 去掉指定的操作
+func removeActions(actions []string, remove ...string) []string {
+	kept := actions[:0]
+	for _, a := range actions {
+		if !contains(remove, a) {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
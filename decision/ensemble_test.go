@@ -0,0 +1,251 @@
+package decision
+
+import (
+	"reflect"
+	"testing"
+)
+
+// voterOutput 构造一个模型的投票输出
+func voterOutput(model string, weight float64, decisions ...Decision) ModelOutput {
+	return ModelOutput{Model: model, Weight: weight, Decisions: decisions}
+}
+
+// failedOutput 构造一个调用失败（弃权）的模型输出
+func failedOutput(model string, weight float64) ModelOutput {
+	return ModelOutput{Model: model, Weight: weight, Error: "timeout"}
+}
+
+func TestMergeVotes(t *testing.T) {
+	openLong := Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 100, Confidence: 80}
+	openShort := Decision{Symbol: "BTCUSDT", Action: "open_short", Leverage: 5, PositionSizeUSD: 100, Confidence: 80}
+	wait := Decision{Symbol: "BTCUSDT", Action: "wait"}
+	hold := Decision{Symbol: "ETHUSDT", Action: "hold"}
+	closeLong := Decision{Symbol: "ETHUSDT", Action: "close_long", Confidence: 70}
+
+	tests := []struct {
+		name        string
+		mode        string
+		outputs     []ModelOutput
+		want        []string // 通过的 "币种 操作"，按输出顺序
+		wantSummary int      // 分歧摘要条数
+	}{
+		{
+			name: "多数票通过开仓",
+			mode: VoteMajority,
+			outputs: []ModelOutput{
+				voterOutput("a", 1, openLong),
+				voterOutput("b", 1, openLong),
+				voterOutput("c", 1, wait),
+			},
+			want:        []string{"BTCUSDT open_long"},
+			wantSummary: 1,
+		},
+		{
+			name: "多数票未过半时不操作",
+			mode: VoteMajority,
+			outputs: []ModelOutput{
+				voterOutput("a", 1, openLong),
+				voterOutput("b", 1, openShort),
+				voterOutput("c", 1, wait),
+			},
+			want:        nil,
+			wantSummary: 1,
+		},
+		{
+			name: "全体一致时没有分歧摘要",
+			mode: VoteMajority,
+			outputs: []ModelOutput{
+				voterOutput("a", 1, openLong),
+				voterOutput("b", 1, openLong),
+			},
+			want:        []string{"BTCUSDT open_long"},
+			wantSummary: 0,
+		},
+		{
+			name: "失败的模型弃权，不计入过半的分母",
+			mode: VoteMajority,
+			outputs: []ModelOutput{
+				voterOutput("a", 1, openLong),
+				failedOutput("b", 1),
+				failedOutput("c", 1),
+			},
+			want:        []string{"BTCUSDT open_long"},
+			wantSummary: 0,
+		},
+		{
+			name: "全部模型失败",
+			mode: VoteMajority,
+			outputs: []ModelOutput{
+				failedOutput("a", 1),
+				failedOutput("b", 1),
+			},
+			want:        nil,
+			wantSummary: 0,
+		},
+		{
+			name: "同一模型重复的决策只算一票",
+			mode: VoteMajority,
+			outputs: []ModelOutput{
+				voterOutput("a", 1, openLong, openLong),
+				voterOutput("b", 1, wait),
+				voterOutput("c", 1, wait),
+			},
+			want:        []string{"BTCUSDT wait"},
+			wantSummary: 1,
+		},
+		{
+			name: "未提及的币种视为不操作",
+			mode: VoteMajority,
+			outputs: []ModelOutput{
+				voterOutput("a", 1, openLong),
+				voterOutput("b", 1),
+				voterOutput("c", 1),
+			},
+			want:        nil,
+			wantSummary: 1,
+		},
+		{
+			name: "一致同意：开仓需要全部模型同意",
+			mode: VoteUnanimous,
+			outputs: []ModelOutput{
+				voterOutput("a", 1, openLong),
+				voterOutput("b", 1, openLong),
+				voterOutput("c", 1, wait),
+			},
+			want:        nil,
+			wantSummary: 1,
+		},
+		{
+			name: "一致同意：平仓按多数票",
+			mode: VoteUnanimous,
+			outputs: []ModelOutput{
+				voterOutput("a", 1, closeLong),
+				voterOutput("b", 1, closeLong),
+				voterOutput("c", 1, hold),
+			},
+			want:        []string{"ETHUSDT close_long"},
+			wantSummary: 1,
+		},
+		{
+			name: "加权投票：权重过半即通过",
+			mode: VoteWeighted,
+			outputs: []ModelOutput{
+				voterOutput("a", 3, openLong),
+				voterOutput("b", 1, wait),
+				voterOutput("c", 1, wait),
+			},
+			want:        []string{"BTCUSDT open_long"},
+			wantSummary: 1,
+		},
+		{
+			name: "加权投票：票数多但权重未过半",
+			mode: VoteWeighted,
+			outputs: []ModelOutput{
+				voterOutput("a", 1, openLong),
+				voterOutput("b", 1, openLong),
+				voterOutput("c", 2, wait),
+			},
+			want:        nil,
+			wantSummary: 1,
+		},
+		{
+			name: "开多和开空同时通过时均不执行",
+			mode: VoteMajority,
+			outputs: []ModelOutput{
+				voterOutput("a", 1, openLong, openShort),
+				voterOutput("b", 1, openLong, openShort),
+			},
+			want:        nil,
+			wantSummary: 1,
+		},
+		{
+			name: "有实际操作通过时去掉 hold/wait",
+			mode: VoteMajority,
+			outputs: []ModelOutput{
+				voterOutput("a", 1, closeLong, hold),
+				voterOutput("b", 1, closeLong, hold),
+				voterOutput("c", 1, closeLong),
+			},
+			want:        []string{"ETHUSDT close_long"},
+			wantSummary: 1,
+		},
+		{
+			name: "多个币种按首次出现的顺序输出",
+			mode: VoteMajority,
+			outputs: []ModelOutput{
+				voterOutput("a", 1, closeLong, openLong),
+				voterOutput("b", 1, openLong, closeLong),
+			},
+			want:        []string{"ETHUSDT close_long", "BTCUSDT open_long"},
+			wantSummary: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decisions, summary := mergeVotes(tt.outputs, tt.mode)
+			var got []string
+			for _, d := range decisions {
+				got = append(got, d.Symbol+" "+d.Action)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("决策 = %v, want %v", got, tt.want)
+			}
+			if len(summary) != tt.wantSummary {
+				t.Errorf("分歧摘要 = %v, want %d 条", summary, tt.wantSummary)
+			}
+		})
+	}
+}
+
+func TestMergeBallots(t *testing.T) {
+	tests := []struct {
+		name       string
+		outputs    []ModelOutput
+		ballots    []ballot
+		validCount int
+		want       Decision
+	}{
+		{
+			name:    "开仓：止损止盈取信心度最高的模型，杠杆和仓位取最小值，信心度按权重平均",
+			outputs: []ModelOutput{{Model: "a", Weight: 1}, {Model: "b", Weight: 3}},
+			ballots: []ballot{
+				{voter: 0, decision: Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 3, PositionSizeUSD: 200, StopLoss: 90, TakeProfit: 120, Confidence: 90, Reasoning: "a"}},
+				{voter: 1, decision: Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 10, PositionSizeUSD: 100, StopLoss: 95, TakeProfit: 110, Confidence: 70, Reasoning: "b"}},
+			},
+			validCount: 3,
+			want: Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 3, PositionSizeUSD: 100, StopLoss: 90, TakeProfit: 120,
+				Confidence: 75, Reasoning: "[投票 2/3] a"},
+		},
+		{
+			name:    "信心度相同时取权重高的模型",
+			outputs: []ModelOutput{{Model: "a", Weight: 1}, {Model: "b", Weight: 2}},
+			ballots: []ballot{
+				{voter: 0, decision: Decision{Symbol: "BTCUSDT", Action: "open_short", Leverage: 5, PositionSizeUSD: 100, StopLoss: 110, Confidence: 80, Reasoning: "a"}},
+				{voter: 1, decision: Decision{Symbol: "BTCUSDT", Action: "open_short", Leverage: 5, PositionSizeUSD: 100, StopLoss: 105, Confidence: 80, Reasoning: "b"}},
+			},
+			validCount: 2,
+			want: Decision{Symbol: "BTCUSDT", Action: "open_short", Leverage: 5, PositionSizeUSD: 100, StopLoss: 105,
+				Confidence: 80, Reasoning: "[投票 2/2] b"},
+		},
+		{
+			name:    "平仓不调整杠杆和仓位",
+			outputs: []ModelOutput{{Model: "a", Weight: 1}, {Model: "b", Weight: 1}},
+			ballots: []ballot{
+				{voter: 0, decision: Decision{Symbol: "ETHUSDT", Action: "close_long", Leverage: 10, Confidence: 60, Reasoning: "a"}},
+				{voter: 1, decision: Decision{Symbol: "ETHUSDT", Action: "close_long", Leverage: 2, Confidence: 61, Reasoning: "b"}},
+			},
+			validCount: 2,
+			want:       Decision{Symbol: "ETHUSDT", Action: "close_long", Leverage: 2, Confidence: 61, Reasoning: "[投票 2/2] b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeBallots(tt.outputs, tt.ballots, tt.validCount)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeBallots() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package decision
+
+import "fmt"
+
+// prompt语言（决定prompt模板、市场数据标签以及返回给模型的决策校验信息）
+const (
+	LangChinese   = "zh"
+	LangEnglish   = "en"
+	LangRussian   = "ru"
+	LangUkrainian = "uk"
+)
+
+// DefaultLanguage 默认prompt语言
+const DefaultLanguage = LangChinese
+
+// Languages 支持的prompt语言
+var Languages = []string{LangChinese, LangEnglish, LangRussian, LangUkrainian}
+
+// IsSupportedLanguage 是否为支持的prompt语言
+func IsSupportedLanguage(lang string) bool {
+	for _, l := range Languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// 本地化消息ID
+const (
+	msgDecisionInvalid  = "decision_invalid"
+	msgNoArrayStart     = "no_array_start"
+	msgNoArrayEnd       = "no_array_end"
+	msgJSONInvalid      = "json_invalid"
+	msgInvalidAction    = "invalid_action"
+	msgLeverageRange    = "leverage_range"
+	msgPositionSize     = "position_size"
+	msgMaxPositionMajor = "max_position_major"
+	msgMaxPositionAlt   = "max_position_alt"
+	msgStopsRequired    = "stops_required"
+	msgLongStops        = "long_stops"
+	msgShortStops       = "short_stops"
+	msgRiskReward       = "risk_reward"
+	msgTagDualSignal    = "tag_dual_signal"
+	msgTagOITop         = "tag_oi_top"
+	msgTagHyperliquid   = "tag_hyperliquid"
+	msgMinutes          = "minutes"
+	msgHoursMinutes     = "hours_minutes"
+	msgReflectionSystem = "reflection_system"
+)
+
+// messages 各语言的消息格式（参数顺序在各语言中一致）
+var messages = map[string]map[string]string{
+	msgDecisionInvalid: {
+		LangChinese:   "决策 #%d 验证失败: %w",
+		LangEnglish:   "decision #%d is invalid: %w",
+		LangRussian:   "решение #%d недействительно: %w",
+		LangUkrainian: "рішення #%d недійсне: %w",
+	},
+	msgNoArrayStart: {
+		LangChinese:   "无法找到JSON数组起始",
+		LangEnglish:   "could not find the start of the JSON array",
+		LangRussian:   "не найдено начало JSON-массива",
+		LangUkrainian: "не знайдено початок JSON-масиву",
+	},
+	msgNoArrayEnd: {
+		LangChinese:   "无法找到JSON数组结束",
+		LangEnglish:   "could not find the end of the JSON array",
+		LangRussian:   "не найден конец JSON-массива",
+		LangUkrainian: "не знайдено кінець JSON-масиву",
+	},
+	msgJSONInvalid: {
+		LangChinese:   "JSON解析失败: %w\nJSON内容: %s",
+		LangEnglish:   "invalid JSON: %w\nJSON content: %s",
+		LangRussian:   "некорректный JSON: %w\nСодержимое JSON: %s",
+		LangUkrainian: "некоректний JSON: %w\nВміст JSON: %s",
+	},
+	msgInvalidAction: {
+		LangChinese:   "无效的action: %s",
+		LangEnglish:   "invalid action: %s",
+		LangRussian:   "недопустимое значение action: %s",
+		LangUkrainian: "неприпустиме значення action: %s",
+	},
+	msgLeverageRange: {
+		LangChinese:   "杠杆必须在1-%d之间（%s，当前配置上限%d倍）: %d",
+		LangEnglish:   "leverage must be between 1 and %d (%s, configured limit %dx): %d",
+		LangRussian:   "плечо должно быть от 1 до %d (%s, настроенный лимит %dx): %d",
+		LangUkrainian: "плече має бути від 1 до %d (%s, налаштований ліміт %dx): %d",
+	},
+	msgPositionSize: {
+		LangChinese:   "仓位大小必须大于0: %.2f",
+		LangEnglish:   "position size must be greater than 0: %.2f",
+		LangRussian:   "размер позиции должен быть больше 0: %.2f",
+		LangUkrainian: "розмір позиції має бути більшим за 0: %.2f",
+	},
+	msgMaxPositionMajor: {
+		LangChinese:   "BTC/ETH单币种仓位价值不能超过%.0f USDT（10倍账户净值），实际: %.0f",
+		LangEnglish:   "a BTC/ETH position may not exceed %.0f USDT (10x account equity), got: %.0f",
+		LangRussian:   "позиция по BTC/ETH не может превышать %.0f USDT (10x капитала счёта), получено: %.0f",
+		LangUkrainian: "позиція з BTC/ETH не може перевищувати %.0f USDT (10x капіталу рахунку), отримано: %.0f",
+	},
+	msgMaxPositionAlt: {
+		LangChinese:   "山寨币单币种仓位价值不能超过%.0f USDT（1.5倍账户净值），实际: %.0f",
+		LangEnglish:   "an altcoin position may not exceed %.0f USDT (1.5x account equity), got: %.0f",
+		LangRussian:   "позиция по альткоину не может превышать %.0f USDT (1.5x капитала счёта), получено: %.0f",
+		LangUkrainian: "позиція з альткоїна не може перевищувати %.0f USDT (1.5x капіталу рахунку), отримано: %.0f",
+	},
+	msgStopsRequired: {
+		LangChinese:   "止损和止盈必须大于0",
+		LangEnglish:   "stop_loss and take_profit must be greater than 0",
+		LangRussian:   "stop_loss и take_profit должны быть больше 0",
+		LangUkrainian: "stop_loss і take_profit мають бути більшими за 0",
+	},
+	msgLongStops: {
+		LangChinese:   "做多时止损价必须小于止盈价",
+		LangEnglish:   "for a long, stop_loss must be below take_profit",
+		LangRussian:   "для лонга stop_loss должен быть ниже take_profit",
+		LangUkrainian: "для лонга stop_loss має бути нижчим за take_profit",
+	},
+	msgShortStops: {
+		LangChinese:   "做空时止损价必须大于止盈价",
+		LangEnglish:   "for a short, stop_loss must be above take_profit",
+		LangRussian:   "для шорта stop_loss должен быть выше take_profit",
+		LangUkrainian: "для шорта stop_loss має бути вищим за take_profit",
+	},
+	msgRiskReward: {
+		LangChinese:   "风险回报比过低(%.2f:1)，必须≥3.0:1 [风险:%.2f%% 收益:%.2f%%] [止损:%.2f 止盈:%.2f]",
+		LangEnglish:   "reward/risk ratio too low (%.2f:1), must be ≥3.0:1 [risk: %.2f%% reward: %.2f%%] [stop loss: %.2f take profit: %.2f]",
+		LangRussian:   "слишком низкое соотношение прибыль/риск (%.2f:1), требуется ≥3.0:1 [риск: %.2f%% прибыль: %.2f%%] [стоп-лосс: %.2f тейк-профит: %.2f]",
+		LangUkrainian: "занадто низьке співвідношення прибуток/ризик (%.2f:1), потрібно ≥3.0:1 [ризик: %.2f%% прибуток: %.2f%%] [стоп-лос: %.2f тейк-профіт: %.2f]",
+	},
+	msgTagDualSignal: {
+		LangChinese:   " (AI500+OI_Top双重信号)",
+		LangEnglish:   " (AI500+OI_Top dual signal)",
+		LangRussian:   " (двойной сигнал AI500+OI_Top)",
+		LangUkrainian: " (подвійний сигнал AI500+OI_Top)",
+	},
+	msgTagOITop: {
+		LangChinese:   " (OI_Top持仓增长)",
+		LangEnglish:   " (OI_Top open interest growth)",
+		LangRussian:   " (рост открытого интереса OI_Top)",
+		LangUkrainian: " (зростання відкритого інтересу OI_Top)",
+	},
+	msgTagHyperliquid: {
+		LangChinese:   " (Hyperliquid OI数据)",
+		LangEnglish:   " (Hyperliquid OI data)",
+		LangRussian:   " (данные OI Hyperliquid)",
+		LangUkrainian: " (дані OI Hyperliquid)",
+	},
+	msgMinutes: {
+		LangChinese:   "%d分钟",
+		LangEnglish:   "%d min",
+		LangRussian:   "%d мин",
+		LangUkrainian: "%d хв",
+	},
+	msgHoursMinutes: {
+		LangChinese:   "%d小时%d分钟",
+		LangEnglish:   "%dh %dmin",
+		LangRussian:   "%d ч %d мин",
+		LangUkrainian: "%d год %d хв",
+	},
+	msgReflectionSystem: {
+		LangChinese:   "你是专业的加密货币交易复盘教练。根据交易的开仓理由、开平仓时的市场数据和结果，找出这笔交易盈利或亏损的真正原因，总结可以用于以后交易的具体经验。只输出经验总结的纯文本，不要输出JSON。",
+		LangEnglish:   "You are a professional crypto trading coach reviewing a closed trade. From the entry reasoning, the market data at entry and exit, and the outcome, find the real reason the trade won or lost and state concrete lessons for future trades. Output only the lessons as plain text, no JSON.",
+		LangRussian:   "Вы профессиональный тренер по криптотрейдингу и разбираете закрытую сделку. По причине входа, рыночным данным на входе и выходе и результату определите настоящую причину прибыли или убытка и сформулируйте конкретные уроки для будущих сделок. Выведите только уроки обычным текстом, без JSON.",
+		LangUkrainian: "Ви професійний тренер із криптотрейдингу й розбираєте закриту угоду. За причиною входу, ринковими даними на вході та виході й результатом визначте справжню причину прибутку чи збитку та сформулюйте конкретні уроки для майбутніх угод. Виведіть лише уроки звичайним текстом, без JSON.",
+	},
+}
+
+// localize 按语言取消息格式（未知语言使用中文）
+func localize(lang, id string) string {
+	if format, ok := messages[id][lang]; ok {
+		return format
+	}
+	return messages[id][DefaultLanguage]
+}
+
+// localizef 格式化本地化消息
+func localizef(lang, id string, args ...interface{}) string {
+	return fmt.Sprintf(localize(lang, id), args...)
+}
+
+// localizedError 创建本地化错误（格式中可以使用 %w）
+func localizedError(lang, id string, args ...interface{}) error {
+	return fmt.Errorf(localize(lang, id), args...)
+}
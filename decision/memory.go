@@ -0,0 +1,62 @@
+package decision
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// 交易日志标签：模型在JSON决策之后用该标签输出更新后的交易日志
+const (
+	journalStartTag = "<journal>"
+	journalEndTag   = "</journal>"
+)
+
+// Memory 跨周期记忆（trader启用 memory 时填充，为空时prompt不包含记忆部分）
+type Memory struct {
+	Journal         string        // 模型维护的交易日志（最近一次 <journal> 输出）
+	MaxJournalChars int           // 交易日志的最大字符数（提示模型控制长度）
+	Cycles          []MemoryCycle // 最近N个周期（从旧到新）
+}
+
+// MemoryCycle 记忆中的一个历史周期
+type MemoryCycle struct {
+	CycleNumber int      `json:"cycle_number"`
+	Time        string   `json:"time"`                // 周期时间（01-02 15:04）
+	Decisions   []string `json:"decisions,omitempty"` // 决策摘要，如 "BTCUSDT open_long"
+	Error       string   `json:"error,omitempty"`     // 周期失败原因（成功时为空）
+	CoTSummary  string   `json:"cot_summary"`         // 压缩后的思维链
+}
+
+// extractJournal 从AI响应中取出 <journal>...</journal> 交易日志，返回日志和去掉日志后的响应
+// 缺少结束标签时取到响应末尾；没有日志时返回空字符串和原响应
+func extractJournal(response string) (string, string) {
+	start := strings.Index(response, journalStartTag)
+	if start == -1 {
+		return "", response
+	}
+
+	rest := response[start+len(journalStartTag):]
+	journal, after, found := strings.Cut(rest, journalEndTag)
+	if !found {
+		after = ""
+	}
+	return strings.TrimSpace(journal), response[:start] + after
+}
+
+// CondenseText 合并空白并截断到 maxChars 个字符（保留末尾，思维链的结论通常在最后），maxChars<=0 时不截断
+func CondenseText(text string, maxChars int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if maxChars <= 0 || utf8.RuneCountInString(text) <= maxChars {
+		return text
+	}
+	runes := []rune(text)
+	return "…" + string(runes[len(runes)-maxChars:])
+}
+
+// TruncateText 截断到 maxChars 个字符（保留开头），maxChars<=0 时不截断
+func TruncateText(text string, maxChars int) string {
+	if maxChars <= 0 || utf8.RuneCountInString(text) <= maxChars {
+		return text
+	}
+	return string([]rune(text)[:maxChars])
+}
@@ -0,0 +1,288 @@
+package decision
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// DefaultPromptTemplate 内置prompt模板名称
+const DefaultPromptTemplate = "default"
+
+// prompt模板文件名，其他语言的模板带语言后缀（如 system.en.tmpl）
+const (
+	systemTemplateFile     = "system"
+	userTemplateFile       = "user"
+	reflectionTemplateFile = "reflection"
+)
+
+//go:embed prompts/default/*.tmpl
+var builtinPrompts embed.FS
+
+// defaultPrompt 内置中文模板（未指定模板的上下文共用）
+var defaultPrompt = sync.OnceValues(func() (*PromptTemplate, error) {
+	return LoadPromptTemplate(DefaultPromptTemplate, DefaultLanguage)
+})
+
+// PromptTemplate 一组prompt模板（system + user）
+type PromptTemplate struct {
+	Name       string // "default" 或自定义模板目录
+	Language   string // prompt语言
+	system     *template.Template
+	user       *template.Template
+	reflection *template.Template // 平仓后的交易复盘
+}
+
+// PromptData 渲染prompt模板时可用的变量
+// 除下列字段外，Context 的全部字段（.Account、.Positions、.CandidateCoins、.MarketDataMap、.OITopDataMap 等）可直接使用
+type PromptData struct {
+	*Context
+	Candidates  []PromptCandidate // 有市场数据的候选币种（按候选顺序从1编号）
+	HasSharpe   bool              // 是否有历史表现数据
+	SharpeRatio float64           // 夏普比率（来自 .Performance）
+}
+
+// PromptCandidate 候选币种及其市场数据
+type PromptCandidate struct {
+	Index   int
+	Symbol  string
+	Sources []string          // 来源: "ai500" 和/或 "oi_top"
+	Tags    string            // 来源标注，如 " (AI500+OI_Top双重信号)"
+	Data    *market.Data      // 完整市场数据（使用 formatMarket 输出）
+	OITop   *market.OITopData // Hyperliquid OI数据（可能为空）
+}
+
+// promptFuncs 模板函数（市场数据和持仓时长按prompt语言输出）
+func promptFuncs(lang string) template.FuncMap {
+	return template.FuncMap{
+		"add":   func(a, b int) int { return a + b },
+		"mul":   func(a, b float64) float64 { return a * b },
+		"pct":   func(a, b float64) float64 { return a / b * 100 },
+		"upper": strings.ToUpper,
+		"join":  strings.Join,
+		"formatMarket": func(data *market.Data) string {
+			return market.FormatLang(data, lang)
+		},
+		"holdingDuration": func(updateTime int64) string {
+			return holdingDuration(updateTime, lang)
+		},
+	}
+}
+
+// LoadPromptTemplate 加载指定语言的prompt模板：为空或 "default" 使用内置模板，否则为模板目录
+// 目录中依次查找 system.<语言>.tmpl 和 system.tmpl（user同理），都不存在时使用该语言的内置模板
+func LoadPromptTemplate(name, lang string) (*PromptTemplate, error) {
+	if name == "" {
+		name = DefaultPromptTemplate
+	}
+	if lang == "" {
+		lang = DefaultLanguage
+	}
+	if !IsSupportedLanguage(lang) {
+		return nil, fmt.Errorf("不支持的prompt语言: %s（支持 %s）", lang, strings.Join(Languages, ", "))
+	}
+	if name != DefaultPromptTemplate {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, fmt.Errorf("prompt模板目录不可用: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("prompt模板 %s 不是目录", name)
+		}
+	}
+	pt := &PromptTemplate{Name: name, Language: lang}
+
+	var err error
+	if pt.system, err = loadTemplateFile(name, systemTemplateFile, lang); err != nil {
+		return nil, err
+	}
+	if pt.user, err = loadTemplateFile(name, userTemplateFile, lang); err != nil {
+		return nil, err
+	}
+	if pt.reflection, err = loadTemplateFile(name, reflectionTemplateFile, lang); err != nil {
+		return nil, err
+	}
+	return pt, nil
+}
+
+// templateFileName 模板文件名（中文模板不带语言后缀）
+func templateFileName(base, lang string) string {
+	if lang == LangChinese {
+		return base + ".tmpl"
+	}
+	return base + "." + lang + ".tmpl"
+}
+
+// loadTemplateFile 解析模板目录中的一个文件，文件不存在时使用内置模板
+func loadTemplateFile(name, base, lang string) (*template.Template, error) {
+	var content []byte
+	var path string
+	if name != DefaultPromptTemplate {
+		for _, file := range []string{templateFileName(base, lang), base + ".tmpl"} {
+			data, err := os.ReadFile(filepath.Join(name, file))
+			if err == nil {
+				content, path = data, filepath.Join(name, file)
+				break
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("读取prompt模板失败: %w", err)
+			}
+		}
+	}
+	if content == nil {
+		path = "prompts/default/" + templateFileName(base, lang)
+		var err error
+		if content, err = builtinPrompts.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("读取内置prompt模板失败: %w", err)
+		}
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Funcs(promptFuncs(lang)).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("解析prompt模板 %s 失败: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// Render 渲染 System Prompt（规则）和 User Prompt（动态数据），调用前需已获取市场数据
+func (pt *PromptTemplate) Render(ctx *Context) (string, string, error) {
+	return pt.render(newPromptData(ctx, pt.Language))
+}
+
+// render 使用已准备好的模板变量渲染prompt
+func (pt *PromptTemplate) render(data *PromptData) (string, string, error) {
+	var system, user bytes.Buffer
+	if err := pt.system.Execute(&system, data); err != nil {
+		return "", "", fmt.Errorf("渲染system prompt失败: %w", err)
+	}
+	if err := pt.user.Execute(&user, data); err != nil {
+		return "", "", fmt.Errorf("渲染user prompt失败: %w", err)
+	}
+	return system.String(), user.String(), nil
+}
+
+// buildPrompts 使用trader的prompt模板（未设置时使用内置模板）渲染prompt，并控制在上下文的token预算内
+func buildPrompts(ctx *Context) (string, string, *BudgetReport, error) {
+	pt := ctx.PromptTemplate
+	if pt == nil {
+		var err error
+		if pt, err = defaultPrompt(); err != nil {
+			return "", "", nil, err
+		}
+	}
+	return pt.renderWithinBudget(ctx, ctx.TokenBudget)
+}
+
+// PromptPreview 渲染后的prompt（不调用AI）
+type PromptPreview struct {
+	Template     string        `json:"template"`
+	Language     string        `json:"language"`
+	Variant      string        `json:"variant,omitempty"` // prompt实验变体（由trader设置）
+	Tokens       int           `json:"tokens"`            // 估算token数
+	Budget       *BudgetReport `json:"budget,omitempty"`  // 超出token预算时的压缩记录
+	SystemPrompt string        `json:"system_prompt"`
+	UserPrompt   string        `json:"user_prompt"`
+}
+
+// PreviewPrompt 获取市场数据并按当前上下文渲染prompt，不调用AI
+func PreviewPrompt(ctx *Context) (*PromptPreview, error) {
+	if err := fetchMarketDataForContext(ctx); err != nil {
+		return nil, fmt.Errorf("获取市场数据失败: %w", err)
+	}
+	systemPrompt, userPrompt, budget, err := buildPrompts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := DefaultPromptTemplate
+	if ctx.PromptTemplate != nil {
+		name = ctx.PromptTemplate.Name
+	}
+	return &PromptPreview{
+		Template:     name,
+		Language:     ctx.Language(),
+		Tokens:       estimatePromptTokens(systemPrompt, userPrompt),
+		Budget:       budget,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+	}, nil
+}
+
+// newPromptData 准备模板变量（候选币种来源标注、夏普比率）
+func newPromptData(ctx *Context, lang string) *PromptData {
+	data := &PromptData{Context: ctx}
+
+	for _, coin := range ctx.CandidateCoins {
+		marketData, hasData := ctx.MarketDataMap[coin.Symbol]
+		if !hasData {
+			continue
+		}
+
+		sourceTags := ""
+		if len(coin.Sources) > 1 {
+			sourceTags = localize(lang, msgTagDualSignal)
+		} else if len(coin.Sources) == 1 && coin.Sources[0] == "oi_top" {
+			sourceTags = localize(lang, msgTagOITop)
+		}
+
+		// 检查是否有Hyperliquid OI数据
+		oiData := ctx.OITopDataMap[coin.Symbol]
+		if oiData != nil && oiData.OIDeltaValue > 0 {
+			if sourceTags == "" {
+				sourceTags = localize(lang, msgTagHyperliquid)
+			} else {
+				sourceTags += "+Hyperliquid OI"
+			}
+		}
+
+		data.Candidates = append(data.Candidates, PromptCandidate{
+			Index:   len(data.Candidates) + 1,
+			Symbol:  coin.Symbol,
+			Sources: coin.Sources,
+			Tags:    sourceTags,
+			Data:    marketData,
+			OITop:   oiData,
+		})
+	}
+
+	// 夏普比率（Performance 为 logger.PerformanceAnalysis，通过JSON提取避免包依赖）
+	if ctx.Performance != nil {
+		var perfData struct {
+			SharpeRatio float64 `json:"sharpe_ratio"`
+		}
+		if jsonData, err := json.Marshal(ctx.Performance); err == nil {
+			if err := json.Unmarshal(jsonData, &perfData); err == nil {
+				data.HasSharpe = true
+				data.SharpeRatio = perfData.SharpeRatio
+			}
+		}
+	}
+	return data
+}
+
+// holdingDuration 持仓时长，如 "25分钟"、"2小时5分钟"（开仓时间未知时为空）
+func holdingDuration(updateTime int64, lang string) string {
+	if updateTime <= 0 {
+		return ""
+	}
+	durationMin := (time.Now().UnixMilli() - updateTime) / (1000 * 60) // 转换为分钟
+	return formatMinutes(durationMin, lang)
+}
+
+// formatMinutes 按prompt语言格式化分钟数（超过1小时显示为小时+分钟）
+func formatMinutes(durationMin int64, lang string) string {
+	if durationMin < 60 {
+		return localizef(lang, msgMinutes, durationMin)
+	}
+	return localizef(lang, msgHoursMinutes, durationMin/60, durationMin%60)
+}
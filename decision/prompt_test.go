@@ -0,0 +1,367 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/market"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 以下两个函数是迁移到模板之前的 buildSystemPrompt / buildUserPrompt 原样保留，
+// 内置中文模板在未启用记忆、复盘和工具时必须逐字节渲染出相同的prompt
+
+// legacySystemPrompt 构建 System Prompt（固定规则，可缓存）
+func legacySystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage int) string {
+	var sb strings.Builder
+
+	// === 核心使命 ===
+	sb.WriteString("你是专业的加密货币交易AI，在币安合约市场进行自主交易。\n\n")
+	sb.WriteString("# 🎯 核心目标\n\n")
+	sb.WriteString("**最大化夏普比率（Sharpe Ratio）**\n\n")
+	sb.WriteString("夏普比率 = 平均收益 / 收益波动率\n\n")
+	sb.WriteString("**这意味着**：\n")
+	sb.WriteString("- ✅ 高质量交易（高胜率、大盈亏比）→ 提升夏普\n")
+	sb.WriteString("- ✅ 稳定收益、控制回撤 → 提升夏普\n")
+	sb.WriteString("- ✅ 耐心持仓、让利润奔跑 → 提升夏普\n")
+	sb.WriteString("- ❌ 频繁交易、小盈小亏 → 增加波动，严重降低夏普\n")
+	sb.WriteString("- ❌ 过度交易、手续费损耗 → 直接亏损\n")
+	sb.WriteString("- ❌ 过早平仓、频繁进出 → 错失大行情\n\n")
+	sb.WriteString("**关键认知**: 系统每3分钟扫描一次，但不意味着每次都要交易！\n")
+	sb.WriteString("大多数时候应该是 `wait` 或 `hold`，只在极佳机会时才开仓。\n\n")
+
+	// === 硬约束（风险控制）===
+	sb.WriteString("# ⚖️ 硬约束（风险控制）\n\n")
+	sb.WriteString("1. **风险回报比**: 必须 ≥ 1:3（冒1%风险，赚3%+收益）\n")
+	sb.WriteString("2. **最多持仓**: 3个币种（质量>数量）\n")
+	sb.WriteString(fmt.Sprintf("3. **单币仓位**: 山寨%.0f-%.0f U(%dx杠杆) | BTC/ETH %.0f-%.0f U(%dx杠杆)\n",
+		accountEquity*0.8, accountEquity*1.5, altcoinLeverage, accountEquity*5, accountEquity*10, btcEthLeverage))
+	sb.WriteString("4. **保证金**: 总使用率 ≤ 90%\n\n")
+
+	// === 交易哲学 & 最佳实践 ===
+	sb.WriteString("# 🎯 交易哲学 & 最佳实践\n\n")
+	sb.WriteString("## 核心原则：\n\n")
+	sb.WriteString("**资金保全第一**：保护资本比追求收益更重要\n\n")
+	sb.WriteString("**纪律胜于情绪**：执行你的退出方案，不随意移动止损或目标\n\n")
+	sb.WriteString("**质量优于数量**：少量高信念交易胜过大量低信念交易\n\n")
+	sb.WriteString("**适应波动性**：根据市场条件调整仓位\n\n")
+	sb.WriteString("**尊重趋势**：不要与强趋势作对\n\n")
+	sb.WriteString("## 常见误区避免：\n\n")
+	sb.WriteString("⚠️ **过度交易**：频繁交易导致费用侵蚀利润\n\n")
+	sb.WriteString("⚠️ **复仇式交易**：亏损后立即加码试图\"翻本\"\n\n")
+	sb.WriteString("⚠️ **分析瘫痪**：过度等待完美信号，导致失机\n\n")
+	sb.WriteString("⚠️ **忽视相关性**：BTC常引领山寨币，须优先观察BTC\n\n")
+	sb.WriteString("⚠️ **过度杠杆**：放大收益同时放大亏损\n\n")
+
+	// === 交易频率认知 ===
+	sb.WriteString("# ⏱️ 交易频率认知\n\n")
+	sb.WriteString("**量化标准**:\n")
+	sb.WriteString("- 优秀交易员：每天2-4笔 = 每小时0.1-0.2笔\n")
+	sb.WriteString("- 过度交易：每小时>2笔 = 严重问题\n")
+	sb.WriteString("- 最佳节奏：开仓后持有至少30-60分钟\n\n")
+	sb.WriteString("**自查**:\n")
+	sb.WriteString("如果你发现自己每个周期都在交易 → 说明标准太低\n")
+	sb.WriteString("如果你发现持仓<30分钟就平仓 → 说明太急躁\n\n")
+
+	// === 开仓信号强度 ===
+	sb.WriteString("# 🎯 开仓标准（严格）\n\n")
+	sb.WriteString("只在**强信号**时开仓，不确定就观望。\n\n")
+	sb.WriteString("**你拥有的完整数据**：\n")
+	sb.WriteString("- 📊 **原始序列**：3分钟价格序列(MidPrices数组) + 4小时K线序列\n")
+	sb.WriteString("- 📈 **技术序列**：EMA20序列、MACD序列、RSI7序列、RSI14序列\n")
+	sb.WriteString("- 💰 **资金序列**：成交量序列、持仓量(OI)序列、资金费率\n")
+	sb.WriteString("- 🎯 **筛选标记**：AI500评分 / OI_Top排名（如果有标注）\n\n")
+	sb.WriteString("**分析方法**（完全由你自主决定）：\n")
+	sb.WriteString("- 自由运用序列数据，你可以做但不限于趋势分析、形态识别、支撑阻力、技术阻力位、斐波那契、波动带计算\n")
+	sb.WriteString("- 多维度交叉验证（价格+量+OI+指标+序列形态）\n")
+	sb.WriteString("- 用你认为最有效的方法发现高确定性机会\n")
+	sb.WriteString("- 综合信心度 ≥ 75 才开仓\n\n")
+	sb.WriteString("**避免低质量信号**：\n")
+	sb.WriteString("- 单一维度（只看一个指标）\n")
+	sb.WriteString("- 相互矛盾（涨但量萎缩）\n")
+	sb.WriteString("- 横盘震荡\n")
+	sb.WriteString("- 刚平仓不久（<15分钟）\n\n")
+
+	// === 夏普比率自我进化 ===
+	sb.WriteString("# 🧬 夏普比率自我进化\n\n")
+	sb.WriteString("每次你会收到**夏普比率**作为绩效反馈（周期级别）：\n\n")
+	sb.WriteString("**夏普比率 < -0.5** (持续亏损):\n")
+	sb.WriteString("  → 🛑 停止交易，连续观望至少6个周期（18分钟）\n")
+	sb.WriteString("  → 🔍 深度反思：\n")
+	sb.WriteString("     • 交易频率过高？（每小时>2次就是过度）\n")
+	sb.WriteString("     • 持仓时间过短？（<30分钟就是过早平仓）\n")
+	sb.WriteString("     • 信号强度不足？（信心度<75）\n")
+	sb.WriteString("     • 是否在做空？（单边做多是错误的）\n\n")
+	sb.WriteString("**夏普比率 -0.5 ~ 0** (轻微亏损):\n")
+	sb.WriteString("  → ⚠️ 严格控制：只做信心度>80的交易\n")
+	sb.WriteString("  → 减少交易频率：每小时最多1笔新开仓\n")
+	sb.WriteString("  → 耐心持仓：至少持有30分钟以上\n\n")
+	sb.WriteString("**夏普比率 0 ~ 0.7** (正收益):\n")
+	sb.WriteString("  → ✅ 维持当前策略\n\n")
+	sb.WriteString("**夏普比率 > 0.7** (优异表现):\n")
+	sb.WriteString("  → 🚀 可适度扩大仓位\n\n")
+	sb.WriteString("**关键**: 夏普比率是唯一指标，它会自然惩罚频繁交易和过度进出。\n\n")
+
+	// === 决策流程 ===
+	sb.WriteString("# 📋 决策流程\n\n")
+	sb.WriteString("1. **分析夏普比率**: 当前策略是否有效？需要调整吗？\n")
+	sb.WriteString("2. **评估持仓**: 趋势是否改变？是否该止盈/止损？\n")
+	sb.WriteString("3. **寻找新机会**: 有强信号吗？多空机会？\n")
+	sb.WriteString("4. **输出决策**: 思维链分析 + JSON\n\n")
+
+	// === 输出格式 ===
+	sb.WriteString("# 📤 输出格式\n\n")
+	sb.WriteString("**第一步: 思维链（纯文本）**\n")
+	sb.WriteString("简洁分析你的思考过程\n\n")
+	sb.WriteString("**第二步: JSON决策数组**\n\n")
+	sb.WriteString("```json\n[\n")
+	sb.WriteString(fmt.Sprintf("  {\"symbol\": \"BTCUSDT\", \"action\": \"open_short\", \"leverage\": %d, \"position_size_usd\": %.0f, \"stop_loss\": 97000, \"take_profit\": 91000, \"confidence\": 85, \"risk_usd\": 300, \"reasoning\": \"下跌趋势+MACD死叉\"},\n", btcEthLeverage, accountEquity*5))
+	sb.WriteString("  {\"symbol\": \"ETHUSDT\", \"action\": \"close_long\", \"reasoning\": \"止盈离场\"}\n")
+	sb.WriteString("]\n```\n\n")
+	sb.WriteString("**字段说明**:\n")
+	sb.WriteString("- `action`: open_long | open_short | close_long | close_short | hold | wait\n")
+	sb.WriteString("- `confidence`: 0-100（开仓建议≥75）\n")
+	sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning\n\n")
+
+	// === 关键提醒 ===
+	sb.WriteString("---\n\n")
+	sb.WriteString("**记住**: \n")
+	sb.WriteString("- 目标是夏普比率，不是交易频率\n")
+	// sb.WriteString("- 做空 = 做多，都是赚钱工具\n")
+	sb.WriteString("- 宁可错过，不做低质量交易\n")
+	sb.WriteString("- 风险回报比1:3是底线\n")
+
+	sb.WriteString("**最终指令**: \n")
+	sb.WriteString("- 仔细阅读整个用户 Prompt 后再进行决策\n")
+	sb.WriteString("- 核实你的头寸规模计算（双重检查数学）\n")
+	sb.WriteString("- 确保你的 JSON 输出合法且完整\n")
+	sb.WriteString("- 提供诚实的 confidence 分数（不要夸大信念）\n")
+	sb.WriteString("- 与你的退出方案保持一致（不要提前取消止损或目标）\n")
+
+	return sb.String()
+}
+
+// legacyUserPrompt 构建 User Prompt（动态数据）
+func legacyUserPrompt(ctx *Context) string {
+	var sb strings.Builder
+
+	// 系统状态
+	sb.WriteString(fmt.Sprintf("**时间**: %s | **周期**: #%d | **运行**: %d分钟\n\n",
+		ctx.CurrentTime, ctx.CallCount, ctx.RuntimeMinutes))
+
+	// 白名单状态
+	if ctx.CoinWhitelistEnabled {
+		sb.WriteString(fmt.Sprintf("**币种白名单**: 已启用，仅交易以下%d个币种: %s\n\n",
+			len(ctx.CoinWhitelist), strings.Join(ctx.CoinWhitelist, ", ")))
+	} else {
+		sb.WriteString("**币种白名单**: 未启用，可交易所有币种\n\n")
+	}
+
+	// BTC 市场
+	if btcData, hasBTC := ctx.MarketDataMap["BTCUSDT"]; hasBTC {
+		sb.WriteString(fmt.Sprintf("**BTC**: %.2f (1h: %+.2f%%, 4h: %+.2f%%) | MACD: %.4f | RSI: %.2f\n\n",
+			btcData.CurrentPrice, btcData.PriceChange1h, btcData.PriceChange4h,
+			btcData.CurrentMACD, btcData.CurrentRSI7))
+	}
+
+	// 账户
+	sb.WriteString(fmt.Sprintf("**账户**: 净值%.2f | 余额%.2f (%.1f%%) | 盈亏%+.2f%% | 保证金%.1f%% | 持仓%d个\n\n",
+		ctx.Account.TotalEquity,
+		ctx.Account.AvailableBalance,
+		(ctx.Account.AvailableBalance/ctx.Account.TotalEquity)*100,
+		ctx.Account.TotalPnLPct,
+		ctx.Account.MarginUsedPct,
+		ctx.Account.PositionCount))
+
+	// 持仓（完整市场数据）
+	if len(ctx.Positions) > 0 {
+		sb.WriteString("## 当前持仓\n")
+		for i, pos := range ctx.Positions {
+			// 计算持仓时长
+			holdingDuration := ""
+			if pos.UpdateTime > 0 {
+				durationMs := time.Now().UnixMilli() - pos.UpdateTime
+				durationMin := durationMs / (1000 * 60) // 转换为分钟
+				if durationMin < 60 {
+					holdingDuration = fmt.Sprintf(" | 持仓时长%d分钟", durationMin)
+				} else {
+					durationHour := durationMin / 60
+					durationMinRemainder := durationMin % 60
+					holdingDuration = fmt.Sprintf(" | 持仓时长%d小时%d分钟", durationHour, durationMinRemainder)
+				}
+			}
+
+			sb.WriteString(fmt.Sprintf("%d. %s %s | 入场价%.4f 当前价%.4f | 盈亏%+.2f%% | 杠杆%dx | 保证金%.0f | 强平价%.4f%s\n\n",
+				i+1, pos.Symbol, strings.ToUpper(pos.Side),
+				pos.EntryPrice, pos.MarkPrice, pos.UnrealizedPnLPct,
+				pos.Leverage, pos.MarginUsed, pos.LiquidationPrice, holdingDuration))
+
+			// 使用FormatMarketData输出完整市场数据
+			if marketData, ok := ctx.MarketDataMap[pos.Symbol]; ok {
+				sb.WriteString(market.Format(marketData))
+				sb.WriteString("\n")
+			}
+		}
+	} else {
+		sb.WriteString("**当前持仓**: 无\n\n")
+	}
+
+	// 候选币种（完整市场数据）
+	sb.WriteString(fmt.Sprintf("## 候选币种 (%d个)\n\n", len(ctx.MarketDataMap)))
+	displayedCount := 0
+	for _, coin := range ctx.CandidateCoins {
+		marketData, hasData := ctx.MarketDataMap[coin.Symbol]
+		if !hasData {
+			continue
+		}
+		displayedCount++
+
+		sourceTags := ""
+		if len(coin.Sources) > 1 {
+			sourceTags = " (AI500+OI_Top双重信号)"
+		} else if len(coin.Sources) == 1 && coin.Sources[0] == "oi_top" {
+			sourceTags = " (OI_Top持仓增长)"
+		}
+
+		// 检查是否有Hyperliquid OI数据
+		if oiData, hasOIData := ctx.OITopDataMap[coin.Symbol]; hasOIData && oiData.OIDeltaValue > 0 {
+			if sourceTags == "" {
+				sourceTags = " (Hyperliquid OI数据)"
+			} else {
+				sourceTags += "+Hyperliquid OI"
+			}
+		}
+
+		// 使用FormatMarketData输出完整市场数据
+		sb.WriteString(fmt.Sprintf("### %d. %s%s\n\n", displayedCount, coin.Symbol, sourceTags))
+		sb.WriteString(market.Format(marketData))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+
+	// 夏普比率（直接传值，不要复杂格式化）
+	if ctx.Performance != nil {
+		// 直接从interface{}中提取SharpeRatio
+		type PerformanceData struct {
+			SharpeRatio float64 `json:"sharpe_ratio"`
+		}
+		var perfData PerformanceData
+		if jsonData, err := json.Marshal(ctx.Performance); err == nil {
+			if err := json.Unmarshal(jsonData, &perfData); err == nil {
+				sb.WriteString(fmt.Sprintf("## 📊 夏普比率: %.2f\n\n", perfData.SharpeRatio))
+			}
+		}
+	}
+
+	sb.WriteString("---\n\n")
+	sb.WriteString("现在请分析并输出决策（思维链 + JSON）\n")
+
+	return sb.String()
+}
+
+func TestDefaultTemplateMatchesLegacyPrompt(t *testing.T) {
+	btc := sampleMarketData("BTCUSDT", 95000)
+	sol := sampleMarketData("SOLUSDT", 180.5)
+	doge := sampleMarketData("DOGEUSDT", 0.1234)
+	pepe := sampleMarketData("PEPEUSDT", 0.00001)
+
+	tests := []struct {
+		name string
+		ctx  *Context
+	}{
+		{
+			name: "空账户",
+			ctx: &Context{
+				CurrentTime:     "2025-01-01 00:00:00",
+				Account:         AccountInfo{TotalEquity: 1000, AvailableBalance: 1000},
+				BTCETHLeverage:  5,
+				AltcoinLeverage: 5,
+			},
+		},
+		{
+			name: "持仓、候选币种、白名单和夏普比率",
+			ctx: &Context{
+				CurrentTime:    "2025-01-02 03:04:05",
+				RuntimeMinutes: 125,
+				CallCount:      42,
+				Account: AccountInfo{
+					TotalEquity:      1234.56,
+					AvailableBalance: 800.12,
+					TotalPnLPct:      -3.456,
+					MarginUsedPct:    35.2,
+					PositionCount:    2,
+				},
+				Positions: []PositionInfo{
+					{Symbol: "BTCUSDT", Side: "long", EntryPrice: 94000, MarkPrice: 95000, Quantity: 0.01, Leverage: 10,
+						UnrealizedPnLPct: 10.64, LiquidationPrice: 85000, MarginUsed: 95,
+						UpdateTime: time.Now().Add(-95*time.Minute - 30*time.Second).UnixMilli()},
+					{Symbol: "XRPUSDT", Side: "short", EntryPrice: 2.5, MarkPrice: 2.45, Leverage: 3,
+						UnrealizedPnLPct: 6, LiquidationPrice: 3.2, MarginUsed: 40,
+						UpdateTime: time.Now().Add(-20*time.Minute - 30*time.Second).UnixMilli()},
+				},
+				CandidateCoins: []CandidateCoin{
+					{Symbol: "SOLUSDT", Sources: []string{"ai500", "oi_top"}},
+					{Symbol: "MISSINGUSDT", Sources: []string{"ai500"}},
+					{Symbol: "DOGEUSDT", Sources: []string{"oi_top"}},
+					{Symbol: "PEPEUSDT", Sources: []string{"ai500"}},
+					{Symbol: "BTCUSDT", Sources: []string{"ai500"}},
+				},
+				MarketDataMap: map[string]*market.Data{
+					"BTCUSDT":  btc,
+					"SOLUSDT":  sol,
+					"DOGEUSDT": doge,
+					"PEPEUSDT": pepe,
+				},
+				OITopDataMap: map[string]*market.OITopData{
+					"SOLUSDT":  {Rank: 1, OIDeltaValue: 5000},
+					"PEPEUSDT": {Rank: 2, OIDeltaValue: 100},
+					"DOGEUSDT": {Rank: 3, OIDeltaValue: -10},
+				},
+				Performance:          map[string]interface{}{"sharpe_ratio": 0.4567, "total_trades": 12},
+				BTCETHLeverage:       20,
+				AltcoinLeverage:      8,
+				CoinWhitelistEnabled: true,
+				CoinWhitelist:        []string{"BTCUSDT", "SOLUSDT"},
+			},
+		},
+	}
+
+	pt, err := LoadPromptTemplate(DefaultPromptTemplate, LangChinese)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			system, user, err := pt.Render(tt.ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := legacySystemPrompt(tt.ctx.Account.TotalEquity, tt.ctx.BTCETHLeverage, tt.ctx.AltcoinLeverage); system != want {
+				t.Errorf("system prompt 与旧版不一致:\n%s", firstDiff(system, want))
+			}
+			if want := legacyUserPrompt(tt.ctx); user != want {
+				t.Errorf("user prompt 与旧版不一致:\n%s", firstDiff(user, want))
+			}
+		})
+	}
+}
+
+// firstDiff 定位两个prompt第一处不同的行
+func firstDiff(got, want string) string {
+	gotLines := strings.Split(got, "\n")
+	wantLines := strings.Split(want, "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			return fmt.Sprintf("第%d行\n got: %q\nwant: %q", i+1, g, w)
+		}
+	}
+	return "仅换行不同"
+}
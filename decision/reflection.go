@@ -0,0 +1,86 @@
+package decision
+
+import (
+	"bytes"
+	"fmt"
+	"nofx/market"
+	"nofx/mcp"
+	"strings"
+	"time"
+)
+
+// TradeReview 需要复盘的一笔已平仓交易
+type TradeReview struct {
+	Symbol         string
+	Side           string // long 或 short
+	Leverage       int
+	OpenPrice      float64
+	ClosePrice     float64
+	StopLoss       float64 // 开仓时的止损价（0表示未知）
+	PnL            float64 // 盈亏（USDT）
+	PnLPct         float64 // 盈亏百分比（相对保证金）
+	RMultiple      float64 // R倍数（0表示无止损信息）
+	OpenTime       time.Time
+	CloseTime      time.Time
+	EntryReasoning string           // 开仓理由
+	ExitReasoning  string           // 平仓理由
+	EntryMarket    *market.Snapshot // 开仓时的市场快照（可能为空）
+	ExitMarket     *market.Snapshot // 平仓时的市场快照（可能为空）
+}
+
+// ReflectionData 渲染复盘模板时可用的变量（TradeReview 的字段可直接使用）
+type ReflectionData struct {
+	*TradeReview
+	Holding  string // 持仓时长（按prompt语言）
+	MaxChars int    // 复盘的最大字符数
+}
+
+// Lesson 一笔交易复盘的经验教训（放入后续周期的prompt）
+type Lesson struct {
+	Symbol string
+	Side   string
+	PnLPct float64
+	Text   string
+}
+
+// RenderReflection 渲染交易复盘的 System Prompt 和 User Prompt
+func (pt *PromptTemplate) RenderReflection(review *TradeReview, maxChars int) (string, string, error) {
+	data := &ReflectionData{
+		TradeReview: review,
+		Holding:     formatMinutes(int64(review.CloseTime.Sub(review.OpenTime).Minutes()), pt.Language),
+		MaxChars:    maxChars,
+	}
+
+	var user bytes.Buffer
+	if err := pt.reflection.Execute(&user, data); err != nil {
+		return "", "", fmt.Errorf("渲染复盘prompt失败: %w", err)
+	}
+	return localize(pt.Language, msgReflectionSystem), user.String(), nil
+}
+
+// GetTradeReflection 让模型复盘一笔已平仓交易，返回经验总结和实际使用的模型
+// pt 为空时使用内置中文模板；总结超过 maxChars 个字符时截断
+func GetTradeReflection(client *mcp.Client, pt *PromptTemplate, review *TradeReview, maxChars int) (string, string, error) {
+	if pt == nil {
+		var err error
+		if pt, err = defaultPrompt(); err != nil {
+			return "", "", err
+		}
+	}
+
+	systemPrompt, userPrompt, err := pt.RenderReflection(review, maxChars)
+	if err != nil {
+		return "", "", err
+	}
+	result, err := client.CallWithFallback(systemPrompt, userPrompt)
+	if err != nil {
+		return "", "", fmt.Errorf("调用AI API失败: %w", err)
+	}
+
+	lesson := strings.TrimSpace(result.Content)
+	lesson = strings.TrimSpace(strings.Trim(lesson, "`"))
+	if lesson == "" {
+		return "", result.Model, fmt.Errorf("AI返回的复盘为空")
+	}
+	return TruncateText(lesson, maxChars), result.Model, nil
+}
@@ -0,0 +1,127 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/mcp"
+	"time"
+)
+
+// ReplayInput 重放一个周期所需的输入：原样的prompt、工具对话和验证决策时使用的参数
+type ReplayInput struct {
+	SystemPrompt    string
+	UserPrompt      string
+	Transcript      *mcp.Transcript // 原周期的工具调用和完整结果（未调用工具时为空）
+	AccountEquity   float64
+	BTCETHLeverage  int
+	AltcoinLeverage int
+	Language        string
+}
+
+// Replay 把记录的prompt（和工具对话）原样发送给指定模型并解析决策
+// 不使用备用模型，也不实际调用工具，保证结果来自该模型本身且输入与原周期相同；解析失败时仍返回原始输出
+func Replay(client *mcp.Client, in ReplayInput) (*FullDecision, error) {
+	var content string
+	var err error
+	if in.Transcript != nil {
+		content, err = client.CallWithTranscript(in.SystemPrompt, in.UserPrompt, in.Transcript)
+	} else {
+		content, err = client.CallWithMessages(in.SystemPrompt, in.UserPrompt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("调用AI API失败: %w", err)
+	}
+
+	decision, err := parseFullDecisionResponse(content, in.AccountEquity, in.BTCETHLeverage, in.AltcoinLeverage, in.Language)
+	params := client.Params()
+	decision.Model = client.Label()
+	decision.Timestamp = time.Now()
+	decision.SystemPrompt = in.SystemPrompt
+	decision.UserPrompt = in.UserPrompt
+	decision.RawResponse = content
+	decision.Params = &params
+	if err != nil {
+		return decision, fmt.Errorf("解析AI响应失败: %w", err)
+	}
+	return decision, nil
+}
+
+// DecisionDiff 同一币种上原决策与重放决策的差异
+type DecisionDiff struct {
+	Symbol   string    `json:"symbol"`
+	Original *Decision `json:"original,omitempty"` // 为空表示重放新增的决策
+	Replayed *Decision `json:"replayed,omitempty"` // 为空表示重放中没有该决策
+	Changes  []string  `json:"changes,omitempty"`  // 变化的字段，如 "stop_loss: 95000 → 94000"
+}
+
+// DiffDecisions 对比两组决策，只返回有差异的项
+// 按币种配对（同一币种有多个决策时按顺序配对），理由文字不参与比较
+func DiffDecisions(original, replayed []Decision) []DecisionDiff {
+	var symbols []string
+	originalBySymbol := make(map[string][]Decision)
+	replayedBySymbol := make(map[string][]Decision)
+	for _, d := range original {
+		if _, ok := originalBySymbol[d.Symbol]; !ok {
+			symbols = append(symbols, d.Symbol)
+		}
+		originalBySymbol[d.Symbol] = append(originalBySymbol[d.Symbol], d)
+	}
+	for _, d := range replayed {
+		if _, ok := originalBySymbol[d.Symbol]; !ok {
+			if _, ok := replayedBySymbol[d.Symbol]; !ok {
+				symbols = append(symbols, d.Symbol)
+			}
+		}
+		replayedBySymbol[d.Symbol] = append(replayedBySymbol[d.Symbol], d)
+	}
+
+	var diffs []DecisionDiff
+	for _, symbol := range symbols {
+		a, b := originalBySymbol[symbol], replayedBySymbol[symbol]
+		for i := 0; i < len(a) || i < len(b); i++ {
+			diff := DecisionDiff{Symbol: symbol}
+			if i < len(a) {
+				diff.Original = &a[i]
+			}
+			if i < len(b) {
+				diff.Replayed = &b[i]
+			}
+			if diff.Original != nil && diff.Replayed != nil {
+				diff.Changes = decisionChanges(*diff.Original, *diff.Replayed)
+				if len(diff.Changes) == 0 {
+					continue
+				}
+			}
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs
+}
+
+// decisionChanges 两个决策之间变化的字段
+func decisionChanges(a, b Decision) []string {
+	var changes []string
+	if a.Action != b.Action {
+		changes = append(changes, fmt.Sprintf("action: %s → %s", a.Action, b.Action))
+	}
+	if a.Leverage != b.Leverage {
+		changes = append(changes, fmt.Sprintf("leverage: %d → %d", a.Leverage, b.Leverage))
+	}
+	floats := []struct {
+		name string
+		a, b float64
+	}{
+		{"position_size_usd", a.PositionSizeUSD, b.PositionSizeUSD},
+		{"stop_loss", a.StopLoss, b.StopLoss},
+		{"take_profit", a.TakeProfit, b.TakeProfit},
+		{"risk_usd", a.RiskUSD, b.RiskUSD},
+	}
+	for _, f := range floats {
+		if f.a != f.b {
+			changes = append(changes, fmt.Sprintf("%s: %g → %g", f.name, f.a, f.b))
+		}
+	}
+	if a.Confidence != b.Confidence {
+		changes = append(changes, fmt.Sprintf("confidence: %d → %d", a.Confidence, b.Confidence))
+	}
+	return changes
+}
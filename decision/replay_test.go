@@ -0,0 +1,131 @@
+package decision
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffDecisions(t *testing.T) {
+	openBTC := Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500, StopLoss: 90000, TakeProfit: 110000, Confidence: 80, RiskUSD: 50, Reasoning: "突破"}
+	closeETH := Decision{Symbol: "ETHUSDT", Action: "close_short", Reasoning: "止盈"}
+	waitSOL := Decision{Symbol: "SOLUSDT", Action: "wait"}
+
+	with := func(d Decision, change func(*Decision)) Decision {
+		change(&d)
+		return d
+	}
+
+	// diff 用于比较的简化结果：币种、原动作、重放动作和变化字段
+	type diff struct {
+		Symbol   string
+		Original string
+		Replayed string
+		Changes  []string
+	}
+
+	tests := []struct {
+		name     string
+		original []Decision
+		replayed []Decision
+		want     []diff
+	}{
+		{
+			name:     "完全相同",
+			original: []Decision{openBTC, closeETH},
+			replayed: []Decision{openBTC, closeETH},
+			want:     nil,
+		},
+		{
+			name:     "顺序不同和理由不同不算差异",
+			original: []Decision{openBTC, closeETH},
+			replayed: []Decision{closeETH, with(openBTC, func(d *Decision) { d.Reasoning = "另一种理由" })},
+			want:     nil,
+		},
+		{
+			name:     "都为空",
+			original: nil,
+			replayed: nil,
+			want:     nil,
+		},
+		{
+			name:     "字段变化",
+			original: []Decision{openBTC},
+			replayed: []Decision{with(openBTC, func(d *Decision) {
+				d.Leverage = 3
+				d.StopLoss = 92000
+				d.Confidence = 75
+			})},
+			want: []diff{{
+				Symbol: "BTCUSDT", Original: "open_long", Replayed: "open_long",
+				Changes: []string{"leverage: 5 → 3", "stop_loss: 90000 → 92000", "confidence: 80 → 75"},
+			}},
+		},
+		{
+			name:     "动作和仓位变化",
+			original: []Decision{openBTC},
+			replayed: []Decision{{Symbol: "BTCUSDT", Action: "wait"}},
+			want: []diff{{
+				Symbol: "BTCUSDT", Original: "open_long", Replayed: "wait",
+				Changes: []string{
+					"action: open_long → wait", "leverage: 5 → 0", "position_size_usd: 500 → 0", "stop_loss: 90000 → 0",
+					"take_profit: 110000 → 0", "risk_usd: 50 → 0", "confidence: 80 → 0",
+				},
+			}},
+		},
+		{
+			name:     "重放缺少和新增的决策",
+			original: []Decision{openBTC, closeETH},
+			replayed: []Decision{closeETH, waitSOL},
+			want: []diff{
+				{Symbol: "BTCUSDT", Original: "open_long"},
+				{Symbol: "SOLUSDT", Replayed: "wait"},
+			},
+		},
+		{
+			name:     "原决策为空",
+			original: nil,
+			replayed: []Decision{waitSOL, openBTC},
+			want: []diff{
+				{Symbol: "SOLUSDT", Replayed: "wait"},
+				{Symbol: "BTCUSDT", Replayed: "open_long"},
+			},
+		},
+		{
+			name:     "同一币种的多个决策按顺序配对",
+			original: []Decision{{Symbol: "ETHUSDT", Action: "close_long"}, {Symbol: "ETHUSDT", Action: "open_short", Leverage: 3}},
+			replayed: []Decision{{Symbol: "ETHUSDT", Action: "close_long"}},
+			want: []diff{
+				{Symbol: "ETHUSDT", Original: "open_short"},
+			},
+		},
+		{
+			name:     "按原决策的币种顺序输出，新增币种在后",
+			original: []Decision{closeETH, openBTC},
+			replayed: []Decision{waitSOL, with(openBTC, func(d *Decision) { d.TakeProfit = 105000 }), with(closeETH, func(d *Decision) { d.Action = "hold" })},
+			want: []diff{
+				{Symbol: "ETHUSDT", Original: "close_short", Replayed: "hold", Changes: []string{"action: close_short → hold"}},
+				{Symbol: "BTCUSDT", Original: "open_long", Replayed: "open_long", Changes: []string{"take_profit: 110000 → 105000"}},
+				{Symbol: "SOLUSDT", Replayed: "wait"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []diff
+			for _, d := range DiffDecisions(tt.original, tt.replayed) {
+				g := diff{Symbol: d.Symbol, Changes: d.Changes}
+				if d.Original != nil {
+					g.Original = d.Original.Action
+				}
+				if d.Replayed != nil {
+					g.Replayed = d.Replayed.Action
+				}
+				got = append(got, g)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffDecisions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
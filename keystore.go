@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"nofx/config"
+	"os"
+	"sort"
+	"strings"
+)
+
+// runKeystoreCommand 管理加密密钥库: nofx keystore [-file keystore.json] <set|delete|list> [名称]
+// 口令从 NOFX_KEYSTORE_PASSPHRASE(_FILE) 读取，未设置时从标准输入读取第一行；set 的密钥值从标准输入读取
+func runKeystoreCommand(args []string) error {
+	fs := flag.NewFlagSet("keystore", flag.ContinueOnError)
+	path := fs.String("file", config.DefaultKeystoreFile, "密钥库文件（与config.json中的keystore_file一致）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+
+	usage := fmt.Errorf("用法: nofx keystore [-file %s] <set|delete|list> [名称]", config.DefaultKeystoreFile)
+	if len(args) == 0 {
+		return usage
+	}
+	action := args[0]
+	if (action == "set" || action == "delete") && len(args) != 2 {
+		return usage
+	}
+
+	input := bufio.NewReader(os.Stdin)
+	passphrase, err := config.KeystorePassphrase()
+	if err != nil {
+		fmt.Fprint(os.Stderr, "密钥库口令: ")
+		if passphrase, err = readLine(input); err != nil || passphrase == "" {
+			return fmt.Errorf("未提供密钥库口令")
+		}
+	}
+
+	secrets := make(map[string]string)
+	if _, err := os.Stat(*path); err == nil {
+		if secrets, err = config.OpenKeystore(*path, passphrase); err != nil {
+			return err
+		}
+	} else if action != "set" {
+		return fmt.Errorf("密钥库 %s 不存在", *path)
+	}
+
+	switch action {
+	case "list":
+		names := make([]string, 0, len(secrets))
+		for name := range secrets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s\tkeystore:%s\n", name, name)
+		}
+		return nil
+
+	case "set":
+		name := args[1]
+		fmt.Fprintf(os.Stderr, "%s 的值: ", name)
+		value, err := readLine(input)
+		if err != nil || value == "" {
+			return fmt.Errorf("未提供密钥值")
+		}
+		secrets[name] = value
+		if err := config.SaveKeystore(*path, passphrase, secrets); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "✓ 已保存，在config.json中使用 \"keystore:%s\" 引用\n", name)
+		return nil
+
+	case "delete":
+		name := args[1]
+		if _, ok := secrets[name]; !ok {
+			return fmt.Errorf("密钥库中不存在 '%s'", name)
+		}
+		delete(secrets, name)
+		if err := config.SaveKeystore(*path, passphrase, secrets); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "✓ 已删除 '%s'\n", name)
+		return nil
+	}
+	return usage
+}
+
+// readLine 读取一行（去除换行符）
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -1,17 +1,20 @@
 package logger
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"math"
+	"nofx/market"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
 // DecisionRecord 决策记录
 type DecisionRecord struct {
+	ID             int64              `json:"id,omitempty"`    // 存储中的记录ID
 	Timestamp      time.Time          `json:"timestamp"`       // 决策时间
 	CycleNumber    int                `json:"cycle_number"`    // 周期编号
 	InputPrompt    string             `json:"input_prompt"`    // 发送给AI的输入prompt
@@ -24,6 +27,89 @@ type DecisionRecord struct {
 	ExecutionLog   []string           `json:"execution_log"`   // 执行日志
 	Success        bool               `json:"success"`         // 是否成功
 	ErrorMessage   string             `json:"error_message"`   // 错误信息（如果有）
+	AIModel        string             `json:"ai_model"`        // 实际使用的模型（主模型故障时为备用模型，投票时为全部有效模型）
+
+	// 多模型投票（未启用时为空）
+	ModelOutputs []ModelOutput `json:"model_outputs,omitempty"` // 每个模型的原始输出
+	VoteSummary  []string      `json:"vote_summary,omitempty"`  // 存在分歧的币种及投票结果
+
+	// prompt实验（未参与实验时为空）
+	Experiment    string `json:"experiment,omitempty"`     // 实验名称
+	PromptVariant string `json:"prompt_variant,omitempty"` // 本周期使用的prompt变体
+
+	// prompt超出token预算时的压缩记录（未超出时为空）
+	PromptBudget *PromptBudget `json:"prompt_budget,omitempty"`
+
+	// 模型在本周期调用的工具（未启用工具调用时为空）
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// 调用过工具的模型的完整对话（含未截断的工具结果，重放时使用）
+	ToolTranscripts []ToolTranscript `json:"tool_transcripts,omitempty"`
+
+	// 重放所需的其余输入和输出（InputPrompt 为 user prompt；投票时每个模型的原始输出和参数见 ModelOutputs）
+	SystemPrompt string            `json:"system_prompt,omitempty"`
+	RawResponse  string            `json:"raw_response,omitempty"` // 模型原始输出
+	ModelParams  *ModelParams      `json:"model_params,omitempty"` // 实际使用的模型参数
+	Validation   *ValidationParams `json:"validation,omitempty"`   // 验证决策时使用的参数
+}
+
+// ModelParams 调用模型时的参数
+type ModelParams struct {
+	Provider    string  `json:"provider"`
+	Model       string  `json:"model"`
+	BaseURL     string  `json:"base_url"`
+	Temperature float64 `json:"temperature"`
+	MaxTokens   int     `json:"max_tokens"`
+}
+
+// ValidationParams 验证AI决策时使用的参数（重放时按相同条件验证）
+type ValidationParams struct {
+	AccountEquity   float64 `json:"account_equity"`
+	BTCETHLeverage  int     `json:"btc_eth_leverage"`
+	AltcoinLeverage int     `json:"altcoin_leverage"`
+	Language        string  `json:"language"`
+}
+
+// PromptBudget prompt超出token预算时的压缩和移除情况
+type PromptBudget struct {
+	BudgetTokens    int      `json:"budget_tokens"`         // system + user prompt 的token预算
+	OriginalTokens  int      `json:"original_tokens"`       // 压缩前的估算token数
+	EstimatedTokens int      `json:"estimated_tokens"`      // 最终prompt的估算token数
+	Compressed      []string `json:"compressed,omitempty"`  // 只保留最近几个序列数据点的候选币种
+	Dropped         []string `json:"dropped,omitempty"`     // 从prompt中移除的候选币种
+	OverBudget      bool     `json:"over_budget,omitempty"` // 移除全部候选币种后仍超出预算
+}
+
+// ModelOutput 多模型投票中单个模型的输出
+type ModelOutput struct {
+	Model        string  `json:"model"`
+	UsedModel    string  `json:"used_model,omitempty"` // 该模型故障时实际使用的备用模型
+	Weight       float64 `json:"weight"`
+	RawResponse  string  `json:"raw_response"`    // 模型原始输出
+	DecisionJSON string  `json:"decision_json"`   // 解析出的决策JSON
+	Error        string  `json:"error,omitempty"` // 失败原因（该模型弃权）
+	DurationMs   int64   `json:"duration_ms"`
+
+	// 实际使用的模型参数（调用失败时为空）
+	Params *ModelParams `json:"params,omitempty"`
+}
+
+// ToolCall 模型的一次工具调用
+type ToolCall struct {
+	Model      string    `json:"model"`
+	Name       string    `json:"name"`
+	Arguments  string    `json:"arguments"`
+	Result     string    `json:"result,omitempty"` // 返回给模型的结果（截断）
+	Error      string    `json:"error,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// ToolTranscript 一个模型调用工具的完整对话（初始prompt之后的消息）
+type ToolTranscript struct {
+	Model    string          `json:"model"`
+	Tools    json.RawMessage `json:"tools"`    // 工具定义
+	Messages json.RawMessage `json:"messages"` // 工具调用请求和完整的工具结果（不含最终回复）
 }
 
 // AccountSnapshot 账户状态快照
@@ -49,27 +135,43 @@ type PositionSnapshot struct {
 
 // DecisionAction 决策动作
 type DecisionAction struct {
-	Action    string    `json:"action"`    // open_long, open_short, close_long, close_short
-	Symbol    string    `json:"symbol"`    // 币种
-	Quantity  float64   `json:"quantity"`  // 数量
-	Leverage  int       `json:"leverage"`  // 杠杆（开仓时）
-	Price     float64   `json:"price"`     // 执行价格
-	OrderID   int64     `json:"order_id"`  // 订单ID
-	Timestamp time.Time `json:"timestamp"` // 执行时间
-	Success   bool      `json:"success"`   // 是否成功
-	Error     string    `json:"error"`     // 错误信息
+	Action     string    `json:"action"`                // open_long, open_short, close_long, close_short
+	Symbol     string    `json:"symbol"`                // 币种
+	Quantity   float64   `json:"quantity"`              // 数量
+	Leverage   int       `json:"leverage"`              // 杠杆（开仓时）
+	Price      float64   `json:"price"`                 // 执行价格
+	StopLoss   float64   `json:"stop_loss,omitempty"`   // 止损价（开仓时）
+	TakeProfit float64   `json:"take_profit,omitempty"` // 止盈价（开仓时）
+	OrderID    int64     `json:"order_id"`              // 订单ID
+	Timestamp  time.Time `json:"timestamp"`             // 执行时间
+	Success    bool      `json:"success"`               // 是否成功
+	Error      string    `json:"error"`                 // 错误信息
+
+	Reasoning string           `json:"reasoning,omitempty"` // AI给出的决策理由
+	Market    *market.Snapshot `json:"market,omitempty"`    // 执行时的市场快照（用于交易复盘）
 }
 
 // DecisionLogger 决策日志记录器
 type DecisionLogger struct {
 	logDir      string
+	store       Store
 	cycleNumber int
+	mu          sync.Mutex
+}
+
+// defaultLogDir 决策日志的根目录（每个trader一个子目录）
+const defaultLogDir = "decision_logs"
+
+// TraderLogDir trader的决策日志目录
+func TraderLogDir(traderID string) string {
+	return filepath.Join(defaultLogDir, traderID)
 }
 
 // NewDecisionLogger 创建决策日志记录器
+// 默认使用 logDir/decisions.db（SQLite），首次启动时自动导入目录中旧版的JSON日志
 func NewDecisionLogger(logDir string) *DecisionLogger {
 	if logDir == "" {
-		logDir = "decision_logs"
+		logDir = defaultLogDir
 	}
 
 	// 确保日志目录存在
@@ -77,130 +179,222 @@ func NewDecisionLogger(logDir string) *DecisionLogger {
 		fmt.Printf("⚠ 创建日志目录失败: %v\n", err)
 	}
 
+	var store Store
+	sqliteStore, err := NewSQLiteStore(filepath.Join(logDir, "decisions.db"))
+	if err != nil {
+		// SQLite不可用时降级为JSON文件存储，不影响交易主流程
+		fmt.Printf("⚠ 初始化SQLite决策日志失败，降级为JSON文件存储: %v\n", err)
+		store = &FileStore{logDir: logDir}
+	} else {
+		store = sqliteStore
+		imported, err := MigrateJSONLogs(logDir, sqliteStore)
+		if err != nil {
+			fmt.Printf("⚠ 迁移旧版JSON决策日志失败: %v\n", err)
+		} else if imported > 0 {
+			fmt.Printf("📦 已将 %d 条旧版JSON决策日志导入SQLite: %s\n", imported, logDir)
+		}
+	}
+
+	// 周期编号在重启后继续递增，保证同一trader的周期编号唯一
+	cycleNumber, err := store.MaxCycleNumber()
+	if err != nil {
+		fmt.Printf("⚠ 读取最大周期编号失败: %v\n", err)
+	}
+
 	return &DecisionLogger{
 		logDir:      logDir,
-		cycleNumber: 0,
+		store:       store,
+		cycleNumber: cycleNumber,
 	}
 }
 
+// OpenDecisionLoggerReadOnly 以只读方式打开已有的决策日志（用于离线工具，如重放）
+// 不创建目录、不执行结构迁移和旧版日志导入；没有SQLite数据库时读取旧版JSON文件
+func OpenDecisionLoggerReadOnly(logDir string) (*DecisionLogger, error) {
+	if _, err := os.Stat(logDir); err != nil {
+		return nil, fmt.Errorf("决策日志目录不存在: %w", err)
+	}
+
+	var store Store
+	dbPath := filepath.Join(logDir, "decisions.db")
+	if _, err := os.Stat(dbPath); err == nil {
+		sqliteStore, err := OpenSQLiteStoreReadOnly(dbPath)
+		if err != nil {
+			return nil, err
+		}
+		store = sqliteStore
+	} else {
+		store = &FileStore{logDir: logDir}
+	}
+
+	return &DecisionLogger{logDir: logDir, store: store}, nil
+}
+
 // LogDecision 记录决策
 func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
+	l.mu.Lock()
 	l.cycleNumber++
 	record.CycleNumber = l.cycleNumber
+	l.mu.Unlock()
 	record.Timestamp = time.Now()
 
-	// 生成文件名：decision_YYYYMMDD_HHMMSS_cycleN.json
-	filename := fmt.Sprintf("decision_%s_cycle%d.json",
-		record.Timestamp.Format("20060102_150405"),
-		record.CycleNumber)
-
-	filepath := filepath.Join(l.logDir, filename)
-
-	// 序列化为JSON（带缩进，方便阅读）
-	data, err := json.MarshalIndent(record, "", "  ")
-	if err != nil {
-		return fmt.Errorf("序列化决策记录失败: %w", err)
+	if err := l.store.Save(record); err != nil {
+		return err
 	}
 
-	// 写入文件
-	if err := ioutil.WriteFile(filepath, data, 0644); err != nil {
-		return fmt.Errorf("写入决策记录失败: %w", err)
-	}
-
-	fmt.Printf("📝 决策记录已保存: %s\n", filename)
+	fmt.Printf("📝 决策记录已保存: 周期#%d\n", record.CycleNumber)
 	return nil
 }
 
 // GetLatestRecords 获取最近N条记录（按时间正序：从旧到新）
 func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
-	files, err := ioutil.ReadDir(l.logDir)
+	records, err := l.store.Latest(n)
 	if err != nil {
-		return nil, fmt.Errorf("读取日志目录失败: %w", err)
+		return nil, fmt.Errorf("读取决策记录失败: %w", err)
 	}
+	return records, nil
+}
 
-	// 先按修改时间倒序收集（最新的在前）
-	var records []*DecisionRecord
-	count := 0
-	for i := len(files) - 1; i >= 0 && count < n; i-- {
-		file := files[i]
-		if file.IsDir() {
-			continue
-		}
+// GetRecordByCycle 按周期编号获取决策记录，不存在时返回nil
+func (l *DecisionLogger) GetRecordByCycle(cycle int) (*DecisionRecord, error) {
+	records, err := l.store.Query(RecordQuery{Cycle: cycle, Limit: 1, Desc: true})
+	if err != nil {
+		return nil, fmt.Errorf("读取决策记录失败: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return records[0], nil
+}
 
-		filepath := filepath.Join(l.logDir, file.Name())
-		data, err := ioutil.ReadFile(filepath)
-		if err != nil {
-			continue
-		}
+// GetSymbolActions 获取最近N个周期内某个币种成功执行的开平仓动作（按时间正序：从旧到新）
+func (l *DecisionLogger) GetSymbolActions(symbol string, lookbackCycles int) ([]DecisionAction, error) {
+	records, err := l.GetLatestRecords(lookbackCycles)
+	if err != nil {
+		return nil, err
+	}
 
-		var record DecisionRecord
-		if err := json.Unmarshal(data, &record); err != nil {
-			continue
+	actions := []DecisionAction{}
+	for _, record := range records {
+		for _, action := range record.Decisions {
+			if action.Symbol != symbol || !action.Success {
+				continue
+			}
+			switch action.Action {
+			case "open_long", "open_short", "close_long", "close_short":
+				action.Reasoning = actionReasoning(record, action)
+				actions = append(actions, action)
+			}
 		}
-
-		records = append(records, &record)
-		count++
 	}
+	return actions, nil
+}
 
-	// 反转数组，让时间从旧到新排列（用于图表显示）
-	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
-		records[i], records[j] = records[j], records[i]
+// GetRecordsInRange 获取时间范围内的记录（from包含、to不包含，零值表示不限制），支持分页
+func (l *DecisionLogger) GetRecordsInRange(from, to time.Time, limit, offset int) ([]*DecisionRecord, error) {
+	records, err := l.store.Query(RecordQuery{From: from, To: to, Limit: limit, Offset: offset})
+	if err != nil {
+		return nil, fmt.Errorf("读取决策记录失败: %w", err)
 	}
-
 	return records, nil
 }
 
-// GetRecordByDate 获取指定日期的所有记录
-func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, error) {
-	dateStr := date.Format("20060102")
-	pattern := filepath.Join(l.logDir, fmt.Sprintf("decision_%s_*.json", dateStr))
+// DecisionQuery 决策记录检索条件（用于审计）
+type DecisionQuery struct {
+	From    time.Time // 起始时间（包含），零值表示不限制
+	To      time.Time // 结束时间（不包含），零值表示不限制
+	Symbol  string    // 执行动作的币种
+	Action  string    // 执行动作类型
+	Success *bool     // 执行结果（指定Symbol/Action时作用于动作，否则作用于周期）
+	Limit   int       // 每页条数
+	Cursor  string    // 上一页返回的 NextCursor，为空表示从最新的记录开始
+}
 
-	files, err := filepath.Glob(pattern)
-	if err != nil {
-		return nil, fmt.Errorf("查找日志文件失败: %w", err)
+// DecisionPage 一页决策记录（从新到旧）
+type DecisionPage struct {
+	Records    []*DecisionRecord `json:"records"`
+	NextCursor string            `json:"next_cursor,omitempty"` // 为空表示没有更多记录
+}
+
+// QueryDecisions 按条件分页检索决策记录（从新到旧，基于游标翻页）
+func (l *DecisionLogger) QueryDecisions(q DecisionQuery) (*DecisionPage, error) {
+	if q.Limit <= 0 {
+		q.Limit = 50
 	}
 
-	var records []*DecisionRecord
-	for _, filepath := range files {
-		data, err := ioutil.ReadFile(filepath)
+	rq := RecordQuery{
+		From:    q.From,
+		To:      q.To,
+		Symbol:  q.Symbol,
+		Action:  q.Action,
+		Success: q.Success,
+		Limit:   q.Limit + 1, // 多取一条用于判断是否还有下一页
+		Desc:    true,
+	}
+	if q.Cursor != "" {
+		cursor, err := DecodeCursor(q.Cursor)
 		if err != nil {
-			continue
+			return nil, err
 		}
+		rq.Before = cursor
+	}
 
-		var record DecisionRecord
-		if err := json.Unmarshal(data, &record); err != nil {
-			continue
-		}
+	records, err := l.store.Query(rq)
+	if err != nil {
+		return nil, fmt.Errorf("检索决策记录失败: %w", err)
+	}
 
-		records = append(records, &record)
+	page := &DecisionPage{Records: records}
+	if len(records) > q.Limit {
+		page.Records = records[:q.Limit]
+		last := page.Records[q.Limit-1]
+		page.NextCursor = EncodeCursor(RecordCursor{Timestamp: last.Timestamp, ID: last.ID})
 	}
+	return page, nil
+}
 
-	return records, nil
+// EncodeCursor 将记录位置编码为不透明的游标字符串
+func EncodeCursor(c RecordCursor) string {
+	raw := fmt.Sprintf("%d:%d", c.Timestamp.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
 }
 
-// CleanOldRecords 清理N天前的旧记录
-func (l *DecisionLogger) CleanOldRecords(days int) error {
-	cutoffTime := time.Now().AddDate(0, 0, -days)
+// DecodeCursor 解析游标字符串
+func DecodeCursor(cursor string) (*RecordCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("无效的游标: %s", cursor)
+	}
+
+	var ts, id int64
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &ts, &id); err != nil {
+		return nil, fmt.Errorf("无效的游标: %s", cursor)
+	}
+	return &RecordCursor{Timestamp: time.Unix(0, ts), ID: id}, nil
+}
 
-	files, err := ioutil.ReadDir(l.logDir)
+// GetAccountHistory 获取账户快照历史（按时间正序，limit为最近的N条，<=0表示不限制）
+func (l *DecisionLogger) GetAccountHistory(from, to time.Time, limit int) ([]AccountPoint, error) {
+	points, err := l.store.AccountHistory(from, to, limit)
 	if err != nil {
-		return fmt.Errorf("读取日志目录失败: %w", err)
+		return nil, fmt.Errorf("读取账户历史失败: %w", err)
 	}
+	return points, nil
+}
 
-	removedCount := 0
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
+// GetRecordByDate 获取指定日期的所有记录
+func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, error) {
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	return l.GetRecordsInRange(dayStart, dayStart.AddDate(0, 0, 1), 0, 0)
+}
 
-		if file.ModTime().Before(cutoffTime) {
-			filepath := filepath.Join(l.logDir, file.Name())
-			if err := os.Remove(filepath); err != nil {
-				fmt.Printf("⚠ 删除旧记录失败 %s: %v\n", file.Name(), err)
-				continue
-			}
-			removedCount++
-		}
+// CleanOldRecords 清理N天前的旧记录
+func (l *DecisionLogger) CleanOldRecords(days int) error {
+	cutoffTime := time.Now().AddDate(0, 0, -days)
+
+	removedCount, err := l.store.DeleteBefore(cutoffTime)
+	if err != nil {
+		return err
 	}
 
 	if removedCount > 0 {
@@ -212,50 +406,12 @@ func (l *DecisionLogger) CleanOldRecords(days int) error {
 
 // GetStatistics 获取统计信息
 func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
-	files, err := ioutil.ReadDir(l.logDir)
-	if err != nil {
-		return nil, fmt.Errorf("读取日志目录失败: %w", err)
-	}
-
-	stats := &Statistics{}
-
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		filepath := filepath.Join(l.logDir, file.Name())
-		data, err := ioutil.ReadFile(filepath)
-		if err != nil {
-			continue
-		}
-
-		var record DecisionRecord
-		if err := json.Unmarshal(data, &record); err != nil {
-			continue
-		}
-
-		stats.TotalCycles++
-
-		for _, action := range record.Decisions {
-			if action.Success {
-				switch action.Action {
-				case "open_long", "open_short":
-					stats.TotalOpenPositions++
-				case "close_long", "close_short":
-					stats.TotalClosePositions++
-				}
-			}
-		}
-
-		if record.Success {
-			stats.SuccessfulCycles++
-		} else {
-			stats.FailedCycles++
-		}
-	}
+	return l.store.Statistics()
+}
 
-	return stats, nil
+// Close 关闭底层存储
+func (l *DecisionLogger) Close() error {
+	return l.store.Close()
 }
 
 // Statistics 统计信息
@@ -265,24 +421,49 @@ type Statistics struct {
 	FailedCycles        int `json:"failed_cycles"`
 	TotalOpenPositions  int `json:"total_open_positions"`
 	TotalClosePositions int `json:"total_close_positions"`
+	CorruptRecords      int `json:"corrupt_records"` // 内容损坏、查询时被跳过的记录数
 }
 
 // TradeOutcome 单笔交易结果
 type TradeOutcome struct {
-	Symbol        string    `json:"symbol"`         // 币种
-	Side          string    `json:"side"`           // long/short
-	Quantity      float64   `json:"quantity"`       // 仓位数量
-	Leverage      int       `json:"leverage"`       // 杠杆倍数
-	OpenPrice     float64   `json:"open_price"`     // 开仓价
-	ClosePrice    float64   `json:"close_price"`    // 平仓价
-	PositionValue float64   `json:"position_value"` // 仓位价值（quantity × openPrice）
-	MarginUsed    float64   `json:"margin_used"`    // 保证金使用（positionValue / leverage）
-	PnL           float64   `json:"pn_l"`           // 盈亏（USDT）
-	PnLPct        float64   `json:"pn_l_pct"`       // 盈亏百分比（相对保证金）
-	Duration      string    `json:"duration"`       // 持仓时长
-	OpenTime      time.Time `json:"open_time"`      // 开仓时间
-	CloseTime     time.Time `json:"close_time"`     // 平仓时间
-	WasStopLoss   bool      `json:"was_stop_loss"`  // 是否止损
+	Symbol        string    `json:"symbol"`                   // 币种
+	Side          string    `json:"side"`                     // long/short
+	Quantity      float64   `json:"quantity"`                 // 仓位数量
+	Leverage      int       `json:"leverage"`                 // 杠杆倍数
+	OpenPrice     float64   `json:"open_price"`               // 开仓价
+	ClosePrice    float64   `json:"close_price"`              // 平仓价
+	PositionValue float64   `json:"position_value"`           // 仓位价值（quantity × openPrice）
+	MarginUsed    float64   `json:"margin_used"`              // 保证金使用（positionValue / leverage）
+	PnL           float64   `json:"pn_l"`                     // 盈亏（USDT）
+	PnLPct        float64   `json:"pn_l_pct"`                 // 盈亏百分比（相对保证金）
+	Duration      string    `json:"duration"`                 // 持仓时长
+	OpenTime      time.Time `json:"open_time"`                // 开仓时间
+	CloseTime     time.Time `json:"close_time"`               // 平仓时间
+	WasStopLoss   bool      `json:"was_stop_loss"`            // 是否止损
+	StopLoss      float64   `json:"stop_loss,omitempty"`      // 开仓时的止损价
+	RMultiple     float64   `json:"r_multiple,omitempty"`     // R倍数（盈亏 / 开仓时的初始风险），无止损信息时为0
+	Experiment    string    `json:"experiment,omitempty"`     // 开仓周期所属的prompt实验
+	PromptVariant string    `json:"prompt_variant,omitempty"` // 开仓周期使用的prompt变体
+
+	// 交易复盘
+	ID             string           `json:"id"`                        // 交易标识（币种_方向_开仓时间）
+	OpenCycle      int              `json:"open_cycle"`                // 开仓周期编号
+	CloseCycle     int              `json:"close_cycle"`               // 平仓周期编号
+	EntryReasoning string           `json:"entry_reasoning,omitempty"` // 开仓理由
+	ExitReasoning  string           `json:"exit_reasoning,omitempty"`  // 平仓理由
+	EntryMarket    *market.Snapshot `json:"entry_market,omitempty"`    // 开仓时的市场快照
+	ExitMarket     *market.Snapshot `json:"exit_market,omitempty"`     // 平仓时的市场快照
+	Reflection     string           `json:"reflection,omitempty"`      // 平仓后模型总结的经验教训
+}
+
+// TradeID 交易标识：同一币种同一方向的持仓按开仓时间区分
+func (t TradeOutcome) TradeID() string {
+	return fmt.Sprintf("%s_%s_%d", t.Symbol, t.Side, t.OpenTime.Unix())
+}
+
+// HasRisk 开仓时是否设置了止损（有初始风险，R倍数有意义；持平交易的R倍数为0但同样计入R倍数统计）
+func (t TradeOutcome) HasRisk() bool {
+	return t.StopLoss > 0 && t.Quantity*math.Abs(t.OpenPrice-t.StopLoss) > 0
 }
 
 // PerformanceAnalysis 交易表现分析
@@ -294,11 +475,33 @@ type PerformanceAnalysis struct {
 	AvgWin        float64                       `json:"avg_win"`        // 平均盈利
 	AvgLoss       float64                       `json:"avg_loss"`       // 平均亏损
 	ProfitFactor  float64                       `json:"profit_factor"`  // 盈亏比
-	SharpeRatio   float64                       `json:"sharpe_ratio"`   // 夏普比率（风险调整后收益）
+	SharpeRatio   float64                       `json:"sharpe_ratio"`   // 周期级别（未年化）的夏普比率，年化值见 AnnualizedSharpe
 	RecentTrades  []TradeOutcome                `json:"recent_trades"`  // 最近N笔交易
 	SymbolStats   map[string]*SymbolPerformance `json:"symbol_stats"`   // 各币种表现
 	BestSymbol    string                        `json:"best_symbol"`    // 表现最好的币种
 	WorstSymbol   string                        `json:"worst_symbol"`   // 表现最差的币种
+
+	// 风险调整后收益指标（基于净值序列，按实际周期间隔年化；Annualized为false时窗口不足1天，年化字段为0）
+	Annualized           bool    `json:"annualized"`            // 是否计算了年化指标
+	AnnualizedReturnPct  float64 `json:"annualized_return_pct"` // 年化收益率（算术年化）
+	AnnualizedVolatility float64 `json:"annualized_volatility"` // 年化波动率（百分比）
+	AnnualizedSharpe     float64 `json:"annualized_sharpe"`     // 年化夏普比率（无风险利率为0）
+	SortinoRatio         float64 `json:"sortino_ratio"`         // 年化索提诺比率（只惩罚下行波动）
+	CalmarRatio          float64 `json:"calmar_ratio"`          // 卡玛比率（年化收益 / 最大回撤）
+	MaxDrawdownPct       float64 `json:"max_drawdown_pct"`      // 最大回撤百分比（正数）
+	MaxDrawdownDuration  string  `json:"max_drawdown_duration"` // 最长回撤持续时间（从峰值到恢复，未恢复则到最后一个周期）
+	MaxDrawdownMinutes   float64 `json:"max_drawdown_minutes"`  // 最长回撤持续分钟数
+	ExposurePct          float64 `json:"exposure_pct"`          // 持仓时间占比（有持仓的时间 / 分析窗口）
+
+	// 基于交易账本的指标
+	Expectancy        float64 `json:"expectancy"`          // 期望值（每笔交易平均盈亏，USDT）
+	AvgRMultiple      float64 `json:"avg_r_multiple"`      // 平均R倍数（仅统计有止损信息的交易）
+	RMultipleTrades   int     `json:"r_multiple_trades"`   // 参与R倍数统计的交易数
+	LongestWinStreak  int     `json:"longest_win_streak"`  // 最长连续盈利笔数
+	LongestLossStreak int     `json:"longest_loss_streak"` // 最长连续亏损笔数
+
+	// prompt实验：各变体表现（key为 "实验/变体"，交易按开仓周期的变体归属）
+	VariantStats map[string]*VariantPerformance `json:"variant_stats,omitempty"`
 }
 
 // SymbolPerformance 币种表现统计
@@ -358,11 +561,17 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 				case "open_long", "open_short":
 					// 记录开仓
 					openPositions[posKey] = map[string]interface{}{
-						"side":      side,
-						"openPrice": action.Price,
-						"openTime":  action.Timestamp,
-						"quantity":  action.Quantity,
-						"leverage":  action.Leverage,
+						"side":       side,
+						"openPrice":  action.Price,
+						"openTime":   action.Timestamp,
+						"quantity":   action.Quantity,
+						"leverage":   action.Leverage,
+						"stopLoss":   action.StopLoss,
+						"experiment": record.Experiment,
+						"variant":    record.PromptVariant,
+						"cycle":      record.CycleNumber,
+						"reasoning":  actionReasoning(record, action),
+						"market":     action.Market,
 					}
 				case "close_long", "close_short":
 					// 移除已平仓记录
@@ -392,11 +601,17 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 			case "open_long", "open_short":
 				// 更新开仓记录（可能已经在预填充时记录过了）
 				openPositions[posKey] = map[string]interface{}{
-					"side":      side,
-					"openPrice": action.Price,
-					"openTime":  action.Timestamp,
-					"quantity":  action.Quantity,
-					"leverage":  action.Leverage,
+					"side":       side,
+					"openPrice":  action.Price,
+					"openTime":   action.Timestamp,
+					"quantity":   action.Quantity,
+					"leverage":   action.Leverage,
+					"stopLoss":   action.StopLoss,
+					"experiment": record.Experiment,
+					"variant":    record.PromptVariant,
+					"cycle":      record.CycleNumber,
+					"reasoning":  actionReasoning(record, action),
+					"market":     action.Market,
 				}
 
 			case "close_long", "close_short":
@@ -407,6 +622,12 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					side := openPos["side"].(string)
 					quantity := openPos["quantity"].(float64)
 					leverage := openPos["leverage"].(int)
+					stopLoss := openPos["stopLoss"].(float64)
+					experiment := openPos["experiment"].(string)
+					variant := openPos["variant"].(string)
+					openCycle := openPos["cycle"].(int)
+					entryReasoning := openPos["reasoning"].(string)
+					entryMarket := openPos["market"].(*market.Snapshot)
 
 					// 计算实际盈亏（USDT）
 					// 合约交易 PnL 计算：quantity × 价格差
@@ -426,6 +647,15 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						pnlPct = (pnl / marginUsed) * 100
 					}
 
+					// R倍数 = 盈亏 / 初始风险（开仓价到止损价的距离 × 数量）
+					rMultiple := 0.0
+					if stopLoss > 0 {
+						initialRisk := quantity * math.Abs(openPrice-stopLoss)
+						if initialRisk > 0 {
+							rMultiple = pnl / initialRisk
+						}
+					}
+
 					// 记录交易结果
 					outcome := TradeOutcome{
 						Symbol:        symbol,
@@ -441,7 +671,20 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						Duration:      action.Timestamp.Sub(openTime).String(),
 						OpenTime:      openTime,
 						CloseTime:     action.Timestamp,
+						StopLoss:      stopLoss,
+						RMultiple:     rMultiple,
+						Experiment:    experiment,
+						PromptVariant: variant,
+
+						// 交易复盘（理由和开平仓时的市场快照）
+						OpenCycle:      openCycle,
+						CloseCycle:     record.CycleNumber,
+						EntryReasoning: entryReasoning,
+						ExitReasoning:  actionReasoning(record, action),
+						EntryMarket:    entryMarket,
+						ExitMarket:     action.Market,
 					}
+					outcome.ID = outcome.TradeID()
 
 					analysis.RecentTrades = append(analysis.RecentTrades, outcome)
 					analysis.TotalTrades++
@@ -522,6 +765,10 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		}
 	}
 
+	// 基于完整交易账本计算期望值、R倍数和连胜连亏（需在截断最近交易之前）
+	applyTradeMetrics(analysis, analysis.RecentTrades)
+	applyVariantMetrics(analysis, records, analysis.RecentTrades)
+
 	// 只保留最近的交易（倒序：最新的在前）
 	if len(analysis.RecentTrades) > 10 {
 		// 反转数组，让最新的在前
@@ -536,76 +783,22 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		}
 	}
 
-	// 计算夏普比率（需要至少2个数据点）
-	analysis.SharpeRatio = l.calculateSharpeRatio(records)
-
-	return analysis, nil
-}
-
-// calculateSharpeRatio 计算夏普比率
-// 基于账户净值的变化计算风险调整后收益
-func (l *DecisionLogger) calculateSharpeRatio(records []*DecisionRecord) float64 {
-	if len(records) < 2 {
-		return 0.0
-	}
-
-	// 提取每个周期的账户净值
-	// 注意：TotalBalance字段实际存储的是TotalEquity（账户总净值）
-	// TotalUnrealizedProfit字段实际存储的是TotalPnL（相对初始余额的盈亏）
-	var equities []float64
-	for _, record := range records {
-		// 直接使用TotalBalance，因为它已经是完整的账户净值
-		equity := record.AccountState.TotalBalance
-		if equity > 0 {
-			equities = append(equities, equity)
-		}
-	}
-
-	if len(equities) < 2 {
-		return 0.0
-	}
-
-	// 计算周期收益率（period returns）
-	var returns []float64
-	for i := 1; i < len(equities); i++ {
-		if equities[i-1] > 0 {
-			periodReturn := (equities[i] - equities[i-1]) / equities[i-1]
-			returns = append(returns, periodReturn)
-		}
+	// 已完成复盘的交易附带经验教训
+	if err := l.attachReflections(analysis.RecentTrades); err != nil {
+		fmt.Printf("⚠ %v\n", err)
 	}
 
-	if len(returns) == 0 {
-		return 0.0
+	// 计算风险调整后收益指标（夏普、年化收益、索提诺、卡玛、最大回撤、持仓时间占比）
+	// 净值序列只读取账户快照列，至少覆盖 PerformanceWindow，使年化指标不受回看周期数和扫描间隔影响
+	from := time.Now().Add(-PerformanceWindow)
+	if records[0].Timestamp.Before(from) {
+		from = records[0].Timestamp
 	}
-
-	// 计算平均收益率
-	sumReturns := 0.0
-	for _, r := range returns {
-		sumReturns += r
-	}
-	meanReturn := sumReturns / float64(len(returns))
-
-	// 计算收益率标准差
-	sumSquaredDiff := 0.0
-	for _, r := range returns {
-		diff := r - meanReturn
-		sumSquaredDiff += diff * diff
+	if points, err := l.store.AccountHistory(from, time.Time{}, 0); err != nil {
+		fmt.Printf("⚠ 读取净值序列失败: %v\n", err)
+	} else {
+		applyEquityMetrics(analysis, points)
 	}
-	variance := sumSquaredDiff / float64(len(returns))
-	stdDev := math.Sqrt(variance)
 
-	// 避免除以零
-	if stdDev == 0 {
-		if meanReturn > 0 {
-			return 999.0 // 无波动的正收益
-		} else if meanReturn < 0 {
-			return -999.0 // 无波动的负收益
-		}
-		return 0.0
-	}
-
-	// 计算夏普比率（假设无风险利率为0）
-	// 注：直接返回周期级别的夏普比率（非年化），正常范围 -2 到 +2
-	sharpeRatio := meanReturn / stdDev
-	return sharpeRatio
+	return analysis, nil
 }
@@ -0,0 +1,84 @@
+package logger
+
+import "time"
+
+// EquityBar 一个时间桶内的净值K线（OHLC）
+type EquityBar struct {
+	Timestamp        time.Time // 桶起始时间（未降采样时为周期时间）
+	CycleNumber      int       // 桶内最后一个周期编号
+	Open             float64   // 桶内第一个净值
+	High             float64   // 桶内最高净值
+	Low              float64   // 桶内最低净值
+	Close            float64   // 桶内最后一个净值
+	AvailableBalance float64   // 桶内最后一个可用余额
+	TotalPnL         float64   // 桶内最后一个总盈亏
+	PositionCount    int       // 桶内最后一个持仓数量
+	MarginUsedPct    float64   // 桶内最后一个保证金使用率
+	Samples          int       // 桶内周期数
+	DrawdownPct      float64   // 收盘净值相对历史最高净值的回撤百分比（<=0）
+}
+
+// DownsampleEquity 将账户快照按时间桶聚合为净值K线
+// bucket<=0 时不聚合，每个快照一根K线；净值<=0的快照（如获取账户失败的周期）会被跳过
+// points 需按时间正序排列
+func DownsampleEquity(points []AccountPoint, bucket time.Duration) []EquityBar {
+	bars := make([]EquityBar, 0, len(points))
+
+	for _, p := range points {
+		equity := p.Account.TotalBalance
+		if equity <= 0 {
+			continue
+		}
+
+		bucketStart := p.Timestamp
+		if bucket > 0 {
+			bucketStart = p.Timestamp.Truncate(bucket)
+		}
+
+		if bucket > 0 && len(bars) > 0 && bars[len(bars)-1].Timestamp.Equal(bucketStart) {
+			bar := &bars[len(bars)-1]
+			if equity > bar.High {
+				bar.High = equity
+			}
+			if equity < bar.Low {
+				bar.Low = equity
+			}
+			bar.Close = equity
+			bar.CycleNumber = p.CycleNumber
+			bar.AvailableBalance = p.Account.AvailableBalance
+			bar.TotalPnL = p.Account.TotalUnrealizedProfit
+			bar.PositionCount = p.Account.PositionCount
+			bar.MarginUsedPct = p.Account.MarginUsedPct
+			bar.Samples++
+			continue
+		}
+
+		bars = append(bars, EquityBar{
+			Timestamp:        bucketStart,
+			CycleNumber:      p.CycleNumber,
+			Open:             equity,
+			High:             equity,
+			Low:              equity,
+			Close:            equity,
+			AvailableBalance: p.Account.AvailableBalance,
+			// TotalUnrealizedProfit字段实际存储的是TotalPnL（相对初始余额）
+			TotalPnL:      p.Account.TotalUnrealizedProfit,
+			PositionCount: p.Account.PositionCount,
+			MarginUsedPct: p.Account.MarginUsedPct,
+			Samples:       1,
+		})
+	}
+
+	// 计算回撤序列（以桶内最高净值更新峰值）
+	peak := 0.0
+	for i := range bars {
+		if bars[i].High > peak {
+			peak = bars[i].High
+		}
+		if peak > 0 {
+			bars[i].DrawdownPct = (bars[i].Close - peak) / peak * 100
+		}
+	}
+
+	return bars
+}
@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDownsampleEquity(t *testing.T) {
+	base := time.Unix(1700000000, 0).Truncate(time.Hour)
+	at := func(offset time.Duration, cycle int, equity float64) AccountPoint {
+		return AccountPoint{
+			Timestamp:   base.Add(offset),
+			CycleNumber: cycle,
+			Account: AccountSnapshot{
+				TotalBalance:          equity,
+				AvailableBalance:      equity / 2,
+				TotalUnrealizedProfit: equity - 100,
+				PositionCount:         cycle % 2,
+				MarginUsedPct:         float64(cycle),
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		points []AccountPoint
+		bucket time.Duration
+		want   []EquityBar
+	}{
+		{
+			name:   "没有数据",
+			bucket: time.Hour,
+			want:   []EquityBar{},
+		},
+		{
+			name:   "单个点",
+			points: []AccountPoint{at(10*time.Minute, 1, 100)},
+			bucket: time.Hour,
+			want: []EquityBar{
+				{Timestamp: base, CycleNumber: 1, Open: 100, High: 100, Low: 100, Close: 100,
+					AvailableBalance: 50, TotalPnL: 0, PositionCount: 1, MarginUsedPct: 1, Samples: 1},
+			},
+		},
+		{
+			name: "不聚合时每个快照一根K线",
+			points: []AccountPoint{
+				at(time.Minute, 1, 100),
+				at(2*time.Minute, 2, 90),
+			},
+			bucket: 0,
+			want: []EquityBar{
+				{Timestamp: base.Add(time.Minute), CycleNumber: 1, Open: 100, High: 100, Low: 100, Close: 100,
+					AvailableBalance: 50, TotalPnL: 0, PositionCount: 1, MarginUsedPct: 1, Samples: 1},
+				{Timestamp: base.Add(2 * time.Minute), CycleNumber: 2, Open: 90, High: 90, Low: 90, Close: 90,
+					AvailableBalance: 45, TotalPnL: -10, PositionCount: 0, MarginUsedPct: 2, Samples: 1, DrawdownPct: -10},
+			},
+		},
+		{
+			// 桶内 100 → 120 → 80 → 110，其余字段取最后一个快照
+			name: "桶内聚合为OHLC",
+			points: []AccountPoint{
+				at(0, 1, 100),
+				at(15*time.Minute, 2, 120),
+				at(30*time.Minute, 3, 80),
+				at(45*time.Minute, 4, 110),
+			},
+			bucket: time.Hour,
+			want: []EquityBar{
+				{Timestamp: base, CycleNumber: 4, Open: 100, High: 120, Low: 80, Close: 110,
+					AvailableBalance: 55, TotalPnL: 10, PositionCount: 0, MarginUsedPct: 4, Samples: 4,
+					DrawdownPct: (110.0 - 120) / 120 * 100},
+			},
+		},
+		{
+			// 恰好落在整点的快照属于新桶
+			name: "桶边界上的点归入下一个桶",
+			points: []AccountPoint{
+				at(0, 1, 100),
+				at(time.Hour-time.Second, 2, 105),
+				at(time.Hour, 3, 95),
+			},
+			bucket: time.Hour,
+			want: []EquityBar{
+				{Timestamp: base, CycleNumber: 2, Open: 100, High: 105, Low: 100, Close: 105,
+					AvailableBalance: 52.5, TotalPnL: 5, PositionCount: 0, MarginUsedPct: 2, Samples: 2},
+				{Timestamp: base.Add(time.Hour), CycleNumber: 3, Open: 95, High: 95, Low: 95, Close: 95,
+					AvailableBalance: 47.5, TotalPnL: -5, PositionCount: 1, MarginUsedPct: 3, Samples: 1,
+					DrawdownPct: (95.0 - 105) / 105 * 100},
+			},
+		},
+		{
+			// 峰值取桶内最高价：第一个桶最高150，之后的收盘都按150计算回撤
+			name: "回撤以桶内最高净值为峰值",
+			points: []AccountPoint{
+				at(0, 1, 100),
+				at(10*time.Minute, 2, 150),
+				at(20*time.Minute, 3, 120),
+				at(time.Hour, 4, 135),
+				at(2*time.Hour, 5, 160),
+			},
+			bucket: time.Hour,
+			want: []EquityBar{
+				{Timestamp: base, CycleNumber: 3, Open: 100, High: 150, Low: 100, Close: 120,
+					AvailableBalance: 60, TotalPnL: 20, PositionCount: 1, MarginUsedPct: 3, Samples: 3, DrawdownPct: -20},
+				{Timestamp: base.Add(time.Hour), CycleNumber: 4, Open: 135, High: 135, Low: 135, Close: 135,
+					AvailableBalance: 67.5, TotalPnL: 35, PositionCount: 0, MarginUsedPct: 4, Samples: 1, DrawdownPct: -10},
+				{Timestamp: base.Add(2 * time.Hour), CycleNumber: 5, Open: 160, High: 160, Low: 160, Close: 160,
+					AvailableBalance: 80, TotalPnL: 60, PositionCount: 1, MarginUsedPct: 5, Samples: 1},
+			},
+		},
+		{
+			name: "跳过净值为0的快照",
+			points: []AccountPoint{
+				at(0, 1, 0),
+				at(time.Hour, 2, 100),
+				at(time.Hour+time.Minute, 3, -1),
+			},
+			bucket: time.Hour,
+			want: []EquityBar{
+				{Timestamp: base.Add(time.Hour), CycleNumber: 2, Open: 100, High: 100, Low: 100, Close: 100,
+					AvailableBalance: 50, TotalPnL: 0, PositionCount: 0, MarginUsedPct: 2, Samples: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DownsampleEquity(tt.points, tt.bucket)
+			if len(got) != len(tt.want) {
+				t.Fatalf("len = %d, want %d (%+v)", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if !g.Timestamp.Equal(w.Timestamp) || g.CycleNumber != w.CycleNumber ||
+					g.PositionCount != w.PositionCount || g.Samples != w.Samples {
+					t.Errorf("bar[%d] = %+v, want %+v", i, g, w)
+					continue
+				}
+				for _, f := range []struct {
+					name      string
+					got, want float64
+				}{
+					{"Open", g.Open, w.Open},
+					{"High", g.High, w.High},
+					{"Low", g.Low, w.Low},
+					{"Close", g.Close, w.Close},
+					{"AvailableBalance", g.AvailableBalance, w.AvailableBalance},
+					{"TotalPnL", g.TotalPnL, w.TotalPnL},
+					{"MarginUsedPct", g.MarginUsedPct, w.MarginUsedPct},
+					{"DrawdownPct", g.DrawdownPct, w.DrawdownPct},
+				} {
+					if !approxEqual(f.got, f.want) {
+						t.Errorf("bar[%d].%s = %v, want %v", i, f.name, f.got, f.want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package logger
+
+import "math"
+
+// z95 95%置信区间对应的正态分位数
+const z95 = 1.96
+
+// VariantPerformance prompt实验中单个变体的交易表现（置信区间均为95%）
+type VariantPerformance struct {
+	Experiment      string  `json:"experiment"`
+	Variant         string  `json:"variant"`
+	Cycles          int     `json:"cycles"`            // 使用该变体的决策周期数
+	FailedCycles    int     `json:"failed_cycles"`     // 其中失败的周期数（AI调用、解析失败等）
+	TotalTrades     int     `json:"total_trades"`      // 已平仓交易数（按开仓周期的变体归属）
+	WinningTrades   int     `json:"winning_trades"`    // 盈利交易数
+	LosingTrades    int     `json:"losing_trades"`     // 亏损交易数
+	WinRate         float64 `json:"win_rate"`          // 胜率（百分比）
+	WinRateLow      float64 `json:"win_rate_low"`      // 胜率置信区间下限（Wilson区间）
+	WinRateHigh     float64 `json:"win_rate_high"`     // 胜率置信区间上限
+	TotalPnL        float64 `json:"total_pn_l"`        // 总盈亏（USDT）
+	AvgPnL          float64 `json:"avg_pn_l"`          // 每笔交易平均盈亏（USDT）
+	AvgPnLLow       float64 `json:"avg_pn_l_low"`      // 平均盈亏置信区间下限（正态近似，少于2笔交易时等于平均值）
+	AvgPnLHigh      float64 `json:"avg_pn_l_high"`     // 平均盈亏置信区间上限
+	PnLStdDev       float64 `json:"pn_l_std_dev"`      // 每笔盈亏的样本标准差
+	AvgRMultiple    float64 `json:"avg_r_multiple"`    // 平均R倍数（仅统计有止损信息的交易）
+	RMultipleTrades int     `json:"r_multiple_trades"` // 参与R倍数统计的交易数
+
+	// 合并多个trader的统计时使用的累加值
+	sumSqPnL float64
+	sumR     float64
+}
+
+// variantKey VariantStats 的key
+func variantKey(experiment, variant string) string {
+	return experiment + "/" + variant
+}
+
+// applyVariantMetrics 按prompt变体统计周期数和交易表现（未参与实验的周期和交易不统计）
+func applyVariantMetrics(analysis *PerformanceAnalysis, records []*DecisionRecord, trades []TradeOutcome) {
+	stats := make(map[string]*VariantPerformance)
+	get := func(experiment, variant string) *VariantPerformance {
+		key := variantKey(experiment, variant)
+		if stats[key] == nil {
+			stats[key] = &VariantPerformance{Experiment: experiment, Variant: variant}
+		}
+		return stats[key]
+	}
+
+	for _, record := range records {
+		if record.PromptVariant == "" {
+			continue
+		}
+		vp := get(record.Experiment, record.PromptVariant)
+		vp.Cycles++
+		if !record.Success {
+			vp.FailedCycles++
+		}
+	}
+	for _, trade := range trades {
+		if trade.PromptVariant == "" {
+			continue
+		}
+		get(trade.Experiment, trade.PromptVariant).addTrade(trade)
+	}
+
+	if len(stats) == 0 {
+		return
+	}
+	for _, vp := range stats {
+		vp.finalize()
+	}
+	analysis.VariantStats = stats
+}
+
+// addTrade 累加一笔交易
+func (vp *VariantPerformance) addTrade(trade TradeOutcome) {
+	vp.TotalTrades++
+	if trade.PnL > 0 {
+		vp.WinningTrades++
+	} else if trade.PnL < 0 {
+		vp.LosingTrades++
+	}
+	vp.TotalPnL += trade.PnL
+	vp.sumSqPnL += trade.PnL * trade.PnL
+	if trade.HasRisk() {
+		vp.sumR += trade.RMultiple
+		vp.RMultipleTrades++
+	}
+}
+
+// Merge 合并同一变体在另一个trader上的统计，并重新计算比率和置信区间
+func (vp *VariantPerformance) Merge(other *VariantPerformance) {
+	vp.Cycles += other.Cycles
+	vp.FailedCycles += other.FailedCycles
+	vp.TotalTrades += other.TotalTrades
+	vp.WinningTrades += other.WinningTrades
+	vp.LosingTrades += other.LosingTrades
+	vp.TotalPnL += other.TotalPnL
+	vp.sumSqPnL += other.sumSqPnL
+	vp.sumR += other.sumR
+	vp.RMultipleTrades += other.RMultipleTrades
+	vp.finalize()
+}
+
+// finalize 由累加值计算胜率、平均盈亏、标准差和置信区间
+func (vp *VariantPerformance) finalize() {
+	vp.WinRate, vp.WinRateLow, vp.WinRateHigh = 0, 0, 0
+	vp.AvgPnL, vp.AvgPnLLow, vp.AvgPnLHigh, vp.PnLStdDev = 0, 0, 0, 0
+	vp.AvgRMultiple = 0
+	if vp.RMultipleTrades > 0 {
+		vp.AvgRMultiple = vp.sumR / float64(vp.RMultipleTrades)
+	}
+
+	n := float64(vp.TotalTrades)
+	if n == 0 {
+		return
+	}
+	vp.WinRate = float64(vp.WinningTrades) / n * 100
+	low, high := wilsonInterval(vp.WinningTrades, vp.TotalTrades)
+	vp.WinRateLow, vp.WinRateHigh = low*100, high*100
+
+	vp.AvgPnL = vp.TotalPnL / n
+	vp.AvgPnLLow, vp.AvgPnLHigh = vp.AvgPnL, vp.AvgPnL
+	if vp.TotalTrades >= 2 {
+		variance := (vp.sumSqPnL - n*vp.AvgPnL*vp.AvgPnL) / (n - 1)
+		if variance > 0 {
+			vp.PnLStdDev = math.Sqrt(variance)
+		}
+		margin := z95 * vp.PnLStdDev / math.Sqrt(n)
+		vp.AvgPnLLow, vp.AvgPnLHigh = vp.AvgPnL-margin, vp.AvgPnL+margin
+	}
+}
+
+// wilsonInterval 胜率的Wilson得分区间（样本较少或胜率接近0/100%时比正态近似可靠）
+func wilsonInterval(wins, n int) (float64, float64) {
+	if n == 0 {
+		return 0, 0
+	}
+	p := float64(wins) / float64(n)
+	nf := float64(n)
+	denom := 1 + z95*z95/nf
+	center := (p + z95*z95/(2*nf)) / denom
+	margin := z95 * math.Sqrt(p*(1-p)/nf+z95*z95/(4*nf*nf)) / denom
+	return math.Max(0, center-margin), math.Min(1, center+margin)
+}
@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"math"
+	"testing"
+)
+
+// approxEqual 浮点比较（容差1e-9）
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestWilsonInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		wins, n int
+		low     float64
+		high    float64
+	}{
+		{"没有交易", 0, 0, 0, 0},
+		{"一半胜率", 5, 10, 0.23658959361548731, 0.7634104063845126},
+		{"全部亏损时下限为0", 0, 10, 0, 0.2775401687666166},
+		{"全部盈利时上限为1", 10, 10, 0.7224598312333834, 1},
+		{"单笔盈利的区间很宽", 1, 1, 0.20654329147389294, 1},
+		{"偏高胜率", 8, 10, 0.49015684672072335, 0.9433190520193067},
+		{"大样本区间变窄", 30, 100, 0.21894753866228117, 0.39585038432811953},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			low, high := wilsonInterval(tt.wins, tt.n)
+			if !approxEqual(low, tt.low) || !approxEqual(high, tt.high) {
+				t.Errorf("wilsonInterval(%d, %d) = (%v, %v), want (%v, %v)", tt.wins, tt.n, low, high, tt.low, tt.high)
+			}
+		})
+	}
+}
+
+func TestVariantPerformanceFinalize(t *testing.T) {
+	// 盈亏 10/-5/20/0：平均6.25，样本标准差11.0868；R倍数 2/-1/4/0
+	mixed := []TradeOutcome{stopTrade(10), stopTrade(-5), stopTrade(20), stopTrade(0)}
+
+	tests := []struct {
+		name   string
+		trades [][]TradeOutcome // 每组交易来自一个trader，多组时依次 Merge
+		want   VariantPerformance
+	}{
+		{
+			name:   "没有交易时所有比率为0",
+			trades: [][]TradeOutcome{nil},
+			want:   VariantPerformance{},
+		},
+		{
+			name:   "单笔交易的平均盈亏区间等于平均值",
+			trades: [][]TradeOutcome{{stopTrade(7.5)}},
+			want: VariantPerformance{
+				TotalTrades: 1, WinningTrades: 1, TotalPnL: 7.5,
+				WinRate: 100, WinRateLow: 20.654329147389294, WinRateHigh: 100,
+				AvgPnL: 7.5, AvgPnLLow: 7.5, AvgPnLHigh: 7.5,
+				AvgRMultiple: 1.5, RMultipleTrades: 1,
+			},
+		},
+		{
+			name:   "盈亏相同时标准差为0，无止损的交易不计入R倍数",
+			trades: [][]TradeOutcome{{{PnL: 5}, {PnL: 5}}},
+			want: VariantPerformance{
+				TotalTrades: 2, WinningTrades: 2, TotalPnL: 10,
+				WinRate: 100, WinRateLow: 34.23719528896193, WinRateHigh: 100,
+				AvgPnL: 5, AvgPnLLow: 5, AvgPnLHigh: 5,
+			},
+		},
+		{
+			name:   "盈亏混合，保本交易不计入胜负，但有止损时计入R倍数",
+			trades: [][]TradeOutcome{mixed},
+			want: VariantPerformance{
+				TotalTrades: 4, WinningTrades: 2, LosingTrades: 1, TotalPnL: 25,
+				WinRate: 50, WinRateLow: 15.003570882017148, WinRateHigh: 84.99642911798285,
+				AvgPnL: 6.25, AvgPnLLow: -4.615043334780891, AvgPnLHigh: 17.115043334780893,
+				PnLStdDev:    11.086778913041726,
+				AvgRMultiple: 1.25, RMultipleTrades: 4,
+			},
+		},
+		{
+			name:   "合并多个trader与一次统计结果相同",
+			trades: [][]TradeOutcome{mixed[:1], mixed[1:3], nil, mixed[3:]},
+			want: VariantPerformance{
+				TotalTrades: 4, WinningTrades: 2, LosingTrades: 1, TotalPnL: 25,
+				WinRate: 50, WinRateLow: 15.003570882017148, WinRateHigh: 84.99642911798285,
+				AvgPnL: 6.25, AvgPnLLow: -4.615043334780891, AvgPnLHigh: 17.115043334780893,
+				PnLStdDev:    11.086778913041726,
+				AvgRMultiple: 1.25, RMultipleTrades: 4,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *VariantPerformance
+			for _, trades := range tt.trades {
+				vp := &VariantPerformance{
+					// 上次统计遗留的比率应被重新计算
+					WinRate: 99, AvgPnL: 99, PnLStdDev: 99, AvgRMultiple: 99,
+				}
+				for _, trade := range trades {
+					vp.addTrade(trade)
+				}
+				vp.finalize()
+				if got == nil {
+					got = vp
+				} else {
+					got.Merge(vp)
+				}
+			}
+
+			checks := []struct {
+				field     string
+				got, want float64
+			}{
+				{"TotalTrades", float64(got.TotalTrades), float64(tt.want.TotalTrades)},
+				{"WinningTrades", float64(got.WinningTrades), float64(tt.want.WinningTrades)},
+				{"LosingTrades", float64(got.LosingTrades), float64(tt.want.LosingTrades)},
+				{"TotalPnL", got.TotalPnL, tt.want.TotalPnL},
+				{"WinRate", got.WinRate, tt.want.WinRate},
+				{"WinRateLow", got.WinRateLow, tt.want.WinRateLow},
+				{"WinRateHigh", got.WinRateHigh, tt.want.WinRateHigh},
+				{"AvgPnL", got.AvgPnL, tt.want.AvgPnL},
+				{"AvgPnLLow", got.AvgPnLLow, tt.want.AvgPnLLow},
+				{"AvgPnLHigh", got.AvgPnLHigh, tt.want.AvgPnLHigh},
+				{"PnLStdDev", got.PnLStdDev, tt.want.PnLStdDev},
+				{"AvgRMultiple", got.AvgRMultiple, tt.want.AvgRMultiple},
+				{"RMultipleTrades", float64(got.RMultipleTrades), float64(tt.want.RMultipleTrades)},
+			}
+			for _, c := range checks {
+				if !approxEqual(c.got, c.want) {
+					t.Errorf("%s = %v, want %v", c.field, c.got, c.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 文件存储中决策记录文件的前缀，以及跨周期记忆、交易复盘的保存文件
+const (
+	recordFilePrefix = "decision_"
+	memoryFile       = "memory.json"
+	reflectionFile   = "reflections.json"
+)
+
+// FileStore 基于JSON文件的存储（每个周期一个文件）
+// 旧版本的存储格式，现在仅用于SQLite不可用时的降级以及历史数据迁移
+type FileStore struct {
+	logDir string
+	mu     sync.Mutex // 保护记忆和复盘文件的读-改-写
+}
+
+// NewFileStore 创建JSON文件存储
+func NewFileStore(logDir string) (*FileStore, error) {
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %w", err)
+	}
+	return &FileStore{logDir: logDir}, nil
+}
+
+// Save 保存决策记录为单个JSON文件
+func (s *FileStore) Save(record *DecisionRecord) error {
+	// 生成文件名：decision_YYYYMMDD_HHMMSS_cycleN.json
+	filename := fmt.Sprintf("%s%s_cycle%d.json", recordFilePrefix,
+		record.Timestamp.Format("20060102_150405"),
+		record.CycleNumber)
+
+	// 序列化为JSON（带缩进，方便阅读）
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化决策记录失败: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(s.logDir, filename), data, 0644); err != nil {
+		return fmt.Errorf("写入决策记录失败: %w", err)
+	}
+	return nil
+}
+
+// Latest 获取最近N条记录（按时间正序：从旧到新）
+func (s *FileStore) Latest(n int) ([]*DecisionRecord, error) {
+	records, err := s.Query(RecordQuery{Limit: n, Desc: true})
+	if err != nil {
+		return nil, err
+	}
+	reverseRecords(records)
+	return records, nil
+}
+
+// Query 按条件查询记录
+// 文件存储没有索引，需要读取整个目录，仅适合小规模数据
+func (s *FileStore) Query(q RecordQuery) ([]*DecisionRecord, error) {
+	records, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// 过滤时间范围和执行动作
+	filtered := records[:0]
+	for _, record := range records {
+		if q.matchRecord(record) {
+			filtered = append(filtered, record)
+		}
+	}
+
+	if q.Desc {
+		reverseRecords(filtered)
+	}
+
+	// 分页
+	if q.Offset > 0 {
+		if q.Offset >= len(filtered) {
+			return []*DecisionRecord{}, nil
+		}
+		filtered = filtered[q.Offset:]
+	}
+	if q.Limit > 0 && len(filtered) > q.Limit {
+		filtered = filtered[:q.Limit]
+	}
+
+	return filtered, nil
+}
+
+// AccountHistory 查询账户快照
+func (s *FileStore) AccountHistory(from, to time.Time, limit int) ([]AccountPoint, error) {
+	records, err := s.Query(RecordQuery{From: from, To: to, Limit: limit, Desc: true})
+	if err != nil {
+		return nil, err
+	}
+
+	// 取最新的limit条，再按时间正序返回
+	points := make([]AccountPoint, 0, len(records))
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		points = append(points, AccountPoint{
+			Timestamp:   record.Timestamp,
+			CycleNumber: record.CycleNumber,
+			Account:     record.AccountState,
+		})
+	}
+	return points, nil
+}
+
+// Statistics 获取统计信息
+func (s *FileStore) Statistics() (*Statistics, error) {
+	records, corrupt, err := s.readRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Statistics{CorruptRecords: corrupt}
+	for _, record := range records {
+		stats.TotalCycles++
+
+		for _, action := range record.Decisions {
+			if action.Success {
+				switch action.Action {
+				case "open_long", "open_short":
+					stats.TotalOpenPositions++
+				case "close_long", "close_short":
+					stats.TotalClosePositions++
+				}
+			}
+		}
+
+		if record.Success {
+			stats.SuccessfulCycles++
+		} else {
+			stats.FailedCycles++
+		}
+	}
+
+	return stats, nil
+}
+
+// MaxCycleNumber 获取最大的周期编号
+func (s *FileStore) MaxCycleNumber() (int, error) {
+	records, err := s.readAll()
+	if err != nil {
+		return 0, err
+	}
+
+	maxCycle := 0
+	for _, record := range records {
+		if record.CycleNumber > maxCycle {
+			maxCycle = record.CycleNumber
+		}
+	}
+	return maxCycle, nil
+}
+
+// DeleteBefore 删除修改时间早于cutoff的记录文件
+func (s *FileStore) DeleteBefore(cutoff time.Time) (int, error) {
+	entries, err := os.ReadDir(s.logDir)
+	if err != nil {
+		return 0, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	removedCount := 0
+	for _, entry := range entries {
+		if !isRecordFile(entry) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(s.logDir, entry.Name())); err != nil {
+			fmt.Printf("⚠ 删除旧记录失败 %s: %v\n", entry.Name(), err)
+			continue
+		}
+		removedCount++
+	}
+
+	return removedCount, nil
+}
+
+// Memory 读取记忆文件（从未保存时返回空记忆）
+func (s *FileStore) Memory() (*Memory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadMemory()
+}
+
+// SaveJournal 更新记忆文件中的交易日志（保留重置点）
+func (s *FileStore) SaveJournal(journal string, cycle int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	memory, err := s.loadMemory()
+	if err != nil {
+		return err
+	}
+	memory.Journal = journal
+	memory.JournalCycle = cycle
+	memory.UpdatedAt = at
+	return writeJSONFile(filepath.Join(s.logDir, memoryFile), memory)
+}
+
+// ResetMemory 清空交易日志并记录重置点
+func (s *FileStore) ResetMemory(cycle int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeJSONFile(filepath.Join(s.logDir, memoryFile), &Memory{ResetCycle: cycle, ResetAt: at})
+}
+
+// loadMemory 读取记忆文件（调用方需持有 mu）
+func (s *FileStore) loadMemory() (*Memory, error) {
+	data, err := os.ReadFile(filepath.Join(s.logDir, memoryFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &Memory{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取记忆文件失败: %w", err)
+	}
+
+	var memory Memory
+	if err := json.Unmarshal(data, &memory); err != nil {
+		return nil, fmt.Errorf("解析记忆文件失败: %w", err)
+	}
+	return &memory, nil
+}
+
+// SaveReflection 保存一笔交易的复盘（同一笔交易重复保存时覆盖），按平仓时间排序后只保留最新的 maxStoredReflections 条
+func (s *FileStore) SaveReflection(reflection TradeReflection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reflections, err := s.loadReflections()
+	if err != nil {
+		return err
+	}
+	kept := reflections[:0]
+	for _, r := range reflections {
+		if r.TradeID != reflection.TradeID {
+			kept = append(kept, r)
+		}
+	}
+	kept = append(kept, reflection)
+	sortReflections(kept)
+	if len(kept) > maxStoredReflections {
+		kept = kept[len(kept)-maxStoredReflections:]
+	}
+	return writeJSONFile(filepath.Join(s.logDir, reflectionFile), kept)
+}
+
+// Reflections 获取平仓时间最新的N条复盘（按平仓时间正序）
+func (s *FileStore) Reflections(n int) ([]TradeReflection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reflections, err := s.loadReflections()
+	if err != nil {
+		return nil, err
+	}
+	// 旧版文件按保存顺序存放，读取时重新排序
+	sortReflections(reflections)
+	if n > 0 && len(reflections) > n {
+		reflections = reflections[len(reflections)-n:]
+	}
+	return reflections, nil
+}
+
+// loadReflections 读取复盘文件（调用方需持有 mu）
+func (s *FileStore) loadReflections() ([]TradeReflection, error) {
+	data, err := os.ReadFile(filepath.Join(s.logDir, reflectionFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取复盘文件失败: %w", err)
+	}
+
+	var reflections []TradeReflection
+	if err := json.Unmarshal(data, &reflections); err != nil {
+		return nil, fmt.Errorf("解析复盘文件失败: %w", err)
+	}
+	return reflections, nil
+}
+
+// Close 文件存储无需关闭
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// readAll 读取目录下所有决策记录（按时间正序）
+func (s *FileStore) readAll() ([]*DecisionRecord, error) {
+	records, _, err := s.readRecords()
+	return records, err
+}
+
+// readRecords 读取目录下所有决策记录（按时间正序），同时返回跳过的损坏文件数
+func (s *FileStore) readRecords() ([]*DecisionRecord, int, error) {
+	entries, err := os.ReadDir(s.logDir)
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	var records []*DecisionRecord
+	corrupt := 0
+	for _, entry := range entries {
+		if !isRecordFile(entry) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.logDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		var record DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			corrupt++
+			continue
+		}
+		// 文件中没有存储ID，用周期编号代替（时间戳相同时游标分页靠ID区分先后）
+		if record.ID == 0 {
+			record.ID = int64(record.CycleNumber)
+		}
+		records = append(records, &record)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Timestamp.Equal(records[j].Timestamp) {
+			return records[i].ID < records[j].ID
+		}
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+
+	return records, corrupt, nil
+}
+
+// isRecordFile 是否为决策记录文件（记忆等其他JSON文件不参与查询和清理）
+func isRecordFile(entry os.DirEntry) bool {
+	name := entry.Name()
+	return !entry.IsDir() && strings.HasPrefix(name, recordFilePrefix) && strings.HasSuffix(name, ".json")
+}
+
+// writeJSONFile 序列化并写入JSON文件（先写临时文件再重命名，避免写到一半时损坏原文件）
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 %s 失败: %w", filepath.Base(path), err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入 %s 失败: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+)
+
+// Memory 跨周期记忆：模型维护的交易日志和记忆重置点
+type Memory struct {
+	Journal      string    `json:"journal"`       // 模型维护的交易日志
+	JournalCycle int       `json:"journal_cycle"` // 最后一次更新日志的周期编号
+	UpdatedAt    time.Time `json:"updated_at"`    // 最后一次更新日志的时间
+	ResetCycle   int       `json:"reset_cycle"`   // 重置时的周期编号（此前的周期不再作为记忆）
+	ResetAt      time.Time `json:"reset_at"`      // 最后一次重置的时间
+}
+
+// GetMemory 读取跨周期记忆（从未保存时返回空记忆）
+func (l *DecisionLogger) GetMemory() (*Memory, error) {
+	memory, err := l.store.Memory()
+	if err != nil {
+		return nil, fmt.Errorf("读取跨周期记忆失败: %w", err)
+	}
+	return memory, nil
+}
+
+// SaveJournal 保存模型更新后的交易日志（在本周期的决策记录保存前调用，日志归属于即将保存的周期）
+func (l *DecisionLogger) SaveJournal(journal string) error {
+	l.mu.Lock()
+	cycle := l.cycleNumber + 1
+	l.mu.Unlock()
+
+	if err := l.store.SaveJournal(journal, cycle, time.Now()); err != nil {
+		return fmt.Errorf("保存交易日志失败: %w", err)
+	}
+	return nil
+}
+
+// ResetMemory 清空交易日志，并让此前的周期不再出现在记忆中
+func (l *DecisionLogger) ResetMemory() error {
+	l.mu.Lock()
+	cycle := l.cycleNumber
+	l.mu.Unlock()
+
+	if err := l.store.ResetMemory(cycle, time.Now()); err != nil {
+		return fmt.Errorf("重置跨周期记忆失败: %w", err)
+	}
+	return nil
+}
+
+// GetMemoryRecords 获取重置点之后的最近N条记录（按时间正序：从旧到新）
+func (l *DecisionLogger) GetMemoryRecords(n int) ([]*DecisionRecord, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	memory, err := l.GetMemory()
+	if err != nil {
+		return nil, err
+	}
+	records, err := l.GetLatestRecords(n)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := records[:0]
+	for _, record := range records {
+		if record.CycleNumber > memory.ResetCycle {
+			kept = append(kept, record)
+		}
+	}
+	return kept, nil
+}
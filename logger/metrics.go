@@ -0,0 +1,192 @@
+package logger
+
+import (
+	"math"
+	"time"
+)
+
+// YearDuration 一年的时长，用于按实际周期间隔年化
+const YearDuration = 365 * 24 * time.Hour
+
+// minAnnualizationSpan 计算年化指标所需的最短净值跨度
+// 几个小时的窗口按周期间隔年化会把噪声放大成没有意义的数字，窗口不足时年化指标留空
+const minAnnualizationSpan = 24 * time.Hour
+
+// PerformanceWindow 净值指标至少回看的时间跨度（只读取账户快照列，不加载完整记录）
+// 需大于 minAnnualizationSpan（留出漏掉的周期的余量），否则默认窗口永远拿不到年化指标
+const PerformanceWindow = 2 * minAnnualizationSpan
+
+// DefaultLookbackCycles 默认交易分析回看的周期数（避免长期持仓的交易记录丢失）
+const DefaultLookbackCycles = 100
+
+// PeriodsPerYear 按采样间隔推算每年的周期数
+func PeriodsPerYear(interval time.Duration) float64 {
+	if interval <= 0 {
+		return 0
+	}
+	return float64(YearDuration) / float64(interval)
+}
+
+// AnnualizeSharpe 把周期级别的夏普比率（平均收益 / 标准差）按采样间隔年化（无风险利率为0）
+func AnnualizeSharpe(mean, stdDev float64, interval time.Duration) float64 {
+	if stdDev <= 0 {
+		return 0
+	}
+	return mean / stdDev * math.Sqrt(PeriodsPerYear(interval))
+}
+
+// applyEquityMetrics 基于净值序列计算夏普、年化收益、波动率、索提诺、卡玛、最大回撤及持仓时间占比
+// points 需按时间正序排列；净值<=0的周期（如获取账户失败）会被跳过
+// 净值跨度不足 minAnnualizationSpan 时只计算周期级别的夏普、最大回撤和持仓时间占比
+func applyEquityMetrics(analysis *PerformanceAnalysis, points []AccountPoint) {
+	type sample struct {
+		t        time.Time
+		equity   float64
+		hasPosit bool
+	}
+
+	var samples []sample
+	for _, p := range points {
+		if p.Account.TotalBalance > 0 {
+			samples = append(samples, sample{
+				t:        p.Timestamp,
+				equity:   p.Account.TotalBalance,
+				hasPosit: p.Account.PositionCount > 0,
+			})
+		}
+	}
+	if len(samples) < 2 {
+		return
+	}
+
+	span := samples[len(samples)-1].t.Sub(samples[0].t)
+	if span <= 0 {
+		return
+	}
+
+	// 周期收益率
+	returns := make([]float64, 0, len(samples)-1)
+	exposed := time.Duration(0)
+	for i := 1; i < len(samples); i++ {
+		returns = append(returns, (samples[i].equity-samples[i-1].equity)/samples[i-1].equity)
+		// 区间起点有持仓，则整个区间计为持仓时间
+		if samples[i-1].hasPosit {
+			exposed += samples[i].t.Sub(samples[i-1].t)
+		}
+	}
+	analysis.ExposurePct = float64(exposed) / float64(span) * 100
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	downside := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	stdDev := math.Sqrt(variance / float64(len(returns)))
+	downsideDev := math.Sqrt(downside / float64(len(returns)))
+
+	// 周期级别（未年化）的夏普比率，无波动时为0
+	if stdDev > 0 {
+		analysis.SharpeRatio = mean / stdDev
+	}
+
+	// 按平均周期间隔年化
+	annualizedReturn := 0.0
+	analysis.Annualized = span >= minAnnualizationSpan
+	if analysis.Annualized {
+		interval := span / time.Duration(len(returns))
+		periodsPerYear := PeriodsPerYear(interval)
+		annualizedReturn = mean * periodsPerYear
+		analysis.AnnualizedReturnPct = annualizedReturn * 100
+		analysis.AnnualizedVolatility = stdDev * math.Sqrt(periodsPerYear) * 100
+		analysis.AnnualizedSharpe = AnnualizeSharpe(mean, stdDev, interval)
+		analysis.SortinoRatio = AnnualizeSharpe(mean, downsideDev, interval)
+	}
+
+	// 最大回撤及最长回撤持续时间
+	peak := samples[0].equity
+	peakTime := samples[0].t
+	maxDD := 0.0
+	longestDD := time.Duration(0)
+	inDrawdown := false
+	for _, s := range samples[1:] {
+		if s.equity >= peak {
+			// 回撤恢复：持续时间从峰值算到恢复时刻
+			if inDrawdown {
+				if d := s.t.Sub(peakTime); d > longestDD {
+					longestDD = d
+				}
+				inDrawdown = false
+			}
+			peak = s.equity
+			peakTime = s.t
+			continue
+		}
+
+		inDrawdown = true
+		if dd := (peak - s.equity) / peak; dd > maxDD {
+			maxDD = dd
+		}
+		// 尚未恢复的回撤计算到当前周期
+		if d := s.t.Sub(peakTime); d > longestDD {
+			longestDD = d
+		}
+	}
+	analysis.MaxDrawdownPct = maxDD * 100
+	analysis.MaxDrawdownMinutes = longestDD.Minutes()
+	analysis.MaxDrawdownDuration = longestDD.Round(time.Second).String()
+	if analysis.Annualized && maxDD > 0 {
+		analysis.CalmarRatio = annualizedReturn / maxDD
+	}
+}
+
+// applyTradeMetrics 基于交易账本（按平仓时间正序）计算期望值、平均R倍数和最长连胜/连亏
+func applyTradeMetrics(analysis *PerformanceAnalysis, trades []TradeOutcome) {
+	if len(trades) == 0 {
+		return
+	}
+
+	totalPnL := 0.0
+	totalR := 0.0
+	winStreak, lossStreak := 0, 0
+	for _, trade := range trades {
+		totalPnL += trade.PnL
+
+		if trade.HasRisk() {
+			totalR += trade.RMultiple
+			analysis.RMultipleTrades++
+		}
+
+		// 持平交易会中断连胜和连亏
+		switch {
+		case trade.PnL > 0:
+			winStreak++
+			lossStreak = 0
+		case trade.PnL < 0:
+			lossStreak++
+			winStreak = 0
+		default:
+			winStreak, lossStreak = 0, 0
+		}
+		if winStreak > analysis.LongestWinStreak {
+			analysis.LongestWinStreak = winStreak
+		}
+		if lossStreak > analysis.LongestLossStreak {
+			analysis.LongestLossStreak = lossStreak
+		}
+	}
+
+	// 期望值 = 胜率 × 平均盈利 + 败率 × 平均亏损 = 总盈亏 / 交易数
+	analysis.Expectancy = totalPnL / float64(len(trades))
+	if analysis.RMultipleTrades > 0 {
+		analysis.AvgRMultiple = totalR / float64(analysis.RMultipleTrades)
+	}
+}
@@ -0,0 +1,229 @@
+package logger
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAnnualizeSharpe(t *testing.T) {
+	tests := []struct {
+		name     string
+		mean     float64
+		stdDev   float64
+		interval time.Duration
+		want     float64
+	}{
+		{"无波动", 0.001, 0, time.Hour, 0},
+		{"负标准差按无波动处理", 0.001, -0.01, time.Hour, 0},
+		{"小时周期", 0.001, 0.01, time.Hour, 0.1 * math.Sqrt(8760)},
+		{"日周期", 0.001, 0.01, 24 * time.Hour, 0.1 * math.Sqrt(365)},
+		{"亏损为负", -0.002, 0.01, 24 * time.Hour, -0.2 * math.Sqrt(365)},
+		{"间隔无效", 0.001, 0.01, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AnnualizeSharpe(tt.mean, tt.stdDev, tt.interval); !approxEqual(got, tt.want) {
+				t.Errorf("AnnualizeSharpe(%v, %v, %v) = %v, want %v", tt.mean, tt.stdDev, tt.interval, got, tt.want)
+			}
+		})
+	}
+}
+
+// equityPoint 构造账户快照（offset为相对起始时间的偏移）
+func equityPoint(offset time.Duration, equity float64, positions int) AccountPoint {
+	return AccountPoint{
+		Timestamp: time.Unix(1700000000, 0).Add(offset),
+		Account:   AccountSnapshot{TotalBalance: equity, PositionCount: positions},
+	}
+}
+
+func TestApplyEquityMetrics(t *testing.T) {
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name   string
+		points []AccountPoint
+		want   PerformanceAnalysis
+	}{
+		{
+			name: "没有数据",
+			want: PerformanceAnalysis{},
+		},
+		{
+			name:   "单个点",
+			points: []AccountPoint{equityPoint(0, 1000, 1)},
+			want:   PerformanceAnalysis{},
+		},
+		{
+			name: "净值不变",
+			points: []AccountPoint{
+				equityPoint(0, 1000, 0),
+				equityPoint(day, 1000, 0),
+				equityPoint(2*day, 1000, 0),
+			},
+			want: PerformanceAnalysis{Annualized: true, MaxDrawdownDuration: "0s"},
+		},
+		{
+			// 收益率 +20%、-10%：均值0.05，标准差0.15，下行偏差 sqrt(0.01/2)，每年365个周期
+			// 峰值120回撤到108（10%），回撤从第1天持续到最后一个点（1天）
+			name: "按日年化",
+			points: []AccountPoint{
+				equityPoint(0, 100, 1),
+				equityPoint(day, 120, 0),
+				equityPoint(2*day, 108, 1),
+			},
+			want: PerformanceAnalysis{
+				SharpeRatio:          0.05 / 0.15,
+				Annualized:           true,
+				AnnualizedReturnPct:  0.05 * 365 * 100,
+				AnnualizedVolatility: 0.15 * math.Sqrt(365) * 100,
+				AnnualizedSharpe:     0.05 / 0.15 * math.Sqrt(365),
+				SortinoRatio:         0.05 / math.Sqrt(0.005) * math.Sqrt(365),
+				CalmarRatio:          0.05 * 365 / 0.1,
+				MaxDrawdownPct:       10,
+				MaxDrawdownDuration:  "24h0m0s",
+				MaxDrawdownMinutes:   24 * 60,
+				ExposurePct:          50,
+			},
+		},
+		{
+			// 收益率 -10%、+11.11%、-5%；第一段回撤从0h持续到3h恢复，第二段从3h到4h未恢复
+			name: "不足一天不年化，回撤持续时间取最长的一段",
+			points: []AccountPoint{
+				equityPoint(0, 100, 1),
+				equityPoint(time.Hour, 90, 1),
+				equityPoint(3*time.Hour, 100, 0),
+				equityPoint(4*time.Hour, 95, 0),
+			},
+			want: PerformanceAnalysis{
+				SharpeRatio:         periodSharpe(-0.1, 10.0/90, -0.05),
+				MaxDrawdownPct:      10,
+				MaxDrawdownDuration: "3h0m0s",
+				MaxDrawdownMinutes:  180,
+				ExposurePct:         75,
+			},
+		},
+		{
+			name: "跳过净值为0的周期",
+			points: []AccountPoint{
+				equityPoint(0, 100, 0),
+				equityPoint(time.Hour, 0, 0),
+				equityPoint(2*time.Hour, 100, 0),
+			},
+			want: PerformanceAnalysis{MaxDrawdownDuration: "0s"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got PerformanceAnalysis
+			applyEquityMetrics(&got, tt.points)
+
+			if got.Annualized != tt.want.Annualized {
+				t.Errorf("Annualized = %v, want %v", got.Annualized, tt.want.Annualized)
+			}
+			if got.MaxDrawdownDuration != tt.want.MaxDrawdownDuration {
+				t.Errorf("MaxDrawdownDuration = %q, want %q", got.MaxDrawdownDuration, tt.want.MaxDrawdownDuration)
+			}
+			for _, f := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"SharpeRatio", got.SharpeRatio, tt.want.SharpeRatio},
+				{"AnnualizedReturnPct", got.AnnualizedReturnPct, tt.want.AnnualizedReturnPct},
+				{"AnnualizedVolatility", got.AnnualizedVolatility, tt.want.AnnualizedVolatility},
+				{"AnnualizedSharpe", got.AnnualizedSharpe, tt.want.AnnualizedSharpe},
+				{"SortinoRatio", got.SortinoRatio, tt.want.SortinoRatio},
+				{"CalmarRatio", got.CalmarRatio, tt.want.CalmarRatio},
+				{"MaxDrawdownPct", got.MaxDrawdownPct, tt.want.MaxDrawdownPct},
+				{"MaxDrawdownMinutes", got.MaxDrawdownMinutes, tt.want.MaxDrawdownMinutes},
+				{"ExposurePct", got.ExposurePct, tt.want.ExposurePct},
+			} {
+				if !approxEqual(f.got, f.want) {
+					t.Errorf("%s = %v, want %v", f.name, f.got, f.want)
+				}
+			}
+		})
+	}
+}
+
+// periodSharpe 周期级别夏普比率（总体标准差）
+func periodSharpe(returns ...float64) float64 {
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	return mean / math.Sqrt(variance/float64(len(returns)))
+}
+
+// stopTrade 开仓时设置了止损的交易（开仓价100，止损95，数量1）
+func stopTrade(pnl float64) TradeOutcome {
+	return TradeOutcome{PnL: pnl, Quantity: 1, OpenPrice: 100, StopLoss: 95, RMultiple: pnl / 5}
+}
+
+func TestApplyTradeMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		trades []TradeOutcome
+		want   PerformanceAnalysis
+	}{
+		{
+			name: "没有交易",
+			want: PerformanceAnalysis{},
+		},
+		{
+			name:   "单笔交易",
+			trades: []TradeOutcome{stopTrade(10)},
+			want:   PerformanceAnalysis{Expectancy: 10, AvgRMultiple: 2, RMultipleTrades: 1, LongestWinStreak: 1},
+		},
+		{
+			name:   "无止损的交易不计入R倍数",
+			trades: []TradeOutcome{stopTrade(10), {PnL: -4}},
+			want:   PerformanceAnalysis{Expectancy: 3, AvgRMultiple: 2, RMultipleTrades: 1, LongestWinStreak: 1, LongestLossStreak: 1},
+		},
+		{
+			name:   "有止损的持平交易计入R倍数",
+			trades: []TradeOutcome{stopTrade(10), stopTrade(0)},
+			want:   PerformanceAnalysis{Expectancy: 5, AvgRMultiple: 1, RMultipleTrades: 2, LongestWinStreak: 1},
+		},
+		{
+			// 盈亏 +10 +5 -3 -2 -1 0 +4：总和13
+			name: "连胜连亏，持平交易中断连续",
+			trades: []TradeOutcome{
+				stopTrade(10), stopTrade(5), stopTrade(-3), stopTrade(-2), stopTrade(-1), stopTrade(0), stopTrade(4),
+			},
+			want: PerformanceAnalysis{
+				Expectancy:        13.0 / 7,
+				AvgRMultiple:      13.0 / 5 / 7,
+				RMultipleTrades:   7,
+				LongestWinStreak:  2,
+				LongestLossStreak: 3,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got PerformanceAnalysis
+			applyTradeMetrics(&got, tt.trades)
+
+			if !approxEqual(got.Expectancy, tt.want.Expectancy) || !approxEqual(got.AvgRMultiple, tt.want.AvgRMultiple) {
+				t.Errorf("Expectancy, AvgRMultiple = %v, %v, want %v, %v",
+					got.Expectancy, got.AvgRMultiple, tt.want.Expectancy, tt.want.AvgRMultiple)
+			}
+			if got.RMultipleTrades != tt.want.RMultipleTrades ||
+				got.LongestWinStreak != tt.want.LongestWinStreak || got.LongestLossStreak != tt.want.LongestLossStreak {
+				t.Errorf("RMultipleTrades, LongestWinStreak, LongestLossStreak = %d, %d, %d, want %d, %d, %d",
+					got.RMultipleTrades, got.LongestWinStreak, got.LongestLossStreak,
+					tt.want.RMultipleTrades, tt.want.LongestWinStreak, tt.want.LongestLossStreak)
+			}
+		})
+	}
+}
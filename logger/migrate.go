@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// migratedMarkerFile 迁移完成标记文件（存在时不再重复迁移）
+const migratedMarkerFile = ".migrated_to_sqlite"
+
+// MigrateJSONLogs 将旧版 decision_logs/<trader_id>/*.json 文件一次性导入到SQLite中
+// 全部记录在同一事务中导入，提交后才写入标记文件；原JSON文件保留不删除（便于回滚）
+// 中途失败不会留下部分数据，标记文件写入失败时下次启动重试也会跳过已导入的记录
+// 返回导入的记录条数；目录不存在或已迁移过时返回0
+func MigrateJSONLogs(jsonDir string, store *SQLiteStore) (int, error) {
+	if _, err := os.Stat(filepath.Join(jsonDir, migratedMarkerFile)); err == nil {
+		return 0, nil
+	}
+	if _, err := os.Stat(jsonDir); os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	legacy := &FileStore{logDir: jsonDir}
+	records, err := legacy.readAll()
+	if err != nil {
+		return 0, fmt.Errorf("读取旧版决策日志失败: %w", err)
+	}
+
+	// 保留原始的周期编号和时间戳
+	imported, err := store.Import(records)
+	if err != nil {
+		return 0, err
+	}
+
+	marker := fmt.Sprintf("migrated %d records at %s\n", imported, time.Now().Format(time.RFC3339))
+	if err := os.WriteFile(filepath.Join(jsonDir, migratedMarkerFile), []byte(marker), 0644); err != nil {
+		return imported, fmt.Errorf("写入迁移标记失败: %w", err)
+	}
+
+	return imported, nil
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// maxStoredReflections 最多保留的复盘条数（超出时丢弃最早的）
+const maxStoredReflections = 200
+
+// TradeReflection 一笔已平仓交易的复盘
+type TradeReflection struct {
+	TradeID   string    `json:"trade_id"` // 对应 TradeOutcome.ID
+	Symbol    string    `json:"symbol"`
+	Side      string    `json:"side"`
+	OpenTime  time.Time `json:"open_time"`
+	CloseTime time.Time `json:"close_time"`
+	PnL       float64   `json:"pn_l"`
+	PnLPct    float64   `json:"pn_l_pct"`
+	Lesson    string    `json:"lesson"` // 模型总结的经验教训
+	Model     string    `json:"model"`  // 生成复盘的模型
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SaveReflection 保存一笔交易的复盘（同一笔交易重复保存时覆盖）
+func (l *DecisionLogger) SaveReflection(reflection TradeReflection) error {
+	if err := l.store.SaveReflection(reflection); err != nil {
+		return fmt.Errorf("保存交易复盘失败: %w", err)
+	}
+	return nil
+}
+
+// GetReflections 获取平仓时间最新的N条复盘（按平仓时间正序：从旧到新），n<=0 时返回全部
+func (l *DecisionLogger) GetReflections(n int) ([]TradeReflection, error) {
+	reflections, err := l.store.Reflections(n)
+	if err != nil {
+		return nil, fmt.Errorf("读取交易复盘失败: %w", err)
+	}
+	return reflections, nil
+}
+
+// attachReflections 为交易填充已保存的复盘
+func (l *DecisionLogger) attachReflections(trades []TradeOutcome) error {
+	if len(trades) == 0 {
+		return nil
+	}
+	reflections, err := l.GetReflections(0)
+	if err != nil {
+		return err
+	}
+	lessons := make(map[string]string, len(reflections))
+	for _, r := range reflections {
+		lessons[r.TradeID] = r.Lesson
+	}
+	for i := range trades {
+		trades[i].Reflection = lessons[trades[i].ID]
+	}
+	return nil
+}
+
+// sortReflections 按平仓时间正序排列复盘（平仓时间相同时按交易ID）
+func sortReflections(reflections []TradeReflection) {
+	sort.SliceStable(reflections, func(i, j int) bool {
+		if reflections[i].CloseTime.Equal(reflections[j].CloseTime) {
+			return reflections[i].TradeID < reflections[j].TradeID
+		}
+		return reflections[i].CloseTime.Before(reflections[j].CloseTime)
+	})
+}
+
+// actionReasoning 执行动作对应的AI决策理由
+// 早期记录的动作中没有保存理由，从本周期的决策JSON中按币种和操作查找
+func actionReasoning(record *DecisionRecord, action DecisionAction) string {
+	if action.Reasoning != "" || record.DecisionJSON == "" {
+		return action.Reasoning
+	}
+
+	var decisions []struct {
+		Symbol    string `json:"symbol"`
+		Action    string `json:"action"`
+		Reasoning string `json:"reasoning"`
+	}
+	if err := json.Unmarshal([]byte(record.DecisionJSON), &decisions); err != nil {
+		return ""
+	}
+	for _, d := range decisions {
+		if d.Symbol == action.Symbol && d.Action == action.Action {
+			return d.Reasoning
+		}
+	}
+	return ""
+}
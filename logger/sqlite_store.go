@@ -0,0 +1,734 @@
+package logger
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // 纯Go实现的SQLite驱动（无需CGO）
+)
+
+// schemaMigrations 数据库结构迁移（按顺序执行，PRAGMA user_version 记录已执行的版本）
+// 新增字段时只能追加，不能修改已有条目
+var schemaMigrations = []string{
+	// v1: 决策记录、执行动作、持仓快照
+	`CREATE TABLE IF NOT EXISTS decision_records (
+		id                      INTEGER PRIMARY KEY AUTOINCREMENT,
+		cycle_number            INTEGER NOT NULL,
+		timestamp               INTEGER NOT NULL,
+		success                 INTEGER NOT NULL,
+		error_message           TEXT    NOT NULL DEFAULT '',
+		total_balance           REAL    NOT NULL DEFAULT 0,
+		available_balance       REAL    NOT NULL DEFAULT 0,
+		total_unrealized_profit REAL    NOT NULL DEFAULT 0,
+		position_count          INTEGER NOT NULL DEFAULT 0,
+		margin_used_pct         REAL    NOT NULL DEFAULT 0,
+		payload                 TEXT    NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_records_timestamp ON decision_records(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_records_cycle ON decision_records(cycle_number);
+
+	CREATE TABLE IF NOT EXISTS decision_actions (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		record_id INTEGER NOT NULL REFERENCES decision_records(id) ON DELETE CASCADE,
+		seq       INTEGER NOT NULL,
+		action    TEXT    NOT NULL,
+		symbol    TEXT    NOT NULL,
+		quantity  REAL    NOT NULL DEFAULT 0,
+		leverage  INTEGER NOT NULL DEFAULT 0,
+		price     REAL    NOT NULL DEFAULT 0,
+		order_id  INTEGER NOT NULL DEFAULT 0,
+		timestamp INTEGER NOT NULL,
+		success   INTEGER NOT NULL,
+		error     TEXT    NOT NULL DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS idx_actions_record ON decision_actions(record_id);
+	CREATE INDEX IF NOT EXISTS idx_actions_symbol_action ON decision_actions(symbol, action, timestamp);
+
+	CREATE TABLE IF NOT EXISTS decision_positions (
+		id                INTEGER PRIMARY KEY AUTOINCREMENT,
+		record_id         INTEGER NOT NULL REFERENCES decision_records(id) ON DELETE CASCADE,
+		seq               INTEGER NOT NULL,
+		symbol            TEXT    NOT NULL,
+		side              TEXT    NOT NULL,
+		position_amt      REAL    NOT NULL DEFAULT 0,
+		entry_price       REAL    NOT NULL DEFAULT 0,
+		mark_price        REAL    NOT NULL DEFAULT 0,
+		unrealized_profit REAL    NOT NULL DEFAULT 0,
+		leverage          REAL    NOT NULL DEFAULT 0,
+		liquidation_price REAL    NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS idx_positions_record ON decision_positions(record_id);`,
+
+	// v2: 开仓动作记录止损止盈价（用于计算R倍数）
+	`ALTER TABLE decision_actions ADD COLUMN stop_loss REAL NOT NULL DEFAULT 0;
+	ALTER TABLE decision_actions ADD COLUMN take_profit REAL NOT NULL DEFAULT 0;`,
+
+	// v3: 跨周期记忆（交易日志和重置点，每个数据库只有一行）
+	`CREATE TABLE IF NOT EXISTS trader_memory (
+		id            INTEGER PRIMARY KEY CHECK (id = 1),
+		journal       TEXT    NOT NULL DEFAULT '',
+		journal_cycle INTEGER NOT NULL DEFAULT 0,
+		updated_at    INTEGER NOT NULL DEFAULT 0,
+		reset_cycle   INTEGER NOT NULL DEFAULT 0,
+		reset_at      INTEGER NOT NULL DEFAULT 0
+	);`,
+
+	// v4: 执行动作记录决策理由和市场快照（用于交易复盘）
+	`ALTER TABLE decision_actions ADD COLUMN reasoning TEXT NOT NULL DEFAULT '';
+	ALTER TABLE decision_actions ADD COLUMN market TEXT NOT NULL DEFAULT '';`,
+
+	// v5: 已平仓交易的复盘
+	`CREATE TABLE IF NOT EXISTS trade_reflections (
+		trade_id   TEXT    PRIMARY KEY,
+		symbol     TEXT    NOT NULL,
+		side       TEXT    NOT NULL,
+		open_time  INTEGER NOT NULL DEFAULT 0,
+		close_time INTEGER NOT NULL DEFAULT 0,
+		pnl        REAL    NOT NULL DEFAULT 0,
+		pnl_pct    REAL    NOT NULL DEFAULT 0,
+		lesson     TEXT    NOT NULL DEFAULT '',
+		model      TEXT    NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS idx_reflections_close_time ON trade_reflections(close_time);`,
+}
+
+// sqliteBatchSize 批量加载子表时每批的记录数（避免超出SQLite参数上限）
+const sqliteBatchSize = 500
+
+// SQLiteStore 基于嵌入式SQLite的决策记录存储
+// 记录主体（prompt、思维链等）以JSON存放在payload列，执行动作和持仓快照拆分到独立的带索引表中
+type SQLiteStore struct {
+	db   *sql.DB
+	path string
+}
+
+// NewSQLiteStore 打开（或创建）SQLite数据库并执行结构迁移
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开SQLite数据库失败: %w", err)
+	}
+	// SQLite只允许单个写连接，统一串行化访问
+	db.SetMaxOpenConns(1)
+
+	s := &SQLiteStore{db: db, path: path}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// OpenSQLiteStoreReadOnly 以只读方式打开已有的SQLite数据库（不执行结构迁移，trader运行中也可以安全读取）
+// 数据库版本低于当前程序时返回错误，需先由trader启动完成迁移
+func OpenSQLiteStoreReadOnly(path string) (*SQLiteStore, error) {
+	dsn := fmt.Sprintf("file:%s?mode=ro&_pragma=busy_timeout(5000)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开SQLite数据库失败: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("读取数据库版本失败: %w", err)
+	}
+	if version < len(schemaMigrations) {
+		db.Close()
+		return nil, fmt.Errorf("数据库版本v%d低于当前版本v%d，请先启动一次trader完成迁移", version, len(schemaMigrations))
+	}
+	return &SQLiteStore{db: db, path: path}, nil
+}
+
+// migrate 按 user_version 执行未应用的结构迁移
+func (s *SQLiteStore) migrate() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("读取数据库版本失败: %w", err)
+	}
+
+	for i := version; i < len(schemaMigrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("开启迁移事务失败: %w", err)
+		}
+		if _, err := tx.Exec(schemaMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("执行数据库迁移v%d失败: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("更新数据库版本失败: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("提交数据库迁移v%d失败: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Save 保存决策记录（主记录、执行动作、持仓快照在同一事务中写入）
+func (s *SQLiteStore) Save(record *DecisionRecord) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	recordID, err := insertRecord(tx, record)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交决策记录失败: %w", err)
+	}
+
+	record.ID = recordID
+	return nil
+}
+
+// Import 在同一事务中批量导入记录（用于迁移旧版日志），全部成功或全部回滚
+// 已存在相同周期编号和时间戳的记录会被跳过，重复导入不会产生重复记录；返回实际导入的条数
+func (s *SQLiteStore) Import(records []*DecisionRecord) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	imported := 0
+	for _, record := range records {
+		var exists int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM decision_records WHERE cycle_number = ? AND timestamp = ?`,
+			record.CycleNumber, record.Timestamp.UnixNano()).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("检查决策记录(周期#%d)失败: %w", record.CycleNumber, err)
+		}
+		if exists > 0 {
+			continue
+		}
+		if _, err := insertRecord(tx, record); err != nil {
+			return 0, fmt.Errorf("导入决策记录(周期#%d)失败: %w", record.CycleNumber, err)
+		}
+		imported++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交导入事务失败: %w", err)
+	}
+	return imported, nil
+}
+
+// insertRecord 在事务中写入一条决策记录及其执行动作和持仓快照，返回记录ID
+func insertRecord(tx *sql.Tx, record *DecisionRecord) (int64, error) {
+	// payload中不重复存放已拆分到子表的数据
+	payloadRecord := *record
+	payloadRecord.Decisions = nil
+	payloadRecord.Positions = nil
+	payload, err := json.Marshal(&payloadRecord)
+	if err != nil {
+		return 0, fmt.Errorf("序列化决策记录失败: %w", err)
+	}
+
+	res, err := tx.Exec(`INSERT INTO decision_records
+		(cycle_number, timestamp, success, error_message, total_balance, available_balance,
+		 total_unrealized_profit, position_count, margin_used_pct, payload)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.CycleNumber, record.Timestamp.UnixNano(), boolToInt(record.Success), record.ErrorMessage,
+		record.AccountState.TotalBalance, record.AccountState.AvailableBalance,
+		record.AccountState.TotalUnrealizedProfit, record.AccountState.PositionCount,
+		record.AccountState.MarginUsedPct, string(payload))
+	if err != nil {
+		return 0, fmt.Errorf("写入决策记录失败: %w", err)
+	}
+	recordID, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("获取记录ID失败: %w", err)
+	}
+
+	for i, action := range record.Decisions {
+		market := ""
+		if action.Market != nil {
+			data, err := json.Marshal(action.Market)
+			if err != nil {
+				return 0, fmt.Errorf("序列化市场快照失败: %w", err)
+			}
+			market = string(data)
+		}
+		if _, err := tx.Exec(`INSERT INTO decision_actions
+			(record_id, seq, action, symbol, quantity, leverage, price, stop_loss, take_profit, order_id, timestamp, success, error,
+			 reasoning, market)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			recordID, i, action.Action, action.Symbol, action.Quantity, action.Leverage, action.Price,
+			action.StopLoss, action.TakeProfit, action.OrderID, action.Timestamp.UnixNano(),
+			boolToInt(action.Success), action.Error, action.Reasoning, market); err != nil {
+			return 0, fmt.Errorf("写入执行动作失败: %w", err)
+		}
+	}
+
+	for i, pos := range record.Positions {
+		if _, err := tx.Exec(`INSERT INTO decision_positions
+			(record_id, seq, symbol, side, position_amt, entry_price, mark_price, unrealized_profit, leverage, liquidation_price)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			recordID, i, pos.Symbol, pos.Side, pos.PositionAmt, pos.EntryPrice, pos.MarkPrice,
+			pos.UnrealizedProfit, pos.Leverage, pos.LiquidationPrice); err != nil {
+			return 0, fmt.Errorf("写入持仓快照失败: %w", err)
+		}
+	}
+
+	return recordID, nil
+}
+
+// Latest 获取最近N条记录（按时间正序：从旧到新）
+func (s *SQLiteStore) Latest(n int) ([]*DecisionRecord, error) {
+	records, err := s.Query(RecordQuery{Limit: n, Desc: true})
+	if err != nil {
+		return nil, err
+	}
+	reverseRecords(records)
+	return records, nil
+}
+
+// Query 按条件查询记录（时间范围走timestamp索引，动作过滤走symbol/action索引）
+// 读满 Limit 条但其中有损坏的记录时，从最后读取的位置继续补读，返回条数不会因损坏记录而减少（分页据此判断是否还有下一页）
+func (s *SQLiteStore) Query(q RecordQuery) ([]*DecisionRecord, error) {
+	order := "ASC"
+	if q.Desc {
+		order = "DESC"
+	}
+
+	records := []*DecisionRecord{}
+	for {
+		where, args := queryClause(q)
+		query := fmt.Sprintf("SELECT id, timestamp, payload FROM decision_records%s ORDER BY timestamp %s, id %s", where, order, order)
+		query, args = appendLimitOffset(query, args, q.Limit, q.Offset)
+
+		batch, skipped, last, err := s.loadRecords(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, batch...)
+		if q.Limit <= 0 || skipped == 0 || len(batch)+skipped < q.Limit {
+			return records, nil
+		}
+
+		q.Limit, q.Offset = skipped, 0
+		if q.Desc {
+			q.Before = last
+		} else {
+			q.after = last
+		}
+	}
+}
+
+// AccountHistory 只查询账户快照列（按时间正序）
+func (s *SQLiteStore) AccountHistory(from, to time.Time, limit int) ([]AccountPoint, error) {
+	where, args := timeRangeClause(from, to)
+	query := fmt.Sprintf(`SELECT cycle_number, timestamp, total_balance, available_balance,
+		total_unrealized_profit, position_count, margin_used_pct
+		FROM decision_records%s ORDER BY timestamp DESC, id DESC`, where)
+	query, args = appendLimitOffset(query, args, limit, 0)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询账户历史失败: %w", err)
+	}
+	defer rows.Close()
+
+	var points []AccountPoint
+	for rows.Next() {
+		var p AccountPoint
+		var ts int64
+		if err := rows.Scan(&p.CycleNumber, &ts, &p.Account.TotalBalance, &p.Account.AvailableBalance,
+			&p.Account.TotalUnrealizedProfit, &p.Account.PositionCount, &p.Account.MarginUsedPct); err != nil {
+			return nil, fmt.Errorf("读取账户历史失败: %w", err)
+		}
+		p.Timestamp = time.Unix(0, ts)
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取账户历史失败: %w", err)
+	}
+
+	// 反转为从旧到新
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+	return points, nil
+}
+
+// Statistics 使用聚合查询获取统计信息
+func (s *SQLiteStore) Statistics() (*Statistics, error) {
+	stats := &Statistics{}
+
+	if err := s.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(success), 0) FROM decision_records`).
+		Scan(&stats.TotalCycles, &stats.SuccessfulCycles); err != nil {
+		return nil, fmt.Errorf("统计周期数失败: %w", err)
+	}
+	stats.FailedCycles = stats.TotalCycles - stats.SuccessfulCycles
+
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM decision_records WHERE NOT json_valid(payload)`).
+		Scan(&stats.CorruptRecords); err != nil {
+		return nil, fmt.Errorf("统计损坏记录数失败: %w", err)
+	}
+
+	if err := s.db.QueryRow(`SELECT
+		COALESCE(SUM(CASE WHEN action IN ('open_long', 'open_short') THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN action IN ('close_long', 'close_short') THEN 1 ELSE 0 END), 0)
+		FROM decision_actions WHERE success = 1`).
+		Scan(&stats.TotalOpenPositions, &stats.TotalClosePositions); err != nil {
+		return nil, fmt.Errorf("统计开平仓次数失败: %w", err)
+	}
+
+	return stats, nil
+}
+
+// MaxCycleNumber 获取最大的周期编号
+func (s *SQLiteStore) MaxCycleNumber() (int, error) {
+	var maxCycle int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(cycle_number), 0) FROM decision_records`).Scan(&maxCycle); err != nil {
+		return 0, fmt.Errorf("查询最大周期编号失败: %w", err)
+	}
+	return maxCycle, nil
+}
+
+// DeleteBefore 删除指定时间之前的记录（子表通过外键级联删除）
+func (s *SQLiteStore) DeleteBefore(cutoff time.Time) (int, error) {
+	res, err := s.db.Exec(`DELETE FROM decision_records WHERE timestamp < ?`, cutoff.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("删除旧记录失败: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// Memory 读取跨周期记忆（从未保存时返回空记忆）
+func (s *SQLiteStore) Memory() (*Memory, error) {
+	var m Memory
+	var updatedAt, resetAt int64
+	err := s.db.QueryRow(`SELECT journal, journal_cycle, updated_at, reset_cycle, reset_at FROM trader_memory WHERE id = 1`).
+		Scan(&m.Journal, &m.JournalCycle, &updatedAt, &m.ResetCycle, &resetAt)
+	if err == sql.ErrNoRows {
+		return &Memory{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询跨周期记忆失败: %w", err)
+	}
+	m.UpdatedAt = nanoToTime(updatedAt)
+	m.ResetAt = nanoToTime(resetAt)
+	return &m, nil
+}
+
+// SaveJournal 更新交易日志（只更新日志相关列，保留重置点）
+func (s *SQLiteStore) SaveJournal(journal string, cycle int, at time.Time) error {
+	if _, err := s.db.Exec(`INSERT INTO trader_memory (id, journal, journal_cycle, updated_at) VALUES (1, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET journal = excluded.journal, journal_cycle = excluded.journal_cycle,
+		updated_at = excluded.updated_at`, journal, cycle, timeToNano(at)); err != nil {
+		return fmt.Errorf("写入交易日志失败: %w", err)
+	}
+	return nil
+}
+
+// ResetMemory 清空交易日志并记录重置点（整行覆盖）
+func (s *SQLiteStore) ResetMemory(cycle int, at time.Time) error {
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO trader_memory (id, reset_cycle, reset_at) VALUES (1, ?, ?)`,
+		cycle, timeToNano(at)); err != nil {
+		return fmt.Errorf("重置跨周期记忆失败: %w", err)
+	}
+	return nil
+}
+
+// SaveReflection 保存一笔交易的复盘（同一笔交易重复保存时覆盖），并只保留平仓时间最新的 maxStoredReflections 条
+func (s *SQLiteStore) SaveReflection(r TradeReflection) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO trade_reflections
+		(trade_id, symbol, side, open_time, close_time, pnl, pnl_pct, lesson, model, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.TradeID, r.Symbol, r.Side, timeToNano(r.OpenTime), timeToNano(r.CloseTime),
+		r.PnL, r.PnLPct, r.Lesson, r.Model, timeToNano(r.CreatedAt)); err != nil {
+		return fmt.Errorf("写入交易复盘失败: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM trade_reflections WHERE trade_id NOT IN
+		(SELECT trade_id FROM trade_reflections ORDER BY close_time DESC, trade_id DESC LIMIT ?)`,
+		maxStoredReflections); err != nil {
+		return fmt.Errorf("清理旧交易复盘失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交交易复盘失败: %w", err)
+	}
+	return nil
+}
+
+// Reflections 获取平仓时间最新的N条复盘（按平仓时间正序）
+func (s *SQLiteStore) Reflections(n int) ([]TradeReflection, error) {
+	query, args := appendLimitOffset(`SELECT trade_id, symbol, side, open_time, close_time, pnl, pnl_pct,
+		lesson, model, created_at FROM trade_reflections ORDER BY close_time DESC, trade_id DESC`, nil, n, 0)
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询交易复盘失败: %w", err)
+	}
+	defer rows.Close()
+
+	var reflections []TradeReflection
+	for rows.Next() {
+		var r TradeReflection
+		var openTime, closeTime, createdAt int64
+		if err := rows.Scan(&r.TradeID, &r.Symbol, &r.Side, &openTime, &closeTime, &r.PnL, &r.PnLPct,
+			&r.Lesson, &r.Model, &createdAt); err != nil {
+			return nil, fmt.Errorf("读取交易复盘失败: %w", err)
+		}
+		r.OpenTime = nanoToTime(openTime)
+		r.CloseTime = nanoToTime(closeTime)
+		r.CreatedAt = nanoToTime(createdAt)
+		reflections = append(reflections, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取交易复盘失败: %w", err)
+	}
+
+	// 反转为从旧到新
+	for i, j := 0, len(reflections)-1; i < j; i, j = i+1, j-1 {
+		reflections[i], reflections[j] = reflections[j], reflections[i]
+	}
+	return reflections, nil
+}
+
+// Close 关闭数据库
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// loadRecords 执行查询（需返回 id, timestamp, payload 三列）并补全执行动作与持仓快照
+// 同时返回跳过的损坏记录数和最后读取的行位置（用于补读）
+func (s *SQLiteStore) loadRecords(query string, args ...interface{}) ([]*DecisionRecord, int, *RecordCursor, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("查询决策记录失败: %w", err)
+	}
+
+	records := []*DecisionRecord{}
+	byID := make(map[int64]*DecisionRecord)
+	skipped := 0
+	var last *RecordCursor
+	for rows.Next() {
+		var id, ts int64
+		var payload string
+		if err := rows.Scan(&id, &ts, &payload); err != nil {
+			rows.Close()
+			return nil, 0, nil, fmt.Errorf("读取决策记录失败: %w", err)
+		}
+		last = &RecordCursor{Timestamp: time.Unix(0, ts), ID: id}
+
+		var record DecisionRecord
+		if err := json.Unmarshal([]byte(payload), &record); err != nil {
+			// 单条记录损坏时跳过（与FileStore一致），不影响其他记录的查询
+			skipped++
+			fmt.Printf("⚠ 跳过损坏的决策记录(id=%d): %v\n", id, err)
+			continue
+		}
+		record.ID = id
+		records = append(records, &record)
+		byID[id] = &record
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, nil, fmt.Errorf("读取决策记录失败: %w", err)
+	}
+
+	// 分批加载子表
+	for start := 0; start < len(records); start += sqliteBatchSize {
+		end := start + sqliteBatchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		ids := make([]interface{}, 0, end-start)
+		for _, record := range records[start:end] {
+			ids = append(ids, record.ID)
+		}
+		if err := s.loadActions(byID, ids); err != nil {
+			return nil, 0, nil, err
+		}
+		if err := s.loadPositions(byID, ids); err != nil {
+			return nil, 0, nil, err
+		}
+	}
+
+	return records, skipped, last, nil
+}
+
+// loadActions 加载一批记录的执行动作
+func (s *SQLiteStore) loadActions(byID map[int64]*DecisionRecord, ids []interface{}) error {
+	rows, err := s.db.Query(fmt.Sprintf(`SELECT record_id, action, symbol, quantity, leverage, price,
+		stop_loss, take_profit, order_id, timestamp, success, error, reasoning, market FROM decision_actions
+		WHERE record_id IN (%s) ORDER BY record_id, seq`, placeholders(len(ids))), ids...)
+	if err != nil {
+		return fmt.Errorf("查询执行动作失败: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var recordID, ts int64
+		var success int
+		var market string
+		var a DecisionAction
+		if err := rows.Scan(&recordID, &a.Action, &a.Symbol, &a.Quantity, &a.Leverage, &a.Price,
+			&a.StopLoss, &a.TakeProfit, &a.OrderID, &ts, &success, &a.Error, &a.Reasoning, &market); err != nil {
+			return fmt.Errorf("读取执行动作失败: %w", err)
+		}
+		a.Timestamp = time.Unix(0, ts)
+		a.Success = success != 0
+		if market != "" {
+			// 快照损坏时忽略（只影响复盘内容）
+			if err := json.Unmarshal([]byte(market), &a.Market); err != nil {
+				a.Market = nil
+			}
+		}
+		if record, ok := byID[recordID]; ok {
+			record.Decisions = append(record.Decisions, a)
+		}
+	}
+	return rows.Err()
+}
+
+// loadPositions 加载一批记录的持仓快照
+func (s *SQLiteStore) loadPositions(byID map[int64]*DecisionRecord, ids []interface{}) error {
+	rows, err := s.db.Query(fmt.Sprintf(`SELECT record_id, symbol, side, position_amt, entry_price, mark_price,
+		unrealized_profit, leverage, liquidation_price FROM decision_positions
+		WHERE record_id IN (%s) ORDER BY record_id, seq`, placeholders(len(ids))), ids...)
+	if err != nil {
+		return fmt.Errorf("查询持仓快照失败: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var recordID int64
+		var p PositionSnapshot
+		if err := rows.Scan(&recordID, &p.Symbol, &p.Side, &p.PositionAmt, &p.EntryPrice, &p.MarkPrice,
+			&p.UnrealizedProfit, &p.Leverage, &p.LiquidationPrice); err != nil {
+			return fmt.Errorf("读取持仓快照失败: %w", err)
+		}
+		if record, ok := byID[recordID]; ok {
+			record.Positions = append(record.Positions, p)
+		}
+	}
+	return rows.Err()
+}
+
+// timeRangeClause 生成时间范围的WHERE子句
+func timeRangeClause(from, to time.Time) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if !from.IsZero() {
+		conds = append(conds, "timestamp >= ?")
+		args = append(args, from.UnixNano())
+	}
+	if !to.IsZero() {
+		conds = append(conds, "timestamp < ?")
+		args = append(args, to.UnixNano())
+	}
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// queryClause 生成完整查询条件的WHERE子句
+func queryClause(q RecordQuery) (string, []interface{}) {
+	where, args := timeRangeClause(q.From, q.To)
+	var conds []string
+	if where != "" {
+		conds = append(conds, strings.TrimPrefix(where, " WHERE "))
+	}
+
+	if q.Cycle > 0 {
+		conds = append(conds, "cycle_number = ?")
+		args = append(args, q.Cycle)
+	}
+
+	if q.Before != nil {
+		ts := q.Before.Timestamp.UnixNano()
+		conds = append(conds, "(timestamp < ? OR (timestamp = ? AND id < ?))")
+		args = append(args, ts, ts, q.Before.ID)
+	}
+	if q.after != nil {
+		ts := q.after.Timestamp.UnixNano()
+		conds = append(conds, "(timestamp > ? OR (timestamp = ? AND id > ?))")
+		args = append(args, ts, ts, q.after.ID)
+	}
+
+	if q.hasActionFilter() {
+		var actionConds []string
+		if q.Symbol != "" {
+			actionConds = append(actionConds, "symbol = ?")
+			args = append(args, q.Symbol)
+		}
+		if q.Action != "" {
+			actionConds = append(actionConds, "action = ?")
+			args = append(args, q.Action)
+		}
+		if q.Success != nil {
+			actionConds = append(actionConds, "success = ?")
+			args = append(args, boolToInt(*q.Success))
+		}
+		conds = append(conds, "id IN (SELECT record_id FROM decision_actions WHERE "+strings.Join(actionConds, " AND ")+")")
+	} else if q.Success != nil {
+		conds = append(conds, "success = ?")
+		args = append(args, boolToInt(*q.Success))
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// appendLimitOffset 追加分页子句
+func appendLimitOffset(query string, args []interface{}, limit, offset int) (string, []interface{}) {
+	if limit <= 0 && offset <= 0 {
+		return query, args
+	}
+	if limit <= 0 {
+		limit = -1 // SQLite中 LIMIT -1 表示不限制
+	}
+	query += " LIMIT ? OFFSET ?"
+	return query, append(args, limit, offset)
+}
+
+// placeholders 生成n个SQL占位符
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// timeToNano 时间转为纳秒时间戳，零值存为0（time.Time{}.UnixNano() 超出int64范围）
+func timeToNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// nanoToTime 纳秒时间戳转为时间，0还原为零值
+func nanoToTime(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// boolToInt 布尔值转SQLite整数
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,389 @@
+package logger
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueryClause(t *testing.T) {
+	from := time.Unix(100, 0)
+	to := time.Unix(200, 0)
+	before := time.Unix(150, 5)
+	yes, no := true, false
+
+	tests := []struct {
+		name      string
+		q         RecordQuery
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "无条件",
+			q:         RecordQuery{},
+			wantWhere: "",
+			wantArgs:  nil,
+		},
+		{
+			name:      "时间范围",
+			q:         RecordQuery{From: from, To: to},
+			wantWhere: " WHERE timestamp >= ? AND timestamp < ?",
+			wantArgs:  []interface{}{from.UnixNano(), to.UnixNano()},
+		},
+		{
+			name:      "游标按时间戳和ID区分同一时间的记录",
+			q:         RecordQuery{Before: &RecordCursor{Timestamp: before, ID: 42}},
+			wantWhere: " WHERE (timestamp < ? OR (timestamp = ? AND id < ?))",
+			wantArgs:  []interface{}{before.UnixNano(), before.UnixNano(), int64(42)},
+		},
+		{
+			name:      "游标与时间范围组合",
+			q:         RecordQuery{From: from, Before: &RecordCursor{Timestamp: before, ID: 7}},
+			wantWhere: " WHERE timestamp >= ? AND (timestamp < ? OR (timestamp = ? AND id < ?))",
+			wantArgs:  []interface{}{from.UnixNano(), before.UnixNano(), before.UnixNano(), int64(7)},
+		},
+		{
+			name:      "周期编号",
+			q:         RecordQuery{Cycle: 3},
+			wantWhere: " WHERE cycle_number = ?",
+			wantArgs:  []interface{}{3},
+		},
+		{
+			name:      "只有执行结果时匹配整个周期",
+			q:         RecordQuery{Success: &no},
+			wantWhere: " WHERE success = ?",
+			wantArgs:  []interface{}{0},
+		},
+		{
+			name:      "动作过滤时执行结果作用于动作",
+			q:         RecordQuery{Symbol: "SOLUSDT", Action: "open_short", Success: &yes},
+			wantWhere: " WHERE id IN (SELECT record_id FROM decision_actions WHERE symbol = ? AND action = ? AND success = ?)",
+			wantArgs:  []interface{}{"SOLUSDT", "open_short", 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := queryClause(tt.q)
+			if where != tt.wantWhere {
+				t.Errorf("where = %q, want %q", where, tt.wantWhere)
+			}
+			if len(args) != 0 || len(tt.wantArgs) != 0 {
+				if !reflect.DeepEqual(args, tt.wantArgs) {
+					t.Errorf("args = %v, want %v", args, tt.wantArgs)
+				}
+			}
+		})
+	}
+}
+
+// testStores 两种存储后端（用于验证SQLite与文件存储的行为一致）
+var testStores = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{"sqlite", func(t *testing.T) Store {
+		s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "decisions.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	}},
+	{"file", func(t *testing.T) Store {
+		s, err := NewFileStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}},
+}
+
+func TestQueryDecisionsCursorTies(t *testing.T) {
+	// 7条记录，其中周期2-4和周期5-6的时间戳相同
+	base := time.Unix(1700000000, 0)
+	timestamps := []time.Time{
+		base,
+		base.Add(time.Minute),
+		base.Add(time.Minute),
+		base.Add(time.Minute),
+		base.Add(2 * time.Minute),
+		base.Add(2 * time.Minute),
+		base.Add(3 * time.Minute),
+	}
+
+	for _, st := range testStores {
+		for _, limit := range []int{1, 2, 3, 10} {
+			t.Run(fmt.Sprintf("%s_limit%d", st.name, limit), func(t *testing.T) {
+				store := st.open(t)
+				for i, ts := range timestamps {
+					// FileStore 用时间和周期编号命名文件，同一秒内的记录也不会互相覆盖
+					if err := store.Save(&DecisionRecord{CycleNumber: i + 1, Timestamp: ts}); err != nil {
+						t.Fatal(err)
+					}
+				}
+				l := &DecisionLogger{store: store}
+
+				var cycles []int
+				cursor := ""
+				for page := 0; page < len(timestamps)+1; page++ {
+					result, err := l.QueryDecisions(DecisionQuery{Limit: limit, Cursor: cursor})
+					if err != nil {
+						t.Fatal(err)
+					}
+					for _, r := range result.Records {
+						cycles = append(cycles, r.CycleNumber)
+					}
+					if result.NextCursor == "" {
+						break
+					}
+					cursor = result.NextCursor
+				}
+
+				want := []int{7, 6, 5, 4, 3, 2, 1}
+				if !reflect.DeepEqual(cycles, want) {
+					t.Errorf("周期顺序 = %v, want %v", cycles, want)
+				}
+			})
+		}
+	}
+}
+
+func TestSQLiteStoreSkipsCorruptPayload(t *testing.T) {
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "decisions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	base := time.Unix(1700000000, 0)
+	for i := 0; i < 3; i++ {
+		record := &DecisionRecord{CycleNumber: i + 1, Timestamp: base.Add(time.Duration(i) * time.Minute)}
+		if err := s.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.db.Exec(`UPDATE decision_records SET payload = '{bad' WHERE cycle_number = 2`); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := s.Latest(10)
+	if err != nil {
+		t.Fatalf("损坏记录不应导致查询失败: %v", err)
+	}
+	var cycles []int
+	for _, r := range records {
+		cycles = append(cycles, r.CycleNumber)
+	}
+	if want := []int{1, 3}; !reflect.DeepEqual(cycles, want) {
+		t.Errorf("周期 = %v, want %v", cycles, want)
+	}
+	stats, err := s.Statistics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.CorruptRecords != 1 {
+		t.Errorf("CorruptRecords = %d, want 1", stats.CorruptRecords)
+	}
+}
+
+func TestSQLiteStorePagesAcrossCorruptPayload(t *testing.T) {
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "decisions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	base := time.Unix(1700000000, 0)
+	for i := 0; i < 7; i++ {
+		record := &DecisionRecord{CycleNumber: i + 1, Timestamp: base.Add(time.Duration(i) * time.Minute)}
+		if err := s.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 周期3、5、6损坏，其中5和6相邻
+	if _, err := s.db.Exec(`UPDATE decision_records SET payload = '{bad' WHERE cycle_number IN (3, 5, 6)`); err != nil {
+		t.Fatal(err)
+	}
+	l := &DecisionLogger{store: s}
+
+	for _, limit := range []int{1, 2, 3, 4} {
+		t.Run(fmt.Sprintf("limit%d", limit), func(t *testing.T) {
+			var cycles []int
+			cursor := ""
+			for page := 0; page < 8; page++ {
+				result, err := l.QueryDecisions(DecisionQuery{Limit: limit, Cursor: cursor})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(result.Records) < limit && result.NextCursor != "" {
+					t.Errorf("第%d页只有%d条却还有下一页", page+1, len(result.Records))
+				}
+				for _, r := range result.Records {
+					cycles = append(cycles, r.CycleNumber)
+				}
+				if result.NextCursor == "" {
+					break
+				}
+				cursor = result.NextCursor
+			}
+			if want := []int{7, 4, 2, 1}; !reflect.DeepEqual(cycles, want) {
+				t.Errorf("周期顺序 = %v, want %v", cycles, want)
+			}
+		})
+	}
+
+	// 正序查询同样补足条数
+	records, err := s.Query(RecordQuery{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cycles []int
+	for _, r := range records {
+		cycles = append(cycles, r.CycleNumber)
+	}
+	if want := []int{1, 2, 4}; !reflect.DeepEqual(cycles, want) {
+		t.Errorf("正序前3条 = %v, want %v", cycles, want)
+	}
+}
+
+func TestStoreMemory(t *testing.T) {
+	resetAt := time.Unix(1700000000, 0)
+	savedAt := resetAt.Add(time.Hour)
+	for _, st := range testStores {
+		t.Run(st.name, func(t *testing.T) {
+			store := st.open(t)
+
+			memory, err := store.Memory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(memory, &Memory{}) {
+				t.Errorf("从未保存时 = %+v, want 空记忆", memory)
+			}
+
+			if err := store.ResetMemory(5, resetAt); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SaveJournal("BTC趋势未确认前不追多", 7, savedAt); err != nil {
+				t.Fatal(err)
+			}
+			memory, err = store.Memory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if memory.Journal != "BTC趋势未确认前不追多" || memory.JournalCycle != 7 || !memory.UpdatedAt.Equal(savedAt) {
+				t.Errorf("交易日志 = %+v", memory)
+			}
+			if memory.ResetCycle != 5 || !memory.ResetAt.Equal(resetAt) {
+				t.Errorf("保存交易日志不应覆盖重置点: %+v", memory)
+			}
+
+			if err := store.ResetMemory(9, savedAt); err != nil {
+				t.Fatal(err)
+			}
+			memory, err = store.Memory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if memory.Journal != "" || memory.ResetCycle != 9 {
+				t.Errorf("重置后 = %+v", memory)
+			}
+
+			// 记忆不应被当作决策记录读取
+			records, err := store.Latest(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 0 {
+				t.Errorf("记录数 = %d, want 0", len(records))
+			}
+		})
+	}
+}
+
+func TestStoreReflectionsOrderedByCloseTime(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	reflection := func(id string, closedAfter time.Duration, lesson string) TradeReflection {
+		return TradeReflection{TradeID: id, Symbol: "BTCUSDT", Side: "long", CloseTime: base.Add(closedAfter), Lesson: lesson}
+	}
+
+	for _, st := range testStores {
+		t.Run(st.name, func(t *testing.T) {
+			store := st.open(t)
+
+			// 一批复盘按平仓时间倒序保存（与 RecentTrades 的顺序一致）
+			for _, r := range []TradeReflection{
+				reflection("c", 3*time.Hour, "c"),
+				reflection("b", 2*time.Hour, "b"),
+				reflection("a", time.Hour, "a"),
+				reflection("b", 2*time.Hour, "b2"), // 重复保存时覆盖
+			} {
+				if err := store.SaveReflection(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			latest, err := store.Reflections(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range latest {
+				got = append(got, r.TradeID+":"+r.Lesson)
+			}
+			if want := []string{"b:b2", "c:c"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("最近2条 = %v, want %v", got, want)
+			}
+
+			for i := 0; i < maxStoredReflections; i++ {
+				if err := store.SaveReflection(reflection(fmt.Sprintf("n%03d", i), time.Duration(i+4)*time.Hour, "")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			all, err := store.Reflections(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != maxStoredReflections || all[0].TradeID != "n000" {
+				t.Errorf("保留 %d 条，最早为 %s，want %d 条，最早为 n000", len(all), all[0].TradeID, maxStoredReflections)
+			}
+		})
+	}
+}
+
+func TestOpenSQLiteStoreReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.db")
+	writer, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	if err := writer.Save(&DecisionRecord{CycleNumber: 1, Timestamp: time.Unix(1700000000, 0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// trader运行中（写连接未关闭）也可以只读打开
+	reader, err := OpenSQLiteStoreReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	records, err := reader.Latest(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].CycleNumber != 1 {
+		t.Errorf("records = %+v, want 周期#1", records)
+	}
+	if err := reader.Save(&DecisionRecord{CycleNumber: 2, Timestamp: time.Unix(1700000060, 0)}); err == nil {
+		t.Error("只读存储不应允许写入")
+	}
+
+	if _, err := OpenSQLiteStoreReadOnly(filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Error("数据库不存在时应返回错误")
+	}
+}
@@ -0,0 +1,151 @@
+package logger
+
+import "time"
+
+// Store 决策记录存储后端
+// 默认使用嵌入式SQLite（见 SQLiteStore），JSON文件存储（FileStore）仅作为兼容和降级方案
+type Store interface {
+	// Save 保存一条决策记录（保存成功后回填 record.ID）
+	Save(record *DecisionRecord) error
+
+	// Latest 获取最近N条记录（按时间正序：从旧到新）
+	Latest(n int) ([]*DecisionRecord, error)
+
+	// Query 按时间范围查询记录（支持分页）
+	Query(q RecordQuery) ([]*DecisionRecord, error)
+
+	// AccountHistory 只查询账户快照（用于收益曲线，不加载prompt等大字段），按时间正序
+	AccountHistory(from, to time.Time, limit int) ([]AccountPoint, error)
+
+	// Statistics 获取统计信息
+	Statistics() (*Statistics, error)
+
+	// MaxCycleNumber 获取已保存记录中最大的周期编号
+	MaxCycleNumber() (int, error)
+
+	// DeleteBefore 删除指定时间之前的记录，返回删除条数
+	DeleteBefore(cutoff time.Time) (int, error)
+
+	// Memory 读取跨周期记忆（从未保存时返回空记忆）
+	Memory() (*Memory, error)
+
+	// SaveJournal 保存模型维护的交易日志及其所属周期（保留重置点）
+	SaveJournal(journal string, cycle int, at time.Time) error
+
+	// ResetMemory 清空交易日志并记录重置点
+	ResetMemory(cycle int, at time.Time) error
+
+	// SaveReflection 保存一笔交易的复盘（同一笔交易重复保存时覆盖），只保留平仓时间最新的 maxStoredReflections 条
+	SaveReflection(reflection TradeReflection) error
+
+	// Reflections 获取平仓时间最新的N条复盘（按平仓时间正序：从旧到新），n<=0 时返回全部
+	Reflections(n int) ([]TradeReflection, error)
+
+	// Close 关闭存储
+	Close() error
+}
+
+// RecordQuery 决策记录查询条件
+type RecordQuery struct {
+	From   time.Time // 起始时间（包含），零值表示不限制
+	To     time.Time // 结束时间（不包含），零值表示不限制
+	Limit  int       // 最多返回条数，<=0 表示不限制
+	Offset int       // 跳过的条数（用于分页）
+	Desc   bool      // true: 从新到旧；false: 从旧到新
+
+	// 执行动作过滤（记录中至少有一个动作同时满足所有条件）
+	Symbol string // 币种，如 SOLUSDT
+	Action string // 动作类型，如 open_short
+	// Success 执行结果过滤：指定了Symbol/Action时匹配动作的执行结果，否则匹配整个周期的结果
+	Success *bool
+
+	// Before 游标：只返回排序在该位置之前（更旧）的记录，配合 Desc 使用
+	Before *RecordCursor
+	// after 只返回排序在该位置之后（更新）的记录（SQLite正序查询跳过损坏记录后补读时使用）
+	after *RecordCursor
+
+	// Cycle 周期编号，0表示不限制
+	Cycle int
+}
+
+// RecordCursor 记录在时间线上的位置（时间戳相同时用ID区分）
+type RecordCursor struct {
+	Timestamp time.Time
+	ID        int64
+}
+
+// hasActionFilter 是否按执行动作过滤
+func (q RecordQuery) hasActionFilter() bool {
+	return q.Symbol != "" || q.Action != ""
+}
+
+// matchRecord 判断记录是否满足所有过滤条件（不含分页）
+func (q RecordQuery) matchRecord(record *DecisionRecord) bool {
+	if !q.matchTimeRange(record.Timestamp) {
+		return false
+	}
+
+	if q.Cycle > 0 && record.CycleNumber != q.Cycle {
+		return false
+	}
+
+	if q.Before != nil {
+		if record.Timestamp.After(q.Before.Timestamp) {
+			return false
+		}
+		if record.Timestamp.Equal(q.Before.Timestamp) && record.ID >= q.Before.ID {
+			return false
+		}
+	}
+	if q.after != nil {
+		if record.Timestamp.Before(q.after.Timestamp) {
+			return false
+		}
+		if record.Timestamp.Equal(q.after.Timestamp) && record.ID <= q.after.ID {
+			return false
+		}
+	}
+
+	if !q.hasActionFilter() {
+		return q.Success == nil || record.Success == *q.Success
+	}
+
+	for _, action := range record.Decisions {
+		if q.Symbol != "" && action.Symbol != q.Symbol {
+			continue
+		}
+		if q.Action != "" && action.Action != q.Action {
+			continue
+		}
+		if q.Success != nil && action.Success != *q.Success {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// AccountPoint 某个周期的账户快照（收益曲线数据点）
+type AccountPoint struct {
+	Timestamp   time.Time       `json:"timestamp"`
+	CycleNumber int             `json:"cycle_number"`
+	Account     AccountSnapshot `json:"account"`
+}
+
+// matchTimeRange 判断时间是否落在查询范围内
+func (q RecordQuery) matchTimeRange(t time.Time) bool {
+	if !q.From.IsZero() && t.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !t.Before(q.To) {
+		return false
+	}
+	return true
+}
+
+// reverseRecords 原地反转记录顺序
+func reverseRecords(records []*DecisionRecord) {
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+}
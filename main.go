@@ -1,30 +1,75 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"nofx/api"
 	"nofx/config"
 	"nofx/manager"
+	"nofx/market"
 	"nofx/pool"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 )
 
 func main() {
+	// 日志输出中的密钥脱敏
+	log.SetOutput(config.NewRedactingWriter(os.Stderr))
+
+	// 子命令
+	if len(os.Args) > 1 {
+		var run func([]string) error
+		switch os.Args[1] {
+		case "keystore":
+			run = runKeystoreCommand
+		case "validate-config":
+			run = runValidateConfig
+		case "replay":
+			run = runReplay
+		}
+		if run != nil {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	// 命令行参数（配置文件路径也可以作为第一个位置参数传入，兼容旧用法）
+	configPath := flag.String("config", "config.json", "配置文件路径")
+	var sets stringList
+	flag.Var(&sets, "set", "覆盖配置项，格式 路径=值，可重复（如 -set leverage.btc_eth_leverage=10）")
+	printConfig := flag.Bool("print-effective-config", false, "打印合并环境变量和命令行参数后的生效配置（密钥已脱敏）并退出")
+	flag.Parse()
+	configFile := *configPath
+	if flag.NArg() > 0 {
+		configFile = flag.Arg(0)
+	}
+	if err := config.SetFlagOverrides(sets); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	if *printConfig {
+		cfg, err := config.LoadConfig(configFile)
+		if err != nil {
+			log.Fatalf("❌ 加载配置失败: %v", err)
+		}
+		data, _ := json.MarshalIndent(cfg.Masked(), "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
 	fmt.Println("╔════════════════════════════════════════════════════════════╗")
 	fmt.Println("║    🏆 AI模型交易竞赛系统 - Qwen vs DeepSeek               ║")
 	fmt.Println("╚════════════════════════════════════════════════════════════╝")
 	fmt.Println()
 
-	// 加载配置文件
-	configFile := "config.json"
-	if len(os.Args) > 1 {
-		configFile = os.Args[1]
-	}
-
 	log.Printf("📋 加载配置文件: %s", configFile)
 	cfg, err := config.LoadConfig(configFile)
 	if err != nil {
@@ -32,6 +77,13 @@ func main() {
 	}
 
 	log.Printf("✓ 配置加载成功，共%d个trader参赛", len(cfg.Traders))
+
+	// 检查币种是否为可交易的合约（网络不可用时跳过）
+	if symbols, err := market.GetFuturesSymbols(); err != nil {
+		log.Printf("⚠️  无法获取合约列表，跳过币种检查: %v", err)
+	} else if err := cfg.ValidateSymbols(symbols); err != nil {
+		log.Printf("⚠️  币种检查: %v", err)
+	}
 	fmt.Println()
 
 	// 设置默认主流币种列表
@@ -55,6 +107,8 @@ func main() {
 
 	// 创建TraderManager
 	traderManager := manager.NewTraderManager()
+	traderManager.SetCompetitionConfig(cfg.Competition)
+	traderManager.SetConfig(configFile, cfg)
 
 	// 添加所有启用的trader
 	enabledCount := 0
@@ -114,7 +168,7 @@ func main() {
 	fmt.Println()
 
 	// 创建并启动API服务器
-	apiServer := api.NewServer(traderManager, cfg.APIServerPort)
+	apiServer := api.NewServer(traderManager, cfg)
 	go func() {
 		if err := apiServer.Start(); err != nil {
 			log.Printf("❌ API服务器错误: %v", err)
@@ -125,11 +179,28 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	// SIGHUP 重新加载配置
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			log.Printf("📋 收到SIGHUP，重新加载配置文件: %s", configFile)
+			if _, err := traderManager.ReloadConfig(); err != nil {
+				log.Printf("❌ 配置热加载失败，继续使用当前配置: %v", err)
+			}
+		}
+	}()
+
+	// 监听配置文件变化，自动热加载
+	stopWatch := make(chan struct{})
+	go traderManager.WatchConfig(2*time.Second, stopWatch)
+
 	// 启动所有trader
 	traderManager.StartAll()
 
 	// 等待退出信号
 	<-sigChan
+	close(stopWatch)
 	fmt.Println()
 	fmt.Println()
 	log.Println("📛 收到退出信号，正在停止所有trader...")
@@ -138,3 +209,15 @@ func main() {
 	fmt.Println()
 	fmt.Println("👋 感谢使用AI交易竞赛系统！")
 }
+
+// stringList 可重复的字符串命令行参数
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"fmt"
+	"nofx/config"
+	"nofx/logger"
+	"nofx/trader"
+	"sort"
+)
+
+// ExperimentReport prompt实验的各变体表现（汇总所有参与的trader）
+type ExperimentReport struct {
+	Name     string              `json:"name"`
+	Variants []ExperimentVariant `json:"variants"` // 按变体名称排序
+}
+
+// ExperimentVariant 单个变体在所有trader上的合并统计
+type ExperimentVariant struct {
+	*logger.VariantPerformance
+	Traders []string `json:"traders"` // 使用过该变体的trader ID
+}
+
+// resolvePromptVariants 解析trader参与的实验：固定变体时只返回该变体，否则返回全部变体
+// 变体未设置的模板和语言使用trader自身的设置（实验不存在时视为未参与，由配置校验报告）
+func resolvePromptVariants(cfg config.TraderConfig, fullConfig *config.Config) (string, []trader.PromptVariant) {
+	if cfg.Experiment == nil {
+		return "", nil
+	}
+	exp := fullConfig.FindExperiment(cfg.Experiment.Name)
+	if exp == nil {
+		return "", nil
+	}
+
+	var variants []trader.PromptVariant
+	for _, v := range exp.Variants {
+		if cfg.Experiment.Variant != "" && v.Name != cfg.Experiment.Variant {
+			continue
+		}
+		variant := trader.PromptVariant{
+			Name:           v.Name,
+			PromptTemplate: v.PromptTemplate,
+			PromptLanguage: v.PromptLanguage,
+		}
+		if variant.PromptTemplate == "" {
+			variant.PromptTemplate = cfg.PromptTemplate
+		}
+		if variant.PromptLanguage == "" {
+			variant.PromptLanguage = cfg.EffectivePromptLanguage(fullConfig.PromptLanguage)
+		}
+		variants = append(variants, variant)
+	}
+	return exp.Name, variants
+}
+
+// GetExperimentReports 按prompt变体汇总所有trader最近N个周期的交易表现
+// 统计来自决策记录中的实验标注，已从配置中移除的实验只要仍在分析窗口内也会出现
+func (tm *TraderManager) GetExperimentReports(lookbackCycles int) ([]ExperimentReport, error) {
+	merged := make(map[string]map[string]*ExperimentVariant) // 实验 -> 变体 -> 统计
+	for id, at := range tm.GetAllTraders() {
+		analysis, err := at.GetDecisionLogger().AnalyzePerformance(lookbackCycles)
+		if err != nil {
+			return nil, fmt.Errorf("分析trader %s 的表现失败: %w", id, err)
+		}
+		for _, vp := range analysis.VariantStats {
+			if merged[vp.Experiment] == nil {
+				merged[vp.Experiment] = make(map[string]*ExperimentVariant)
+			}
+			ev := merged[vp.Experiment][vp.Variant]
+			if ev == nil {
+				ev = &ExperimentVariant{
+					VariantPerformance: &logger.VariantPerformance{Experiment: vp.Experiment, Variant: vp.Variant},
+				}
+				merged[vp.Experiment][vp.Variant] = ev
+			}
+			ev.Merge(vp)
+			ev.Traders = append(ev.Traders, id)
+		}
+	}
+
+	reports := make([]ExperimentReport, 0, len(merged))
+	for name, variants := range merged {
+		report := ExperimentReport{Name: name, Variants: make([]ExperimentVariant, 0, len(variants))}
+		for _, ev := range variants {
+			sort.Strings(ev.Traders)
+			report.Variants = append(report.Variants, *ev)
+		}
+		sort.Slice(report.Variants, func(i, j int) bool { return report.Variants[i].Variant < report.Variants[j].Variant })
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Name < reports[j].Name })
+	return reports, nil
+}
@@ -0,0 +1,351 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"nofx/config"
+	"nofx/logger"
+	"sort"
+	"time"
+)
+
+// ErrSeasonNotFound 指定的赛季不存在
+var ErrSeasonNotFound = errors.New("赛季不存在")
+
+// SeasonInfo 排行榜对应的赛季
+type SeasonInfo struct {
+	Name   string    `json:"name"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"` // 零值表示不限
+	Active bool      `json:"active"`
+}
+
+// LeaderboardEntry 排行榜中单个trader的排名
+type LeaderboardEntry struct {
+	Rank           int       `json:"rank"`
+	PreviousRank   int       `json:"previous_rank"` // 上一个采样点的排名（0表示上一个采样点尚未参与排名）
+	RankChange     int       `json:"rank_change"`   // 相对上一个采样点的名次变化（正数表示上升）
+	BestRank       int       `json:"best_rank"`
+	WorstRank      int       `json:"worst_rank"`
+	TraderID       string    `json:"trader_id"`
+	TraderName     string    `json:"trader_name"`
+	AIModel        string    `json:"ai_model"`
+	IsRunning      bool      `json:"is_running"`
+	Score          float64   `json:"score"`
+	StartEquity    float64   `json:"start_equity"`
+	Equity         float64   `json:"equity"`
+	ReturnPct      float64   `json:"return_pct"`
+	SharpeRatio    float64   `json:"sharpe_ratio"` // 按采样间隔年化
+	MaxDrawdownPct float64   `json:"max_drawdown_pct"`
+	Cycles         int       `json:"cycles"`
+	LastUpdate     time.Time `json:"last_update"`
+}
+
+// RankSnapshot 某一采样时刻的排名
+type RankSnapshot struct {
+	Timestamp time.Time          `json:"timestamp"`
+	Ranks     map[string]int     `json:"ranks"`  // trader_id -> 名次
+	Scores    map[string]float64 `json:"scores"` // trader_id -> 得分
+}
+
+// Leaderboard 竞赛排行榜
+type Leaderboard struct {
+	Season    *SeasonInfo          `json:"season"` // 未配置赛季时为null（从第一条记录开始排名）
+	Scoring   config.ScoringConfig `json:"scoring"`
+	Interval  string               `json:"interval"`
+	UpdatedAt time.Time            `json:"updated_at"`
+	Entries   []LeaderboardEntry   `json:"entries"`
+	History   []RankSnapshot       `json:"history,omitempty"`
+}
+
+// SetCompetitionConfig 设置竞赛排行榜配置
+func (tm *TraderManager) SetCompetitionConfig(cfg config.CompetitionConfig) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.competition = cfg
+}
+
+// GetSeasons 获取所有赛季
+func (tm *TraderManager) GetSeasons(now time.Time) ([]SeasonInfo, error) {
+	tm.mu.RLock()
+	seasons := tm.competition.Seasons
+	tm.mu.RUnlock()
+
+	result := make([]SeasonInfo, 0, len(seasons))
+	for _, sc := range seasons {
+		start, end, err := sc.Period()
+		if err != nil {
+			return nil, fmt.Errorf("赛季 '%s' 配置无效: %w", sc.Name, err)
+		}
+		result = append(result, SeasonInfo{
+			Name:   sc.Name,
+			Start:  start,
+			End:    end,
+			Active: !now.Before(start) && (end.IsZero() || now.Before(end)),
+		})
+	}
+	return result, nil
+}
+
+// selectSeason 选择赛季：指定名称时按名称查找；否则取进行中的赛季，没有则取最近开始的赛季
+func (tm *TraderManager) selectSeason(name string, now time.Time) (*SeasonInfo, error) {
+	seasons, err := tm.GetSeasons(now)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != "" {
+		for i := range seasons {
+			if seasons[i].Name == name {
+				return &seasons[i], nil
+			}
+		}
+		return nil, fmt.Errorf("%w: '%s'", ErrSeasonNotFound, name)
+	}
+
+	var selected *SeasonInfo
+	for i := range seasons {
+		s := &seasons[i]
+		if s.Start.After(now) {
+			continue
+		}
+		if selected == nil ||
+			(s.Active && !selected.Active) ||
+			(s.Active == selected.Active && s.Start.After(selected.Start)) {
+			selected = s
+		}
+	}
+	return selected, nil
+}
+
+// GetLeaderboard 计算竞赛排行榜
+// 排名基于各trader决策日志中的净值序列，按 interval 采样（<=0 时使用配置的采样间隔），
+// 每个采样点用截至该时刻的数据重新评分，因此排名历史可随时从日志重建
+func (tm *TraderManager) GetLeaderboard(seasonName string, interval time.Duration) (*Leaderboard, error) {
+	now := time.Now()
+	season, err := tm.selectSeason(seasonName, now)
+	if err != nil {
+		return nil, err
+	}
+
+	tm.mu.RLock()
+	scoring := tm.competition.Scoring
+	if interval <= 0 {
+		interval = tm.competition.GetRankInterval()
+	}
+	traders := make(map[string]*leaderboardTracker, len(tm.traders))
+	for id, t := range tm.traders {
+		status := t.GetStatus()
+		initialBalance, _ := status["initial_balance"].(float64)
+		isRunning, _ := status["is_running"].(bool)
+		traders[id] = &leaderboardTracker{
+			entry: LeaderboardEntry{
+				TraderID:   id,
+				TraderName: t.GetName(),
+				AIModel:    t.GetAIModel(),
+				IsRunning:  isRunning,
+			},
+			initialBalance: initialBalance,
+			decisionLogger: t.GetDecisionLogger(),
+		}
+	}
+	tm.mu.RUnlock()
+
+	// 兜底：配置未经过Validate（如手动构造）时至少按收益率排名
+	if scoring.ReturnWeight == 0 && scoring.SharpeWeight == 0 && scoring.DrawdownPenalty == 0 {
+		scoring.ReturnWeight = 1
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	entries, history, err := rankTraders(traders, season, scoring, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Leaderboard{
+		Season:    season,
+		Scoring:   scoring,
+		Interval:  interval.String(),
+		UpdatedAt: now,
+		Entries:   entries,
+		History:   history,
+	}, nil
+}
+
+// rankTraders 加载各trader在赛季内的净值序列，在每个采样点重新评分排名，返回当前排名和排名历史
+func rankTraders(traders map[string]*leaderboardTracker, season *SeasonInfo, scoring config.ScoringConfig, interval time.Duration) ([]LeaderboardEntry, []RankSnapshot, error) {
+	var from, to time.Time
+	if season != nil {
+		from, to = season.Start, season.End
+	}
+
+	// 加载净值序列并按采样间隔聚合
+	timeline := make(map[time.Time]bool)
+	for id, tracker := range traders {
+		points, err := tracker.decisionLogger.GetAccountHistory(from, to, 0)
+		if err != nil {
+			return nil, nil, fmt.Errorf("读取trader '%s' 净值历史失败: %w", id, err)
+		}
+		tracker.bars = logger.DownsampleEquity(points, interval)
+		for _, bar := range tracker.bars {
+			timeline[bar.Timestamp] = true
+		}
+
+		// 未指定赛季时以初始余额为基准（与竞赛总览的收益率一致），否则以赛季内第一个净值为基准
+		if len(tracker.bars) > 0 {
+			tracker.base = tracker.bars[0].Open
+			if season == nil && tracker.initialBalance > 0 {
+				tracker.base = tracker.initialBalance
+			}
+		}
+	}
+
+	times := make([]time.Time, 0, len(timeline))
+	for t := range timeline {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	history := make([]RankSnapshot, 0, len(times))
+	for _, ts := range times {
+		ranked := make([]*leaderboardTracker, 0, len(traders))
+		for _, tracker := range traders {
+			tracker.advance(ts)
+			if tracker.entry.Cycles > 0 {
+				tracker.score(scoring, interval)
+				ranked = append(ranked, tracker)
+			}
+		}
+		sortTrackers(ranked)
+
+		snapshot := RankSnapshot{
+			Timestamp: ts,
+			Ranks:     make(map[string]int, len(ranked)),
+			Scores:    make(map[string]float64, len(ranked)),
+		}
+		for i, tracker := range ranked {
+			rank := i + 1
+			tracker.entry.PreviousRank = tracker.entry.Rank
+			tracker.entry.Rank = rank
+			if tracker.entry.BestRank == 0 || rank < tracker.entry.BestRank {
+				tracker.entry.BestRank = rank
+			}
+			if rank > tracker.entry.WorstRank {
+				tracker.entry.WorstRank = rank
+			}
+			snapshot.Ranks[tracker.entry.TraderID] = rank
+			snapshot.Scores[tracker.entry.TraderID] = tracker.entry.Score
+		}
+		history = append(history, snapshot)
+	}
+
+	// 当前排名：有数据的trader按最终排名，没有数据的排在最后
+	all := make([]*leaderboardTracker, 0, len(traders))
+	for _, tracker := range traders {
+		all = append(all, tracker)
+	}
+	sortTrackers(all)
+
+	entries := make([]LeaderboardEntry, 0, len(all))
+	for _, tracker := range all {
+		entry := tracker.entry
+		if entry.PreviousRank > 0 {
+			entry.RankChange = entry.PreviousRank - entry.Rank
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, history, nil
+}
+
+// equityHistory 排行榜的净值序列来源（trader的决策日志）
+type equityHistory interface {
+	GetAccountHistory(from, to time.Time, limit int) ([]logger.AccountPoint, error)
+}
+
+// leaderboardTracker 逐个采样点累积单个trader的评分指标
+type leaderboardTracker struct {
+	entry          LeaderboardEntry
+	initialBalance float64
+	decisionLogger equityHistory
+	bars           []logger.EquityBar
+	next           int // 下一个待处理的K线
+
+	base       float64 // 收益率基准净值
+	peak       float64
+	lastEquity float64
+
+	// Welford算法累计采样点收益率的均值和方差
+	returns int
+	mean    float64
+	m2      float64
+}
+
+// advance 处理截至 ts（含）的所有净值K线
+func (lt *leaderboardTracker) advance(ts time.Time) {
+	for lt.next < len(lt.bars) && !lt.bars[lt.next].Timestamp.After(ts) {
+		bar := lt.bars[lt.next]
+		lt.next++
+
+		if lt.lastEquity > 0 {
+			r := (bar.Close - lt.lastEquity) / lt.lastEquity
+			lt.returns++
+			delta := r - lt.mean
+			lt.mean += delta / float64(lt.returns)
+			lt.m2 += delta * (r - lt.mean)
+		}
+		lt.lastEquity = bar.Close
+
+		// 回撤用桶内最高/最低净值，避免降采样掩盖桶内的回撤
+		if bar.High > lt.peak {
+			lt.peak = bar.High
+		}
+		if lt.peak > 0 {
+			if dd := (lt.peak - bar.Low) / lt.peak * 100; dd > lt.entry.MaxDrawdownPct {
+				lt.entry.MaxDrawdownPct = dd
+			}
+		}
+
+		lt.entry.Equity = bar.Close
+		lt.entry.Cycles += bar.Samples
+		lt.entry.LastUpdate = bar.Timestamp
+	}
+}
+
+// score 根据评分规则计算当前得分
+func (lt *leaderboardTracker) score(rules config.ScoringConfig, interval time.Duration) {
+	lt.entry.StartEquity = lt.base
+	if lt.base > 0 {
+		lt.entry.ReturnPct = (lt.entry.Equity - lt.base) / lt.base * 100
+	}
+
+	lt.entry.SharpeRatio = 0
+	if lt.returns > 1 {
+		stdDev := math.Sqrt(lt.m2 / float64(lt.returns))
+		lt.entry.SharpeRatio = logger.AnnualizeSharpe(lt.mean, stdDev, interval)
+	}
+
+	lt.entry.Score = lt.entry.ReturnPct*rules.ReturnWeight +
+		lt.entry.SharpeRatio*rules.SharpeWeight -
+		lt.entry.MaxDrawdownPct*rules.DrawdownPenalty
+}
+
+// sortTrackers 按得分降序排列（没有数据的排在最后，同分按收益率、trader ID排序保证稳定）
+func sortTrackers(trackers []*leaderboardTracker) {
+	sort.Slice(trackers, func(i, j int) bool {
+		a, b := trackers[i].entry, trackers[j].entry
+		if (a.Cycles > 0) != (b.Cycles > 0) {
+			return a.Cycles > 0
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.ReturnPct != b.ReturnPct {
+			return a.ReturnPct > b.ReturnPct
+		}
+		return a.TraderID < b.TraderID
+	})
+}
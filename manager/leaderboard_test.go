@@ -0,0 +1,262 @@
+package manager
+
+import (
+	"errors"
+	"math"
+	"nofx/config"
+	"nofx/logger"
+	"testing"
+	"time"
+)
+
+var leaderboardStart = time.Unix(1700000000, 0).Truncate(time.Hour)
+
+// fakeHistory 按 [from, to) 过滤的内存净值序列
+type fakeHistory struct {
+	points []logger.AccountPoint
+	err    error
+}
+
+func (f *fakeHistory) GetAccountHistory(from, to time.Time, limit int) ([]logger.AccountPoint, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var result []logger.AccountPoint
+	for _, p := range f.points {
+		if (!from.IsZero() && p.Timestamp.Before(from)) || (!to.IsZero() && !p.Timestamp.Before(to)) {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// hourlyTracker 构造每小时一个净值快照的trader
+func hourlyTracker(id string, initialBalance float64, equities ...float64) *leaderboardTracker {
+	points := make([]logger.AccountPoint, len(equities))
+	for i, equity := range equities {
+		points[i] = logger.AccountPoint{
+			Timestamp:   leaderboardStart.Add(time.Duration(i) * time.Hour),
+			CycleNumber: i + 1,
+			Account:     logger.AccountSnapshot{TotalBalance: equity},
+		}
+	}
+	return &leaderboardTracker{
+		entry:          LeaderboardEntry{TraderID: id},
+		initialBalance: initialBalance,
+		decisionLogger: &fakeHistory{points: points},
+	}
+}
+
+func TestRankTraders(t *testing.T) {
+	returnOnly := config.ScoringConfig{ReturnWeight: 1}
+
+	type want struct {
+		id         string
+		rank       int
+		rankChange int
+		returnPct  float64
+		score      float64
+	}
+
+	tests := []struct {
+		name    string
+		traders []*leaderboardTracker // 第0小时各trader同分，按ID排名
+		season  *SeasonInfo
+		scoring config.ScoringConfig
+		want    []want
+	}{
+		{
+			name: "按得分降序",
+			traders: []*leaderboardTracker{
+				hourlyTracker("a", 100, 100, 110),
+				hourlyTracker("b", 100, 100, 120),
+				hourlyTracker("c", 100, 100, 105),
+			},
+			scoring: returnOnly,
+			want: []want{
+				{id: "b", rank: 1, rankChange: 1, returnPct: 20, score: 20},
+				{id: "a", rank: 2, rankChange: -1, returnPct: 10, score: 10},
+				{id: "c", rank: 3, returnPct: 5, score: 5},
+			},
+		},
+		{
+			// 只扣回撤分时都没有回撤，得分相同：先比收益率，再按trader ID
+			name: "同分按收益率和ID排序",
+			traders: []*leaderboardTracker{
+				hourlyTracker("d", 100, 100, 110),
+				hourlyTracker("b", 100, 100, 120),
+				hourlyTracker("c", 100, 100, 110),
+			},
+			scoring: config.ScoringConfig{DrawdownPenalty: 1},
+			want: []want{
+				{id: "b", rank: 1, returnPct: 20},
+				{id: "c", rank: 2, returnPct: 10},
+				{id: "d", rank: 3, returnPct: 10},
+			},
+		},
+		{
+			// a 收益10%但从150回撤到110（26.67%），b 收益5%没有回撤
+			name: "回撤惩罚",
+			traders: []*leaderboardTracker{
+				hourlyTracker("a", 100, 100, 150, 110),
+				hourlyTracker("b", 100, 100, 105, 105),
+			},
+			scoring: config.ScoringConfig{ReturnWeight: 1, DrawdownPenalty: 1},
+			want: []want{
+				{id: "b", rank: 1, rankChange: 1, returnPct: 5, score: 5},
+				{id: "a", rank: 2, rankChange: -1, returnPct: 10, score: 10 - 40.0/150*100},
+			},
+		},
+		{
+			// 第0小时同分按ID a第一；第2小时b反超
+			name: "名次变化相对上一个采样点",
+			traders: []*leaderboardTracker{
+				hourlyTracker("a", 100, 100, 120, 120),
+				hourlyTracker("b", 100, 100, 110, 130),
+			},
+			scoring: returnOnly,
+			want: []want{
+				{id: "b", rank: 1, rankChange: 1, returnPct: 30, score: 30},
+				{id: "a", rank: 2, rankChange: -1, returnPct: 20, score: 20},
+			},
+		},
+		{
+			name: "没有数据的排在最后",
+			traders: []*leaderboardTracker{
+				hourlyTracker("a", 100),
+				hourlyTracker("z", 100, 100, 90),
+			},
+			scoring: returnOnly,
+			want: []want{
+				{id: "z", rank: 1, returnPct: -10, score: -10},
+				{id: "a"},
+			},
+		},
+		{
+			name: "未指定赛季以初始余额为基准",
+			traders: []*leaderboardTracker{
+				hourlyTracker("a", 50, 100, 110),
+			},
+			scoring: returnOnly,
+			want: []want{
+				{id: "a", rank: 1, returnPct: 120, score: 120},
+			},
+		},
+		{
+			// 赛季为第1到第3小时（不含）：只用200、210，以赛季内第一个净值为基准
+			name: "按赛季截取净值序列",
+			traders: []*leaderboardTracker{
+				hourlyTracker("a", 50, 100, 200, 210, 220, 500),
+				hourlyTracker("b", 50, 100, 100, 220),
+			},
+			season: &SeasonInfo{
+				Name:  "s1",
+				Start: leaderboardStart.Add(time.Hour),
+				End:   leaderboardStart.Add(3 * time.Hour),
+			},
+			scoring: returnOnly,
+			want: []want{
+				{id: "b", rank: 1, rankChange: 1, returnPct: 120, score: 120},
+				{id: "a", rank: 2, rankChange: -1, returnPct: 5, score: 5},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traders := make(map[string]*leaderboardTracker, len(tt.traders))
+			for _, tracker := range tt.traders {
+				traders[tracker.entry.TraderID] = tracker
+			}
+
+			entries, history, err := rankTraders(traders, tt.season, tt.scoring, time.Hour)
+			if err != nil {
+				t.Fatalf("rankTraders() error = %v", err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("len(entries) = %d, want %d", len(entries), len(tt.want))
+			}
+			for i, w := range tt.want {
+				e := entries[i]
+				if e.TraderID != w.id || e.Rank != w.rank || e.RankChange != w.rankChange {
+					t.Errorf("entries[%d] = %s rank %d change %d, want %s rank %d change %d",
+						i, e.TraderID, e.Rank, e.RankChange, w.id, w.rank, w.rankChange)
+				}
+				if math.Abs(e.ReturnPct-w.returnPct) > 1e-9 || math.Abs(e.Score-w.score) > 1e-9 {
+					t.Errorf("entries[%d] (%s) return, score = %v, %v, want %v, %v",
+						i, e.TraderID, e.ReturnPct, e.Score, w.returnPct, w.score)
+				}
+			}
+
+			// 排名历史的最后一个采样点与当前排名一致
+			if len(history) > 0 {
+				last := history[len(history)-1]
+				for _, e := range entries {
+					if last.Ranks[e.TraderID] != e.Rank {
+						t.Errorf("history rank of %s = %d, want %d", e.TraderID, last.Ranks[e.TraderID], e.Rank)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRankTradersHistoryError(t *testing.T) {
+	errRead := errors.New("磁盘错误")
+	traders := map[string]*leaderboardTracker{
+		"a": {entry: LeaderboardEntry{TraderID: "a"}, decisionLogger: &fakeHistory{err: errRead}},
+	}
+
+	if _, _, err := rankTraders(traders, nil, config.ScoringConfig{ReturnWeight: 1}, time.Hour); !errors.Is(err, errRead) {
+		t.Errorf("rankTraders() error = %v, want %v", err, errRead)
+	}
+}
+
+func TestGetLeaderboardSeason(t *testing.T) {
+	seasons := []config.SeasonConfig{
+		{Name: "s1", Start: "2024-01-01T00:00:00Z", End: "2024-02-01T00:00:00Z"},
+		{Name: "s2", Start: "2024-02-01T00:00:00Z"},
+	}
+
+	tests := []struct {
+		name         string
+		seasons      []config.SeasonConfig
+		season       string
+		wantSeason   string
+		wantNotFound bool
+		wantErr      bool
+	}{
+		{name: "未配置赛季", wantSeason: ""},
+		{name: "默认取进行中的赛季", seasons: seasons, wantSeason: "s2"},
+		{name: "指定赛季", seasons: seasons, season: "s1", wantSeason: "s1"},
+		{name: "赛季不存在", seasons: seasons, season: "s3", wantErr: true, wantNotFound: true},
+		{name: "赛季配置无效", seasons: []config.SeasonConfig{{Name: "bad", Start: "yesterday"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := NewTraderManager()
+			tm.SetCompetitionConfig(config.CompetitionConfig{Seasons: tt.seasons})
+
+			lb, err := tm.GetLeaderboard(tt.season, time.Hour)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetLeaderboard(%q) error = %v, wantErr %v", tt.season, err, tt.wantErr)
+			}
+			if errors.Is(err, ErrSeasonNotFound) != tt.wantNotFound {
+				t.Errorf("errors.Is(%v, ErrSeasonNotFound) = %v, want %v", err, !tt.wantNotFound, tt.wantNotFound)
+			}
+			if err != nil {
+				return
+			}
+
+			got := ""
+			if lb.Season != nil {
+				got = lb.Season.Name
+			}
+			if got != tt.wantSeason {
+				t.Errorf("season = %q, want %q", got, tt.wantSeason)
+			}
+		})
+	}
+}
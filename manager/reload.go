@@ -0,0 +1,403 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"nofx/config"
+	"nofx/pool"
+	"nofx/trader"
+	"os"
+	"strings"
+	"time"
+)
+
+// ReloadResult 配置热加载结果
+type ReloadResult struct {
+	Added     []string            `json:"added"`     // 新增并启动的trader
+	Removed   []string            `json:"removed"`   // 已停止并移除的trader
+	Rebuilt   []string            `json:"rebuilt"`   // 凭证/交易所/模型等变更，已重建的trader
+	Updated   map[string][]string `json:"updated"`   // 热更新的trader及变更项（在周期之间生效）
+	Global    []string            `json:"global"`    // 已应用的全局配置变更
+	Warnings  []string            `json:"warnings"`  // 未应用或部分失败的变更
+	Unchanged bool                `json:"unchanged"` // 配置无变化
+}
+
+// SetConfig 记录当前生效的配置和配置文件路径（热加载时以此为基准对比差异）
+func (tm *TraderManager) SetConfig(path string, cfg *config.Config) {
+	tm.reloadMu.Lock()
+	defer tm.reloadMu.Unlock()
+	tm.configPath = path
+	tm.config = cfg
+}
+
+// OnConfigApplied 注册配置生效后的回调（如API服务器更新认证和CORS设置）
+func (tm *TraderManager) OnConfigApplied(fn func(cfg *config.Config)) {
+	tm.reloadMu.Lock()
+	defer tm.reloadMu.Unlock()
+	tm.reloadHooks = append(tm.reloadHooks, fn)
+}
+
+// ReloadConfig 重新读取配置文件并应用；配置无效时返回错误，运行中的trader不受影响
+func (tm *TraderManager) ReloadConfig() (*ReloadResult, error) {
+	tm.reloadMu.Lock()
+	path := tm.configPath
+	tm.reloadMu.Unlock()
+	if path == "" {
+		return nil, fmt.Errorf("未设置配置文件路径")
+	}
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return tm.ApplyConfig(cfg)
+}
+
+// ApplyConfig 对比新配置与当前配置，增删trader、重建凭证变更的trader并热更新其余参数
+func (tm *TraderManager) ApplyConfig(cfg *config.Config) (*ReloadResult, error) {
+	tm.reloadMu.Lock()
+	defer tm.reloadMu.Unlock()
+
+	if tm.config == nil {
+		return nil, fmt.Errorf("当前配置未初始化")
+	}
+
+	enabled := make(map[string]config.TraderConfig)
+	for _, tc := range cfg.Traders {
+		if tc.Enabled {
+			enabled[tc.ID] = tc
+		}
+	}
+	if len(enabled) == 0 {
+		return nil, fmt.Errorf("新配置中没有启用的trader")
+	}
+
+	old := tm.config
+	oldTraders := make(map[string]config.TraderConfig)
+	for _, tc := range old.Traders {
+		if tc.Enabled {
+			oldTraders[tc.ID] = tc
+		}
+	}
+
+	result := &ReloadResult{Updated: make(map[string][]string)}
+	tm.applyGlobalConfig(old, cfg, result)
+
+	// 移除已删除或禁用的trader
+	for id := range oldTraders {
+		if _, ok := enabled[id]; ok {
+			continue
+		}
+		if err := tm.RemoveTrader(id); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("移除trader '%s' 失败: %v", id, err))
+			continue
+		}
+		result.Removed = append(result.Removed, id)
+	}
+
+	for id, tc := range enabled {
+		newCfg := buildTraderConfig(tc, cfg.CoinPoolAPIURL, cfg.MaxDailyLoss, cfg.MaxDrawdown, cfg.StopTradingMinutes, cfg.Leverage, cfg)
+
+		at, err := tm.GetTrader(id)
+		if err != nil {
+			// 新增trader
+			if err := tm.AddTrader(tc, cfg.CoinPoolAPIURL, cfg.MaxDailyLoss, cfg.MaxDrawdown, cfg.StopTradingMinutes, cfg.Leverage, cfg); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("添加trader '%s' 失败: %v", id, err))
+				continue
+			}
+			if tm.isStarted() {
+				if err := tm.StartTrader(id); err != nil {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("启动trader '%s' 失败: %v", id, err))
+				}
+			}
+			result.Added = append(result.Added, id)
+			continue
+		}
+
+		// 以上一次生效的配置为基准对比，避免覆盖通过控制面API做的运行时调整
+		prevTC, existed := oldTraders[id]
+		if !existed {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("trader '%s' 不在上一次生效的配置中，跳过", id))
+			continue
+		}
+		prevCfg := buildTraderConfig(prevTC, old.CoinPoolAPIURL, old.MaxDailyLoss, old.MaxDrawdown, old.StopTradingMinutes, old.Leverage, old)
+		fields, rebuild := trader.DiffConfig(prevCfg, newCfg)
+
+		if rebuild {
+			if err := tm.rebuildTrader(at, newCfg); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("重建trader '%s' 失败，继续使用旧配置: %v", id, err))
+				continue
+			}
+			result.Rebuilt = append(result.Rebuilt, id)
+			continue
+		}
+		if len(fields) > 0 {
+			at.UpdateConfig(newCfg, fields)
+			result.Updated[id] = fields
+		}
+	}
+
+	if cfg.APIServerPort != old.APIServerPort {
+		result.Warnings = append(result.Warnings, "api_server_port 变更需要重启进程才能生效")
+	}
+
+	tm.config = cfg
+	for _, hook := range tm.reloadHooks {
+		hook(cfg)
+	}
+
+	result.Unchanged = len(result.Added) == 0 && len(result.Removed) == 0 && len(result.Rebuilt) == 0 &&
+		len(result.Updated) == 0 && len(result.Global) == 0 && len(result.Warnings) == 0
+	log.Printf("🔄 配置已重新加载: 新增%d 移除%d 重建%d 热更新%d 警告%d",
+		len(result.Added), len(result.Removed), len(result.Rebuilt), len(result.Updated), len(result.Warnings))
+	for _, w := range result.Warnings {
+		log.Printf("⚠️  %s", w)
+	}
+	return result, nil
+}
+
+// applyGlobalConfig 应用币种池、竞赛等全局配置
+func (tm *TraderManager) applyGlobalConfig(old, cfg *config.Config, result *ReloadResult) {
+	if strings.Join(old.DefaultCoins, ",") != strings.Join(cfg.DefaultCoins, ",") {
+		pool.SetDefaultCoins(cfg.DefaultCoins)
+		result.Global = append(result.Global, "default_coins")
+	}
+	if old.UseDefaultCoins != cfg.UseDefaultCoins {
+		pool.SetUseDefaultCoins(cfg.UseDefaultCoins)
+		result.Global = append(result.Global, "use_default_coins")
+	}
+	if old.CoinPoolAPIURL != cfg.CoinPoolAPIURL {
+		pool.SetCoinPoolAPI(cfg.CoinPoolAPIURL)
+		result.Global = append(result.Global, "coin_pool_api_url")
+	}
+	if old.OITopAPIURL != cfg.OITopAPIURL {
+		pool.SetOITopAPI(cfg.OITopAPIURL)
+		result.Global = append(result.Global, "oi_top_api_url")
+	}
+	if fmt.Sprintf("%+v", old.Competition) != fmt.Sprintf("%+v", cfg.Competition) {
+		tm.SetCompetitionConfig(cfg.Competition)
+		result.Global = append(result.Global, "competition")
+	}
+	if fmt.Sprintf("%+v", old.APIAuth) != fmt.Sprintf("%+v", cfg.APIAuth) ||
+		strings.Join(old.CORSAllowedOrigins, ",") != strings.Join(cfg.CORSAllowedOrigins, ",") {
+		result.Global = append(result.Global, "api_auth/cors_allowed_origins")
+	}
+}
+
+// rebuildTrader 用新配置重建trader并恢复运行状态
+func (tm *TraderManager) rebuildTrader(at *trader.AutoTrader, cfg trader.AutoTraderConfig) error {
+	wasRunning := at.IsRunning()
+	next, err := at.Rebuild(cfg)
+	if err != nil {
+		if wasRunning {
+			startTrader(at)
+		}
+		return err
+	}
+
+	tm.mu.Lock()
+	if cancel, ok := tm.unsubscribe[cfg.ID]; ok {
+		cancel()
+	}
+	tm.traders[cfg.ID] = next
+	tm.forwardEvents(next)
+	tm.mu.Unlock()
+
+	// 新实例已接管请求后再关闭旧实例的决策日志
+	if err := at.CloseLogger(); err != nil {
+		log.Printf("⚠ 关闭旧决策日志失败: %v", err)
+	}
+
+	if wasRunning {
+		startTrader(next)
+	}
+	log.Printf("♻️  Trader '%s' 已按新配置重建", cfg.Name)
+	return nil
+}
+
+// RemoveTrader 停止并移除trader（等待正在执行的周期结束，不会平仓）
+func (tm *TraderManager) RemoveTrader(id string) error {
+	tm.mu.Lock()
+	at, exists := tm.traders[id]
+	if !exists {
+		tm.mu.Unlock()
+		return fmt.Errorf("trader ID '%s' 不存在", id)
+	}
+	delete(tm.traders, id)
+	if cancel, ok := tm.unsubscribe[id]; ok {
+		cancel()
+		delete(tm.unsubscribe, id)
+	}
+	tm.mu.Unlock()
+
+	// 在锁外等待周期结束，避免阻塞其他API请求
+	if err := at.Shutdown(); err != nil {
+		log.Printf("⚠ 关闭trader '%s' 的决策日志失败: %v", id, err)
+	}
+	log.Printf("✓ Trader '%s' 已移除", at.GetName())
+	return nil
+}
+
+var (
+	// ErrTraderNotFound 没有该ID的trader
+	ErrTraderNotFound = errors.New("trader ID不存在")
+	// ErrTraderExists trader ID已被占用（运行中或配置文件中已存在）
+	ErrTraderExists = errors.New("trader ID已存在")
+	// ErrInvalidTrader trader配置无效或密钥引用无法解析
+	ErrInvalidTrader = errors.New("trader配置无效")
+	// ErrLastTrader 不能删除最后一个启用的trader（配置文件至少需要一个trader）
+	ErrLastTrader = errors.New("不能删除最后一个启用的trader")
+	// ErrIndexedOverride 有按下标引用该trader或其后trader的覆盖，删除后这些覆盖会落到其他trader上
+	ErrIndexedOverride = errors.New("存在按下标引用trader的配置覆盖，请改用 NOFX_TRADERS_<ID>_* 或 -set traders.<ID>.*")
+)
+
+// CreateTrader 按与配置文件相同的规则校验trader配置，创建并启动trader，然后写回配置文件
+// 杠杆、风控、币种池等沿用当前生效的全局配置；写回失败时撤销创建
+// 密钥可以使用 env:/file:/keystore: 引用，配置文件中保存引用本身
+func (tm *TraderManager) CreateTrader(tc config.TraderConfig) error {
+	tm.reloadMu.Lock()
+	defer tm.reloadMu.Unlock()
+
+	if tm.config == nil {
+		return fmt.Errorf("当前配置未初始化")
+	}
+	tc.Enabled = true
+	persisted := tc.Clone()
+	if err := tm.config.ResolveTraderSecrets(&tc); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTrader, err)
+	}
+	if err := tc.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTrader, err)
+	}
+	if err := tm.config.CheckExperiment(tc.Experiment); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTrader, err)
+	}
+	persisted.Exchange = tc.Exchange
+	persisted.ScanIntervalMinutes = tc.ScanIntervalMinutes
+	for _, existing := range tm.config.Traders {
+		if existing.ID == tc.ID {
+			return fmt.Errorf("%w: '%s'", ErrTraderExists, tc.ID)
+		}
+	}
+	if _, err := tm.GetTrader(tc.ID); err == nil {
+		return fmt.Errorf("%w: '%s'", ErrTraderExists, tc.ID)
+	}
+
+	cfg := tm.config
+	if err := tm.AddTrader(tc, cfg.CoinPoolAPIURL, cfg.MaxDailyLoss, cfg.MaxDrawdown, cfg.StopTradingMinutes, cfg.Leverage, cfg); err != nil {
+		return err
+	}
+	if tm.configPath != "" {
+		if err := config.AppendTrader(tm.configPath, persisted); err != nil {
+			tm.RemoveTrader(tc.ID)
+			return fmt.Errorf("保存配置文件失败: %w", err)
+		}
+	}
+
+	// 同步更新生效配置，配置文件变化触发的热加载不会重复添加
+	next := *cfg
+	next.Traders = append(append([]config.TraderConfig(nil), cfg.Traders...), tc)
+	tm.config = &next
+
+	if tm.isStarted() {
+		if err := tm.StartTrader(tc.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteTrader 停止并移除trader，同时从配置文件中删除（不会平仓，已有持仓需另行处理）
+func (tm *TraderManager) DeleteTrader(id string) error {
+	tm.reloadMu.Lock()
+	defer tm.reloadMu.Unlock()
+
+	if _, err := tm.GetTrader(id); err != nil {
+		return err
+	}
+	if tm.config != nil {
+		remaining, position := 0, -1
+		for i, tc := range tm.config.Traders {
+			if tc.ID == id {
+				position = i
+			} else if tc.Enabled {
+				remaining++
+			}
+		}
+		if remaining == 0 {
+			return ErrLastTrader
+		}
+		if position >= 0 {
+			if sources := config.IndexedTraderOverrides(position); len(sources) > 0 {
+				return fmt.Errorf("%w: %s", ErrIndexedOverride, strings.Join(sources, ", "))
+			}
+		}
+	}
+	if tm.configPath != "" {
+		if err := config.RemoveTraderFromFile(tm.configPath, id); err != nil {
+			return fmt.Errorf("保存配置文件失败: %w", err)
+		}
+	}
+	if err := tm.RemoveTrader(id); err != nil {
+		return err
+	}
+
+	if tm.config != nil {
+		next := *tm.config
+		next.Traders = make([]config.TraderConfig, 0, len(tm.config.Traders))
+		for _, tc := range tm.config.Traders {
+			if tc.ID != id {
+				next.Traders = append(next.Traders, tc)
+			}
+		}
+		tm.config = &next
+	}
+	return nil
+}
+
+// isStarted 是否已调用过StartAll（热加载新增的trader是否需要自动启动）
+func (tm *TraderManager) isStarted() bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.started
+}
+
+// WatchConfig 轮询配置文件，内容变化时自动热加载（阻塞直到stop关闭）
+func (tm *TraderManager) WatchConfig(interval time.Duration, stop <-chan struct{}) {
+	tm.reloadMu.Lock()
+	path := tm.configPath
+	tm.reloadMu.Unlock()
+
+	lastSum := fileChecksum(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			sum := fileChecksum(path)
+			if sum == "" || sum == lastSum {
+				continue
+			}
+			lastSum = sum
+
+			log.Printf("📋 检测到配置文件变化: %s", path)
+			if _, err := tm.ReloadConfig(); err != nil {
+				log.Printf("❌ 配置热加载失败，继续使用当前配置: %v", err)
+			}
+		}
+	}
+}
+
+// fileChecksum 文件内容的SHA256（读取失败返回空字符串）
+func fileChecksum(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"nofx/config"
+	"nofx/logger"
+	"nofx/mcp"
 	"nofx/trader"
 	"sync"
 	"time"
@@ -11,17 +13,45 @@ import (
 
 // TraderManager 管理多个trader实例
 type TraderManager struct {
-	traders map[string]*trader.AutoTrader // key: trader ID
-	mu      sync.RWMutex
+	traders     map[string]*trader.AutoTrader // key: trader ID
+	competition config.CompetitionConfig      // 排行榜评分规则和赛季
+	events      *trader.EventBus              // 汇总所有trader事件的竞赛事件总线
+	unsubscribe map[string]func()             // 各trader事件转发的取消函数
+	started     bool                          // 是否已调用StartAll
+	mu          sync.RWMutex
+
+	// 配置热加载
+	reloadMu    sync.Mutex                 // 串行化配置热加载
+	configPath  string                     // 配置文件路径
+	config      *config.Config             // 上一次生效的配置
+	reloadHooks []func(cfg *config.Config) // 配置生效后的回调
 }
 
 // NewTraderManager 创建trader管理器
 func NewTraderManager() *TraderManager {
 	return &TraderManager{
-		traders: make(map[string]*trader.AutoTrader),
+		traders:     make(map[string]*trader.AutoTrader),
+		events:      trader.NewEventBus(),
+		unsubscribe: make(map[string]func()),
 	}
 }
 
+// GetEventBus 获取竞赛事件总线（包含所有trader的事件）
+func (tm *TraderManager) GetEventBus() *trader.EventBus {
+	return tm.events
+}
+
+// forwardEvents 将trader事件转发到竞赛事件总线（调用方需持有写锁）
+func (tm *TraderManager) forwardEvents(at *trader.AutoTrader) {
+	ch, cancel := at.GetEventBus().Subscribe(256, 0)
+	tm.unsubscribe[at.GetID()] = cancel
+	go func() {
+		for e := range ch {
+			tm.events.Publish(e)
+		}
+	}()
+}
+
 // AddTrader 添加一个trader
 func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string, maxDailyLoss, maxDrawdown float64, stopTradingMinutes int, leverage config.LeverageConfig, fullConfig *config.Config) error {
 	tm.mu.Lock()
@@ -31,8 +61,44 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		return fmt.Errorf("trader ID '%s' 已存在", cfg.ID)
 	}
 
-	// 构建AutoTraderConfig
-	traderConfig := trader.AutoTraderConfig{
+	traderConfig := buildTraderConfig(cfg, coinPoolURL, maxDailyLoss, maxDrawdown, stopTradingMinutes, leverage, fullConfig)
+
+	// 创建trader实例
+	at, err := trader.NewAutoTrader(traderConfig)
+	if err != nil {
+		return fmt.Errorf("创建trader失败: %w", err)
+	}
+
+	tm.traders[cfg.ID] = at
+	tm.forwardEvents(at)
+	log.Printf("✓ Trader '%s' (%s) 已添加", cfg.Name, cfg.AIModel)
+	return nil
+}
+
+// ModelClients 按配置文件中的trader配置创建其全部AI客户端（主模型、备用模型和投票模型），用于重放
+func ModelClients(cfg config.TraderConfig, fullConfig *config.Config) map[string]*mcp.Client {
+	traderConfig := buildTraderConfig(cfg, fullConfig.CoinPoolAPIURL, fullConfig.MaxDailyLoss, fullConfig.MaxDrawdown, fullConfig.StopTradingMinutes, fullConfig.Leverage, fullConfig)
+	return trader.ModelClients(traderConfig)
+}
+
+// buildTraderConfig 由配置文件中的trader配置和全局配置构建AutoTraderConfig
+// trader级的杠杆、风控和白名单设置优先，未设置时使用全局值
+func buildTraderConfig(cfg config.TraderConfig, coinPoolURL string, maxDailyLoss, maxDrawdown float64, stopTradingMinutes int, leverage config.LeverageConfig, fullConfig *config.Config) trader.AutoTraderConfig {
+	leverage = cfg.EffectiveLeverage(leverage)
+	maxDailyLoss, maxDrawdown, stopTradingMinutes = cfg.EffectiveRiskLimits(maxDailyLoss, maxDrawdown, stopTradingMinutes)
+	whitelist := cfg.EffectiveWhitelist(fullConfig.GetWhitelistCoins())
+
+	var ensembleMode string
+	var ensemblePrimaryWeight float64
+	var ensembleModels []trader.ModelConfig
+	if cfg.Ensemble != nil {
+		ensembleMode = cfg.Ensemble.Mode
+		ensemblePrimaryWeight = cfg.Ensemble.PrimaryWeight
+		ensembleModels = toTraderModels(cfg.Ensemble.Models)
+	}
+	experiment, variants := resolvePromptVariants(cfg, fullConfig)
+
+	return trader.AutoTraderConfig{
 		ID:                    cfg.ID,
 		Name:                  cfg.Name,
 		AIModel:               cfg.AIModel,
@@ -52,26 +118,89 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		CustomAPIURL:          cfg.CustomAPIURL,
 		CustomAPIKey:          cfg.CustomAPIKey,
 		CustomModelName:       cfg.CustomModelName,
+		ContextWindow:         cfg.ContextWindow,
+		MaxTokens:             cfg.MaxTokens,
+		EnsembleMode:          ensembleMode,
+		EnsemblePrimaryWeight: ensemblePrimaryWeight,
+		EnsembleModels:        ensembleModels,
+		FallbackModels:        toTraderModels(cfg.FallbackModels),
+		PromptTemplate:        cfg.PromptTemplate,
+		PromptLanguage:        cfg.EffectivePromptLanguage(fullConfig.PromptLanguage),
+		Experiment:            experiment,
+		PromptVariants:        variants,
 		ScanInterval:          cfg.GetScanInterval(),
 		InitialBalance:        cfg.InitialBalance,
-		BTCETHLeverage:        leverage.BTCETHLeverage,         // 使用配置的杠杆倍数
-		AltcoinLeverage:       leverage.AltcoinLeverage,        // 使用配置的杠杆倍数
-		CoinWhitelistEnabled:  fullConfig.IsWhitelistEnabled(), // 币种白名单配置（DefaultCoins 不为空时自动启用）
-		CoinWhitelist:         fullConfig.GetWhitelistCoins(),  // 币种白名单列表（使用 DefaultCoins）
+		BTCETHLeverage:        leverage.BTCETHLeverage,  // 使用配置的杠杆倍数
+		AltcoinLeverage:       leverage.AltcoinLeverage, // 使用配置的杠杆倍数
+		CoinWhitelistEnabled:  len(whitelist) > 0,       // 币种白名单配置（DefaultCoins 不为空时自动启用）
+		CoinWhitelist:         whitelist,                // 币种白名单列表（trader级 default_coins 优先）
 		MaxDailyLoss:          maxDailyLoss,
 		MaxDrawdown:           maxDrawdown,
 		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
+		SafeMode:              toTraderSafeMode(cfg.EffectiveSafeMode(fullConfig.SafeMode)),
+		Memory:                toTraderMemory(cfg.EffectiveMemory(fullConfig.Memory)),
+		Reflection:            toTraderReflection(cfg.EffectiveReflection(fullConfig.Reflection)),
+		Tools:                 toTraderTools(cfg.EffectiveTools(fullConfig.Tools)),
 	}
+}
 
-	// 创建trader实例
-	at, err := trader.NewAutoTrader(traderConfig)
-	if err != nil {
-		return fmt.Errorf("创建trader失败: %w", err)
+// toTraderTools 转换工具调用配置
+func toTraderTools(tc config.ToolsConfig) trader.ToolsConfig {
+	return trader.ToolsConfig{
+		Enabled:  tc.Enabled,
+		MaxCalls: tc.MaxCalls,
 	}
+}
 
-	tm.traders[cfg.ID] = at
-	log.Printf("✓ Trader '%s' (%s) 已添加", cfg.Name, cfg.AIModel)
-	return nil
+// toTraderReflection 转换交易复盘配置
+func toTraderReflection(rc config.ReflectionConfig) trader.ReflectionConfig {
+	return trader.ReflectionConfig{
+		Enabled:     rc.Enabled,
+		MaxPerCycle: rc.MaxPerCycle,
+		MaxChars:    rc.MaxChars,
+		Lessons:     rc.Lessons,
+	}
+}
+
+// toTraderMemory 转换跨周期记忆配置
+func toTraderMemory(mc config.MemoryConfig) trader.MemoryConfig {
+	return trader.MemoryConfig{
+		Enabled:         mc.Enabled,
+		Cycles:          mc.Cycles,
+		MaxCoTChars:     mc.MaxCoTChars,
+		MaxJournalChars: mc.MaxJournalChars,
+	}
+}
+
+// toTraderSafeMode 转换安全模式配置
+func toTraderSafeMode(sm config.SafeModeConfig) trader.SafeModeConfig {
+	return trader.SafeModeConfig{
+		Enabled:        sm.Enabled,
+		AfterFailures:  sm.AfterFailures,
+		FlattenAll:     sm.FlattenAll,
+		MaxAdversePct:  sm.MaxAdversePct,
+		MaxHoldHours:   sm.MaxHoldHours,
+		TightenStopPct: sm.TightenStopPct,
+	}
+}
+
+// toTraderModels 转换投票/备用模型配置
+func toTraderModels(models []config.ModelConfig) []trader.ModelConfig {
+	var result []trader.ModelConfig
+	for _, m := range models {
+		result = append(result, trader.ModelConfig{
+			Name:      m.Name,
+			AIModel:   m.AIModel,
+			APIKey:    m.APIKey,
+			APIURL:    m.APIURL,
+			ModelName: m.ModelName,
+			Weight:    m.Weight,
+
+			ContextWindow: m.ContextWindow,
+			MaxTokens:     m.MaxTokens,
+		})
+	}
+	return result
 }
 
 // GetTrader 获取指定ID的trader
@@ -81,7 +210,7 @@ func (tm *TraderManager) GetTrader(id string) (*trader.AutoTrader, error) {
 
 	t, exists := tm.traders[id]
 	if !exists {
-		return nil, fmt.Errorf("trader ID '%s' 不存在", id)
+		return nil, fmt.Errorf("%w: '%s'", ErrTraderNotFound, id)
 	}
 	return t, nil
 }
@@ -112,17 +241,13 @@ func (tm *TraderManager) GetTraderIDs() []string {
 
 // StartAll 启动所有trader
 func (tm *TraderManager) StartAll() {
-	tm.mu.RLock()
-	defer tm.mu.RUnlock()
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 
+	tm.started = true
 	log.Println("🚀 启动所有Trader...")
-	for id, t := range tm.traders {
-		go func(traderID string, at *trader.AutoTrader) {
-			log.Printf("▶️  启动 %s...", at.GetName())
-			if err := at.Run(); err != nil {
-				log.Printf("❌ %s 运行错误: %v", at.GetName(), err)
-			}
-		}(id, t)
+	for _, t := range tm.traders {
+		startTrader(t)
 	}
 }
 
@@ -137,6 +262,105 @@ func (tm *TraderManager) StopAll() {
 	}
 }
 
+// startTrader 在后台运行trader主循环
+func startTrader(at *trader.AutoTrader) {
+	go func() {
+		log.Printf("▶️  启动 %s...", at.GetName())
+		if err := at.Run(); err != nil {
+			log.Printf("❌ %s 运行错误: %v", at.GetName(), err)
+		}
+	}()
+}
+
+// StartTrader 启动指定trader
+func (tm *TraderManager) StartTrader(id string) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if t.IsRunning() {
+		return fmt.Errorf("%w: trader '%s' 已在运行中", trader.ErrStateConflict, id)
+	}
+	startTrader(t)
+	return nil
+}
+
+// StopTrader 停止指定trader
+func (tm *TraderManager) StopTrader(id string) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	if !t.IsRunning() {
+		return fmt.Errorf("%w: trader '%s' 未运行", trader.ErrStateConflict, id)
+	}
+	t.Stop()
+	return nil
+}
+
+// PauseTrader 暂停指定trader（跳过定时周期，保留持仓）
+func (tm *TraderManager) PauseTrader(id string) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	return t.Pause()
+}
+
+// ResumeTrader 恢复指定trader
+func (tm *TraderManager) ResumeTrader(id string) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	return t.Resume()
+}
+
+// TriggerCycle 立即触发指定trader的交易周期
+func (tm *TraderManager) TriggerCycle(id string) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	return t.TriggerCycle()
+}
+
+// ResetMemory 重置指定trader的跨周期记忆
+func (tm *TraderManager) ResetMemory(id string) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	return t.ResetMemory()
+}
+
+// SetScanInterval 调整指定trader的扫描间隔
+func (tm *TraderManager) SetScanInterval(id string, interval time.Duration) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	return t.SetScanInterval(interval)
+}
+
+// SetLeverageLimits 调整指定trader的杠杆上限
+func (tm *TraderManager) SetLeverageLimits(id string, btcEthLeverage, altcoinLeverage int) error {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return err
+	}
+	return t.SetLeverageLimits(btcEthLeverage, altcoinLeverage)
+}
+
+// CloseAllPositions 平掉指定trader的所有持仓
+func (tm *TraderManager) CloseAllPositions(id string) ([]logger.DecisionAction, error) {
+	t, err := tm.GetTrader(id)
+	if err != nil {
+		return nil, err
+	}
+	return t.CloseAllPositions()
+}
+
 // GetComparisonData 获取对比数据
 func (tm *TraderManager) GetComparisonData() (map[string]interface{}, error) {
 	tm.mu.RLock()
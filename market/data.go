@@ -8,8 +8,12 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// binanceHTTPClient 行情请求使用的HTTP客户端（带超时，避免Binance无响应时阻塞调用方）
+var binanceHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
 // Data 市场数据结构
 type Data struct {
 	Symbol            string
@@ -151,8 +155,17 @@ func Get(symbol string) (*Data, error) {
 func getKlines(symbol, interval string, limit int) ([]Kline, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/klines?symbol=%s&interval=%s&limit=%d",
 		symbol, interval, limit)
+	return fetchKlines(url)
+}
 
-	resp, err := http.Get(url)
+// GetKlines 获取最近 limit 根K线（按时间正序）
+func GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	return getKlines(Normalize(symbol), interval, limit)
+}
+
+// fetchKlines 请求并解析K线数据
+func fetchKlines(url string) ([]Kline, error) {
+	resp, err := binanceHTTPClient.Get(url)
 	if err != nil {
 		return nil, err
 	}
@@ -192,6 +205,45 @@ func getKlines(symbol, interval string, limit int) ([]Kline, error) {
 	return klines, nil
 }
 
+// maxKlinesPerRequest Binance单次K线请求的最大条数
+const maxKlinesPerRequest = 1500
+
+// maxKlinesRangePages GetKlinesRange 最多请求的页数，范围过大时应改用更大的K线周期
+const maxKlinesRangePages = 10
+
+// GetKlinesRange 获取时间范围内的K线（自动分页，按时间正序）
+// 超过 maxKlinesRangePages 页仍未取完时返回错误
+func GetKlinesRange(symbol, interval string, start, end time.Time) ([]Kline, error) {
+	symbol = Normalize(symbol)
+
+	var result []Kline
+	startMs := start.UnixMilli()
+	endMs := end.UnixMilli()
+	for page := 0; startMs < endMs; page++ {
+		if page >= maxKlinesRangePages {
+			return nil, fmt.Errorf("K线时间范围过大（%s周期超过%d页），请使用更大的K线周期", interval, maxKlinesRangePages)
+		}
+		url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/klines?symbol=%s&interval=%s&startTime=%d&endTime=%d&limit=%d",
+			symbol, interval, startMs, endMs, maxKlinesPerRequest)
+		klines, err := fetchKlines(url)
+		if err != nil {
+			return nil, err
+		}
+		if len(klines) == 0 {
+			break
+		}
+
+		result = append(result, klines...)
+		if len(klines) < maxKlinesPerRequest {
+			break
+		}
+		// 下一页从最后一根K线之后开始
+		startMs = klines[len(klines)-1].OpenTime + 1
+	}
+
+	return result, nil
+}
+
 // calculateEMA 计算EMA
 func calculateEMA(klines []Kline, period int) float64 {
 	if len(klines) < period {
@@ -401,7 +453,7 @@ func calculateLongerTermData(klines []Kline) *LongerTermData {
 func getOpenInterestData(symbol string) (*OIData, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/openInterest?symbol=%s", symbol)
 
-	resp, err := http.Get(url)
+	resp, err := binanceHTTPClient.Get(url)
 	if err != nil {
 		return nil, err
 	}
@@ -434,7 +486,7 @@ func getOpenInterestData(symbol string) (*OIData, error) {
 func getFundingRate(symbol string) (float64, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/premiumIndex?symbol=%s", symbol)
 
-	resp, err := http.Get(url)
+	resp, err := binanceHTTPClient.Get(url)
 	if err != nil {
 		return 0, err
 	}
@@ -463,98 +515,127 @@ func getFundingRate(symbol string) (float64, error) {
 	return rate, nil
 }
 
-// Format 格式化输出市场数据
+// Format 格式化输出市场数据（中文prompt使用的格式）
 func Format(data *Data) string {
-	var sb strings.Builder
+	return FormatLang(data, "zh")
+}
 
-	sb.WriteString(fmt.Sprintf("current_price = %.2f, current_ema20 = %.3f, current_macd = %.3f, current_rsi (7 period) = %.3f\n\n",
-		data.CurrentPrice, data.CurrentEMA20, data.CurrentMACD, data.CurrentRSI7))
+// FormatLang 按prompt语言（zh、en、ru、uk）格式化输出市场数据
+func FormatLang(data *Data, lang string) string {
+	l, ok := labelsByLanguage[lang]
+	if !ok {
+		l = labelsByLanguage["zh"]
+	}
+	var sb strings.Builder
 
-	sb.WriteString(fmt.Sprintf("In addition, here is the latest %s open interest and funding rate for perps:\n\n",
-		data.Symbol))
+	sb.WriteString(fmt.Sprintf(l.current, data.CurrentPrice, data.CurrentEMA20, data.CurrentMACD, data.CurrentRSI7))
+	sb.WriteString(fmt.Sprintf(l.oiIntro, data.Symbol))
 
 	if data.OpenInterest != nil {
-		sb.WriteString(fmt.Sprintf("Open Interest: Latest: %.2f Average: %.2f\n\n",
-			data.OpenInterest.Latest, data.OpenInterest.Average))
+		sb.WriteString(fmt.Sprintf(l.openInterest, data.OpenInterest.Latest, data.OpenInterest.Average))
 	}
 
-	sb.WriteString(fmt.Sprintf("Funding Rate: %.2e\n\n", data.FundingRate))
+	sb.WriteString(fmt.Sprintf(l.fundingRate, data.FundingRate))
 
 	// 添加OI Top数据
 	if data.OITopData != nil {
-		sb.WriteString("OI Top Data (持仓量分析):\n\n")
-		
+		sb.WriteString(l.oiTop)
+
 		if data.OITopData.Rank > 0 {
-			sb.WriteString(fmt.Sprintf("Rank: #%d\n", data.OITopData.Rank))
+			sb.WriteString(fmt.Sprintf(l.rank, data.OITopData.Rank))
 		}
-		
+
 		if data.OITopData.OIDeltaValue > 0 {
-			sb.WriteString(fmt.Sprintf("OI Value: %.2f\n", data.OITopData.OIDeltaValue))
+			sb.WriteString(fmt.Sprintf(l.oiValue, data.OITopData.OIDeltaValue))
 		}
-		
+
 		if data.OITopData.OIDeltaPercent != 0 {
-			sb.WriteString(fmt.Sprintf("OI Change: %.2f%%\n", data.OITopData.OIDeltaPercent))
+			sb.WriteString(fmt.Sprintf(l.oiChange, data.OITopData.OIDeltaPercent))
 		}
-		
+
 		if data.OITopData.PriceDeltaPercent != 0 {
-			sb.WriteString(fmt.Sprintf("Price Change: %.2f%%\n", data.OITopData.PriceDeltaPercent))
+			sb.WriteString(fmt.Sprintf(l.priceChange, data.OITopData.PriceDeltaPercent))
 		}
-		
+
 		if data.OITopData.NetLong > 0 || data.OITopData.NetShort > 0 {
-			sb.WriteString(fmt.Sprintf("Net Long: %.2f | Net Short: %.2f\n", data.OITopData.NetLong, data.OITopData.NetShort))
+			sb.WriteString(fmt.Sprintf(l.netLongShort, data.OITopData.NetLong, data.OITopData.NetShort))
 		}
-		
+
 		sb.WriteString("\n")
 	}
 
 	if data.IntradaySeries != nil {
-		sb.WriteString("Intraday series (3‑minute intervals, oldest → latest):\n\n")
+		sb.WriteString(l.intraday)
 
 		if len(data.IntradaySeries.MidPrices) > 0 {
-			sb.WriteString(fmt.Sprintf("Mid prices: %s\n\n", formatFloatSlice(data.IntradaySeries.MidPrices)))
+			sb.WriteString(fmt.Sprintf(l.midPrices, formatFloatSlice(data.IntradaySeries.MidPrices)))
 		}
 
 		if len(data.IntradaySeries.EMA20Values) > 0 {
-			sb.WriteString(fmt.Sprintf("EMA indicators (20‑period): %s\n\n", formatFloatSlice(data.IntradaySeries.EMA20Values)))
+			sb.WriteString(fmt.Sprintf(l.ema20, formatFloatSlice(data.IntradaySeries.EMA20Values)))
 		}
 
 		if len(data.IntradaySeries.MACDValues) > 0 {
-			sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", formatFloatSlice(data.IntradaySeries.MACDValues)))
+			sb.WriteString(fmt.Sprintf(l.macd, formatFloatSlice(data.IntradaySeries.MACDValues)))
 		}
 
 		if len(data.IntradaySeries.RSI7Values) > 0 {
-			sb.WriteString(fmt.Sprintf("RSI indicators (7‑Period): %s\n\n", formatFloatSlice(data.IntradaySeries.RSI7Values)))
+			sb.WriteString(fmt.Sprintf(l.rsi7, formatFloatSlice(data.IntradaySeries.RSI7Values)))
 		}
 
 		if len(data.IntradaySeries.RSI14Values) > 0 {
-			sb.WriteString(fmt.Sprintf("RSI indicators (14‑Period): %s\n\n", formatFloatSlice(data.IntradaySeries.RSI14Values)))
+			sb.WriteString(fmt.Sprintf(l.rsi14, formatFloatSlice(data.IntradaySeries.RSI14Values)))
 		}
 	}
 
 	if data.LongerTermContext != nil {
-		sb.WriteString("Longer‑term context (4‑hour timeframe):\n\n")
-
-		sb.WriteString(fmt.Sprintf("20‑Period EMA: %.3f vs. 50‑Period EMA: %.3f\n\n",
-			data.LongerTermContext.EMA20, data.LongerTermContext.EMA50))
+		sb.WriteString(l.longerTerm)
 
-		sb.WriteString(fmt.Sprintf("3‑Period ATR: %.3f vs. 14‑Period ATR: %.3f\n\n",
-			data.LongerTermContext.ATR3, data.LongerTermContext.ATR14))
-
-		sb.WriteString(fmt.Sprintf("Current Volume: %.3f vs. Average Volume: %.3f\n\n",
-			data.LongerTermContext.CurrentVolume, data.LongerTermContext.AverageVolume))
+		sb.WriteString(fmt.Sprintf(l.emaCompare, data.LongerTermContext.EMA20, data.LongerTermContext.EMA50))
+		sb.WriteString(fmt.Sprintf(l.atrCompare, data.LongerTermContext.ATR3, data.LongerTermContext.ATR14))
+		sb.WriteString(fmt.Sprintf(l.volumeCompare, data.LongerTermContext.CurrentVolume, data.LongerTermContext.AverageVolume))
 
 		if len(data.LongerTermContext.MACDValues) > 0 {
-			sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", formatFloatSlice(data.LongerTermContext.MACDValues)))
+			sb.WriteString(fmt.Sprintf(l.macd, formatFloatSlice(data.LongerTermContext.MACDValues)))
 		}
 
 		if len(data.LongerTermContext.RSI14Values) > 0 {
-			sb.WriteString(fmt.Sprintf("RSI indicators (14‑Period): %s\n\n", formatFloatSlice(data.LongerTermContext.RSI14Values)))
+			sb.WriteString(fmt.Sprintf(l.rsi14, formatFloatSlice(data.LongerTermContext.RSI14Values)))
 		}
 	}
 
 	return sb.String()
 }
 
+// Trim 返回每个序列只保留最近n个点的副本（用于压缩prompt，不修改原数据）
+func Trim(data *Data, n int) *Data {
+	trimmed := *data
+	if data.IntradaySeries != nil {
+		trimmed.IntradaySeries = &IntradayData{
+			MidPrices:   lastN(data.IntradaySeries.MidPrices, n),
+			EMA20Values: lastN(data.IntradaySeries.EMA20Values, n),
+			MACDValues:  lastN(data.IntradaySeries.MACDValues, n),
+			RSI7Values:  lastN(data.IntradaySeries.RSI7Values, n),
+			RSI14Values: lastN(data.IntradaySeries.RSI14Values, n),
+		}
+	}
+	if data.LongerTermContext != nil {
+		longer := *data.LongerTermContext
+		longer.MACDValues = lastN(longer.MACDValues, n)
+		longer.RSI14Values = lastN(longer.RSI14Values, n)
+		trimmed.LongerTermContext = &longer
+	}
+	return &trimmed
+}
+
+// lastN 切片的最后n个元素
+func lastN(values []float64, n int) []float64 {
+	if len(values) <= n {
+		return values
+	}
+	return values[len(values)-n:]
+}
+
 // formatFloatSlice 格式化float64切片为字符串
 func formatFloatSlice(values []float64) string {
 	strValues := make([]string, len(values))
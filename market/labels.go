@@ -0,0 +1,103 @@
+package market
+
+// formatLabels Format 输出的标签（按prompt语言，格式与参数顺序在各语言中一致）
+type formatLabels struct {
+	current       string
+	oiIntro       string
+	openInterest  string
+	fundingRate   string
+	oiTop         string
+	rank          string
+	oiValue       string
+	oiChange      string
+	priceChange   string
+	netLongShort  string
+	intraday      string
+	midPrices     string
+	ema20         string
+	macd          string
+	rsi7          string
+	rsi14         string
+	longerTerm    string
+	emaCompare    string
+	atrCompare    string
+	volumeCompare string
+}
+
+// englishLabels 英文标签（中文prompt也使用英文指标名称）
+var englishLabels = formatLabels{
+	current:       "current_price = %.2f, current_ema20 = %.3f, current_macd = %.3f, current_rsi (7 period) = %.3f\n\n",
+	oiIntro:       "In addition, here is the latest %s open interest and funding rate for perps:\n\n",
+	openInterest:  "Open Interest: Latest: %.2f Average: %.2f\n\n",
+	fundingRate:   "Funding Rate: %.2e\n\n",
+	oiTop:         "OI Top Data (open interest analysis):\n\n",
+	rank:          "Rank: #%d\n",
+	oiValue:       "OI Value: %.2f\n",
+	oiChange:      "OI Change: %.2f%%\n",
+	priceChange:   "Price Change: %.2f%%\n",
+	netLongShort:  "Net Long: %.2f | Net Short: %.2f\n",
+	intraday:      "Intraday series (3‑minute intervals, oldest → latest):\n\n",
+	midPrices:     "Mid prices: %s\n\n",
+	ema20:         "EMA indicators (20‑period): %s\n\n",
+	macd:          "MACD indicators: %s\n\n",
+	rsi7:          "RSI indicators (7‑Period): %s\n\n",
+	rsi14:         "RSI indicators (14‑Period): %s\n\n",
+	longerTerm:    "Longer‑term context (4‑hour timeframe):\n\n",
+	emaCompare:    "20‑Period EMA: %.3f vs. 50‑Period EMA: %.3f\n\n",
+	atrCompare:    "3‑Period ATR: %.3f vs. 14‑Period ATR: %.3f\n\n",
+	volumeCompare: "Current Volume: %.3f vs. Average Volume: %.3f\n\n",
+}
+
+// labelsByLanguage 各prompt语言的标签（未知语言使用中文）
+var labelsByLanguage = map[string]formatLabels{
+	"zh": func() formatLabels {
+		l := englishLabels
+		l.oiTop = "OI Top Data (持仓量分析):\n\n"
+		return l
+	}(),
+	"en": englishLabels,
+	"ru": {
+		current:       "Текущая цена = %.2f, EMA20 = %.3f, MACD = %.3f, RSI (7 периодов) = %.3f\n\n",
+		oiIntro:       "Ниже — последние данные по открытому интересу и ставке финансирования бессрочных фьючерсов %s:\n\n",
+		openInterest:  "Открытый интерес: последний: %.2f, средний: %.2f\n\n",
+		fundingRate:   "Ставка финансирования: %.2e\n\n",
+		oiTop:         "Данные OI Top (анализ открытого интереса):\n\n",
+		rank:          "Ранг: #%d\n",
+		oiValue:       "Стоимость OI: %.2f\n",
+		oiChange:      "Изменение OI: %.2f%%\n",
+		priceChange:   "Изменение цены: %.2f%%\n",
+		netLongShort:  "Чистые лонги: %.2f | Чистые шорты: %.2f\n",
+		intraday:      "Внутридневные ряды (интервал 3 минуты, от старых к новым):\n\n",
+		midPrices:     "Средние цены: %s\n\n",
+		ema20:         "EMA (20 периодов): %s\n\n",
+		macd:          "MACD: %s\n\n",
+		rsi7:          "RSI (7 периодов): %s\n\n",
+		rsi14:         "RSI (14 периодов): %s\n\n",
+		longerTerm:    "Долгосрочный контекст (таймфрейм 4 часа):\n\n",
+		emaCompare:    "EMA 20 периодов: %.3f против EMA 50 периодов: %.3f\n\n",
+		atrCompare:    "ATR 3 периода: %.3f против ATR 14 периодов: %.3f\n\n",
+		volumeCompare: "Текущий объём: %.3f против среднего объёма: %.3f\n\n",
+	},
+	"uk": {
+		current:       "Поточна ціна = %.2f, EMA20 = %.3f, MACD = %.3f, RSI (7 періодів) = %.3f\n\n",
+		oiIntro:       "Нижче — останні дані про відкритий інтерес і ставку фінансування безстрокових ф'ючерсів %s:\n\n",
+		openInterest:  "Відкритий інтерес: останній: %.2f, середній: %.2f\n\n",
+		fundingRate:   "Ставка фінансування: %.2e\n\n",
+		oiTop:         "Дані OI Top (аналіз відкритого інтересу):\n\n",
+		rank:          "Ранг: #%d\n",
+		oiValue:       "Вартість OI: %.2f\n",
+		oiChange:      "Зміна OI: %.2f%%\n",
+		priceChange:   "Зміна ціни: %.2f%%\n",
+		netLongShort:  "Чисті лонги: %.2f | Чисті шорти: %.2f\n",
+		intraday:      "Внутрішньоденні ряди (інтервал 3 хвилини, від старих до нових):\n\n",
+		midPrices:     "Середні ціни: %s\n\n",
+		ema20:         "EMA (20 періодів): %s\n\n",
+		macd:          "MACD: %s\n\n",
+		rsi7:          "RSI (7 періодів): %s\n\n",
+		rsi14:         "RSI (14 періодів): %s\n\n",
+		longerTerm:    "Довгостроковий контекст (таймфрейм 4 години):\n\n",
+		emaCompare:    "EMA 20 періодів: %.3f проти EMA 50 періодів: %.3f\n\n",
+		atrCompare:    "ATR 3 періоди: %.3f проти ATR 14 періодів: %.3f\n\n",
+		volumeCompare: "Поточний обсяг: %.3f проти середнього обсягу: %.3f\n\n",
+	},
+}
@@ -0,0 +1,85 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// OrderBookLevel 盘口的一档价格
+type OrderBookLevel struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+// OrderBook 盘口深度
+type OrderBook struct {
+	Symbol string           `json:"symbol"`
+	Bids   []OrderBookLevel `json:"bids"` // 买盘（价格从高到低）
+	Asks   []OrderBookLevel `json:"asks"` // 卖盘（价格从低到高）
+}
+
+// orderBookLimits Binance深度接口支持的档位数
+var orderBookLimits = []int{5, 10, 20, 50, 100}
+
+// GetOrderBook 获取盘口深度（depth 向上取到接口支持的档位数，最多100档）
+func GetOrderBook(symbol string, depth int) (*OrderBook, error) {
+	symbol = Normalize(symbol)
+	limit := orderBookLimits[len(orderBookLimits)-1]
+	for _, l := range orderBookLimits {
+		if depth <= l {
+			limit = l
+			break
+		}
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/depth?symbol=%s&limit=%d", symbol, limit)
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("获取盘口深度失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取盘口深度失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取盘口深度失败 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Bids [][]string `json:"bids"`
+		Asks [][]string `json:"asks"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析盘口深度失败: %w", err)
+	}
+
+	return &OrderBook{
+		Symbol: symbol,
+		Bids:   parseOrderBookLevels(result.Bids, depth),
+		Asks:   parseOrderBookLevels(result.Asks, depth),
+	}, nil
+}
+
+// parseOrderBookLevels 解析 [价格, 数量] 字符串对，最多保留 depth 档
+func parseOrderBookLevels(raw [][]string, depth int) []OrderBookLevel {
+	if depth > 0 && len(raw) > depth {
+		raw = raw[:depth]
+	}
+	levels := make([]OrderBookLevel, 0, len(raw))
+	for _, item := range raw {
+		if len(item) < 2 {
+			continue
+		}
+		price, _ := strconv.ParseFloat(item[0], 64)
+		quantity, _ := strconv.ParseFloat(item[1], 64)
+		levels = append(levels, OrderBookLevel{Price: price, Quantity: quantity})
+	}
+	return levels
+}
@@ -0,0 +1,37 @@
+package market
+
+// Snapshot 某一时刻的精简市场数据（保存在开仓/平仓记录中，用于交易复盘）
+type Snapshot struct {
+	Price         float64 `json:"price"`
+	PriceChange1h float64 `json:"price_change_1h"` // 1小时价格变化百分比
+	PriceChange4h float64 `json:"price_change_4h"` // 4小时价格变化百分比
+	EMA20         float64 `json:"ema20"`           // 3分钟EMA20
+	MACD          float64 `json:"macd"`            // 3分钟MACD
+	RSI7          float64 `json:"rsi7"`            // 3分钟RSI7
+	ATR14         float64 `json:"atr14,omitempty"` // 4小时ATR14
+	FundingRate   float64 `json:"funding_rate"`
+	OpenInterest  float64 `json:"open_interest,omitempty"` // 最新持仓量
+}
+
+// SnapshotOf 从完整市场数据中提取快照，data为空时返回nil
+func SnapshotOf(data *Data) *Snapshot {
+	if data == nil {
+		return nil
+	}
+	snapshot := &Snapshot{
+		Price:         data.CurrentPrice,
+		PriceChange1h: data.PriceChange1h,
+		PriceChange4h: data.PriceChange4h,
+		EMA20:         data.CurrentEMA20,
+		MACD:          data.CurrentMACD,
+		RSI7:          data.CurrentRSI7,
+		FundingRate:   data.FundingRate,
+	}
+	if data.LongerTermContext != nil {
+		snapshot.ATR14 = data.LongerTermContext.ATR14
+	}
+	if data.OpenInterest != nil {
+		snapshot.OpenInterest = data.OpenInterest.Latest
+	}
+	return snapshot
+}
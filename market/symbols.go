@@ -0,0 +1,70 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// symbolsCacheTTL 合约列表缓存时间
+const symbolsCacheTTL = time.Hour
+
+var (
+	symbolsMu        sync.Mutex
+	symbolsCache     map[string]bool
+	symbolsFetchedAt time.Time
+)
+
+// GetFuturesSymbols 获取Binance正在交易的USDT永续合约列表（行情数据来源，结果缓存1小时）
+func GetFuturesSymbols() (map[string]bool, error) {
+	symbolsMu.Lock()
+	defer symbolsMu.Unlock()
+
+	if symbolsCache != nil && time.Since(symbolsFetchedAt) < symbolsCacheTTL {
+		return symbolsCache, nil
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get("https://fapi.binance.com/fapi/v1/exchangeInfo")
+	if err != nil {
+		return nil, fmt.Errorf("获取合约列表失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取合约列表失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取合约列表失败: HTTP %d", resp.StatusCode)
+	}
+
+	var info struct {
+		Symbols []struct {
+			Symbol       string `json:"symbol"`
+			Status       string `json:"status"`
+			ContractType string `json:"contractType"`
+			QuoteAsset   string `json:"quoteAsset"`
+		} `json:"symbols"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("解析合约列表失败: %w", err)
+	}
+
+	symbols := make(map[string]bool)
+	for _, s := range info.Symbols {
+		if s.Status == "TRADING" && s.ContractType == "PERPETUAL" && s.QuoteAsset == "USDT" {
+			symbols[s.Symbol] = true
+		}
+	}
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("合约列表为空")
+	}
+
+	symbolsCache = symbols
+	symbolsFetchedAt = time.Now()
+	return symbols, nil
+}
@@ -28,6 +28,46 @@ type Client struct {
 	Model      string
 	Timeout    time.Duration
 	UseFullURL bool // 是否使用完整URL（不添加/chat/completions）
+
+	Name      string    // 显示名称（为空时为 provider:model）
+	Fallbacks []*Client // 备用模型（CallWithFallback 按顺序尝试）
+
+	ContextWindow int // 上下文窗口（token，0表示使用提供商默认值）
+	MaxTokens     int // 单次回复的最大token数（0表示默认2000）
+
+	// 采样温度（为空时使用 defaultTemperature，重放时可设为0以减少随机性）
+	Temperature *float64
+}
+
+// defaultTemperature 默认采样温度（较低的temperature提高JSON格式稳定性）
+const defaultTemperature = 0.5
+
+// Params 一次调用使用的模型参数（保存在决策记录中，用于重放）
+type Params struct {
+	Provider    Provider `json:"provider"`
+	Model       string   `json:"model"`
+	BaseURL     string   `json:"base_url"`
+	Temperature float64  `json:"temperature"`
+	MaxTokens   int      `json:"max_tokens"`
+}
+
+// Params 当前的模型参数
+func (cfg *Client) Params() Params {
+	return Params{
+		Provider:    cfg.Provider,
+		Model:       cfg.Model,
+		BaseURL:     cfg.BaseURL,
+		Temperature: cfg.EffectiveTemperature(),
+		MaxTokens:   cfg.EffectiveMaxTokens(),
+	}
+}
+
+// EffectiveTemperature 实际使用的采样温度
+func (cfg *Client) EffectiveTemperature() float64 {
+	if cfg.Temperature != nil {
+		return *cfg.Temperature
+	}
+	return defaultTemperature
 }
 
 func New() *Client {
@@ -86,8 +126,38 @@ func (cfg *Client) SetClient(Client Client) {
 
 // CallWithMessages 使用 system + user prompt 调用AI API（推荐）
 func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	reply, err := cfg.chatWithRetry(initialMessages(systemPrompt, userPrompt), nil)
+	if err != nil {
+		return "", err
+	}
+	return reply.Content, nil
+}
+
+// chatMessage 对话消息（OpenAI兼容格式，tool_calls/tool_call_id 仅用于工具调用）
+type chatMessage struct {
+	Role       string            `json:"role"`
+	Content    string            `json:"content"`
+	ToolCalls  []toolCallMessage `json:"tool_calls,omitempty"`
+	ToolCallID string            `json:"tool_call_id,omitempty"`
+}
+
+// initialMessages 构建 messages 数组：可选的 system message + user message
+func initialMessages(systemPrompt, userPrompt string) []chatMessage {
+	messages := []chatMessage{}
+
+	// 如果有 system prompt，添加 system message
+	if systemPrompt != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: systemPrompt})
+	}
+
+	// 添加 user message
+	return append(messages, chatMessage{Role: "user", Content: userPrompt})
+}
+
+// chatWithRetry 调用AI API，网络错误时重试
+func (cfg *Client) chatWithRetry(messages []chatMessage, tools *toolRequest) (*chatMessage, error) {
 	if cfg.APIKey == "" {
-		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
+		return nil, fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
 	}
 
 	// 重试配置
@@ -99,18 +169,18 @@ func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, er
 			fmt.Printf("⚠️  AI API调用失败，正在重试 (%d/%d)...\n", attempt, maxRetries)
 		}
 
-		result, err := cfg.callOnce(systemPrompt, userPrompt)
+		reply, err := cfg.callOnce(messages, tools)
 		if err == nil {
 			if attempt > 1 {
 				fmt.Printf("✓ AI API重试成功\n")
 			}
-			return result, nil
+			return reply, nil
 		}
 
 		lastErr = err
 		// 如果不是网络错误，不重试
 		if !isRetryableError(err) {
-			return "", err
+			return nil, err
 		}
 
 		// 重试前等待
@@ -121,34 +191,21 @@ func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, er
 		}
 	}
 
-	return "", fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
+	return nil, fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
 }
 
-// callOnce 单次调用AI API（内部使用）
-func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
-	// 构建 messages 数组
-	messages := []map[string]string{}
-
-	// 如果有 system prompt，添加 system message
-	if systemPrompt != "" {
-		messages = append(messages, map[string]string{
-			"role":    "system",
-			"content": systemPrompt,
-		})
-	}
-
-	// 添加 user message
-	messages = append(messages, map[string]string{
-		"role":    "user",
-		"content": userPrompt,
-	})
-
+// callOnce 单次调用AI API（内部使用），tools 为空时不启用工具调用
+func (cfg *Client) callOnce(messages []chatMessage, tools *toolRequest) (*chatMessage, error) {
 	// 构建请求体
 	requestBody := map[string]interface{}{
 		"model":       cfg.Model,
 		"messages":    messages,
-		"temperature": 0.5, // 降低temperature以提高JSON格式稳定性
-		"max_tokens":  2000,
+		"temperature": cfg.EffectiveTemperature(),
+		"max_tokens":  cfg.EffectiveMaxTokens(),
+	}
+	if tools != nil {
+		requestBody["tools"] = tools.Definitions
+		requestBody["tool_choice"] = tools.Choice
 	}
 
 	// 注意：response_format 参数仅 OpenAI 支持，DeepSeek/Qwen 不支持
@@ -156,7 +213,7 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
 	}
 
 	// 创建HTTP请求
@@ -170,7 +227,7 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 	}
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -191,38 +248,36 @@ func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 	client := &http.Client{Timeout: cfg.Timeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("发送请求失败: %w", err)
+		return nil, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	// 解析响应
 	var result struct {
 		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
+			Message chatMessage `json:"message"`
 		} `json:"choices"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
+		return nil, fmt.Errorf("解析响应失败: %w", err)
 	}
 
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("API返回空响应")
+		return nil, fmt.Errorf("API返回空响应")
 	}
 
-	return result.Choices[0].Message.Content, nil
+	return &result.Choices[0].Message, nil
 }
 
 // isRetryableError 判断错误是否可重试
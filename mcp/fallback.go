@@ -0,0 +1,208 @@
+package mcp
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 熔断参数：连续失败 breakerThreshold 次（每次已包含重试）后熔断，
+// 冷却期结束后放行一次试探调用，试探失败则冷却时间加倍（不超过 breakerMaxCooldown）
+const (
+	breakerThreshold   = 2
+	breakerCooldown    = 5 * time.Minute
+	breakerMaxCooldown = 30 * time.Minute
+)
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"    // 正常
+	BreakerOpen     = "open"      // 熔断中，跳过该提供商
+	BreakerHalfOpen = "half_open" // 冷却结束，等待试探调用
+)
+
+// breaker 单个提供商账号上某个模型（按API地址、密钥和模型区分）的熔断状态，使用相同配置的trader共享
+type breaker struct {
+	failures  int
+	cooldown  time.Duration
+	openUntil time.Time
+	lastError string
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*breaker)
+)
+
+// BreakerState 熔断器状态（用于状态接口展示）
+type BreakerState struct {
+	Model     string    `json:"model"`
+	Endpoint  string    `json:"endpoint"`
+	State     string    `json:"state"`
+	Failures  int       `json:"failures"`
+	OpenUntil time.Time `json:"open_until,omitzero"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// breakerKey 熔断按API地址、API密钥和模型区分
+// 某个trader的密钥失效或额度用完（401/402/429）只熔断使用该密钥的客户端，不影响同一提供商的其他密钥；
+// 密钥只保留摘要，避免明文出现在内存中的索引里
+func (cfg *Client) breakerKey() string {
+	sum := sha256.Sum256([]byte(cfg.APIKey))
+	return fmt.Sprintf("%s|%s|%x", cfg.BaseURL, cfg.Model, sum[:8])
+}
+
+// Label 模型显示名称（决策记录中使用）
+func (cfg *Client) Label() string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	return fmt.Sprintf("%s:%s", cfg.Provider, cfg.Model)
+}
+
+// available 提供商是否可用（未熔断或冷却已结束）
+func (cfg *Client) available() bool {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b := breakers[cfg.breakerKey()]
+	return b == nil || !time.Now().Before(b.openUntil)
+}
+
+// recordResult 记录调用结果并更新熔断状态
+func (cfg *Client) recordResult(err error) {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	key := cfg.breakerKey()
+	b := breakers[key]
+	if b == nil {
+		b = &breaker{}
+		breakers[key] = b
+	}
+	if err == nil {
+		if b.failures >= breakerThreshold {
+			log.Printf("✓ AI提供商 %s 已恢复", cfg.Label())
+		}
+		*b = breaker{}
+		return
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+	if b.failures < breakerThreshold || time.Now().Before(b.openUntil) {
+		// 未达到阈值，或熔断期间的兜底调用失败（不延长冷却时间）
+		return
+	}
+	// 首次熔断或试探失败：冷却时间加倍
+	if b.cooldown == 0 {
+		b.cooldown = breakerCooldown
+	} else if b.failures > breakerThreshold {
+		b.cooldown *= 2
+		if b.cooldown > breakerMaxCooldown {
+			b.cooldown = breakerMaxCooldown
+		}
+	}
+	b.openUntil = time.Now().Add(b.cooldown)
+	log.Printf("🔌 AI提供商 %s 连续失败%d次，熔断%v: %v", cfg.Label(), b.failures, b.cooldown, err)
+}
+
+// breakerState 当前熔断状态
+func (cfg *Client) breakerState() BreakerState {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	state := BreakerState{Model: cfg.Label(), Endpoint: cfg.BaseURL, State: BreakerClosed}
+	if b := breakers[cfg.breakerKey()]; b != nil {
+		state.Failures = b.failures
+		state.LastError = b.lastError
+		if !b.openUntil.IsZero() {
+			state.State = BreakerHalfOpen
+			if time.Now().Before(b.openUntil) {
+				state.State = BreakerOpen
+				state.OpenUntil = b.openUntil
+			}
+		}
+	}
+	return state
+}
+
+// SetFallbacks 设置备用模型（按顺序尝试）
+func (cfg *Client) SetFallbacks(fallbacks ...*Client) {
+	cfg.Fallbacks = fallbacks
+}
+
+// BreakerStates 主模型和备用模型的熔断状态
+func (cfg *Client) BreakerStates() []BreakerState {
+	states := []BreakerState{cfg.breakerState()}
+	for _, fb := range cfg.Fallbacks {
+		states = append(states, fb.breakerState())
+	}
+	return states
+}
+
+// CallResult 一次调用的结果
+type CallResult struct {
+	Content  string // AI输出
+	Model    string // 实际使用的模型
+	Fallback bool   // 是否使用了备用模型
+	Params   Params // 实际使用的模型参数
+}
+
+// CallWithFallback 依次调用主模型和备用模型，跳过熔断中的提供商，返回第一个成功的结果
+// 全部提供商都在熔断中时仍会试探主模型，避免持仓在故障期间完全无人管理
+func (cfg *Client) CallWithFallback(systemPrompt, userPrompt string) (*CallResult, error) {
+	return cfg.callWithFallback(func(c *Client) (string, error) {
+		return c.CallWithMessages(systemPrompt, userPrompt)
+	})
+}
+
+// CallWithFallbackTools 与 CallWithFallback 相同，但允许模型调用工具（session 为空时不启用工具）
+// 切换到备用模型时工具调用预算不重置，失败模型已执行的调用同样计入预算
+func (cfg *Client) CallWithFallbackTools(systemPrompt, userPrompt string, session *ToolSession) (*CallResult, error) {
+	return cfg.callWithFallback(func(c *Client) (string, error) {
+		return c.CallWithTools(systemPrompt, userPrompt, session)
+	})
+}
+
+// callWithFallback 按顺序用 call 调用主模型和可用的备用模型
+func (cfg *Client) callWithFallback(call func(c *Client) (string, error)) (*CallResult, error) {
+	chain := append([]*Client{cfg}, cfg.Fallbacks...)
+
+	var attempts []*Client
+	var skipped []string
+	for _, c := range chain {
+		if c.available() {
+			attempts = append(attempts, c)
+		} else {
+			skipped = append(skipped, c.Label())
+		}
+	}
+	if len(attempts) == 0 {
+		log.Printf("🔌 全部AI提供商都在熔断中，仍尝试主模型 %s", cfg.Label())
+		attempts = []*Client{cfg}
+	} else if len(skipped) > 0 {
+		log.Printf("🔌 跳过熔断中的AI提供商: %v", skipped)
+	}
+
+	var errs []error
+	for i, c := range attempts {
+		content, err := call(c)
+		c.recordResult(err)
+		if err == nil {
+			if c != cfg {
+				log.Printf("⚠️  主模型 %s 不可用，已使用备用模型 %s", cfg.Label(), c.Label())
+			}
+			return &CallResult{Content: content, Model: c.Label(), Fallback: c != cfg, Params: c.Params()}, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", c.Label(), err))
+		if i < len(attempts)-1 {
+			log.Printf("⚠️  AI模型 %s 调用失败，尝试下一个: %v", c.Label(), err)
+		}
+	}
+	if len(errs) == 1 {
+		return nil, errs[0]
+	}
+	return nil, fmt.Errorf("全部%d个AI模型调用失败: %v", len(errs), errs)
+}
@@ -0,0 +1,71 @@
+package mcp
+
+import "unicode"
+
+// DefaultMaxTokens 单次回复的默认最大token数
+const DefaultMaxTokens = 2000
+
+// 默认模型的上下文窗口（token），自定义API未配置时视为未知
+const (
+	deepSeekContextWindow = 65536
+	qwenContextWindow     = 131072
+)
+
+// EstimateTokens 粗略估算文本的token数（不依赖具体模型的分词器，用于控制prompt大小）
+// ASCII约4个字符1个token，中日韩字符约1个字符1个token，其他字符（如西里尔字母）约2个字符1个token
+func EstimateTokens(text string) int {
+	var ascii, cjk, other int
+	for _, r := range text {
+		switch {
+		case r < 0x80:
+			ascii++
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+		default:
+			other++
+		}
+	}
+	return (ascii+3)/4 + cjk + (other+1)/2
+}
+
+// EffectiveContextWindow 上下文窗口：已配置时使用配置值，否则使用提供商默认模型的窗口（自定义API返回0表示未知）
+func (cfg *Client) EffectiveContextWindow() int {
+	if cfg.ContextWindow > 0 {
+		return cfg.ContextWindow
+	}
+	switch cfg.Provider {
+	case ProviderDeepSeek:
+		return deepSeekContextWindow
+	case ProviderQwen:
+		return qwenContextWindow
+	}
+	return 0
+}
+
+// EffectiveMaxTokens 单次回复的最大token数
+func (cfg *Client) EffectiveMaxTokens() int {
+	if cfg.MaxTokens > 0 {
+		return cfg.MaxTokens
+	}
+	return DefaultMaxTokens
+}
+
+// PromptBudget system + user prompt 可使用的token数：上下文窗口扣除回复预留和5%估算误差
+// 有备用模型时取其中最小的预算（切换备用模型时使用同一份prompt），全部未知时返回0（不限制）
+func (cfg *Client) PromptBudget() int {
+	budget := 0
+	for _, c := range append([]*Client{cfg}, cfg.Fallbacks...) {
+		window := c.EffectiveContextWindow()
+		if window <= 0 {
+			continue
+		}
+		b := window - c.EffectiveMaxTokens() - window/20
+		if b < 1 {
+			b = 1
+		}
+		if budget == 0 || b < budget {
+			budget = b
+		}
+	}
+	return budget
+}
@@ -0,0 +1,324 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 工具结果长度上限：返回给模型的结果按token估算限制（超出时由工具缩减条目数），决策记录中保存的结果截断
+const (
+	maxToolResultTokens  = 1500
+	maxToolRecordedChars = 1000
+)
+
+// toolCallOverheadTokens 每次工具调用除结果外占用的token（模型的调用请求和消息结构）
+const toolCallOverheadTokens = 100
+
+// Tool 模型可以调用的工具（OpenAI function calling 格式）
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}                          // 参数的 JSON Schema
+	Handler     func(args json.RawMessage) (interface{}, error) // 返回值序列化为JSON后发给模型
+
+	// Shrink 结果超出长度上限时缩减条目数（如去掉最早的K线），无法再缩减时返回false；为空时超长结果返回错误
+	Shrink func(result interface{}) (interface{}, bool)
+}
+
+// ToolCall 一次工具调用的记录
+type ToolCall struct {
+	Model      string    `json:"model"`            // 发起调用的模型
+	Name       string    `json:"name"`             // 工具名称
+	Arguments  string    `json:"arguments"`        // 模型传入的参数（JSON）
+	Result     string    `json:"result,omitempty"` // 返回给模型的结果（截断）
+	Error      string    `json:"error,omitempty"`  // 调用失败或超出预算的原因
+	Timestamp  time.Time `json:"timestamp"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// Transcript 一次带工具调用的对话中初始 system/user prompt 之后的消息，用于重放
+// 工具结果与当时发给模型的内容完全相同（不截断）
+type Transcript struct {
+	Model    string          `json:"model"`    // 给出最终回复的模型
+	Tools    json.RawMessage `json:"tools"`    // 工具定义
+	Messages json.RawMessage `json:"messages"` // 模型的工具调用请求和工具结果（不含最终回复）
+}
+
+// ToolSession 一个交易周期的工具调用会话：可用工具、调用次数预算和调用记录
+// 并发安全，多模型投票时全部模型共享同一个预算
+type ToolSession struct {
+	Tools    []Tool
+	MaxCalls int // 本周期最多执行的工具调用次数
+
+	mu          sync.Mutex
+	executed    int // 已占用的调用次数
+	calls       []ToolCall
+	transcripts []Transcript
+}
+
+// NewToolSession 创建工具调用会话
+func NewToolSession(tools []Tool, maxCalls int) *ToolSession {
+	return &ToolSession{Tools: tools, MaxCalls: maxCalls}
+}
+
+// Calls 本周期的全部工具调用记录（按调用顺序）
+func (s *ToolSession) Calls() []ToolCall {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ToolCall(nil), s.calls...)
+}
+
+// Transcripts 本周期调用过工具并给出最终回复的对话（每个模型一条）
+func (s *ToolSession) Transcripts() []Transcript {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Transcript(nil), s.transcripts...)
+}
+
+// Remaining 本周期剩余的工具调用次数
+func (s *ToolSession) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.executed >= s.MaxCalls {
+		return 0
+	}
+	return s.MaxCalls - s.executed
+}
+
+// ReservedTokens 工具调用最多占用的上下文token：工具定义加上全部调用次数的请求和结果
+// 启用工具时需要从prompt预算中扣除，保证对话在上下文窗口内
+func (s *ToolSession) ReservedTokens() int {
+	if s == nil || len(s.Tools) == 0 {
+		return 0
+	}
+	data, _ := json.Marshal(s.definitions())
+	return EstimateTokens(string(data)) + s.MaxCalls*(maxToolResultTokens+toolCallOverheadTokens)
+}
+
+// reserve 占用一次调用预算，预算用完时返回false
+func (s *ToolSession) reserve() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.executed >= s.MaxCalls {
+		return false
+	}
+	s.executed++
+	return true
+}
+
+// record 保存一次调用记录
+func (s *ToolSession) record(call ToolCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, call)
+}
+
+// recordTranscript 保存模型得到最终回复时的对话（只保存初始prompt之后的消息）
+func (s *ToolSession) recordTranscript(model string, definitions []toolDefinition, messages []chatMessage) {
+	tools, err := json.Marshal(definitions)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transcripts = append(s.transcripts, Transcript{Model: model, Tools: tools, Messages: data})
+}
+
+// execute 执行模型请求的一次工具调用并记录，返回发给模型的 tool message 内容
+// 失败和超出预算时把原因返回给模型，由模型决定是否继续
+func (s *ToolSession) execute(model string, request toolCallMessage) string {
+	call := ToolCall{
+		Model:     model,
+		Name:      request.Function.Name,
+		Arguments: request.Function.Arguments,
+		Timestamp: time.Now(),
+	}
+	defer func() {
+		call.DurationMs = time.Since(call.Timestamp).Milliseconds()
+		s.record(call)
+	}()
+
+	// 无效的调用也占用预算，保证对话轮数有上限
+	if !s.reserve() {
+		call.Error = fmt.Sprintf("本周期工具调用次数已用完（%d次），请根据已有数据输出决策", s.MaxCalls)
+		return toolError(call.Error)
+	}
+	var tool *Tool
+	for i := range s.Tools {
+		if s.Tools[i].Name == request.Function.Name {
+			tool = &s.Tools[i]
+			break
+		}
+	}
+	if tool == nil {
+		call.Error = fmt.Sprintf("未知的工具: %s", request.Function.Name)
+		return toolError(call.Error)
+	}
+
+	args := json.RawMessage(request.Function.Arguments)
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	result, err := tool.Handler(args)
+	if err != nil {
+		call.Error = err.Error()
+		log.Printf("  🔧 %s %s(%s) 失败: %v", model, call.Name, call.Arguments, err)
+		return toolError(call.Error)
+	}
+	content, err := fitToolResult(tool, result)
+	if err != nil {
+		call.Error = err.Error()
+		return toolError(call.Error)
+	}
+
+	call.Result = truncateChars(content, maxToolRecordedChars)
+	log.Printf("  🔧 %s %s(%s) → %d字", model, call.Name, call.Arguments, utf8.RuneCountInString(content))
+	return content
+}
+
+// fitToolResult 序列化工具结果，超出长度上限时按工具的 Shrink 逐步缩减条目（保证发给模型的始终是完整的JSON）
+func fitToolResult(tool *Tool, result interface{}) (string, error) {
+	for {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("序列化工具结果失败: %w", err)
+		}
+		tokens := EstimateTokens(string(data))
+		if tokens <= maxToolResultTokens {
+			return string(data), nil
+		}
+
+		shrunk := false
+		if tool.Shrink != nil {
+			result, shrunk = tool.Shrink(result)
+		}
+		if !shrunk {
+			return "", fmt.Errorf("工具结果过长（约%d tokens，上限%d），请减少请求的数量", tokens, maxToolResultTokens)
+		}
+	}
+}
+
+// definitions 工具定义（请求体中的 tools 字段）
+func (s *ToolSession) definitions() []toolDefinition {
+	definitions := make([]toolDefinition, 0, len(s.Tools))
+	for _, t := range s.Tools {
+		def := toolDefinition{Type: "function"}
+		def.Function.Name = t.Name
+		def.Function.Description = t.Description
+		def.Function.Parameters = t.Parameters
+		definitions = append(definitions, def)
+	}
+	return definitions
+}
+
+// toolDefinition 请求中的工具定义
+type toolDefinition struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string                 `json:"name"`
+		Description string                 `json:"description"`
+		Parameters  map[string]interface{} `json:"parameters"`
+	} `json:"function"`
+}
+
+// toolCallMessage 模型回复中的工具调用请求
+type toolCallMessage struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// toolRequest 请求中的工具部分：工具定义和 tool_choice（"auto" 允许调用，"none" 要求直接回答）
+type toolRequest struct {
+	Definitions []toolDefinition
+	Choice      string
+}
+
+// CallWithTools 带工具调用的对话：模型可以多轮调用工具获取数据，预算用完后要求模型直接给出最终回复
+// session 为空或没有工具时等同于 CallWithMessages
+func (cfg *Client) CallWithTools(systemPrompt, userPrompt string, session *ToolSession) (string, error) {
+	if session == nil || len(session.Tools) == 0 {
+		return cfg.CallWithMessages(systemPrompt, userPrompt)
+	}
+
+	messages := initialMessages(systemPrompt, userPrompt)
+	initial := len(messages)
+	tools := &toolRequest{Definitions: session.definitions()}
+	for {
+		// 已有工具结果的对话需要保留工具定义，预算用完时用 tool_choice=none 要求直接回答
+		tools.Choice = "auto"
+		if session.Remaining() == 0 {
+			tools.Choice = "none"
+		}
+
+		reply, err := cfg.chatWithRetry(messages, tools)
+		if err != nil {
+			return "", err
+		}
+		if len(reply.ToolCalls) == 0 || tools.Choice == "none" {
+			if len(messages) > initial {
+				session.recordTranscript(cfg.Label(), tools.Definitions, messages[initial:])
+			}
+			return reply.Content, nil
+		}
+
+		messages = append(messages, *reply)
+		for _, request := range reply.ToolCalls {
+			messages = append(messages, chatMessage{
+				Role:       "tool",
+				ToolCallID: request.ID,
+				Content:    session.execute(cfg.Label(), request),
+			})
+		}
+	}
+}
+
+// CallWithTranscript 重放记录的工具对话：发送原prompt和记录的工具调用及结果，要求模型直接给出最终回复（不再调用工具）
+func (cfg *Client) CallWithTranscript(systemPrompt, userPrompt string, transcript *Transcript) (string, error) {
+	var recorded []chatMessage
+	if err := json.Unmarshal(transcript.Messages, &recorded); err != nil {
+		return "", fmt.Errorf("解析记录的工具对话失败: %w", err)
+	}
+	tools := &toolRequest{Choice: "none"}
+	if err := json.Unmarshal(transcript.Tools, &tools.Definitions); err != nil {
+		return "", fmt.Errorf("解析记录的工具定义失败: %w", err)
+	}
+
+	messages := append(initialMessages(systemPrompt, userPrompt), recorded...)
+	reply, err := cfg.chatWithRetry(messages, tools)
+	if err != nil {
+		return "", err
+	}
+	return reply.Content, nil
+}
+
+// toolError 工具失败时返回给模型的内容
+func toolError(msg string) string {
+	data, _ := json.Marshal(map[string]string{"error": msg})
+	return string(data)
+}
+
+// truncateChars 截断到 maxChars 个字符
+func truncateChars(text string, maxChars int) string {
+	if utf8.RuneCountInString(text) <= maxChars {
+		return text
+	}
+	return string([]rune(text)[:maxChars]) + "…"
+}
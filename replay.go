@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"nofx/config"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/manager"
+	"nofx/trader"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// runReplay 重放一个周期: nofx replay [-config 配置文件] [-model 模型] [-temperature t] [-set 路径=值] <trader> <周期>
+// 把该周期记录的 system/user prompt 原样发给同一个或另一个模型，并与原决策逐项对比
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	configFile := fs.String("config", "config.json", "配置文件路径")
+	model := fs.String("model", "", "重放使用的模型（trader配置中的主模型、备用模型或投票模型名称，默认使用原周期的模型）")
+	temperature := fs.String("temperature", "", "采样温度（默认使用原周期记录的温度）")
+	showCoT := fs.Bool("cot", false, "输出重放的思维链")
+	var sets stringList
+	fs.Var(&sets, "set", "覆盖配置项，格式 路径=值，可重复")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: nofx replay [-config config.json] [-model 模型] [-temperature t] [-cot] [-set 路径=值] <trader> <周期>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("需要 trader ID 和周期编号两个参数")
+	}
+	traderID := fs.Arg(0)
+	cycle, err := strconv.Atoi(fs.Arg(1))
+	if err != nil || cycle <= 0 {
+		return fmt.Errorf("无效的周期编号: %s", fs.Arg(1))
+	}
+
+	if err := config.SetFlagOverrides(sets); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	var traderCfg *config.TraderConfig
+	for i := range cfg.Traders {
+		if cfg.Traders[i].ID == traderID {
+			traderCfg = &cfg.Traders[i]
+			break
+		}
+	}
+	if traderCfg == nil {
+		return fmt.Errorf("配置中没有 trader '%s'", traderID)
+	}
+
+	// 只读打开该trader的决策日志（不创建目录、不迁移，trader运行中也不会写入其数据库）
+	decisionLogger, err := logger.OpenDecisionLoggerReadOnly(logger.TraderLogDir(traderID))
+	if err != nil {
+		return fmt.Errorf("trader '%s' 没有可读取的决策日志: %w", traderID, err)
+	}
+	defer decisionLogger.Close()
+	record, err := decisionLogger.GetRecordByCycle(cycle)
+	if err != nil {
+		return fmt.Errorf("读取决策记录失败: %w", err)
+	}
+	if record == nil {
+		return fmt.Errorf("trader '%s' 没有周期#%d 的决策记录", traderID, cycle)
+	}
+	// 选择模型：默认使用原周期实际给出决策的模型
+	recordedModel := trader.RecordedModel(record)
+	target := *model
+	if target == "" {
+		target = recordedModel
+	}
+	clients := manager.ModelClients(*traderCfg, cfg)
+	client, ok := clients[target]
+	if !ok {
+		names := make([]string, 0, len(clients))
+		for name := range clients {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("trader '%s' 没有模型 '%s'，可用模型: %s", traderID, target, strings.Join(names, ", "))
+	}
+
+	// 使用原周期记录的调用参数；-model 指定了其他模型时只沿用温度
+	params := recordedParams(record, target)
+	if params != nil && *model == "" {
+		if params.Model != "" {
+			client.Model = params.Model
+		}
+		if params.MaxTokens > 0 {
+			client.MaxTokens = params.MaxTokens
+		}
+	}
+	if *temperature != "" {
+		t, err := strconv.ParseFloat(*temperature, 64)
+		if err != nil {
+			return fmt.Errorf("无效的采样温度: %s", *temperature)
+		}
+		client.Temperature = &t
+	} else if params != nil {
+		t := params.Temperature
+		client.Temperature = &t
+	}
+
+	in, err := trader.ReplayInput(record, target)
+	if err != nil {
+		return err
+	}
+
+	original, err := trader.RecordedDecisions(record, target)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🔁 重放 %s 周期#%d（%s）\n", traderID, cycle, record.Timestamp.Format("2006-01-02 15:04:05"))
+	fmt.Printf("   原模型: %s → 重放模型: %s（model=%s, temperature=%g, max_tokens=%d）\n",
+		recordedModel, client.Label(), client.Model, client.EffectiveTemperature(), client.EffectiveMaxTokens())
+	switch {
+	case in.Transcript != nil:
+		fmt.Printf("🔧 使用 %s 在原周期的工具调用和完整结果重放（不再实际调用工具）\n", in.Transcript.Model)
+	case len(record.ToolCalls) > 0:
+		fmt.Printf("⚠️  原周期调用了 %d 次工具，但记录中没有完整的工具对话（早期记录），模型只能看到原始prompt\n", len(record.ToolCalls))
+	}
+
+	replayed, err := decision.Replay(client, in)
+	if err != nil {
+		if replayed != nil && replayed.RawResponse != "" {
+			fmt.Printf("\n原始输出:\n%s\n", replayed.RawResponse)
+		}
+		return err
+	}
+	if *showCoT {
+		fmt.Printf("\n💭 思维链:\n%s\n", replayed.CoTTrace)
+	}
+
+	diffs := decision.DiffDecisions(original, replayed.Decisions)
+	if len(diffs) == 0 {
+		fmt.Printf("\n✓ 决策一致（%d 条）\n", len(original))
+		return nil
+	}
+	fmt.Printf("\n📊 %d 处差异（原 %d 条决策，重放 %d 条）:\n", len(diffs), len(original), len(replayed.Decisions))
+	for _, d := range diffs {
+		switch {
+		case d.Original == nil:
+			fmt.Printf("  + %s %s（重放新增）\n", d.Symbol, describeDecision(d.Replayed))
+		case d.Replayed == nil:
+			fmt.Printf("  - %s %s（重放中没有）\n", d.Symbol, describeDecision(d.Original))
+		default:
+			fmt.Printf("  ~ %s: %s\n", d.Symbol, strings.Join(d.Changes, ", "))
+		}
+	}
+	return nil
+}
+
+// recordedParams 记录中某个模型的调用参数（投票记录中取该模型的参数）
+func recordedParams(record *logger.DecisionRecord, model string) *logger.ModelParams {
+	for _, out := range record.ModelOutputs {
+		if out.Model == model || out.UsedModel == model {
+			return out.Params
+		}
+	}
+	if trader.RecordedModel(record) == model {
+		return record.ModelParams
+	}
+	return nil
+}
+
+// describeDecision 单条决策的简要描述
+func describeDecision(d *decision.Decision) string {
+	desc := d.Action
+	if d.Leverage > 0 {
+		desc += fmt.Sprintf(" %dx", d.Leverage)
+	}
+	if d.PositionSizeUSD > 0 {
+		desc += fmt.Sprintf(" %.2f USDT", d.PositionSizeUSD)
+	}
+	return desc
+}
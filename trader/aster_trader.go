@@ -949,6 +949,52 @@ func (t *AsterTrader) CancelAllOrders(symbol string) error {
 	return err
 }
 
+// GetStopOrders 获取该币种指定方向持仓的止损挂单（单向持仓模式，按买卖方向区分）
+func (t *AsterTrader) GetStopOrders(symbol string, positionSide string) ([]StopOrder, error) {
+	params := map[string]interface{}{
+		"symbol": symbol,
+	}
+	body, err := t.request("GET", "/fapi/v3/openOrders", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []struct {
+		OrderID   int64  `json:"orderId"`
+		Type      string `json:"type"`
+		Side      string `json:"side"`
+		StopPrice string `json:"stopPrice"`
+	}
+	if err := json.Unmarshal(body, &orders); err != nil {
+		return nil, err
+	}
+
+	side := "SELL"
+	if positionSide == "SHORT" {
+		side = "BUY"
+	}
+	var stops []StopOrder
+	for _, order := range orders {
+		if order.Type != "STOP_MARKET" || order.Side != side {
+			continue
+		}
+		stopPrice, _ := strconv.ParseFloat(order.StopPrice, 64)
+		stops = append(stops, StopOrder{OrderID: order.OrderID, StopPrice: stopPrice})
+	}
+	return stops, nil
+}
+
+// CancelOrder 取消单个订单
+func (t *AsterTrader) CancelOrder(symbol string, orderID int64) error {
+	params := map[string]interface{}{
+		"symbol":  symbol,
+		"orderId": orderID,
+	}
+
+	_, err := t.request("DELETE", "/fapi/v3/order", params)
+	return err
+}
+
 // FormatQuantity 格式化数量（实现Trader接口）
 func (t *AsterTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
 	formatted, err := t.formatQuantity(symbol, quantity)
@@ -2,6 +2,7 @@ package trader
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"nofx/decision"
@@ -10,6 +11,8 @@ import (
 	"nofx/mcp"
 	"nofx/pool"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -49,6 +52,26 @@ type AutoTraderConfig struct {
 	CustomAPIKey    string
 	CustomModelName string
 
+	// 主模型的token限制（0表示使用默认值，自定义API未配置上下文窗口时不限制prompt大小）
+	ContextWindow int // 上下文窗口（token）
+	MaxTokens     int // 单次回复的最大token数
+
+	// 多模型投票配置（EnsembleModels 为空时只使用上面的主模型）
+	EnsembleMode          string        // majority、weighted 或 unanimous
+	EnsemblePrimaryWeight float64       // 主模型权重
+	EnsembleModels        []ModelConfig // 额外参与投票的模型
+
+	// 备用模型（主模型失败或熔断时按顺序尝试）
+	FallbackModels []ModelConfig
+
+	// prompt模板（为空或 "default" 使用内置模板，否则为模板目录）
+	PromptTemplate string
+	PromptLanguage string // prompt语言（zh、en、ru、uk，为空使用zh）
+
+	// prompt实验（一个变体为固定分配，多个变体时每个周期轮换）
+	Experiment     string
+	PromptVariants []PromptVariant
+
 	// 扫描配置
 	ScanInterval time.Duration // 扫描间隔（建议3分钟）
 
@@ -67,6 +90,31 @@ type AutoTraderConfig struct {
 	MaxDailyLoss    float64       // 最大日亏损百分比（提示）
 	MaxDrawdown     float64       // 最大回撤百分比（提示）
 	StopTradingTime time.Duration // 触发风控后暂停时长
+
+	// AI连续失败时的持仓管理策略
+	SafeMode SafeModeConfig
+
+	// 跨周期记忆
+	Memory MemoryConfig
+
+	// 平仓后的交易复盘
+	Reflection ReflectionConfig
+
+	// 模型调用工具获取额外数据
+	Tools ToolsConfig
+}
+
+// ModelConfig 额外的AI模型（投票或备用）
+type ModelConfig struct {
+	Name      string
+	AIModel   string // "qwen"、"deepseek" 或 "custom"
+	APIKey    string
+	APIURL    string // 自定义API地址（custom）
+	ModelName string // 模型名称（custom必填，其他可覆盖默认模型）
+	Weight    float64
+
+	ContextWindow int // 上下文窗口（token，0表示使用默认值）
+	MaxTokens     int // 单次回复的最大token数（0表示默认值）
 }
 
 // AutoTrader 自动交易器
@@ -78,15 +126,33 @@ type AutoTrader struct {
 	config                AutoTraderConfig
 	trader                Trader // 使用Trader接口（支持多平台）
 	mcpClient             *mcp.Client
-	decisionLogger        *logger.DecisionLogger // 决策日志记录器
+	voters                []decision.Voter         // 多模型投票的全部模型（含主模型），为空时只使用 mcpClient
+	promptTemplate        *decision.PromptTemplate // prompt模板（创建时加载）
+	promptVariants        []promptVariant          // prompt实验的变体（未参与实验时为空）
+	tokenBudget           int                      // prompt的token预算（所有可能收到prompt的模型中最小的，0表示不限制）
+	decisionLogger        *logger.DecisionLogger   // 决策日志记录器
 	initialBalance        float64
 	dailyPnL              float64
 	lastResetTime         time.Time
 	stopUntil             time.Time
 	isRunning             bool
 	startTime             time.Time        // 系统启动时间
-	callCount             int              // AI调用次数
+	callCount             atomic.Int64     // AI调用次数（周期中写入，状态接口和事件并发读取）
 	positionFirstSeenTime map[string]int64 // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
+	aiFailures            int              // AI连续失败的周期数（达到阈值后进入安全模式，由 stateMu 保护）
+	reflections           *reflectionState // 交易复盘进度（复盘在后台运行）
+	reflectionWG          sync.WaitGroup   // 后台复盘（Rebuild/Shutdown 等待其结束后才返回或关闭决策日志）
+
+	// 运行控制（控制面API通过这些字段启停、暂停和调整trader）
+	stateMu    sync.RWMutex   // 保护 isRunning、isPaused、stopCh 以及可热更新的配置项
+	cycleMu    sync.Mutex     // 交易周期互斥（定时周期、手动触发和手动平仓不会并发执行）
+	isPaused   bool           // 暂停时跳过定时周期，但不影响已有持仓
+	stopCh     chan struct{}  // 每次Run创建，Stop时关闭
+	triggerCh  chan struct{}  // 手动触发周期
+	intervalCh chan struct{}  // 扫描间隔变更通知
+	pending    *pendingConfig // 等待在周期之间生效的配置热更新
+
+	events *EventBus // 交易事件总线（供API实时推送）
 }
 
 // NewAutoTrader 创建自动交易器
@@ -123,6 +189,54 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		log.Printf("🤖 [%s] 使用DeepSeek AI", config.Name)
 	}
 
+	// 备用模型：主模型失败或熔断时按顺序切换
+	mcpClient.Name = config.AIModel
+	mcpClient.ContextWindow = config.ContextWindow
+	mcpClient.MaxTokens = config.MaxTokens
+	if len(config.FallbackModels) > 0 {
+		fallbacks := make([]*mcp.Client, len(config.FallbackModels))
+		names := make([]string, len(config.FallbackModels))
+		for i, m := range config.FallbackModels {
+			fallbacks[i] = newModelClient(m)
+			names[i] = m.Name
+		}
+		mcpClient.SetFallbacks(fallbacks...)
+		log.Printf("🔁 [%s] 备用模型: %s", config.Name, strings.Join(names, " → "))
+	}
+
+	// 多模型投票：主模型和额外模型使用相同的prompt并行决策
+	var voters []decision.Voter
+	if len(config.EnsembleModels) > 0 {
+		voters = append(voters, decision.Voter{Name: config.AIModel, Client: mcpClient, Weight: config.EnsemblePrimaryWeight})
+		for _, m := range config.EnsembleModels {
+			voters = append(voters, decision.Voter{Name: m.Name, Client: newModelClient(m), Weight: m.Weight})
+		}
+		names := make([]string, len(voters))
+		for i, v := range voters {
+			names[i] = v.Name
+		}
+		log.Printf("🗳️  [%s] 启用多模型投票 (%s): %s", config.Name, config.EnsembleMode, strings.Join(names, ", "))
+	}
+
+	// prompt的token预算：同一份prompt会发送给主模型、备用模型和投票模型，按其中最小的上下文窗口计算
+	tokenBudget := promptTokenBudget(mcpClient, voters)
+	if tokenBudget > 0 {
+		log.Printf("✂️  [%s] prompt token预算: %d", config.Name, tokenBudget)
+	}
+
+	// 加载prompt模板（模板文件修改后需重建trader才生效）
+	promptTemplate, err := decision.LoadPromptTemplate(config.PromptTemplate, config.PromptLanguage)
+	if err != nil {
+		return nil, fmt.Errorf("加载prompt模板失败: %w", err)
+	}
+	if promptTemplate.Name != decision.DefaultPromptTemplate || promptTemplate.Language != decision.DefaultLanguage {
+		log.Printf("📝 [%s] 使用prompt模板: %s (%s)", config.Name, promptTemplate.Name, promptTemplate.Language)
+	}
+	promptVariants, err := loadPromptVariants(config)
+	if err != nil {
+		return nil, err
+	}
+
 	// 初始化币种池API
 	if config.CoinPoolAPIURL != "" {
 		pool.SetCoinPoolAPI(config.CoinPoolAPIURL)
@@ -135,7 +249,6 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 
 	// 根据配置创建对应的交易器
 	var trader Trader
-	var err error
 
 	switch config.Exchange {
 	case "binance":
@@ -163,8 +276,7 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 	}
 
 	// 初始化决策日志记录器（使用trader ID创建独立目录）
-	logDir := fmt.Sprintf("decision_logs/%s", config.ID)
-	decisionLogger := logger.NewDecisionLogger(logDir)
+	decisionLogger := logger.NewDecisionLogger(logger.TraderLogDir(config.ID))
 
 	return &AutoTrader{
 		id:                    config.ID,
@@ -174,57 +286,107 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		config:                config,
 		trader:                trader,
 		mcpClient:             mcpClient,
+		voters:                voters,
+		promptTemplate:        promptTemplate,
+		promptVariants:        promptVariants,
+		tokenBudget:           tokenBudget,
 		decisionLogger:        decisionLogger,
 		initialBalance:        config.InitialBalance,
 		lastResetTime:         time.Now(),
 		startTime:             time.Now(),
-		callCount:             0,
 		isRunning:             false,
 		positionFirstSeenTime: make(map[string]int64),
+		reflections:           newReflectionState(),
+		triggerCh:             make(chan struct{}, 1),
+		intervalCh:            make(chan struct{}, 1),
+		events:                NewEventBus(),
 	}, nil
 }
 
-// Run 运行自动交易主循环
+// Run 运行自动交易主循环（阻塞直到Stop）
 func (at *AutoTrader) Run() error {
+	at.stateMu.Lock()
+	if at.isRunning {
+		at.stateMu.Unlock()
+		return fmt.Errorf("%w: trader '%s' 已在运行中", ErrStateConflict, at.id)
+	}
 	at.isRunning = true
+	at.stopCh = make(chan struct{})
+	stopCh := at.stopCh
+	interval := at.config.ScanInterval
+	at.stateMu.Unlock()
+
 	log.Println("🚀 AI驱动自动交易系统启动")
 	log.Printf("💰 初始余额: %.2f USDT", at.initialBalance)
-	log.Printf("⚙️  扫描间隔: %v", at.config.ScanInterval)
+	log.Printf("⚙️  扫描间隔: %v", interval)
 	log.Println("🤖 AI将全权决定杠杆、仓位大小、止损止盈等参数")
+	at.publish(EventStateChange, map[string]interface{}{"state": "running"})
 
-	ticker := time.NewTicker(at.config.ScanInterval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	// 首次立即执行
-	if err := at.runCycle(); err != nil {
-		log.Printf("❌ 执行失败: %v", err)
-	}
+	at.runScheduledCycle()
 
-	for at.isRunning {
+	for {
 		select {
+		case <-stopCh:
+			return nil
 		case <-ticker.C:
-			if err := at.runCycle(); err != nil {
-				log.Printf("❌ 执行失败: %v", err)
-			}
+			at.runScheduledCycle()
+		case <-at.triggerCh:
+			log.Printf("⚡ [%s] 手动触发交易周期", at.name)
+			at.executeCycle()
+		case <-at.intervalCh:
+			interval = at.GetScanInterval()
+			ticker.Reset(interval)
+			log.Printf("⚙️  [%s] 扫描间隔已调整为 %v", at.name, interval)
 		}
 	}
-
-	return nil
 }
 
-// Stop 停止自动交易
+// Stop 停止自动交易（正在执行的周期会执行完毕）
 func (at *AutoTrader) Stop() {
+	at.stateMu.Lock()
+	defer at.stateMu.Unlock()
+
+	if !at.isRunning {
+		return
+	}
 	at.isRunning = false
+	close(at.stopCh)
 	log.Println("⏹ 自动交易系统停止")
+	at.publish(EventStateChange, map[string]interface{}{"state": "stopped"})
+}
+
+// runScheduledCycle 执行定时周期（暂停时跳过）
+func (at *AutoTrader) runScheduledCycle() {
+	if at.IsPaused() {
+		log.Printf("⏸ [%s] 已暂停，跳过本周期", at.name)
+		return
+	}
+	at.executeCycle()
+}
+
+// executeCycle 串行执行一个交易周期
+func (at *AutoTrader) executeCycle() {
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+
+	at.applyPendingConfig()
+	if err := at.runCycle(); err != nil {
+		log.Printf("❌ 执行失败: %v", err)
+	}
 }
 
 // runCycle 运行一个交易周期（使用AI全权决策）
 func (at *AutoTrader) runCycle() error {
-	at.callCount++
+	cycle := at.callCount.Add(1)
 
-	log.Printf("\n" + strings.Repeat("=", 70))
-	log.Printf("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
-	log.Printf(strings.Repeat("=", 70))
+	log.Print("\n" + strings.Repeat("=", 70))
+	log.Printf("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), cycle)
+	log.Print(strings.Repeat("=", 70))
+	at.publish(EventCycleStart, nil)
 
 	// 创建决策记录
 	record := &logger.DecisionRecord{
@@ -238,7 +400,11 @@ func (at *AutoTrader) runCycle() error {
 		log.Printf("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", remaining.Minutes())
-		at.decisionLogger.LogDecision(record)
+		at.publish(EventRiskHalt, map[string]interface{}{
+			"stop_until":        at.stopUntil,
+			"remaining_minutes": remaining.Minutes(),
+		})
+		at.finishCycle(record)
 		return nil
 	}
 
@@ -254,9 +420,12 @@ func (at *AutoTrader) runCycle() error {
 	if err != nil {
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("构建交易上下文失败: %v", err)
-		at.decisionLogger.LogDecision(record)
+		at.finishCycle(record)
 		return fmt.Errorf("构建交易上下文失败: %w", err)
 	}
+	at.applyPromptVariant(ctx, record)
+	// 周期结束后（含决策失败）在后台复盘新平仓的交易
+	defer at.startReflection(ctx)
 
 	// 保存账户状态快照
 	record.AccountState = logger.AccountSnapshot{
@@ -291,41 +460,111 @@ func (at *AutoTrader) runCycle() error {
 
 	// 4. 调用AI获取完整决策
 	log.Println("🤖 正在请求AI分析并决策...")
-	decision, err := decision.GetFullDecision(ctx, at.mcpClient)
+	decision, err := at.getDecision(ctx)
+
+	// 记录模型本周期的工具调用（决策失败时同样记录）
+	if calls := ctx.Tools.Calls(); len(calls) > 0 {
+		record.ToolCalls = toLoggerToolCalls(calls)
+		record.ToolTranscripts = toLoggerTranscripts(ctx.Tools.Transcripts())
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔧 模型请求工具%d次（每周期上限%d次）", len(calls), ctx.Tools.MaxCalls))
+	}
 
 	// 即使有错误，也保存思维链、决策和输入prompt（用于debug）
 	if decision != nil {
 		record.InputPrompt = decision.UserPrompt
 		record.CoTTrace = decision.CoTTrace
+		record.AIModel = decision.Model
+		record.SystemPrompt = decision.SystemPrompt
+		record.RawResponse = decision.RawResponse
+		record.ModelParams = toLoggerModelParams(decision.Params)
+		record.Validation = &logger.ValidationParams{
+			AccountEquity:   ctx.Account.TotalEquity,
+			BTCETHLeverage:  ctx.BTCETHLeverage,
+			AltcoinLeverage: ctx.AltcoinLeverage,
+			Language:        ctx.Language(),
+		}
 		if len(decision.Decisions) > 0 {
 			decisionJSON, _ := json.MarshalIndent(decision.Decisions, "", "  ")
 			record.DecisionJSON = string(decisionJSON)
 		}
+		for _, out := range decision.ModelOutputs {
+			decisionJSON, _ := json.MarshalIndent(out.Decisions, "", "  ")
+			record.ModelOutputs = append(record.ModelOutputs, logger.ModelOutput{
+				Model:        out.Model,
+				UsedModel:    out.UsedModel,
+				Weight:       out.Weight,
+				RawResponse:  out.RawResponse,
+				DecisionJSON: string(decisionJSON),
+				Error:        out.Error,
+				DurationMs:   out.DurationMs,
+				Params:       toLoggerModelParams(out.Params),
+			})
+		}
+		record.VoteSummary = decision.VoteSummary
+		for _, line := range decision.VoteSummary {
+			log.Printf("🗳️  分歧 %s", line)
+		}
+		if b := decision.Budget; b != nil {
+			record.PromptBudget = &logger.PromptBudget{
+				BudgetTokens:    b.BudgetTokens,
+				OriginalTokens:  b.OriginalTokens,
+				EstimatedTokens: b.EstimatedTokens,
+				Compressed:      b.Compressed,
+				Dropped:         b.Dropped,
+				OverBudget:      b.OverBudget,
+			}
+			msg := fmt.Sprintf("✂️ prompt超出token预算(%d/%d)：压缩 %d 个候选币种", b.OriginalTokens, b.BudgetTokens, len(b.Compressed))
+			if len(b.Dropped) > 0 {
+				msg += fmt.Sprintf("，移除 %s", strings.Join(b.Dropped, ", "))
+			}
+			record.ExecutionLog = append(record.ExecutionLog, msg)
+		}
+	}
+
+	aiEvent := map[string]interface{}{"success": err == nil}
+	if record.PromptVariant != "" {
+		aiEvent["prompt_variant"] = record.PromptVariant
+	}
+	if decision != nil {
+		aiEvent["model"] = decision.Model
+		aiEvent["cot_trace"] = decision.CoTTrace
+		aiEvent["decision_count"] = len(decision.Decisions)
+		if len(decision.VoteSummary) > 0 {
+			aiEvent["vote_summary"] = decision.VoteSummary
+		}
+	}
+	if err != nil {
+		aiEvent["error"] = err.Error()
 	}
+	at.publish(EventAIResponse, aiEvent)
 
 	if err != nil {
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("获取AI决策失败: %v", err)
+		at.handleAIFailure(ctx, record)
 
 		// 打印AI思维链（即使有错误）
 		if decision != nil && decision.CoTTrace != "" {
-			log.Printf("\n" + strings.Repeat("-", 70))
+			log.Print("\n" + strings.Repeat("-", 70))
 			log.Println("💭 AI思维链分析（错误情况）:")
 			log.Println(strings.Repeat("-", 70))
 			log.Println(decision.CoTTrace)
-			log.Printf(strings.Repeat("-", 70) + "\n")
+			log.Print(strings.Repeat("-", 70) + "\n")
 		}
 
-		at.decisionLogger.LogDecision(record)
+		at.finishCycle(record)
 		return fmt.Errorf("获取AI决策失败: %w", err)
 	}
 
+	at.resetAIFailures()
+	at.saveJournal(decision.Journal, record)
+
 	// 5. 打印AI思维链
-	log.Printf("\n" + strings.Repeat("-", 70))
+	log.Print("\n" + strings.Repeat("-", 70))
 	log.Println("💭 AI思维链分析:")
 	log.Println(strings.Repeat("-", 70))
 	log.Println(decision.CoTTrace)
-	log.Printf(strings.Repeat("-", 70) + "\n")
+	log.Print(strings.Repeat("-", 70) + "\n")
 
 	// 6. 打印AI决策
 	log.Printf("📋 AI决策列表 (%d 个):\n", len(decision.Decisions))
@@ -340,6 +579,9 @@ func (at *AutoTrader) runCycle() error {
 
 	// 7. 对决策排序：确保先平仓后开仓（防止仓位叠加超限）
 	sortedDecisions := sortDecisionsByPriority(decision.Decisions)
+	at.publish(EventDecisionValidated, map[string]interface{}{
+		"decisions": sortedDecisions,
+	})
 
 	log.Println("🔄 执行顺序（已优化）: 先平仓→后开仓")
 	for i, d := range sortedDecisions {
@@ -350,21 +592,31 @@ func (at *AutoTrader) runCycle() error {
 	// 执行决策并记录结果
 	for _, d := range sortedDecisions {
 		actionRecord := logger.DecisionAction{
-			Action:    d.Action,
-			Symbol:    d.Symbol,
-			Quantity:  0,
-			Leverage:  d.Leverage,
-			Price:     0,
-			Timestamp: time.Now(),
-			Success:   false,
+			Action:     d.Action,
+			Symbol:     d.Symbol,
+			Quantity:   0,
+			Leverage:   d.Leverage,
+			Price:      0,
+			StopLoss:   d.StopLoss,
+			TakeProfit: d.TakeProfit,
+			Timestamp:  time.Now(),
+			Success:    false,
+
+			// 交易复盘使用的开平仓理由和市场快照
+			Reasoning: d.Reasoning,
+			Market:    market.SnapshotOf(ctx.MarketDataMap[d.Symbol]),
 		}
 
 		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
 			log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
 			actionRecord.Error = err.Error()
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+			at.publishOrder(EventOrderFailed, actionRecord)
 		} else {
 			actionRecord.Success = true
+			if d.Action != "hold" && d.Action != "wait" {
+				at.publishOrder(EventOrderPlaced, actionRecord)
+			}
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
 			// 成功执行后短暂延迟
 			time.Sleep(1 * time.Second)
@@ -374,11 +626,101 @@ func (at *AutoTrader) runCycle() error {
 	}
 
 	// 8. 保存决策记录
+	at.finishCycle(record)
+
+	return nil
+}
+
+// getDecision 获取AI决策（启用多模型投票时并行请求全部模型并合并）
+func (at *AutoTrader) getDecision(ctx *decision.Context) (*decision.FullDecision, error) {
+	if len(at.voters) > 0 {
+		return decision.GetEnsembleDecision(ctx, at.voters, at.config.EnsembleMode)
+	}
+	return decision.GetFullDecision(ctx, at.mcpClient)
+}
+
+// ErrCycleRunning 交易周期正在执行（只读请求不排队等待周期结束）
+var ErrCycleRunning = errors.New("交易周期正在执行，请稍后重试")
+
+// PreviewPrompt 按当前账户、持仓和市场数据渲染prompt（不调用AI、不下单）
+// 构建上下文会更新持仓计时，因此与交易周期互斥；周期正在执行时直接返回 ErrCycleRunning，不阻塞等待
+func (at *AutoTrader) PreviewPrompt() (*decision.PromptPreview, error) {
+	if !at.cycleMu.TryLock() {
+		return nil, ErrCycleRunning
+	}
+	defer at.cycleMu.Unlock()
+
+	ctx, err := at.buildTradingContext()
+	if err != nil {
+		return nil, fmt.Errorf("构建交易上下文失败: %w", err)
+	}
+	// 参与prompt实验时预览下一个周期将使用的变体
+	variant := at.variantForCycle(int(at.callCount.Load()) + 1)
+	if variant != nil {
+		ctx.PromptTemplate = variant.template
+	}
+	preview, err := decision.PreviewPrompt(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if variant != nil {
+		preview.Variant = variant.name
+	}
+	return preview, nil
+}
+
+// newModelClient 为投票或备用模型创建AI客户端
+func newModelClient(m ModelConfig) *mcp.Client {
+	client := mcp.New()
+	client.Name = m.Name
+	switch m.AIModel {
+	case "custom":
+		client.SetCustomAPI(m.APIURL, m.APIKey, m.ModelName)
+	case "qwen":
+		client.SetQwenAPIKey(m.APIKey, "")
+	default:
+		client.SetDeepSeekAPIKey(m.APIKey)
+	}
+	if m.AIModel != "custom" && m.ModelName != "" {
+		client.Model = m.ModelName
+	}
+	client.ContextWindow = m.ContextWindow
+	client.MaxTokens = m.MaxTokens
+	return client
+}
+
+// promptTokenBudget 主模型（含备用模型）和投票模型中最小的prompt token预算，全部未知时返回0
+func promptTokenBudget(primary *mcp.Client, voters []decision.Voter) int {
+	budget := primary.PromptBudget()
+	for _, v := range voters {
+		if b := v.Client.PromptBudget(); b > 0 && (budget == 0 || b < budget) {
+			budget = b
+		}
+	}
+	return budget
+}
+
+// finishCycle 保存决策记录并发布周期结束事件
+func (at *AutoTrader) finishCycle(record *logger.DecisionRecord) {
 	if err := at.decisionLogger.LogDecision(record); err != nil {
 		log.Printf("⚠ 保存决策记录失败: %v", err)
 	}
 
-	return nil
+	at.publish(EventCycleEnd, map[string]interface{}{
+		"record_cycle":   record.CycleNumber, // 决策日志中的周期编号（重启后延续）
+		"success":        record.Success,
+		"error":          record.ErrorMessage,
+		"total_equity":   record.AccountState.TotalBalance,
+		"position_count": record.AccountState.PositionCount,
+		"actions":        len(record.Decisions),
+	})
+}
+
+// publishOrder 发布下单结果事件
+func (at *AutoTrader) publishOrder(eventType EventType, action logger.DecisionAction) {
+	at.publish(eventType, map[string]interface{}{
+		"action": action,
+	})
 }
 
 // buildTradingContext 构建交易上下文
@@ -514,9 +856,8 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		marginUsedPct = (totalMarginUsed / totalEquity) * 100
 	}
 
-	// 5. 分析历史表现（最近100个周期，避免长期持仓的交易记录丢失）
-	// 假设每3分钟一个周期，100个周期 = 5小时，足够覆盖大部分交易
-	performance, err := at.decisionLogger.AnalyzePerformance(100)
+	// 5. 分析历史表现（最近100个周期的交易，净值指标至少回看 logger.PerformanceWindow）
+	performance, err := at.decisionLogger.AnalyzePerformance(logger.DefaultLookbackCycles)
 	if err != nil {
 		log.Printf("⚠️  分析历史表现失败: %v", err)
 		// 不影响主流程，继续执行（但设置performance为nil以避免传递错误数据）
@@ -524,12 +865,15 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	}
 
 	// 6. 构建上下文
+	btcEthLeverage, altcoinLeverage := at.GetLeverageLimits()
+	// 工具调用的对话（工具定义和结果）与prompt共用上下文窗口，prompt预算需扣除
+	tools := at.newToolSession()
 	ctx := &decision.Context{
 		CurrentTime:     time.Now().Format("2006-01-02 15:04:05"),
 		RuntimeMinutes:  int(time.Since(at.startTime).Minutes()),
-		CallCount:       at.callCount,
-		BTCETHLeverage:  at.config.BTCETHLeverage,  // 使用配置的杠杆倍数
-		AltcoinLeverage: at.config.AltcoinLeverage, // 使用配置的杠杆倍数
+		CallCount:       int(at.callCount.Load()),
+		BTCETHLeverage:  btcEthLeverage,  // 使用配置的杠杆倍数（可通过控制面API热更新）
+		AltcoinLeverage: altcoinLeverage, // 使用配置的杠杆倍数（可通过控制面API热更新）
 		Account: decision.AccountInfo{
 			TotalEquity:      totalEquity,
 			AvailableBalance: availableBalance,
@@ -544,6 +888,11 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		Performance:    performance, // 添加历史表现分析
 		CoinWhitelistEnabled: at.config.CoinWhitelistEnabled, // 币种白名单配置
 		CoinWhitelist:        at.config.CoinWhitelist,        // 币种白名单列表
+		PromptTemplate:       at.promptTemplate,
+		TokenBudget:          toolPromptBudget(at.tokenBudget, tools),
+		Memory:               at.buildMemory(),
+		Lessons:              at.recentLessons(),
+		Tools:                tools,
 	}
 
 	return ctx, nil
@@ -753,15 +1102,19 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		aiProvider = "Qwen"
 	}
 
+	at.stateMu.RLock()
+	defer at.stateMu.RUnlock()
+
 	return map[string]interface{}{
 		"trader_id":       at.id,
 		"trader_name":     at.name,
 		"ai_model":        at.aiModel,
 		"exchange":        at.exchange,
 		"is_running":      at.isRunning,
+		"is_paused":       at.isPaused,
 		"start_time":      at.startTime.Format(time.RFC3339),
 		"runtime_minutes": int(time.Since(at.startTime).Minutes()),
-		"call_count":      at.callCount,
+		"call_count":      at.callCount.Load(),
 		"initial_balance": at.initialBalance,
 		"scan_interval":   at.config.ScanInterval.String(),
 		"stop_until":      at.stopUntil.Format(time.RFC3339),
@@ -769,6 +1122,11 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		"ai_provider":     aiProvider,
 		"whitelist_enabled": at.config.CoinWhitelistEnabled,
 		"whitelist_coins":   at.config.CoinWhitelist,
+		"btc_eth_leverage":  at.config.BTCETHLeverage,
+		"altcoin_leverage":  at.config.AltcoinLeverage,
+		"model_breakers":    at.mcpClient.BreakerStates(), // 主模型和备用模型的熔断状态
+		"ai_failures":       at.aiFailures,                // AI连续失败的周期数
+		"safe_mode":         at.inSafeMode(),              // 是否处于安全模式
 	}
 }
 
@@ -416,6 +416,38 @@ func (t *FuturesTrader) CancelAllOrders(symbol string) error {
 	return nil
 }
 
+// GetStopOrders 获取该币种指定方向持仓的止损挂单
+func (t *FuturesTrader) GetStopOrders(symbol string, positionSide string) ([]StopOrder, error) {
+	orders, err := t.client.NewListOpenOrdersService().
+		Symbol(symbol).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	var stops []StopOrder
+	for _, order := range orders {
+		if order.Type != futures.OrderTypeStopMarket || string(order.PositionSide) != positionSide {
+			continue
+		}
+		stopPrice, _ := strconv.ParseFloat(order.StopPrice, 64)
+		stops = append(stops, StopOrder{OrderID: order.OrderID, StopPrice: stopPrice})
+	}
+	return stops, nil
+}
+
+// CancelOrder 取消单个挂单
+func (t *FuturesTrader) CancelOrder(symbol string, orderID int64) error {
+	_, err := t.client.NewCancelOrderService().
+		Symbol(symbol).
+		OrderID(orderID).
+		Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("取消订单失败 (orderId=%d): %w", orderID, err)
+	}
+	return nil
+}
+
 // GetMarketPrice 获取市场价格
 func (t *FuturesTrader) GetMarketPrice(symbol string) (float64, error) {
 	prices, err := t.client.NewListPricesService().Symbol(symbol).Do(context.Background())
@@ -0,0 +1,213 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+// maxLeverageLimit 交易所允许的最大杠杆倍数
+const maxLeverageLimit = 125
+
+// ErrStateConflict trader当前状态不允许该操作（如暂停已暂停的trader、触发未运行的trader）
+var ErrStateConflict = errors.New("trader状态冲突")
+
+// IsRunning 是否正在运行
+func (at *AutoTrader) IsRunning() bool {
+	at.stateMu.RLock()
+	defer at.stateMu.RUnlock()
+	return at.isRunning
+}
+
+// IsPaused 是否已暂停
+func (at *AutoTrader) IsPaused() bool {
+	at.stateMu.RLock()
+	defer at.stateMu.RUnlock()
+	return at.isPaused
+}
+
+// Pause 暂停交易：跳过后续定时周期，已有持仓及其止盈止损单保持不变
+func (at *AutoTrader) Pause() error {
+	at.stateMu.Lock()
+	defer at.stateMu.Unlock()
+
+	if at.isPaused {
+		return fmt.Errorf("%w: trader '%s' 已处于暂停状态", ErrStateConflict, at.id)
+	}
+	at.isPaused = true
+	log.Printf("⏸ [%s] 交易已暂停", at.name)
+	at.publish(EventStateChange, map[string]interface{}{"state": "paused"})
+	return nil
+}
+
+// Resume 恢复交易
+func (at *AutoTrader) Resume() error {
+	at.stateMu.Lock()
+	defer at.stateMu.Unlock()
+
+	if !at.isPaused {
+		return fmt.Errorf("%w: trader '%s' 未暂停", ErrStateConflict, at.id)
+	}
+	at.isPaused = false
+	log.Printf("▶️  [%s] 交易已恢复", at.name)
+	at.publish(EventStateChange, map[string]interface{}{"state": "resumed"})
+	return nil
+}
+
+// TriggerCycle 立即触发一个交易周期（异步执行，不影响定时周期）
+func (at *AutoTrader) TriggerCycle() error {
+	at.stateMu.RLock()
+	defer at.stateMu.RUnlock()
+
+	if !at.isRunning {
+		return fmt.Errorf("%w: trader '%s' 未运行", ErrStateConflict, at.id)
+	}
+	if at.isPaused {
+		return fmt.Errorf("%w: trader '%s' 已暂停，请先恢复", ErrStateConflict, at.id)
+	}
+
+	select {
+	case at.triggerCh <- struct{}{}:
+		return nil
+	default:
+		return fmt.Errorf("%w: trader '%s' 已有待执行的手动周期", ErrStateConflict, at.id)
+	}
+}
+
+// GetScanInterval 获取扫描间隔
+func (at *AutoTrader) GetScanInterval() time.Duration {
+	at.stateMu.RLock()
+	defer at.stateMu.RUnlock()
+	return at.config.ScanInterval
+}
+
+// SetScanInterval 调整扫描间隔（运行中立即生效，从调整时刻重新计时）
+func (at *AutoTrader) SetScanInterval(interval time.Duration) error {
+	if interval < time.Minute {
+		return fmt.Errorf("扫描间隔不能小于1分钟")
+	}
+
+	at.stateMu.Lock()
+	at.config.ScanInterval = interval
+	at.stateMu.Unlock()
+
+	select {
+	case at.intervalCh <- struct{}{}:
+	default:
+		// 已有未处理的变更通知，主循环会读取最新的间隔
+	}
+	log.Printf("⚙️  [%s] 扫描间隔设置为 %v", at.name, interval)
+	return nil
+}
+
+// GetLeverageLimits 获取杠杆上限（BTC/ETH, 山寨币）
+func (at *AutoTrader) GetLeverageLimits() (int, int) {
+	at.stateMu.RLock()
+	defer at.stateMu.RUnlock()
+	return at.config.BTCETHLeverage, at.config.AltcoinLeverage
+}
+
+// SetLeverageLimits 调整杠杆上限（从下一个周期开始生效，不影响已有持仓）
+func (at *AutoTrader) SetLeverageLimits(btcEthLeverage, altcoinLeverage int) error {
+	if btcEthLeverage <= 0 || btcEthLeverage > maxLeverageLimit {
+		return fmt.Errorf("BTC/ETH杠杆必须在1-%d之间", maxLeverageLimit)
+	}
+	if altcoinLeverage <= 0 || altcoinLeverage > maxLeverageLimit {
+		return fmt.Errorf("山寨币杠杆必须在1-%d之间", maxLeverageLimit)
+	}
+
+	at.stateMu.Lock()
+	at.config.BTCETHLeverage = btcEthLeverage
+	at.config.AltcoinLeverage = altcoinLeverage
+	at.stateMu.Unlock()
+
+	log.Printf("⚙️  [%s] 杠杆上限设置为 BTC/ETH %dx, 山寨币 %dx", at.name, btcEthLeverage, altcoinLeverage)
+	return nil
+}
+
+// CloseAllPositions 市价平掉所有持仓，并作为一条决策记录保存
+// 会等待正在执行的交易周期结束，避免与AI决策同时下单
+func (at *AutoTrader) CloseAllPositions() ([]logger.DecisionAction, error) {
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+
+	log.Printf("🛑 [%s] 手动平仓所有持仓", at.name)
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	record := &logger.DecisionRecord{
+		ExecutionLog: []string{"🛑 手动平仓所有持仓"},
+		Success:      true,
+	}
+
+	var failed []string
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		markPrice, _ := pos["markPrice"].(float64)
+		quantity, _ := pos["positionAmt"].(float64)
+		if quantity < 0 {
+			quantity = -quantity
+		}
+		if !at.closePosition(symbol, side, quantity, markPrice, record) {
+			failed = append(failed, fmt.Sprintf("%s %s", symbol, side))
+		}
+	}
+
+	if len(failed) > 0 {
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("部分持仓平仓失败: %s", strings.Join(failed, ", "))
+	}
+	at.finishCycle(record)
+
+	if len(failed) > 0 {
+		return record.Decisions, fmt.Errorf("%s", record.ErrorMessage)
+	}
+	return record.Decisions, nil
+}
+
+// closePosition 全部平掉一个持仓，把结果写入决策记录并发布下单事件，返回是否成功（调用方需持有 cycleMu）
+func (at *AutoTrader) closePosition(symbol, side string, quantity, markPrice float64, record *logger.DecisionRecord) bool {
+	actionRecord := logger.DecisionAction{
+		Action:    "close_" + side,
+		Symbol:    symbol,
+		Quantity:  quantity,
+		Price:     markPrice,
+		Timestamp: time.Now(),
+	}
+
+	var order map[string]interface{}
+	var err error
+	switch side {
+	case "long":
+		order, err = at.trader.CloseLong(symbol, 0) // 0 = 全部平仓
+	case "short":
+		order, err = at.trader.CloseShort(symbol, 0)
+	default:
+		err = fmt.Errorf("未知的持仓方向: %s", side)
+	}
+
+	if err != nil {
+		log.Printf("  ❌ %s %s 平仓失败: %v", symbol, side, err)
+		actionRecord.Error = err.Error()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", symbol, actionRecord.Action, err))
+		at.publishOrder(EventOrderFailed, actionRecord)
+	} else {
+		actionRecord.Success = true
+		if orderID, ok := order["orderId"].(int64); ok {
+			actionRecord.OrderID = orderID
+		}
+		delete(at.positionFirstSeenTime, symbol+"_"+side)
+		log.Printf("  ✓ %s %s 平仓成功", symbol, side)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", symbol, actionRecord.Action))
+		at.publishOrder(EventOrderPlaced, actionRecord)
+	}
+	record.Decisions = append(record.Decisions, actionRecord)
+	return err == nil
+}
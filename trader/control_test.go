@@ -0,0 +1,152 @@
+package trader
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newControlTrader 未启动主循环的AutoTrader（只用于运行控制）
+func newControlTrader(running bool) *AutoTrader {
+	return &AutoTrader{
+		id:         "t1",
+		name:       "t1",
+		config:     AutoTraderConfig{ScanInterval: 3 * time.Minute, BTCETHLeverage: 5, AltcoinLeverage: 5},
+		isRunning:  running,
+		triggerCh:  make(chan struct{}, 1),
+		intervalCh: make(chan struct{}, 1),
+		events:     NewEventBus(),
+	}
+}
+
+func TestStateTransitions(t *testing.T) {
+	type step struct {
+		op           string // pause, resume, trigger
+		wantConflict bool
+	}
+
+	tests := []struct {
+		name    string
+		running bool
+		steps   []step
+	}{
+		{
+			name:  "暂停已暂停的trader",
+			steps: []step{{"pause", false}, {"pause", true}},
+		},
+		{
+			name:  "恢复未暂停的trader",
+			steps: []step{{"resume", true}, {"pause", false}, {"resume", false}, {"resume", true}},
+		},
+		{
+			name:  "触发已停止的trader",
+			steps: []step{{"trigger", true}},
+		},
+		{
+			name:    "触发运行中的trader，已有待执行周期时拒绝",
+			running: true,
+			steps:   []step{{"trigger", false}, {"trigger", true}},
+		},
+		{
+			name:    "暂停时不能触发",
+			running: true,
+			steps:   []step{{"pause", false}, {"trigger", true}, {"resume", false}, {"trigger", false}},
+		},
+		{
+			name:  "停止的trader可以暂停和恢复",
+			steps: []step{{"pause", false}, {"resume", false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := newControlTrader(tt.running)
+			ops := map[string]func() error{
+				"pause":   at.Pause,
+				"resume":  at.Resume,
+				"trigger": at.TriggerCycle,
+			}
+
+			for i, s := range tt.steps {
+				err := ops[s.op]()
+				if got := errors.Is(err, ErrStateConflict); got != s.wantConflict || (err != nil && !got) {
+					t.Fatalf("step %d %s: error = %v, want conflict %v", i, s.op, err, s.wantConflict)
+				}
+			}
+		})
+	}
+}
+
+func TestSetScanInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		wantErr  bool
+	}{
+		{"为0", 0, true},
+		{"为负", -time.Minute, true},
+		{"小于1分钟", 59 * time.Second, true},
+		{"恰好1分钟", time.Minute, false},
+		{"10分钟", 10 * time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := newControlTrader(true)
+
+			err := at.SetScanInterval(tt.interval)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetScanInterval(%v) error = %v, wantErr %v", tt.interval, err, tt.wantErr)
+			}
+
+			want := 3 * time.Minute
+			if !tt.wantErr {
+				want = tt.interval
+			}
+			if got := at.GetScanInterval(); got != want {
+				t.Errorf("GetScanInterval() = %v, want %v", got, want)
+			}
+			// 生效时通知主循环重新计时
+			if notified := len(at.intervalCh) == 1; notified == tt.wantErr {
+				t.Errorf("interval notified = %v, want %v", notified, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetLeverageLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		btcEth  int
+		altcoin int
+		wantErr bool
+	}{
+		{"最小值", 1, 1, false},
+		{"最大值", maxLeverageLimit, maxLeverageLimit, false},
+		{"不同杠杆", 20, 10, false},
+		{"BTC/ETH为0", 0, 5, true},
+		{"BTC/ETH超过上限", maxLeverageLimit + 1, 5, true},
+		{"山寨币为负", 5, -1, true},
+		{"山寨币超过上限", 5, maxLeverageLimit + 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := newControlTrader(false)
+
+			err := at.SetLeverageLimits(tt.btcEth, tt.altcoin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetLeverageLimits(%d, %d) error = %v, wantErr %v", tt.btcEth, tt.altcoin, err, tt.wantErr)
+			}
+
+			// 任一参数无效时两个杠杆都不变
+			wantBTCETH, wantAltcoin := 5, 5
+			if !tt.wantErr {
+				wantBTCETH, wantAltcoin = tt.btcEth, tt.altcoin
+			}
+			if btcEth, altcoin := at.GetLeverageLimits(); btcEth != wantBTCETH || altcoin != wantAltcoin {
+				t.Errorf("GetLeverageLimits() = %d, %d, want %d, %d", btcEth, altcoin, wantBTCETH, wantAltcoin)
+			}
+		})
+	}
+}
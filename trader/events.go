@@ -0,0 +1,150 @@
+package trader
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType 交易事件类型
+type EventType string
+
+const (
+	EventCycleStart        EventType = "cycle_start"        // 交易周期开始
+	EventAIResponse        EventType = "ai_response"        // 收到AI响应（含失败）
+	EventDecisionValidated EventType = "decision_validated" // AI决策通过校验
+	EventOrderPlaced       EventType = "order_placed"       // 下单/平仓成功
+	EventOrderFailed       EventType = "order_failed"       // 下单/平仓失败
+	EventRiskHalt          EventType = "risk_halt"          // 风控暂停交易
+	EventSafeMode          EventType = "safe_mode"          // AI连续失败，执行安全模式策略
+	EventCycleEnd          EventType = "cycle_end"          // 交易周期结束（决策记录已保存）
+	EventStateChange       EventType = "state_change"       // 启动、停止、暂停、恢复
+)
+
+// Event 交易事件
+type Event struct {
+	ID          uint64                 `json:"id"` // 事件总线内递增的序号
+	Type        EventType              `json:"type"`
+	TraderID    string                 `json:"trader_id"`
+	TraderName  string                 `json:"trader_name"`
+	CycleNumber int                    `json:"cycle_number,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+}
+
+// eventHistorySize 事件总线保留的最近事件数（用于断线重连后补发）
+const eventHistorySize = 256
+
+// EventBus 进程内事件总线：发布不阻塞，订阅者处理不过来时丢弃事件
+type EventBus struct {
+	mu          sync.RWMutex
+	seq         uint64
+	nextSubID   int
+	subscribers map[int]chan Event
+	history     []Event // 环形缓冲区
+	dropped     uint64  // 因订阅者缓冲区满而丢弃的事件数
+}
+
+// NewEventBus 创建事件总线
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[int]chan Event),
+		history:     make([]Event, 0, eventHistorySize),
+	}
+}
+
+// Publish 发布事件（分配序号后分发给所有订阅者）
+func (b *EventBus) Publish(e Event) {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e.ID = b.seq
+	if len(b.history) < eventHistorySize {
+		b.history = append(b.history, e)
+	} else {
+		b.history[(b.seq-1)%eventHistorySize] = e
+	}
+
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			b.dropped++
+		}
+	}
+}
+
+// Subscribe 订阅事件，返回事件通道和取消订阅函数
+// afterID>0 时先补发序号大于 afterID 且仍在缓冲区中的历史事件
+func (b *EventBus) Subscribe(buffer int, afterID uint64) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []Event
+	if afterID > 0 {
+		backlog = b.since(afterID)
+	}
+	if buffer < len(backlog) {
+		buffer = len(backlog)
+	}
+
+	ch := make(chan Event, buffer)
+	for _, e := range backlog {
+		ch <- e
+	}
+
+	id := b.nextSubID
+	b.nextSubID++
+	b.subscribers[id] = ch
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers, id)
+			close(ch)
+		})
+	}
+}
+
+// since 按序号顺序返回缓冲区中序号大于 afterID 的事件（调用方需持有锁）
+func (b *EventBus) since(afterID uint64) []Event {
+	var events []Event
+	n := uint64(len(b.history))
+	for i := uint64(0); i < n; i++ {
+		// 从最旧的事件开始遍历
+		e := b.history[(b.seq-n+i)%eventHistorySize]
+		if e.ID > afterID {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Dropped 因订阅者处理过慢而丢弃的事件数
+func (b *EventBus) Dropped() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.dropped
+}
+
+// GetEventBus 获取trader的事件总线
+func (at *AutoTrader) GetEventBus() *EventBus {
+	return at.events
+}
+
+// publish 发布本trader的事件
+func (at *AutoTrader) publish(eventType EventType, data map[string]interface{}) {
+	at.events.Publish(Event{
+		Type:        eventType,
+		TraderID:    at.id,
+		TraderName:  at.name,
+		CycleNumber: int(at.callCount.Load()),
+		Data:        data,
+	})
+}
@@ -0,0 +1,70 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+)
+
+// PromptVariant prompt实验中的一个变体（模板和语言已由调用方解析为具体值）
+type PromptVariant struct {
+	Name           string
+	PromptTemplate string
+	PromptLanguage string
+}
+
+// promptVariant 已加载模板的prompt变体
+type promptVariant struct {
+	name     string
+	template *decision.PromptTemplate
+}
+
+// loadPromptVariants 加载实验中各变体的prompt模板（未参与实验时返回nil）
+func loadPromptVariants(config AutoTraderConfig) ([]promptVariant, error) {
+	if config.Experiment == "" || len(config.PromptVariants) == 0 {
+		return nil, nil
+	}
+
+	variants := make([]promptVariant, 0, len(config.PromptVariants))
+	names := make([]string, 0, len(config.PromptVariants))
+	for _, v := range config.PromptVariants {
+		tmpl, err := decision.LoadPromptTemplate(v.PromptTemplate, v.PromptLanguage)
+		if err != nil {
+			return nil, fmt.Errorf("加载实验 %s 变体 %s 的prompt模板失败: %w", config.Experiment, v.Name, err)
+		}
+		variants = append(variants, promptVariant{name: v.Name, template: tmpl})
+		names = append(names, v.Name)
+	}
+
+	if len(variants) == 1 {
+		log.Printf("🧪 [%s] 参与prompt实验 %s，固定使用变体 %s", config.Name, config.Experiment, names[0])
+	} else {
+		log.Printf("🧪 [%s] 参与prompt实验 %s，每个周期轮换变体: %s", config.Name, config.Experiment, strings.Join(names, ", "))
+	}
+	return variants, nil
+}
+
+// variantForCycle 第N个周期（从1开始）使用的prompt变体，未参与实验时返回nil
+func (at *AutoTrader) variantForCycle(cycle int) *promptVariant {
+	if len(at.promptVariants) == 0 {
+		return nil
+	}
+	if cycle < 1 {
+		cycle = 1
+	}
+	return &at.promptVariants[(cycle-1)%len(at.promptVariants)]
+}
+
+// applyPromptVariant 让本周期使用实验变体的prompt模板，并在决策记录中标注实验和变体
+func (at *AutoTrader) applyPromptVariant(ctx *decision.Context, record *logger.DecisionRecord) {
+	variant := at.variantForCycle(int(at.callCount.Load()))
+	if variant == nil {
+		return
+	}
+	ctx.PromptTemplate = variant.template
+	record.Experiment = at.config.Experiment
+	record.PromptVariant = variant.name
+	log.Printf("🧪 prompt实验 %s: 使用变体 %s", at.config.Experiment, variant.name)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sonirico/go-hyperliquid"
@@ -480,6 +481,38 @@ func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
 	return nil
 }
 
+// GetStopOrders 获取该币种指定方向持仓的止损挂单（多仓止损为卖单，空仓止损为买单）
+func (t *HyperliquidTrader) GetStopOrders(symbol string, positionSide string) ([]StopOrder, error) {
+	coin := convertSymbolToHyperliquid(symbol)
+
+	openOrders, err := t.exchange.Info().FrontendOpenOrders(t.ctx, t.walletAddr)
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	side := hyperliquid.OrderSideAsk
+	if positionSide == "SHORT" {
+		side = hyperliquid.OrderSideBid
+	}
+	var stops []StopOrder
+	for _, order := range openOrders {
+		if order.Coin != coin || !order.IsTrigger || order.Side != side || !strings.HasPrefix(order.OrderType, "Stop") {
+			continue
+		}
+		stops = append(stops, StopOrder{OrderID: order.Oid, StopPrice: order.TriggerPx})
+	}
+	return stops, nil
+}
+
+// CancelOrder 取消单个挂单
+func (t *HyperliquidTrader) CancelOrder(symbol string, orderID int64) error {
+	coin := convertSymbolToHyperliquid(symbol)
+	if _, err := t.exchange.Cancel(t.ctx, coin, orderID); err != nil {
+		return fmt.Errorf("取消订单失败 (oid=%d): %w", orderID, err)
+	}
+	return nil
+}
+
 // GetMarketPrice 获取市场价格
 func (t *HyperliquidTrader) GetMarketPrice(symbol string) (float64, error) {
 	coin := convertSymbolToHyperliquid(symbol)
@@ -36,6 +36,18 @@ type Trader interface {
 	// CancelAllOrders 取消该币种的所有挂单
 	CancelAllOrders(symbol string) error
 
+	// GetStopOrders 获取该币种指定方向（LONG/SHORT）持仓当前的止损挂单
+	GetStopOrders(symbol string, positionSide string) ([]StopOrder, error)
+
+	// CancelOrder 取消单个挂单
+	CancelOrder(symbol string, orderID int64) error
+
 	// FormatQuantity 格式化数量到正确的精度
 	FormatQuantity(symbol string, quantity float64) (string, error)
 }
+
+// StopOrder 交易所上的止损挂单
+type StopOrder struct {
+	OrderID   int64
+	StopPrice float64
+}
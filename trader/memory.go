@@ -0,0 +1,141 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+	"unicode/utf8"
+)
+
+// memoryErrorChars 记忆中周期失败原因保留的字符数
+const memoryErrorChars = 120
+
+// MemoryConfig 跨周期记忆：最近N个周期的决策和压缩后的思维链，以及模型维护的交易日志
+type MemoryConfig struct {
+	Enabled         bool
+	Cycles          int // 附带最近N个周期
+	MaxCoTChars     int // 每个周期保留的思维链字符数
+	MaxJournalChars int // 交易日志的最大字符数
+}
+
+// MemorySnapshot 跨周期记忆的当前内容（下一个周期prompt中的记忆部分）
+type MemorySnapshot struct {
+	Enabled bool `json:"enabled"`
+	*logger.Memory
+	Cycles []decision.MemoryCycle `json:"cycles"`
+}
+
+// buildMemory 从决策日志读取交易日志和重置点之后的最近N个周期（未启用时返回nil）
+// 读取失败不影响交易，只是本周期缺少对应的记忆
+func (at *AutoTrader) buildMemory() *decision.Memory {
+	cfg := at.config.Memory
+	if !cfg.Enabled {
+		return nil
+	}
+
+	memory := &decision.Memory{MaxJournalChars: cfg.MaxJournalChars}
+	if saved, err := at.decisionLogger.GetMemory(); err != nil {
+		log.Printf("⚠️  读取交易日志失败: %v", err)
+	} else {
+		memory.Journal = saved.Journal
+	}
+
+	records, err := at.decisionLogger.GetMemoryRecords(cfg.Cycles)
+	if err != nil {
+		log.Printf("⚠️  读取最近周期失败: %v", err)
+		return memory
+	}
+	for _, record := range records {
+		memory.Cycles = append(memory.Cycles, memoryCycle(record, cfg.MaxCoTChars))
+	}
+	return memory
+}
+
+// memoryCycle 把决策记录压缩为记忆中的一个周期：决策摘要、失败原因和截断后的思维链
+func memoryCycle(record *logger.DecisionRecord, maxCoTChars int) decision.MemoryCycle {
+	cycle := decision.MemoryCycle{
+		CycleNumber: record.CycleNumber,
+		Time:        record.Timestamp.Format("01-02 15:04"),
+		CoTSummary:  decision.CondenseText(record.CoTTrace, maxCoTChars),
+	}
+
+	var decisions []decision.Decision
+	if record.DecisionJSON != "" && json.Unmarshal([]byte(record.DecisionJSON), &decisions) == nil {
+		for _, d := range decisions {
+			summary := d.Symbol + " " + d.Action
+			if d.Action == "open_long" || d.Action == "open_short" {
+				summary += fmt.Sprintf("(%dx, %.0f USDT)", d.Leverage, d.PositionSizeUSD)
+			}
+			cycle.Decisions = append(cycle.Decisions, summary)
+		}
+	}
+
+	if !record.Success {
+		// 错误信息可能包含完整思维链，只保留第一行
+		firstLine, _, _ := strings.Cut(record.ErrorMessage, "\n")
+		cycle.Error = decision.TruncateText(firstLine, memoryErrorChars)
+	}
+	// 执行失败的操作（如下单被拒）
+	for _, action := range record.Decisions {
+		if !action.Success && action.Error != "" {
+			cycle.Decisions = append(cycle.Decisions, fmt.Sprintf("%s %s ✗ %s",
+				action.Symbol, action.Action, decision.TruncateText(action.Error, memoryErrorChars)))
+		}
+	}
+	return cycle
+}
+
+// saveJournal 保存模型在本周期更新的交易日志（超出长度上限时截断），并记录到执行日志
+func (at *AutoTrader) saveJournal(journal string, record *logger.DecisionRecord) {
+	if !at.config.Memory.Enabled || journal == "" {
+		return
+	}
+
+	limit := at.config.Memory.MaxJournalChars
+	if n := utf8.RuneCountInString(journal); limit > 0 && n > limit {
+		log.Printf("⚠️  交易日志超出长度上限（%d/%d字），已截断", n, limit)
+		journal = decision.TruncateText(journal, limit)
+	}
+	if err := at.decisionLogger.SaveJournal(journal); err != nil {
+		log.Printf("⚠️  保存交易日志失败: %v", err)
+		return
+	}
+
+	msg := fmt.Sprintf("📓 交易日志已更新（%d字）", utf8.RuneCountInString(journal))
+	log.Print(msg)
+	record.ExecutionLog = append(record.ExecutionLog, msg)
+}
+
+// GetMemory 查看跨周期记忆（交易日志和下一个周期将附带的最近周期）
+func (at *AutoTrader) GetMemory() (*MemorySnapshot, error) {
+	saved, err := at.decisionLogger.GetMemory()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &MemorySnapshot{
+		Enabled: at.config.Memory.Enabled,
+		Memory:  saved,
+		Cycles:  []decision.MemoryCycle{},
+	}
+	if memory := at.buildMemory(); memory != nil {
+		snapshot.Cycles = append(snapshot.Cycles, memory.Cycles...)
+	}
+	return snapshot, nil
+}
+
+// ResetMemory 清空交易日志，之前的周期不再出现在记忆中（决策日志本身不删除）
+// 会等待正在执行的交易周期结束，避免该周期随后写回旧的交易日志
+func (at *AutoTrader) ResetMemory() error {
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+
+	if err := at.decisionLogger.ResetMemory(); err != nil {
+		return fmt.Errorf("重置跨周期记忆失败: %w", err)
+	}
+	log.Printf("🧹 [%s] 跨周期记忆已重置", at.name)
+	return nil
+}
@@ -0,0 +1,202 @@
+package trader
+
+import (
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"sync"
+	"time"
+)
+
+// reflectionWindow 只复盘最近这段时间内平仓的交易（重启后不回头复盘很久以前的交易）
+const reflectionWindow = 24 * time.Hour
+
+// 复盘失败后的重试间隔（每次失败翻倍，不超过上限）
+const (
+	reflectionRetryBase = 5 * time.Minute
+	reflectionRetryMax  = 2 * time.Hour
+)
+
+// ReflectionConfig 交易复盘：持仓平仓后让模型总结盈亏原因，最近的经验放入后续周期的prompt
+type ReflectionConfig struct {
+	Enabled     bool
+	MaxPerCycle int // 每个周期最多复盘的交易数
+	MaxChars    int // 每条复盘的最大字符数
+	Lessons     int // 放入prompt的最近复盘条数
+}
+
+// reflectionState 本次运行中的复盘进度（TradeOutcome.ID -> 状态）
+type reflectionState struct {
+	mu        sync.Mutex
+	running   bool                 // 后台复盘进行中（同一时间只运行一个）
+	done      map[string]bool      // 已保存复盘的交易（历史表现刷新前避免重复复盘）
+	failures  map[string]int       // 连续失败次数
+	nextRetry map[string]time.Time // 失败后下次可重试的时间
+}
+
+// newReflectionState 创建空的复盘进度
+func newReflectionState() *reflectionState {
+	return &reflectionState{
+		done:      make(map[string]bool),
+		failures:  make(map[string]int),
+		nextRetry: make(map[string]time.Time),
+	}
+}
+
+// startReflection 在后台复盘新平仓的交易（周期执行完决策后调用，复盘不占用决策时间）
+// 上一轮复盘仍在进行时跳过；新的经验在之后的周期放入prompt
+// 复盘归属于本次运行：Stop 后不再开始新的复盘，Rebuild/Shutdown 通过 reflectionWG 等待正在进行的复盘结束
+func (at *AutoTrader) startReflection(ctx *decision.Context) {
+	if !at.config.Reflection.Enabled {
+		return
+	}
+	performance, ok := ctx.Performance.(*logger.PerformanceAnalysis)
+	if !ok || performance == nil {
+		return
+	}
+	trades := at.pendingReflections(performance.RecentTrades)
+	if len(trades) == 0 {
+		return
+	}
+
+	at.stateMu.RLock()
+	stopCh := at.stopCh
+	at.stateMu.RUnlock()
+
+	state := at.reflections
+	state.mu.Lock()
+	if state.running {
+		state.mu.Unlock()
+		return
+	}
+	state.running = true
+	state.mu.Unlock()
+
+	at.reflectionWG.Add(1)
+	go func() {
+		defer at.reflectionWG.Done()
+		defer func() {
+			state.mu.Lock()
+			state.running = false
+			state.mu.Unlock()
+		}()
+		for i, trade := range trades {
+			select {
+			case <-stopCh:
+				log.Printf("⏹ [%s] trader已停止，剩余%d笔交易留待下次运行时复盘", at.name, len(trades)-i)
+				return
+			default:
+			}
+			at.reflectTrade(trade)
+		}
+	}()
+}
+
+// pendingReflections 需要复盘的交易：历史表现中还没有复盘、在复盘时间窗口内、且不在重试等待中（最多 MaxPerCycle 笔）
+func (at *AutoTrader) pendingReflections(recent []logger.TradeOutcome) []logger.TradeOutcome {
+	state := at.reflections
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	var trades []logger.TradeOutcome
+	// RecentTrades 按平仓时间倒序，先复盘最新的交易
+	for _, trade := range recent {
+		if len(trades) >= at.config.Reflection.MaxPerCycle {
+			break
+		}
+		if trade.Reflection != "" || state.done[trade.ID] || time.Since(trade.CloseTime) > reflectionWindow {
+			continue
+		}
+		if time.Now().Before(state.nextRetry[trade.ID]) {
+			continue
+		}
+		trades = append(trades, trade)
+	}
+	return trades
+}
+
+// reflectTrade 复盘一笔交易并保存，保存成功后才标记为已复盘，失败时按退避间隔稍后重试
+func (at *AutoTrader) reflectTrade(trade logger.TradeOutcome) {
+	cfg := at.config.Reflection
+	lesson, model, err := decision.GetTradeReflection(at.mcpClient, at.promptTemplate, tradeReview(trade), cfg.MaxChars)
+	if err == nil {
+		err = at.decisionLogger.SaveReflection(logger.TradeReflection{
+			TradeID:   trade.ID,
+			Symbol:    trade.Symbol,
+			Side:      trade.Side,
+			OpenTime:  trade.OpenTime,
+			CloseTime: trade.CloseTime,
+			PnL:       trade.PnL,
+			PnLPct:    trade.PnLPct,
+			Lesson:    lesson,
+			Model:     model,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	state := at.reflections
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if err != nil {
+		state.failures[trade.ID]++
+		delay := reflectionRetryDelay(state.failures[trade.ID])
+		state.nextRetry[trade.ID] = time.Now().Add(delay)
+		log.Printf("⚠️  [%s] 复盘交易失败 (%s %s)，%s后重试: %v", at.name, trade.Symbol, trade.Side, delay, err)
+		return
+	}
+	state.done[trade.ID] = true
+	delete(state.failures, trade.ID)
+	delete(state.nextRetry, trade.ID)
+	log.Printf("🪞 [%s] 复盘 %s %s (%+.2f%%): %s", at.name, trade.Symbol, trade.Side, trade.PnLPct, lesson)
+}
+
+// reflectionRetryDelay 第n次失败后的重试间隔
+func reflectionRetryDelay(failures int) time.Duration {
+	delay := reflectionRetryBase
+	for i := 1; i < failures && delay < reflectionRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, reflectionRetryMax)
+}
+
+// tradeReview 把交易结果转换为复盘输入
+func tradeReview(trade logger.TradeOutcome) *decision.TradeReview {
+	return &decision.TradeReview{
+		Symbol:         trade.Symbol,
+		Side:           trade.Side,
+		Leverage:       trade.Leverage,
+		OpenPrice:      trade.OpenPrice,
+		ClosePrice:     trade.ClosePrice,
+		StopLoss:       trade.StopLoss,
+		PnL:            trade.PnL,
+		PnLPct:         trade.PnLPct,
+		RMultiple:      trade.RMultiple,
+		OpenTime:       trade.OpenTime,
+		CloseTime:      trade.CloseTime,
+		EntryReasoning: trade.EntryReasoning,
+		ExitReasoning:  trade.ExitReasoning,
+		EntryMarket:    trade.EntryMarket,
+		ExitMarket:     trade.ExitMarket,
+	}
+}
+
+// recentLessons 最近N条复盘（从新到旧），未启用时返回nil
+func (at *AutoTrader) recentLessons() []decision.Lesson {
+	cfg := at.config.Reflection
+	if !cfg.Enabled {
+		return nil
+	}
+
+	reflections, err := at.decisionLogger.GetReflections(cfg.Lessons)
+	if err != nil {
+		log.Printf("⚠️  读取交易复盘失败: %v", err)
+		return nil
+	}
+	lessons := make([]decision.Lesson, 0, len(reflections))
+	for i := len(reflections) - 1; i >= 0; i-- {
+		r := reflections[i]
+		lessons = append(lessons, decision.Lesson{Symbol: r.Symbol, Side: r.Side, PnLPct: r.PnLPct, Text: r.Lesson})
+	}
+	return lessons
+}
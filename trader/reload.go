@@ -0,0 +1,169 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+)
+
+// pendingConfig 等待在周期之间生效的配置变更
+type pendingConfig struct {
+	config AutoTraderConfig
+	fields map[string]bool
+}
+
+// 可热更新的配置项（其余字段变更需要重建trader）
+const (
+	FieldScanInterval  = "scan_interval"
+	FieldLeverage      = "leverage"
+	FieldCoinWhitelist = "coin_whitelist"
+	FieldRiskLimits    = "risk_limits"
+	FieldSafeMode      = "safe_mode"
+)
+
+// DiffConfig 对比新旧配置，返回可热更新的变更项，以及是否存在需要重建trader的变更
+// （交易所、凭证、AI模型、名称、初始余额等在创建时绑定的参数）
+func DiffConfig(old, new AutoTraderConfig) ([]string, bool) {
+	var fields []string
+	if old.ScanInterval != new.ScanInterval {
+		fields = append(fields, FieldScanInterval)
+	}
+	if old.BTCETHLeverage != new.BTCETHLeverage || old.AltcoinLeverage != new.AltcoinLeverage {
+		fields = append(fields, FieldLeverage)
+	}
+	if old.CoinWhitelistEnabled != new.CoinWhitelistEnabled || !reflect.DeepEqual(old.CoinWhitelist, new.CoinWhitelist) {
+		fields = append(fields, FieldCoinWhitelist)
+	}
+	if old.MaxDailyLoss != new.MaxDailyLoss || old.MaxDrawdown != new.MaxDrawdown || old.StopTradingTime != new.StopTradingTime {
+		fields = append(fields, FieldRiskLimits)
+	}
+	if old.SafeMode != new.SafeMode {
+		fields = append(fields, FieldSafeMode)
+	}
+
+	// 抹平可热更新字段后仍不相同，说明有需要重建的变更
+	rebuildOld, rebuildNew := old, new
+	for _, c := range []*AutoTraderConfig{&rebuildOld, &rebuildNew} {
+		c.ScanInterval = 0
+		c.BTCETHLeverage, c.AltcoinLeverage = 0, 0
+		c.CoinWhitelistEnabled, c.CoinWhitelist = false, nil
+		c.MaxDailyLoss, c.MaxDrawdown, c.StopTradingTime = 0, 0, 0
+		c.SafeMode = SafeModeConfig{}
+	}
+	return fields, !reflect.DeepEqual(rebuildOld, rebuildNew)
+}
+
+// UpdateConfig 热更新配置中的指定字段
+// 空闲时立即生效；正在执行交易周期时在下一个周期开始前生效，避免周期内参数不一致
+func (at *AutoTrader) UpdateConfig(cfg AutoTraderConfig, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+
+	at.stateMu.Lock()
+	if at.pending == nil {
+		at.pending = &pendingConfig{fields: make(map[string]bool)}
+	}
+	at.pending.config = cfg
+	for _, f := range fields {
+		at.pending.fields[f] = true
+	}
+	at.stateMu.Unlock()
+
+	if at.cycleMu.TryLock() {
+		at.applyPendingConfig()
+		at.cycleMu.Unlock()
+	} else {
+		log.Printf("⏳ [%s] 配置变更将在当前周期结束后生效: %s", at.name, strings.Join(fields, ", "))
+	}
+}
+
+// applyPendingConfig 应用等待中的配置变更（调用方需持有 cycleMu）
+func (at *AutoTrader) applyPendingConfig() {
+	at.stateMu.Lock()
+	pending := at.pending
+	at.pending = nil
+	if pending == nil {
+		at.stateMu.Unlock()
+		return
+	}
+
+	cfg := pending.config
+	var applied []string
+	if pending.fields[FieldScanInterval] {
+		at.config.ScanInterval = cfg.ScanInterval
+		applied = append(applied, fmt.Sprintf("扫描间隔=%v", cfg.ScanInterval))
+	}
+	if pending.fields[FieldLeverage] {
+		at.config.BTCETHLeverage = cfg.BTCETHLeverage
+		at.config.AltcoinLeverage = cfg.AltcoinLeverage
+		applied = append(applied, fmt.Sprintf("杠杆=%dx/%dx", cfg.BTCETHLeverage, cfg.AltcoinLeverage))
+	}
+	if pending.fields[FieldCoinWhitelist] {
+		at.config.CoinWhitelistEnabled = cfg.CoinWhitelistEnabled
+		at.config.CoinWhitelist = cfg.CoinWhitelist
+		applied = append(applied, fmt.Sprintf("白名单=%d个币种", len(cfg.CoinWhitelist)))
+	}
+	if pending.fields[FieldRiskLimits] {
+		at.config.MaxDailyLoss = cfg.MaxDailyLoss
+		at.config.MaxDrawdown = cfg.MaxDrawdown
+		at.config.StopTradingTime = cfg.StopTradingTime
+		applied = append(applied, "风控参数")
+	}
+	if pending.fields[FieldSafeMode] {
+		at.config.SafeMode = cfg.SafeMode
+		applied = append(applied, "安全模式")
+	}
+	at.stateMu.Unlock()
+
+	if pending.fields[FieldScanInterval] {
+		select {
+		case at.intervalCh <- struct{}{}:
+		default:
+		}
+	}
+	log.Printf("🔄 [%s] 配置已热更新: %s", at.name, strings.Join(applied, ", "))
+}
+
+// Rebuild 用新配置重建trader（凭证、交易所、AI模型等变更时使用）
+// 先停止当前实例并等待正在执行的周期和后台复盘结束，再创建新实例；创建失败时当前实例保持可用（但已停止，由调用方决定是否重启）
+// 新实例继承暂停状态和持仓计时，返回时尚未启动
+// 旧实例的决策日志保持打开，调用方替换实例后需调用 CloseLogger，避免替换前的API请求读到已关闭的数据库
+func (at *AutoTrader) Rebuild(cfg AutoTraderConfig) (*AutoTrader, error) {
+	paused := at.IsPaused()
+	at.Stop()
+
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+	// 旧实例的复盘使用旧的AI凭证，结束后新实例才接管复盘进度
+	at.reflectionWG.Wait()
+
+	next, err := NewAutoTrader(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("重建trader失败: %w", err)
+	}
+
+	next.isPaused = paused
+	next.callCount.Store(at.callCount.Load())
+	next.reflections = at.reflections
+	for k, v := range at.positionFirstSeenTime {
+		next.positionFirstSeenTime[k] = v
+	}
+	return next, nil
+}
+
+// CloseLogger 关闭决策日志（Rebuild 后由调用方在新实例接管请求后调用）
+func (at *AutoTrader) CloseLogger() error {
+	return at.decisionLogger.Close()
+}
+
+// Shutdown 停止trader并等待正在执行的周期和后台复盘结束，然后关闭决策日志（用于移除trader）
+func (at *AutoTrader) Shutdown() error {
+	at.Stop()
+
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+	at.reflectionWG.Wait()
+	return at.decisionLogger.Close()
+}
@@ -0,0 +1,126 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/mcp"
+)
+
+// ModelClients 按trader配置创建可用于重放的全部AI客户端（主模型、备用模型和投票模型），按显示名称索引
+func ModelClients(config AutoTraderConfig) map[string]*mcp.Client {
+	primary := ModelConfig{
+		Name:          config.AIModel,
+		AIModel:       config.AIModel,
+		ContextWindow: config.ContextWindow,
+		MaxTokens:     config.MaxTokens,
+	}
+	switch {
+	case config.AIModel == "custom":
+		primary.APIURL = config.CustomAPIURL
+		primary.APIKey = config.CustomAPIKey
+		primary.ModelName = config.CustomModelName
+	case config.UseQwen || config.AIModel == "qwen":
+		primary.AIModel = "qwen"
+		primary.APIKey = config.QwenKey
+	default:
+		primary.AIModel = "deepseek"
+		primary.APIKey = config.DeepSeekKey
+	}
+	if primary.Name == "" {
+		primary.Name = primary.AIModel
+	}
+
+	clients := make(map[string]*mcp.Client)
+	models := append([]ModelConfig{primary}, config.FallbackModels...)
+	for _, m := range append(models, config.EnsembleModels...) {
+		client := newModelClient(m)
+		if _, exists := clients[client.Label()]; !exists {
+			clients[client.Label()] = client
+		}
+	}
+	return clients
+}
+
+// ReplayInput 从决策记录中取出用 model 重放所需的输入（缺少 system prompt 的早期记录无法重放）
+// 原周期调用过工具时附带该模型的工具对话，该模型没有调用工具时使用实际给出决策的模型的对话
+func ReplayInput(record *logger.DecisionRecord, model string) (decision.ReplayInput, error) {
+	if record.SystemPrompt == "" || record.InputPrompt == "" {
+		return decision.ReplayInput{}, fmt.Errorf("周期#%d 的记录中没有完整的prompt（该周期未调用AI或记录早于重放功能），无法重放", record.CycleNumber)
+	}
+
+	in := decision.ReplayInput{
+		SystemPrompt: record.SystemPrompt,
+		UserPrompt:   record.InputPrompt,
+		// 早期记录没有验证参数时使用账户快照中的净值
+		AccountEquity: record.AccountState.TotalBalance,
+		Transcript:    recordedTranscript(record, model),
+	}
+	if v := record.Validation; v != nil {
+		in.AccountEquity = v.AccountEquity
+		in.BTCETHLeverage = v.BTCETHLeverage
+		in.AltcoinLeverage = v.AltcoinLeverage
+		in.Language = v.Language
+	}
+	return in, nil
+}
+
+// recordedTranscript 记录中某个模型的工具对话，没有时取实际给出决策的模型的对话
+func recordedTranscript(record *logger.DecisionRecord, model string) *mcp.Transcript {
+	for _, name := range []string{model, RecordedModel(record)} {
+		for _, t := range record.ToolTranscripts {
+			if t.Model == name {
+				return &mcp.Transcript{Model: t.Model, Tools: t.Tools, Messages: t.Messages}
+			}
+		}
+	}
+	return nil
+}
+
+// RecordedModel 记录中实际给出决策的模型：投票记录取第一个有效模型（主模型优先）
+func RecordedModel(record *logger.DecisionRecord) string {
+	for _, out := range record.ModelOutputs {
+		if out.Error == "" {
+			if out.UsedModel != "" {
+				return out.UsedModel
+			}
+			return out.Model
+		}
+	}
+	return record.AIModel
+}
+
+// RecordedDecisions 记录中某个模型的原决策：投票记录中有该模型的输出时取该模型的决策，否则取最终决策
+func RecordedDecisions(record *logger.DecisionRecord, model string) ([]decision.Decision, error) {
+	decisionJSON := record.DecisionJSON
+	for _, out := range record.ModelOutputs {
+		if out.Model == model || out.UsedModel == model {
+			decisionJSON = out.DecisionJSON
+			break
+		}
+	}
+
+	var decisions []decision.Decision
+	if decisionJSON == "" || decisionJSON == "null" {
+		return decisions, nil
+	}
+	if err := json.Unmarshal([]byte(decisionJSON), &decisions); err != nil {
+		return nil, fmt.Errorf("解析记录中的决策失败: %w", err)
+	}
+	return decisions, nil
+}
+
+// toLoggerModelParams 转换模型参数
+func toLoggerModelParams(p *mcp.Params) *logger.ModelParams {
+	if p == nil {
+		return nil
+	}
+	return &logger.ModelParams{
+		Provider:    string(p.Provider),
+		Model:       p.Model,
+		BaseURL:     p.BaseURL,
+		Temperature: p.Temperature,
+		MaxTokens:   p.MaxTokens,
+	}
+}
@@ -0,0 +1,323 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+// SafeModeConfig AI连续失败时的持仓管理策略（不开新仓，规则按字段顺序执行）
+type SafeModeConfig struct {
+	Enabled        bool
+	AfterFailures  int     // AI连续失败多少个周期后触发
+	FlattenAll     bool    // 平掉全部持仓（启用时忽略其他规则）
+	MaxAdversePct  float64 // 价格相对开仓价反向变动超过X%时平仓（0表示不限制）
+	MaxHoldHours   float64 // 持仓超过N小时平仓（0表示不限制）
+	TightenStopPct float64 // 止损收紧到距标记价格X%处（只收紧不放宽，0表示不调整）
+}
+
+// 安全模式收紧止损时记录的动作（close_* 沿用普通平仓动作，便于绩效统计配对）
+const (
+	ActionTightenStopLong  = "tighten_stop_long"
+	ActionTightenStopShort = "tighten_stop_short"
+)
+
+// inSafeMode 是否处于安全模式（调用方需持有 stateMu）
+func (at *AutoTrader) inSafeMode() bool {
+	policy := at.config.SafeMode
+	return policy.Enabled && at.aiFailures >= policy.AfterFailures
+}
+
+// resetAIFailures AI决策成功，清零连续失败计数
+func (at *AutoTrader) resetAIFailures() {
+	at.stateMu.Lock()
+	defer at.stateMu.Unlock()
+
+	if at.inSafeMode() {
+		log.Printf("✓ [%s] AI已恢复（此前连续失败%d个周期），退出安全模式", at.name, at.aiFailures)
+	}
+	at.aiFailures = 0
+}
+
+// handleAIFailure 记录一次AI失败（调用或解析），连续失败达到阈值后按安全模式策略管理已有持仓
+// 每个失败周期都会执行一次，直到AI恢复（调用方需持有 cycleMu）
+func (at *AutoTrader) handleAIFailure(ctx *decision.Context, record *logger.DecisionRecord) {
+	at.stateMu.Lock()
+	at.aiFailures++
+	failures := at.aiFailures
+	active := at.inSafeMode()
+	policy := at.config.SafeMode
+	at.stateMu.Unlock()
+
+	if !active {
+		if policy.Enabled {
+			log.Printf("⚠️  [%s] AI连续失败%d个周期（第%d个周期起进入安全模式）", at.name, failures, policy.AfterFailures)
+		}
+		return
+	}
+
+	log.Printf("🛡️  [%s] AI连续失败%d个周期，安全模式接管 %d 个持仓", at.name, failures, len(ctx.Positions))
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🛡️ 安全模式：AI连续失败%d个周期", failures))
+	before := len(record.Decisions)
+	at.runSafeMode(ctx.Positions, policy, record)
+
+	at.publish(EventSafeMode, map[string]interface{}{
+		"ai_failures":    failures,
+		"position_count": len(ctx.Positions),
+		"actions":        record.Decisions[before:],
+	})
+}
+
+// runSafeMode 对每个持仓依次检查：全部平仓 → 反向变动过大 → 持仓过久，命中则平仓，否则收紧止损
+func (at *AutoTrader) runSafeMode(positions []decision.PositionInfo, policy SafeModeConfig, record *logger.DecisionRecord) {
+	for _, pos := range positions {
+		var reason string
+		adverse := adverseMovePct(pos)
+		heldHours := 0.0
+		if policy.MaxHoldHours > 0 {
+			heldHours = at.heldHours(pos)
+		}
+
+		switch {
+		case policy.FlattenAll:
+			reason = "平掉全部持仓"
+		case policy.MaxAdversePct > 0 && adverse >= policy.MaxAdversePct:
+			reason = fmt.Sprintf("价格反向变动%.2f%%（上限%.2f%%）", adverse, policy.MaxAdversePct)
+		case policy.MaxHoldHours > 0 && heldHours >= policy.MaxHoldHours:
+			reason = fmt.Sprintf("持仓%.1f小时（上限%.1f小时）", heldHours, policy.MaxHoldHours)
+		}
+
+		if reason != "" {
+			log.Printf("  🛡️ %s %s 平仓: %s", pos.Symbol, pos.Side, reason)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🛡️ %s %s 平仓: %s", pos.Symbol, pos.Side, reason))
+			at.closePosition(pos.Symbol, pos.Side, pos.Quantity, pos.MarkPrice, record)
+			continue
+		}
+		if policy.TightenStopPct > 0 {
+			at.tightenStopLoss(pos, policy.TightenStopPct, record)
+		}
+	}
+}
+
+// tightenStopLoss 把止损移到距标记价格 pct% 处；交易所上已有的止损更紧时不调整，
+// 交易所上没有止损单时总会重新挂单（不比决策记录中的止损更松）
+// 只替换该方向的止损单，止盈等其他挂单保持不变
+func (at *AutoTrader) tightenStopLoss(pos decision.PositionInfo, pct float64, record *logger.DecisionRecord) {
+	action := ActionTightenStopLong
+	stopLoss := pos.MarkPrice * (1 - pct/100)
+	if pos.Side == "short" {
+		action = ActionTightenStopShort
+		stopLoss = pos.MarkPrice * (1 + pct/100)
+	}
+	positionSide := strings.ToUpper(pos.Side)
+
+	actionRecord := logger.DecisionAction{
+		Action:    action,
+		Symbol:    pos.Symbol,
+		Quantity:  pos.Quantity,
+		Price:     pos.MarkPrice,
+		StopLoss:  stopLoss,
+		Timestamp: time.Now(),
+	}
+	fail := func(err error) {
+		log.Printf("  ❌ %s %s 收紧止损失败: %v", pos.Symbol, pos.Side, err)
+		actionRecord.Error = err.Error()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", pos.Symbol, action, err))
+		at.publishOrder(EventOrderFailed, actionRecord)
+		record.Decisions = append(record.Decisions, actionRecord)
+	}
+
+	oldStops, err := at.trader.GetStopOrders(pos.Symbol, positionSide)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	// 以交易所上最紧的止损为准；交易所上没有止损单（已被取消或过期）时必须重新挂单，
+	// 决策记录中的止损更紧且仍在标记价格的保护一侧时按记录的价格恢复，否则使用新止损
+	recordedStop, takeProfit := at.lastProtection(pos.Symbol, pos.Side)
+	actionRecord.TakeProfit = takeProfit
+	lastStop := tightestStop(oldStops, pos.Side)
+	if lastStop > 0 && !stopTighter(stopLoss, lastStop, pos.Side) {
+		log.Printf("  🛡️ %s %s 当前止损 %.4f 已比 %.4f 更紧，保持不变", pos.Symbol, pos.Side, lastStop, stopLoss)
+		return
+	}
+	if lastStop == 0 && recordedStop > 0 && stopTighter(recordedStop, stopLoss, pos.Side) && stopTighter(pos.MarkPrice, recordedStop, pos.Side) {
+		log.Printf("  🛡️ %s %s 交易所上没有止损单，按决策记录恢复止损 %.4f", pos.Symbol, pos.Side, recordedStop)
+		stopLoss = recordedStop
+		actionRecord.StopLoss = stopLoss
+	}
+
+	if err := at.replaceStopLoss(pos, positionSide, stopLoss, oldStops, record); err != nil {
+		fail(err)
+		return
+	}
+
+	actionRecord.Success = true
+	if lastStop > 0 {
+		log.Printf("  🛡️ %s %s 止损收紧: %.4f → %.4f", pos.Symbol, pos.Side, lastStop, stopLoss)
+	} else {
+		log.Printf("  🛡️ %s %s 未找到原止损，止损设置为 %.4f", pos.Symbol, pos.Side, stopLoss)
+	}
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功 (止损 %.4f)", pos.Symbol, action, stopLoss))
+	at.publishOrder(EventOrderPlaced, actionRecord)
+	record.Decisions = append(record.Decisions, actionRecord)
+}
+
+// replaceStopLoss 先挂新止损再取消旧止损，保证持仓任何时刻都有止损保护
+// 交易所拒绝同时存在两个止损单时（如币安每个方向只允许一个 closePosition 止损），
+// 改为先取消旧止损再挂新止损，失败时按原价恢复旧止损
+func (at *AutoTrader) replaceStopLoss(pos decision.PositionInfo, positionSide string, stopLoss float64, oldStops []StopOrder, record *logger.DecisionRecord) error {
+	err := at.trader.SetStopLoss(pos.Symbol, positionSide, pos.Quantity, stopLoss)
+	if err == nil {
+		for _, old := range oldStops {
+			if err := at.trader.CancelOrder(pos.Symbol, old.OrderID); err != nil {
+				log.Printf("  ⚠ %s 取消旧止损 %.4f 失败: %v", pos.Symbol, old.StopPrice, err)
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠ %s 取消旧止损 %.4f 失败: %v", pos.Symbol, old.StopPrice, err))
+			}
+		}
+		return nil
+	}
+	if len(oldStops) == 0 {
+		return err
+	}
+
+	log.Printf("  ⚠ %s 新止损挂单被拒绝（%v），先取消旧止损后重试", pos.Symbol, err)
+	var cancelled []StopOrder
+	for _, old := range oldStops {
+		if cancelErr := at.trader.CancelOrder(pos.Symbol, old.OrderID); cancelErr != nil {
+			at.restoreStops(pos, positionSide, cancelled, record)
+			return fmt.Errorf("取消旧止损失败: %w", cancelErr)
+		}
+		cancelled = append(cancelled, old)
+	}
+	if err := at.trader.SetStopLoss(pos.Symbol, positionSide, pos.Quantity, stopLoss); err != nil {
+		at.restoreStops(pos, positionSide, cancelled, record)
+		return err
+	}
+	return nil
+}
+
+// restoreStops 按原价重新挂上已取消的止损单
+func (at *AutoTrader) restoreStops(pos decision.PositionInfo, positionSide string, stops []StopOrder, record *logger.DecisionRecord) {
+	for _, old := range stops {
+		if err := at.trader.SetStopLoss(pos.Symbol, positionSide, pos.Quantity, old.StopPrice); err != nil {
+			log.Printf("  🚨 %s %s 恢复原止损 %.4f 失败，持仓无止损保护: %v", pos.Symbol, pos.Side, old.StopPrice, err)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚨 %s %s 恢复原止损 %.4f 失败，持仓无止损: %v", pos.Symbol, pos.Side, old.StopPrice, err))
+			continue
+		}
+		log.Printf("  ↩ %s %s 已恢复原止损 %.4f", pos.Symbol, pos.Side, old.StopPrice)
+	}
+}
+
+// stopTighter 止损价 a 是否比 b 更紧（多仓更高、空仓更低）
+func stopTighter(a, b float64, side string) bool {
+	if side == "short" {
+		return a < b
+	}
+	return a > b
+}
+
+// tightestStop 多仓取最高的止损价，空仓取最低的止损价，没有止损单时返回0
+func tightestStop(stops []StopOrder, side string) float64 {
+	tightest := 0.0
+	for _, stop := range stops {
+		if stop.StopPrice <= 0 {
+			continue
+		}
+		if tightest == 0 || stopTighter(stop.StopPrice, tightest, side) {
+			tightest = stop.StopPrice
+		}
+	}
+	return tightest
+}
+
+// lastProtection 从决策记录中查找持仓最近一次设置的止损止盈（开仓或安全模式收紧），找不到时返回0
+func (at *AutoTrader) lastProtection(symbol, side string) (float64, float64) {
+	success := true
+	page, err := at.decisionLogger.QueryDecisions(logger.DecisionQuery{Symbol: symbol, Success: &success, Limit: 50})
+	if err != nil {
+		log.Printf("  ⚠ 查询 %s 历史止损失败: %v", symbol, err)
+		return 0, 0
+	}
+
+	tighten := ActionTightenStopLong
+	if side == "short" {
+		tighten = ActionTightenStopShort
+	}
+	for _, record := range page.Records {
+		for i := len(record.Decisions) - 1; i >= 0; i-- {
+			action := record.Decisions[i]
+			if action.Symbol != symbol || !action.Success {
+				continue
+			}
+			switch action.Action {
+			case "open_" + side, tighten:
+				return action.StopLoss, action.TakeProfit
+			case "close_" + side:
+				// 最近一次平仓之后没有开仓记录，当前持仓不是由本trader开的
+				return 0, 0
+			}
+		}
+	}
+	return 0, 0
+}
+
+// heldHours 持仓时长：以决策记录中最近一次成功开仓的时间为准（重启或重建后不会重新计时），
+// 找不到开仓记录（如手动开的仓）时退回到持仓首次出现的时间
+func (at *AutoTrader) heldHours(pos decision.PositionInfo) float64 {
+	if openedAt := at.lastOpenTime(pos.Symbol, pos.Side); !openedAt.IsZero() {
+		return time.Since(openedAt).Hours()
+	}
+	if pos.UpdateTime > 0 {
+		return time.Since(time.UnixMilli(pos.UpdateTime)).Hours()
+	}
+	return 0
+}
+
+// lastOpenTime 从决策记录中查找持仓最近一次成功开仓的时间；之后已有平仓记录或查询失败时返回零值
+func (at *AutoTrader) lastOpenTime(symbol, side string) time.Time {
+	openedAt, err := at.lastActionTime(symbol, "open_"+side)
+	if err != nil || openedAt.IsZero() {
+		return time.Time{}
+	}
+	closedAt, err := at.lastActionTime(symbol, "close_"+side)
+	if err != nil || closedAt.After(openedAt) {
+		// 最近一次开仓已被平掉，当前持仓不是由本trader开的
+		return time.Time{}
+	}
+	return openedAt
+}
+
+// lastActionTime 查询某币种最近一次成功执行指定动作的时间，没有记录时返回零值
+func (at *AutoTrader) lastActionTime(symbol, action string) (time.Time, error) {
+	success := true
+	page, err := at.decisionLogger.QueryDecisions(logger.DecisionQuery{Symbol: symbol, Action: action, Success: &success, Limit: 1})
+	if err != nil {
+		log.Printf("  ⚠ 查询 %s %s 历史记录失败: %v", symbol, action, err)
+		return time.Time{}, err
+	}
+	for _, record := range page.Records {
+		for i := len(record.Decisions) - 1; i >= 0; i-- {
+			a := record.Decisions[i]
+			if a.Symbol == symbol && a.Action == action && a.Success {
+				return a.Timestamp, nil
+			}
+		}
+	}
+	return time.Time{}, nil
+}
+
+// adverseMovePct 价格相对开仓价的反向变动百分比（不含杠杆，盈利时为负）
+func adverseMovePct(pos decision.PositionInfo) float64 {
+	if pos.EntryPrice <= 0 {
+		return 0
+	}
+	if pos.Side == "short" {
+		return (pos.MarkPrice - pos.EntryPrice) / pos.EntryPrice * 100
+	}
+	return (pos.EntryPrice - pos.MarkPrice) / pos.EntryPrice * 100
+}
@@ -0,0 +1,330 @@
+package trader
+
+import (
+	"errors"
+	"nofx/decision"
+	"nofx/logger"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeStopTrader 只实现止损相关方法的交易所，其他方法调用时panic
+type fakeStopTrader struct {
+	Trader
+
+	stops        []StopOrder
+	nextID       int64
+	oneStopOnly  bool              // 已有止损单时拒绝新止损（如币安每个方向只允许一个 closePosition 止损）
+	rejectPrices map[float64]error // 按价格拒绝挂单
+	cancelErr    error
+	getStopsErr  error
+}
+
+func (f *fakeStopTrader) GetStopOrders(symbol string, positionSide string) ([]StopOrder, error) {
+	if f.getStopsErr != nil {
+		return nil, f.getStopsErr
+	}
+	return append([]StopOrder(nil), f.stops...), nil
+}
+
+func (f *fakeStopTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if err := f.rejectPrices[stopPrice]; err != nil {
+		return err
+	}
+	if f.oneStopOnly && len(f.stops) > 0 {
+		return errors.New("该方向已有止损单")
+	}
+	f.nextID++
+	f.stops = append(f.stops, StopOrder{OrderID: 100 + f.nextID, StopPrice: stopPrice})
+	return nil
+}
+
+func (f *fakeStopTrader) CancelOrder(symbol string, orderID int64) error {
+	if f.cancelErr != nil {
+		return f.cancelErr
+	}
+	for i, stop := range f.stops {
+		if stop.OrderID == orderID {
+			f.stops = append(f.stops[:i], f.stops[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("订单不存在")
+}
+
+// stopPrices 交易所上当前止损价（升序）
+func (f *fakeStopTrader) stopPrices() []float64 {
+	prices := make([]float64, len(f.stops))
+	for i, stop := range f.stops {
+		prices[i] = stop.StopPrice
+	}
+	sort.Float64s(prices)
+	return prices
+}
+
+// newSafeModeTrader 使用临时决策日志的AutoTrader，recorded 为已保存的周期动作
+func newSafeModeTrader(t *testing.T, exchange Trader, recorded ...logger.DecisionAction) *AutoTrader {
+	t.Helper()
+	dl := logger.NewDecisionLogger(t.TempDir())
+	t.Cleanup(func() { dl.Close() })
+
+	for _, action := range recorded {
+		if err := dl.LogDecision(&logger.DecisionRecord{Success: true, Decisions: []logger.DecisionAction{action}}); err != nil {
+			t.Fatalf("LogDecision() error = %v", err)
+		}
+	}
+	return &AutoTrader{id: "test", name: "test", trader: exchange, decisionLogger: dl, events: NewEventBus()}
+}
+
+// withStops 交易所上已有的止损单
+func withStops(prices ...float64) []StopOrder {
+	stops := make([]StopOrder, len(prices))
+	for i, p := range prices {
+		stops[i] = StopOrder{OrderID: int64(i + 1), StopPrice: p}
+	}
+	return stops
+}
+
+func TestTightenStopLoss(t *testing.T) {
+	errRejected := errors.New("挂单被拒绝")
+	openLong := func(stop float64) logger.DecisionAction {
+		return logger.DecisionAction{Action: "open_long", Symbol: "BTCUSDT", StopLoss: stop, TakeProfit: 120, Success: true, Timestamp: time.Now()}
+	}
+
+	tests := []struct {
+		name     string
+		side     string
+		exchange *fakeStopTrader
+		recorded []logger.DecisionAction
+		// 期望结果
+		wantStops   []float64 // 交易所上最终的止损价
+		wantAction  bool      // 是否记录了收紧动作
+		wantSuccess bool
+		wantLog     string // 执行日志中应出现的内容
+	}{
+		{
+			name:        "多仓新止损被接受后取消旧止损",
+			side:        "long",
+			exchange:    &fakeStopTrader{stops: withStops(95)},
+			wantStops:   []float64{98},
+			wantAction:  true,
+			wantSuccess: true,
+		},
+		{
+			name:        "空仓新止损被接受后取消旧止损",
+			side:        "short",
+			exchange:    &fakeStopTrader{stops: withStops(105)},
+			wantStops:   []float64{102},
+			wantAction:  true,
+			wantSuccess: true,
+		},
+		{
+			name:      "多仓已有更紧的止损不放宽",
+			side:      "long",
+			exchange:  &fakeStopTrader{stops: withStops(99)},
+			wantStops: []float64{99},
+		},
+		{
+			name:      "空仓已有更紧的止损不放宽",
+			side:      "short",
+			exchange:  &fakeStopTrader{stops: withStops(101)},
+			wantStops: []float64{101},
+		},
+		{
+			name:        "多个止损单按最紧的比较并全部替换",
+			side:        "long",
+			exchange:    &fakeStopTrader{stops: withStops(95, 97)},
+			wantStops:   []float64{98},
+			wantAction:  true,
+			wantSuccess: true,
+		},
+		{
+			name:        "新止损被拒绝后先取消旧止损再挂单",
+			side:        "long",
+			exchange:    &fakeStopTrader{stops: withStops(95), oneStopOnly: true},
+			wantStops:   []float64{98},
+			wantAction:  true,
+			wantSuccess: true,
+		},
+		{
+			name:       "先取消后挂单仍失败时恢复原止损",
+			side:       "long",
+			exchange:   &fakeStopTrader{stops: withStops(95), oneStopOnly: true, rejectPrices: map[float64]error{98: errRejected}},
+			wantStops:  []float64{95},
+			wantAction: true,
+		},
+		{
+			name: "恢复原止损失败",
+			side: "long",
+			exchange: &fakeStopTrader{stops: withStops(95), oneStopOnly: true,
+				rejectPrices: map[float64]error{98: errRejected, 95: errRejected}},
+			wantStops:  []float64{},
+			wantAction: true,
+			wantLog:    "恢复原止损 95.0000 失败",
+		},
+		{
+			name:       "取消旧止损失败时保留原止损",
+			side:       "long",
+			exchange:   &fakeStopTrader{stops: withStops(95), oneStopOnly: true, cancelErr: errors.New("网络错误")},
+			wantStops:  []float64{95},
+			wantAction: true,
+			wantLog:    "取消旧止损失败",
+		},
+		{
+			name:       "查询止损单失败",
+			side:       "long",
+			exchange:   &fakeStopTrader{stops: withStops(95), getStopsErr: errors.New("网络错误")},
+			wantStops:  []float64{95},
+			wantAction: true,
+		},
+		{
+			name:        "没有止损单也没有记录时挂新止损",
+			side:        "long",
+			exchange:    &fakeStopTrader{},
+			wantStops:   []float64{98},
+			wantAction:  true,
+			wantSuccess: true,
+		},
+		{
+			name:        "交易所上没有止损单时按更紧的记录恢复",
+			side:        "long",
+			exchange:    &fakeStopTrader{},
+			recorded:    []logger.DecisionAction{openLong(99)},
+			wantStops:   []float64{99},
+			wantAction:  true,
+			wantSuccess: true,
+		},
+		{
+			name:        "记录的止损更松时使用新止损",
+			side:        "long",
+			exchange:    &fakeStopTrader{},
+			recorded:    []logger.DecisionAction{openLong(90)},
+			wantStops:   []float64{98},
+			wantAction:  true,
+			wantSuccess: true,
+		},
+		{
+			name:        "记录的止损已越过标记价格时使用新止损",
+			side:        "long",
+			exchange:    &fakeStopTrader{},
+			recorded:    []logger.DecisionAction{openLong(101)},
+			wantStops:   []float64{98},
+			wantAction:  true,
+			wantSuccess: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := newSafeModeTrader(t, tt.exchange, tt.recorded...)
+			pos := decision.PositionInfo{Symbol: "BTCUSDT", Side: tt.side, Quantity: 1, EntryPrice: 100, MarkPrice: 100}
+			record := &logger.DecisionRecord{}
+
+			at.tightenStopLoss(pos, 2, record)
+
+			if got := tt.exchange.stopPrices(); !equalPrices(got, tt.wantStops) {
+				t.Errorf("stops = %v, want %v", got, tt.wantStops)
+			}
+			if got := len(record.Decisions) > 0; got != tt.wantAction {
+				t.Fatalf("recorded action = %v, want %v", got, tt.wantAction)
+			}
+			if tt.wantAction && record.Decisions[0].Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (error %q)", record.Decisions[0].Success, tt.wantSuccess, record.Decisions[0].Error)
+			}
+			if tt.wantSuccess {
+				if got := record.Decisions[0].StopLoss; !equalPrices([]float64{got}, tt.wantStops) {
+					t.Errorf("recorded StopLoss = %v, want %v", got, tt.wantStops)
+				}
+			}
+			if tt.wantLog != "" && !strings.Contains(strings.Join(record.ExecutionLog, "\n"), tt.wantLog) {
+				t.Errorf("ExecutionLog = %q, want to contain %q", record.ExecutionLog, tt.wantLog)
+			}
+		})
+	}
+}
+
+// equalPrices 按0.0001精度比较价格
+func equalPrices(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if d := a[i] - b[i]; d > 1e-4 || d < -1e-4 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTightestStop(t *testing.T) {
+	tests := []struct {
+		name  string
+		stops []StopOrder
+		side  string
+		want  float64
+	}{
+		{"没有止损单", nil, "long", 0},
+		{"多仓取最高", withStops(95, 97, 96), "long", 97},
+		{"空仓取最低", withStops(105, 103, 104), "short", 103},
+		{"忽略无效价格", withStops(0, 95), "short", 95},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tightestStop(tt.stops, tt.side); got != tt.want {
+				t.Errorf("tightestStop(%v, %q) = %v, want %v", tt.stops, tt.side, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastProtection(t *testing.T) {
+	action := func(name string, stop float64, success bool) logger.DecisionAction {
+		return logger.DecisionAction{Action: name, Symbol: "BTCUSDT", StopLoss: stop, TakeProfit: stop * 2, Success: success, Timestamp: time.Now()}
+	}
+
+	tests := []struct {
+		name     string
+		side     string
+		recorded []logger.DecisionAction
+		wantStop float64
+	}{
+		{name: "没有记录", side: "long"},
+		{name: "开仓时的止损", side: "long", recorded: []logger.DecisionAction{action("open_long", 95, true)}, wantStop: 95},
+		{
+			name:     "收紧后的止损",
+			side:     "long",
+			recorded: []logger.DecisionAction{action("open_long", 95, true), action(ActionTightenStopLong, 97, true)},
+			wantStop: 97,
+		},
+		{
+			name:     "失败的收紧不算",
+			side:     "short",
+			recorded: []logger.DecisionAction{action("open_short", 105, true), action(ActionTightenStopShort, 103, false)},
+			wantStop: 105,
+		},
+		{
+			name:     "平仓之后没有开仓",
+			side:     "long",
+			recorded: []logger.DecisionAction{action("open_long", 95, true), action("close_long", 0, true)},
+		},
+		{
+			name:     "另一方向的持仓",
+			side:     "long",
+			recorded: []logger.DecisionAction{action("open_short", 105, true)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := newSafeModeTrader(t, &fakeStopTrader{}, tt.recorded...)
+
+			stop, takeProfit := at.lastProtection("BTCUSDT", tt.side)
+			if stop != tt.wantStop || takeProfit != tt.wantStop*2 {
+				t.Errorf("lastProtection() = %v, %v, want %v, %v", stop, takeProfit, tt.wantStop, tt.wantStop*2)
+			}
+		})
+	}
+}
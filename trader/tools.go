@@ -0,0 +1,289 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/mcp"
+	"slices"
+	"time"
+)
+
+// 工具参数的默认值和上限
+const (
+	defaultToolKlines    = 50
+	maxToolKlines        = 200
+	defaultToolDepth     = 20
+	maxToolDepth         = 100
+	toolHistoryCycles    = 500 // get_position_history 回看的周期数
+	maxToolHistoryTrades = 20  // get_position_history 返回的最多开平仓动作数
+)
+
+// toolKlineIntervals get_klines 支持的K线周期（Binance合约接口）
+var toolKlineIntervals = []string{"1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "6h", "8h", "12h", "1d", "3d", "1w"}
+
+// ToolsConfig 工具调用：模型在决策前可以调用工具获取额外的市场数据和历史交易
+type ToolsConfig struct {
+	Enabled  bool
+	MaxCalls int // 每个周期最多调用次数（投票的多个模型共享）
+}
+
+// newToolSession 创建本周期的工具调用会话（未启用时返回nil）
+func (at *AutoTrader) newToolSession() *mcp.ToolSession {
+	if !at.config.Tools.Enabled {
+		return nil
+	}
+	return mcp.NewToolSession([]mcp.Tool{
+		{
+			Name:        "get_klines",
+			Description: "获取币种任意周期的最近K线（开高低收和成交量，按时间正序）。Get the latest candles of a coin for any interval (OHLCV, oldest first).",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"symbol":   map[string]interface{}{"type": "string", "description": "币种，如 BTCUSDT"},
+					"interval": map[string]interface{}{"type": "string", "enum": toolKlineIntervals},
+					"n":        map[string]interface{}{"type": "integer", "description": fmt.Sprintf("K线数量（默认%d，最多%d）", defaultToolKlines, maxToolKlines)},
+				},
+				"required": []string{"symbol", "interval"},
+			},
+			Handler: toolGetKlines,
+			Shrink:  dropOldest[toolKline],
+		},
+		{
+			Name:        "get_orderbook",
+			Description: "获取币种当前的盘口深度（买盘和卖盘）。Get the current order book depth (bids and asks) of a coin.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"symbol": map[string]interface{}{"type": "string", "description": "币种，如 BTCUSDT"},
+					"depth":  map[string]interface{}{"type": "integer", "description": fmt.Sprintf("档位数（默认%d，最多%d）", defaultToolDepth, maxToolDepth)},
+				},
+				"required": []string{"symbol"},
+			},
+			Handler: toolGetOrderBook,
+			Shrink:  shrinkOrderBook,
+		},
+		{
+			Name:        "get_position_history",
+			Description: "获取本trader在该币种上的历史开平仓记录（含当时的决策理由）和交易统计。Get this trader's past opens/closes on a coin (with the reasoning at the time) and its trade statistics.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"symbol": map[string]interface{}{"type": "string", "description": "币种，如 BTCUSDT"},
+				},
+				"required": []string{"symbol"},
+			},
+			Handler: at.toolGetPositionHistory,
+			Shrink:  shrinkPositionHistory,
+		},
+	}, at.config.Tools.MaxCalls)
+}
+
+// toolPromptBudget 启用工具时从prompt的token预算中扣除工具定义和工具结果最多占用的token（预算未知时不限制）
+func toolPromptBudget(budget int, session *mcp.ToolSession) int {
+	if budget <= 0 {
+		return budget
+	}
+	return max(budget-session.ReservedTokens(), 1)
+}
+
+// toolKline get_klines 返回的一根K线
+type toolKline struct {
+	Time   string  `json:"t"` // 开盘时间（UTC，01-02 15:04）
+	Open   float64 `json:"o"`
+	High   float64 `json:"h"`
+	Low    float64 `json:"l"`
+	Close  float64 `json:"c"`
+	Volume float64 `json:"v"`
+}
+
+// toolGetKlines 工具 get_klines
+func toolGetKlines(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Symbol   string `json:"symbol"`
+		Interval string `json:"interval"`
+		N        int    `json:"n"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("参数格式错误: %w", err)
+	}
+	if args.Symbol == "" {
+		return nil, fmt.Errorf("缺少参数 symbol")
+	}
+	if !slices.Contains(toolKlineIntervals, args.Interval) {
+		return nil, fmt.Errorf("不支持的K线周期 %q，可选: %v", args.Interval, toolKlineIntervals)
+	}
+	if args.N <= 0 {
+		args.N = defaultToolKlines
+	}
+	if args.N > maxToolKlines {
+		args.N = maxToolKlines
+	}
+
+	klines, err := market.GetKlines(args.Symbol, args.Interval, args.N)
+	if err != nil {
+		return nil, fmt.Errorf("获取K线失败: %w", err)
+	}
+	result := make([]toolKline, 0, len(klines))
+	for _, k := range klines {
+		result = append(result, toolKline{
+			Time:   time.UnixMilli(k.OpenTime).UTC().Format("01-02 15:04"),
+			Open:   k.Open,
+			High:   k.High,
+			Low:    k.Low,
+			Close:  k.Close,
+			Volume: k.Volume,
+		})
+	}
+	return result, nil
+}
+
+// toolGetOrderBook 工具 get_orderbook
+func toolGetOrderBook(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Symbol string `json:"symbol"`
+		Depth  int    `json:"depth"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("参数格式错误: %w", err)
+	}
+	if args.Symbol == "" {
+		return nil, fmt.Errorf("缺少参数 symbol")
+	}
+	if args.Depth <= 0 {
+		args.Depth = defaultToolDepth
+	}
+	if args.Depth > maxToolDepth {
+		args.Depth = maxToolDepth
+	}
+	return market.GetOrderBook(args.Symbol, args.Depth)
+}
+
+// dropOldest 结果过长时去掉最早的约10%条目（列表按时间正序）
+func dropOldest[T any](result interface{}) (interface{}, bool) {
+	items, ok := result.([]T)
+	if !ok || len(items) <= 1 {
+		return result, false
+	}
+	return items[max(len(items)/10, 1):], true
+}
+
+// shrinkOrderBook 盘口过长时买卖盘各去掉离盘口最远的一档
+func shrinkOrderBook(result interface{}) (interface{}, bool) {
+	book, ok := result.(*market.OrderBook)
+	if !ok || len(book.Bids) <= 1 && len(book.Asks) <= 1 {
+		return result, false
+	}
+	shrunk := *book
+	if len(shrunk.Bids) > 1 {
+		shrunk.Bids = shrunk.Bids[:len(shrunk.Bids)-1]
+	}
+	if len(shrunk.Asks) > 1 {
+		shrunk.Asks = shrunk.Asks[:len(shrunk.Asks)-1]
+	}
+	return &shrunk, true
+}
+
+// toolPositionAction get_position_history 返回的一次开平仓
+type toolPositionAction struct {
+	Time      string  `json:"time"`
+	Action    string  `json:"action"`
+	Price     float64 `json:"price"`
+	Quantity  float64 `json:"quantity"`
+	Leverage  int     `json:"leverage,omitempty"`
+	StopLoss  float64 `json:"stop_loss,omitempty"`
+	Reasoning string  `json:"reasoning,omitempty"`
+}
+
+// toolPositionHistory get_position_history 的返回结果
+type toolPositionHistory struct {
+	Symbol  string                    `json:"symbol"`
+	Actions []toolPositionAction      `json:"actions"` // 按时间正序
+	Stats   *logger.SymbolPerformance `json:"stats,omitempty"`
+}
+
+// shrinkPositionHistory 历史记录过长时去掉最早的开平仓动作
+func shrinkPositionHistory(result interface{}) (interface{}, bool) {
+	history, ok := result.(*toolPositionHistory)
+	if !ok {
+		return result, false
+	}
+	actions, ok := dropOldest[toolPositionAction](history.Actions)
+	if !ok {
+		return result, false
+	}
+	shrunk := *history
+	shrunk.Actions = actions.([]toolPositionAction)
+	return &shrunk, true
+}
+
+// toolGetPositionHistory 工具 get_position_history
+func (at *AutoTrader) toolGetPositionHistory(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Symbol string `json:"symbol"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("参数格式错误: %w", err)
+	}
+	if args.Symbol == "" {
+		return nil, fmt.Errorf("缺少参数 symbol")
+	}
+	symbol := market.Normalize(args.Symbol)
+
+	actions, err := at.decisionLogger.GetSymbolActions(symbol, toolHistoryCycles)
+	if err != nil {
+		return nil, err
+	}
+	if len(actions) > maxToolHistoryTrades {
+		actions = actions[len(actions)-maxToolHistoryTrades:]
+	}
+	history := make([]toolPositionAction, 0, len(actions))
+	for _, a := range actions {
+		history = append(history, toolPositionAction{
+			Time:      a.Timestamp.Format("01-02 15:04"),
+			Action:    a.Action,
+			Price:     a.Price,
+			Quantity:  a.Quantity,
+			Leverage:  a.Leverage,
+			StopLoss:  a.StopLoss,
+			Reasoning: a.Reasoning,
+		})
+	}
+
+	result := &toolPositionHistory{Symbol: symbol, Actions: history}
+	if performance, err := at.decisionLogger.AnalyzePerformance(toolHistoryCycles); err == nil {
+		result.Stats = performance.SymbolStats[symbol]
+	}
+	return result, nil
+}
+
+// toLoggerToolCalls 转换工具调用记录
+func toLoggerToolCalls(calls []mcp.ToolCall) []logger.ToolCall {
+	var result []logger.ToolCall
+	for _, c := range calls {
+		result = append(result, logger.ToolCall{
+			Model:      c.Model,
+			Name:       c.Name,
+			Arguments:  c.Arguments,
+			Result:     c.Result,
+			Error:      c.Error,
+			Timestamp:  c.Timestamp,
+			DurationMs: c.DurationMs,
+		})
+	}
+	return result
+}
+
+// toLoggerTranscripts 转换工具对话记录
+func toLoggerTranscripts(transcripts []mcp.Transcript) []logger.ToolTranscript {
+	var result []logger.ToolTranscript
+	for _, t := range transcripts {
+		result = append(result, logger.ToolTranscript{
+			Model:    t.Model,
+			Tools:    t.Tools,
+			Messages: t.Messages,
+		})
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"nofx/config"
+	"nofx/market"
+	"os"
+)
+
+// runValidateConfig 校验配置: nofx validate-config [-offline] [-schema] [-set 路径=值] [配置文件]
+// 检查未知字段、类型、取值和密钥引用，并（除非 -offline）检查币种是否为可交易的合约；配置有效时返回nil
+func runValidateConfig(args []string) error {
+	fs := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	offline := fs.Bool("offline", false, "不联网检查币种")
+	printSchema := fs.Bool("schema", false, "打印配置文件的JSON Schema并退出")
+	var sets stringList
+	fs.Var(&sets, "set", "覆盖配置项，格式 路径=值，可重复")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *printSchema {
+		data, err := config.Schema()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	configFile := "config.json"
+	if fs.NArg() > 0 {
+		configFile = fs.Arg(0)
+	}
+	if err := config.SetFlagOverrides(sets); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		var ve *config.ValidationError
+		if errors.As(err, &ve) {
+			printProblems(configFile, ve.Problems)
+			return fmt.Errorf("配置无效")
+		}
+		return err
+	}
+
+	if !*offline {
+		symbols, err := market.GetFuturesSymbols()
+		if err != nil {
+			return fmt.Errorf("无法检查币种（可使用 -offline 跳过）: %w", err)
+		}
+		if err := cfg.ValidateSymbols(symbols); err != nil {
+			var ve *config.ValidationError
+			if errors.As(err, &ve) {
+				printProblems(configFile, ve.Problems)
+				return fmt.Errorf("配置无效")
+			}
+			return err
+		}
+	}
+
+	fmt.Printf("✓ %s 有效（%d 个trader）\n", configFile, len(cfg.Traders))
+	return nil
+}
+
+// printProblems 逐条输出配置问题
+func printProblems(configFile string, problems []config.Problem) {
+	fmt.Fprintf(os.Stderr, "❌ %s 存在 %d 个问题:\n", configFile, len(problems))
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "  • %s\n", p)
+	}
+}